@@ -0,0 +1,174 @@
+package bloom
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Atomic is a concurrency-safe Bloom filter that takes no locks. Bits only
+// ever turn on, so Add sets them with atomic OR operations on the words and
+// MightContain reads them with atomic loads: the two never block each
+// other, and writers contend only when they touch the same word. Under
+// many writers this scales far beyond SafeBloom, whose single RWMutex
+// serializes every Add.
+//
+// Each Add is atomic per bit, not per key: a MightContain running
+// concurrently with the Add of the same key may see some of its bits and
+// report false. Once Add has returned, every later MightContain reports
+// true until the next Reset.
+//
+// Atomic supports the operations that make sense without a lock; use
+// Snapshot for anything else, such as encoding, merging or Stats.
+//
+// The zero value behaves like a zero-value BloomFilter: it contains
+// nothing and Add panics with ErrUninitialized.
+type Atomic struct {
+	bf atomic.Pointer[BloomFilter]
+}
+
+// NewAtomic creates a lock-free Bloom filter using explicit m and k. It
+// panics with the error TryNew would return.
+func NewAtomic(m, k uint64) *Atomic {
+	a, err := NewAtomicWithOptions(0, 0, WithExplicitSize(m, k))
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// NewAtomicWithEstimates creates a lock-free Bloom filter using n and
+// fpRate. It panics with the error TryNewWithEstimates would return.
+func NewAtomicWithEstimates(n uint64, fpRate float64) *Atomic {
+	a, err := NewAtomicWithOptions(n, fpRate)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// NewAtomicWithOptions is NewWithOptions for a lock-free filter.
+// WithOverfillCallback is rejected with ErrInvalidOption: the callback
+// cannot be run exactly once without a lock. So is WithLazyReset.
+func NewAtomicWithOptions(n uint64, fpRate float64, opts ...Option) (*Atomic, error) {
+	bf, err := NewWithOptions(n, fpRate, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if bf.onOverfill != nil {
+		return nil, fmt.Errorf("%w: Atomic does not support WithOverfillCallback", ErrInvalidOption)
+	}
+	if err := rejectLazy(bf, "Atomic"); err != nil {
+		return nil, err
+	}
+	a := &Atomic{}
+	a.bf.Store(bf)
+	return a, nil
+}
+
+// Add inserts data. It panics with ErrUninitialized on a zero-value
+// Atomic.
+func (a *Atomic) Add(data []byte) {
+	bf := a.bf.Load()
+	if !bf.initialized() {
+		panic(ErrUninitialized)
+	}
+	bf.addAtomic(data)
+}
+
+// AddString inserts s without copying it. See BloomFilter.AddString.
+func (a *Atomic) AddString(s string) {
+	a.Add(stringBytes(s))
+}
+
+// MightContain reports whether data might be in the filter.
+func (a *Atomic) MightContain(data []byte) bool {
+	bf := a.bf.Load()
+	if !bf.initialized() {
+		return false
+	}
+	h := bf.hashes(data)
+	for i := uint64(0); i < bf.k; i++ {
+		pos := bf.location(h, i)
+		if atomic.LoadUint64(bf.word(pos/64))&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MightContainString checks membership of s without copying it. See
+// BloomFilter.MightContainString.
+func (a *Atomic) MightContainString(s string) bool {
+	return a.MightContain(stringBytes(s))
+}
+
+// Count returns the number of Add calls since construction or the last
+// Reset.
+func (a *Atomic) Count() uint64 {
+	bf := a.bf.Load()
+	if bf == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&bf.inserts)
+}
+
+// Params returns the filter's parameters. See BloomFilter.Params.
+func (a *Atomic) Params() Params {
+	return a.bf.Load().geometry().Params()
+}
+
+// Reset empties the filter by swapping in a new, empty one of the same
+// geometry; the old words are left to the garbage collector rather than
+// cleared under the feet of concurrent callers. Reset is not linearizable
+// with concurrent Adds: an Add that loaded the old filter before the swap
+// lands in it and is lost, so keys added concurrently with Reset may or
+// may not be present afterwards. Keys added after Reset returns always
+// are.
+func (a *Atomic) Reset() {
+	bf := a.bf.Load()
+	if !bf.initialized() {
+		return
+	}
+	fresh := bf.geometry()
+	fresh.storage = newStorage(bf.wordCount(), bf.chunkShift)
+	a.bf.Store(fresh)
+}
+
+// Snapshot returns a private copy of the filter, or nil for a zero-value
+// Atomic. The words are copied one at a time with atomic loads while Adds
+// continue, so the copy holds every key added before Snapshot was called
+// and possibly some bits of keys added during it.
+func (a *Atomic) Snapshot() *BloomFilter {
+	bf := a.bf.Load()
+	if bf == nil {
+		return nil
+	}
+	c := bf.geometry()
+	c.storage = newStorage(bf.wordCount(), bf.chunkShift)
+	c.inserts = atomic.LoadUint64(&bf.inserts)
+	eachSpanPair(&c.storage, &bf.storage, func(_ int, dst, src []uint64) {
+		for i := range src {
+			dst[i] = atomic.LoadUint64(&src[i])
+		}
+	})
+	c.setBits = c.onesCount()
+	return c
+}
+
+// geometry returns a filter with bf's configuration and no storage. It
+// reads only fields that never change after construction, so it is safe
+// while other goroutines update bf atomically.
+func (bf *BloomFilter) geometry() *BloomFilter {
+	if bf == nil {
+		return nil
+	}
+	return &BloomFilter{
+		m:         bf.m,
+		k:         bf.k,
+		scheme:    bf.scheme,
+		hasher:    bf.hasher,
+		seed:      bf.seed,
+		capacity:  bf.capacity,
+		threshold: bf.threshold,
+	}
+}
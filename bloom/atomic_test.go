@@ -0,0 +1,146 @@
+package bloom
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestAtomic_ConcurrentAddAndQuery(t *testing.T) {
+	const writers, perWriter = 32, 2000
+	a := NewAtomicWithEstimates(writers*perWriter, 0.01)
+	ref := NewWithEstimates(writers*perWriter, 0.01)
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		keys := randomKeys("atomic-"+strconv.Itoa(w), perWriter, uint64(w))
+		ref.AddAll(keys)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for _, k := range keys {
+				a.Add(k)
+				if !a.MightContain(k) {
+					t.Errorf("false negative for %q right after Add", k)
+					return
+				}
+			}
+		}()
+		go func() {
+			// Readers never block on writers; their answers are only
+			// checked once the writers are done.
+			defer wg.Done()
+			for _, k := range keys {
+				a.MightContain(k)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := a.Count(); got != writers*perWriter {
+		t.Fatalf("Count() = %d, want %d", got, writers*perWriter)
+	}
+	snap := a.Snapshot()
+	if !snap.Equal(ref) || snap.setBits != ref.setBits {
+		t.Fatal("concurrently built filter differs from a sequential one")
+	}
+}
+
+func TestAtomic_Reset(t *testing.T) {
+	a := NewAtomic(4096, 4)
+	a.AddString("before")
+	old := a.Snapshot()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				a.AddString("during-" + strconv.Itoa(i))
+				a.MightContainString("before")
+			}
+		}()
+	}
+	a.Reset()
+	wg.Wait()
+
+	a.Reset()
+	if a.MightContainString("before") || a.Count() != 0 || a.Snapshot().setBits != 0 {
+		t.Fatal("Reset left keys behind")
+	}
+	a.AddString("after")
+	if !a.MightContainString("after") {
+		t.Fatal("key added after Reset is missing")
+	}
+	if !old.MightContainString("before") || old.MightContainString("after") {
+		t.Fatal("Snapshot shares storage with the filter")
+	}
+	if a.Params() != old.Params() {
+		t.Fatalf("Reset changed the parameters: %+v, was %+v", a.Params(), old.Params())
+	}
+}
+
+func TestAtomic_ZeroValueAndOptions(t *testing.T) {
+	var a Atomic
+	if a.MightContain([]byte("x")) || a.Count() != 0 || a.Snapshot() != nil {
+		t.Fatal("zero-value Atomic is not empty")
+	}
+	a.Reset()
+	expectPanic(t, ErrUninitialized, func() { a.Add([]byte("x")) })
+
+	if _, err := NewAtomicWithOptions(100, 0.01, WithOverfillCallback(func(uint64, uint64) {})); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("overfill callback: %v", err)
+	}
+	s, err := NewAtomicWithOptions(100, 0.01, WithSeed(5), WithEnhancedDoubleHashing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddString("k")
+	bf, _ := NewWithOptions(100, 0.01, WithSeed(5), WithEnhancedDoubleHashing())
+	bf.AddString("k")
+	if !s.Snapshot().Equal(bf) {
+		t.Fatal("Atomic ignored its options")
+	}
+}
+
+// benchmarkConcurrent runs b.N mixed operations, one Add to three
+// MightContain calls, spread over g goroutines.
+func benchmarkConcurrent(b *testing.B, g int, add func([]byte), contains func([]byte) bool) {
+	keys := randomKeys("bench", 1<<16, 1)
+	b.ResetTimer()
+	var wg sync.WaitGroup
+	for w := 0; w < g; w++ {
+		n := b.N / g
+		if w < b.N%g {
+			n++
+		}
+		wg.Add(1)
+		go func(w, n int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				k := keys[(w*7919+i)&(len(keys)-1)]
+				if i%4 == 0 {
+					add(k)
+				} else {
+					contains(k)
+				}
+			}
+		}(w, n)
+	}
+	wg.Wait()
+}
+
+func BenchmarkConcurrent(b *testing.B) {
+	for _, g := range []int{1, 8, 32} {
+		b.Run("SafeBloom/goroutines="+strconv.Itoa(g), func(b *testing.B) {
+			s := NewSafeWithEstimates(1<<20, 0.01)
+			benchmarkConcurrent(b, g, s.Add, s.MightContain)
+		})
+		b.Run("Atomic/goroutines="+strconv.Itoa(g), func(b *testing.B) {
+			a := NewAtomicWithEstimates(1<<20, 0.01)
+			benchmarkConcurrent(b, g, a.Add, a.MightContain)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package bloom
+
+// batchChunk is the number of keys hashed together before their bits are
+// applied. Hashing a chunk first and then touching memory lets the CPU
+// overlap the cache misses of independent keys instead of serializing
+// hash-then-load for each one; the hashes of a chunk fit on the stack.
+const batchChunk = 64
+
+// AddAll inserts every key. It is equivalent to calling Add for each key
+// in order, and faster for large batches on filters that do not fit in
+// cache.
+// It panics with ErrUninitialized on a zero-value or nil filter.
+func (bf *BloomFilter) AddAll(keys [][]byte) {
+	if !bf.initialized() {
+		panic(ErrUninitialized)
+	}
+
+	var hs [batchChunk]baseHashes
+	for len(keys) > 0 {
+		chunk := keys[:min(len(keys), batchChunk)]
+		keys = keys[len(chunk):]
+		for i, key := range chunk {
+			hs[i] = bf.hashes(key)
+		}
+		for _, h := range hs[:len(chunk)] {
+			bf.addHashes(h)
+		}
+	}
+}
+
+// MightContainBatch reports for each key whether it might be in the
+// filter, exactly as MightContain would. The answers are written to
+// results, resliced to len(keys) if its capacity allows and freshly
+// allocated otherwise, and the slice holding them is returned.
+// A zero-value or nil filter contains nothing.
+func (bf *BloomFilter) MightContainBatch(keys [][]byte, results []bool) []bool {
+	if cap(results) >= len(keys) {
+		results = results[:len(keys)]
+	} else {
+		results = make([]bool, len(keys))
+	}
+	if !bf.initialized() {
+		clear(results)
+		return results
+	}
+
+	var hs [batchChunk]baseHashes
+	for done := 0; done < len(keys); {
+		chunk := keys[done:min(len(keys), done+batchChunk)]
+		for i, key := range chunk {
+			hs[i] = bf.hashes(key)
+		}
+		for i, h := range hs[:len(chunk)] {
+			results[done+i] = bf.containsHashes(h)
+		}
+		done += len(chunk)
+	}
+	return results
+}
+
+// safeBatchChunk is the number of keys SafeBloom's batch methods handle
+// per lock acquisition: enough to amortize the lock over many keys, few
+// enough that a huge batch cannot hold off other callers for long.
+const safeBatchChunk = 4096
+
+// AddBatch inserts every key, taking the write lock once per chunk of
+// keys rather than once per key. It is equivalent to calling Add for each
+// key in order; other goroutines may run between chunks, so a concurrent
+// reader can see part of the batch. It panics with ErrUninitialized on a
+// zero-value SafeBloom. See BloomFilter.AddAll.
+func (s *SafeBloom) AddBatch(keys [][]byte) {
+	for len(keys) > 0 {
+		chunk := keys[:min(len(keys), safeBatchChunk)]
+		keys = keys[len(chunk):]
+		s.addChunk(chunk)
+	}
+}
+
+func (s *SafeBloom) addChunk(keys [][]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bf.AddAll(keys)
+}
+
+// MightContainBatch reports for each key whether it might be in the
+// filter, exactly as MightContain would, taking the read lock once per
+// chunk of keys. results is reused as in BloomFilter.MightContainBatch.
+func (s *SafeBloom) MightContainBatch(keys [][]byte, results []bool) []bool {
+	if cap(results) >= len(keys) {
+		results = results[:len(keys)]
+	} else {
+		results = make([]bool, len(keys))
+	}
+	for done := 0; done < len(keys); {
+		end := min(len(keys), done+safeBatchChunk)
+		s.containsChunk(keys[done:end], results[done:end])
+		done = end
+	}
+	return results
+}
+
+func (s *SafeBloom) containsChunk(keys [][]byte, results []bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.bf.MightContainBatch(keys, results)
+}
@@ -0,0 +1,162 @@
+package bloom
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestBatch_MatchesSingleCalls(t *testing.T) {
+	keys := randomKeys("batch", 1000, 11)
+	keys = append(keys, keys[:100]...) // duplicates within one batch
+
+	single, batched := NewWithEstimates(500, 0.01), NewWithEstimates(500, 0.01)
+	for _, key := range keys[:500] {
+		single.Add(key)
+	}
+	batched.AddAll(keys[:500])
+	if !batched.Equal(single) {
+		t.Fatal("AddAll set different bits than Add")
+	}
+
+	results := make([]bool, 0, len(keys))
+	got := batched.MightContainBatch(keys, results)
+	if len(got) != len(keys) || &got[0] != &results[:1][0] {
+		t.Fatal("MightContainBatch did not reuse a large enough results slice")
+	}
+	for i, key := range keys {
+		if got[i] != single.MightContain(key) {
+			t.Fatalf("key %d: batch says %v, MightContain says %v", i, got[i], !got[i])
+		}
+	}
+
+	small := make([]bool, 3)
+	if got := batched.MightContainBatch(keys[:10], small); len(got) != 10 {
+		t.Fatalf("got %d results, want 10", len(got))
+	}
+	if got := batched.MightContainBatch(nil, nil); len(got) != 0 {
+		t.Fatalf("got %d results for no keys", len(got))
+	}
+	stale := []bool{true, true}
+	if got := new(BloomFilter).MightContainBatch(keys[:2], stale); got[0] || got[1] {
+		t.Fatal("an empty filter reported keys present")
+	}
+	expectPanic(t, ErrUninitialized, func() { new(BloomFilter).AddAll(keys) })
+}
+
+func TestSafeBloom_Batch(t *testing.T) {
+	// More keys than one chunk, so the lock is taken several times.
+	keys := randomKeys("safe-batch", 3*safeBatchChunk+17, 12)
+	single, batched := NewWithEstimates(uint64(len(keys)), 0.01), NewSafeWithEstimates(uint64(len(keys)), 0.01)
+	half := keys[:len(keys)/2]
+	for _, key := range half {
+		single.Add(key)
+	}
+	batched.AddBatch(half)
+	if !batched.Equal(single) || batched.Count() != single.Count() {
+		t.Fatal("AddBatch differs from Add")
+	}
+	got := batched.MightContainBatch(keys, nil)
+	for i, key := range keys {
+		if got[i] != single.MightContain(key) {
+			t.Fatalf("key %d: batch says %v, MightContain says %v", i, got[i], !got[i])
+		}
+	}
+
+	var zero SafeBloom
+	if got := zero.MightContainBatch(keys[:2], []bool{true, true}); got[0] || got[1] {
+		t.Fatal("an empty SafeBloom reported keys present")
+	}
+	expectPanic(t, ErrUninitialized, func() { zero.AddBatch(keys[:1]) })
+	zero.Swap(NewWithEstimates(10, 0.01)) // the lock was released by the panic
+}
+
+func TestSafeBloom_BatchConcurrent(t *testing.T) {
+	const writers, batches = 4, 8
+	s := NewSafeWithEstimates(writers*batches*safeBatchChunk, 0.01)
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		keys := randomKeys("writer-"+strconv.Itoa(w), batches*safeBatchChunk, uint64(w))
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for b := 0; b < batches; b++ {
+				batch := keys[b*safeBatchChunk : (b+1)*safeBatchChunk]
+				s.AddBatch(batch)
+				for _, ok := range s.MightContainBatch(batch, nil) {
+					if !ok {
+						t.Error("false negative right after AddBatch")
+						return
+					}
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for _, key := range keys {
+				s.MightContain(key)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := s.Count(); got != writers*batches*safeBatchChunk {
+		t.Fatalf("Count() = %d, want %d", got, writers*batches*safeBatchChunk)
+	}
+}
+
+func BenchmarkSafeBloom_Batch(b *testing.B) {
+	keys := benchmarkKeys(100_000)
+	s := NewSafeWithEstimates(10_000_000, 0.01)
+	b.Run("Add/loop", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, key := range keys {
+				s.Add(key)
+			}
+		}
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/key")
+	})
+	b.Run("Add/batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			s.AddBatch(keys)
+		}
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/key")
+	})
+}
+
+func BenchmarkBatch(b *testing.B) {
+	for _, n := range []int{1_000, 100_000} {
+		keys := benchmarkKeys(n)
+		// A filter well beyond cache size, as in the ingest path.
+		bf := NewWithEstimates(10_000_000, 0.01)
+		results := make([]bool, n)
+		name := strconv.Itoa(n)
+		b.Run("Add/loop/"+name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, key := range keys {
+					bf.Add(key)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/key")
+		})
+		b.Run("Add/batch/"+name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.AddAll(keys)
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/key")
+		})
+		b.Run("MightContain/loop/"+name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for j, key := range keys {
+					results[j] = bf.MightContain(key)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/key")
+		})
+		b.Run("MightContain/batch/"+name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				results = bf.MightContainBatch(keys, results)
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/key")
+		})
+	}
+}
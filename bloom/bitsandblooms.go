@@ -0,0 +1,104 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// bits-and-blooms serialized form (github.com/bits-and-blooms/bloom/v3
+// BloomFilter.WriteTo, also used by its MarshalBinary and GobEncode), all
+// integers big-endian:
+//
+//	m        uint64  no. of bits
+//	k        uint64  no. of hash functions
+//	length   uint64  bitset length in bits, equal to m
+//	data     (length+63)/64 * uint64
+//
+// Bit i lives in data[i/64] at 1<<(i%64), the same layout this package uses.
+const babHeaderSize = 8 + 8 + 8
+
+// NewBitsAndBloomsWithEstimates creates a filter sized and hashed like
+// bits-and-blooms' bloom.NewWithEstimates(n, fpRate), so it can be exported
+// with ExportBitsAndBlooms and queried by that library.
+//
+// This panics if n == 0 or fpRate is not in (0, 1).
+func NewBitsAndBloomsWithEstimates(n uint64, fpRate float64) *BloomFilter {
+	bf, err := NewWithOptions(n, fpRate, withScheme(schemeBitsAndBlooms))
+	if err != nil {
+		panic(err)
+	}
+	return bf
+}
+
+// ImportBitsAndBlooms decodes a filter written by bits-and-blooms'
+// BloomFilter.WriteTo or MarshalBinary. The result hashes like that
+// library, so membership answers match the ones it gave for the same keys.
+func ImportBitsAndBlooms(r io.Reader) (*BloomFilter, error) {
+	var hbuf [babHeaderSize]byte
+	if _, err := io.ReadFull(r, hbuf[:]); err != nil {
+		return nil, fmt.Errorf("%w: short bits-and-blooms header", ErrCorrupt)
+	}
+	m := binary.BigEndian.Uint64(hbuf[0:])
+	k := binary.BigEndian.Uint64(hbuf[8:])
+	length := binary.BigEndian.Uint64(hbuf[16:])
+	if m == 0 || k == 0 || length != m {
+		return nil, fmt.Errorf("%w: bits-and-blooms m=%d k=%d bitset length=%d", ErrCorrupt, m, k, length)
+	}
+
+	count := wordsFor(m)
+	words := make([]uint64, 0, min(count, streamChunkWords))
+	buf := make([]byte, streamChunkWords*8)
+	for remaining := count; remaining > 0; {
+		chunk := min(remaining, streamChunkWords)
+		if _, err := io.ReadFull(r, buf[:chunk*8]); err != nil {
+			return nil, fmt.Errorf("%w: bits-and-blooms stream ended %d words short", ErrCorrupt, remaining)
+		}
+		for i := uint64(0); i < chunk; i++ {
+			words = append(words, binary.BigEndian.Uint64(buf[i*8:]))
+		}
+		remaining -= chunk
+	}
+
+	h := header{version: encodingVersion, m: m, k: k, words: count, scheme: schemeBitsAndBlooms}
+	return newFromHeader(h, contiguous(words))
+}
+
+// ExportBitsAndBlooms writes the filter in bits-and-blooms' WriteTo form,
+// readable with that library's ReadFrom or UnmarshalBinary. Only filters
+// that hash like bits-and-blooms (from ImportBitsAndBlooms or
+// NewBitsAndBloomsWithEstimates) can be exported; for any other filter the
+// library would compute different positions.
+func (bf *BloomFilter) ExportBitsAndBlooms(w io.Writer) error {
+	if !bf.initialized() {
+		return ErrUninitialized
+	}
+	if bf.scheme != schemeBitsAndBlooms {
+		return fmt.Errorf("%w: probe scheme %s is not bits-and-blooms hashing", ErrUnsupportedFormat, bf.scheme)
+	}
+
+	buf := make([]byte, 0, streamChunkWords*8)
+	buf = binary.BigEndian.AppendUint64(buf, bf.m)
+	buf = binary.BigEndian.AppendUint64(buf, bf.k)
+	buf = binary.BigEndian.AppendUint64(buf, bf.m)
+	_, err := bf.writeWords(w, buf, binary.BigEndian)
+	return err
+}
+
+// bitsAndBloomsHashes mirrors bits-and-blooms' baseHashes: murmur3 x64 128
+// of data, followed by murmur3 x64 128 of data with a 0x01 byte appended.
+func bitsAndBloomsHashes(data []byte) baseHashes {
+	h1, h2 := murmur3x64_128(data, 0)
+
+	var stack [64]byte
+	extended := append(append(stack[:0], data...), 1)
+	h3, h4 := murmur3x64_128(extended, 0)
+	return baseHashes{h1, h2, h3, h4}
+}
+
+// bitsAndBloomsLocation mirrors bits-and-blooms' location: enhanced double
+// hashing that alternates between the base hashes, reduced mod m by the
+// caller.
+func bitsAndBloomsLocation(h baseHashes, i uint64) uint64 {
+	return h[i%2] + i*h[2+((i+i%2)%4)/2]
+}
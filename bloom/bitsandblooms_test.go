@@ -0,0 +1,141 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testdata/bitsandblooms_v3_n100_p001.bin is the WriteTo output of
+// bits-and-blooms' bloom.NewWithEstimates(100, 0.01) (m=959, k=7) after
+// adding "key-0" through "key-99", written by testdata/bitsandblooms/main.go
+// against github.com/bits-and-blooms/bloom/v3 v3.7.1. Of "key-100" through
+// "key-10099", exactly 96 are false positives as reported by the library.
+const babGolden = "testdata/bitsandblooms_v3_n100_p001.bin"
+
+// testdata/bitsandblooms_v3_n1000_p0001_varied.bin is written by the same
+// program from bloom.NewWithEstimates(1000, 0.001) (m=14378, k=10) and keys
+// 0 to 47 bytes long, covering murmur3's blocks and every tail length. Of
+// the next 10000 keys, the library reports 15 false positives.
+const babGoldenVaried = "testdata/bitsandblooms_v3_n1000_p0001_varied.bin"
+
+// babVariedKey is variedKey from testdata/bitsandblooms/main.go.
+func babVariedKey(i int) []byte {
+	return []byte(strings.Repeat("v", i%40) + strconv.Itoa(i))
+}
+
+func TestImportBitsAndBlooms_Golden(t *testing.T) {
+	golden, err := os.ReadFile(babGolden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf, err := ImportBitsAndBlooms(bytes.NewReader(golden))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bf.m != 959 || bf.k != 7 {
+		t.Fatalf("got %s, want m=959 k=7", bf.Info())
+	}
+	for i := 0; i < 100; i++ {
+		if !bf.MightContain([]byte("key-" + strconv.Itoa(i))) {
+			t.Fatalf("false negative for key-%d", i)
+		}
+	}
+	falsePositives := 0
+	for i := 100; i < 10100; i++ {
+		if bf.MightContain([]byte("key-" + strconv.Itoa(i))) {
+			falsePositives++
+		}
+	}
+	if falsePositives != 96 {
+		t.Fatalf("%d false positives, want 96 as reported by bits-and-blooms", falsePositives)
+	}
+
+	var buf bytes.Buffer
+	if err := bf.ExportBitsAndBlooms(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Fatal("re-export differs from the golden file")
+	}
+
+	built := NewBitsAndBloomsWithEstimates(100, 0.01)
+	for i := 0; i < 100; i++ {
+		built.Add([]byte("key-" + strconv.Itoa(i)))
+	}
+	buf.Reset()
+	if err := built.ExportBitsAndBlooms(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Fatal("filter built with bits-and-blooms hashing differs from the golden file")
+	}
+}
+
+func TestImportBitsAndBlooms_GoldenVariedKeys(t *testing.T) {
+	golden, err := os.ReadFile(babGoldenVaried)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf, err := ImportBitsAndBlooms(bytes.NewReader(golden))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bf.m != 14378 || bf.k != 10 {
+		t.Fatalf("got %s, want m=14378 k=10", bf.Info())
+	}
+	for i := 0; i < 1000; i++ {
+		if !bf.MightContain(babVariedKey(i)) {
+			t.Fatalf("false negative for key %d", i)
+		}
+	}
+	falsePositives := 0
+	for i := 1000; i < 11000; i++ {
+		if bf.MightContain(babVariedKey(i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives != 15 {
+		t.Fatalf("%d false positives, want 15 as reported by bits-and-blooms", falsePositives)
+	}
+
+	built := NewBitsAndBloomsWithEstimates(1000, 0.001)
+	for i := 0; i < 1000; i++ {
+		built.Add(babVariedKey(i))
+	}
+	var buf bytes.Buffer
+	if err := built.ExportBitsAndBlooms(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Fatal("filter built with bits-and-blooms hashing differs from the golden file")
+	}
+}
+
+func TestImportBitsAndBlooms_Rejects(t *testing.T) {
+	golden, err := os.ReadFile(babGolden)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mismatch := append([]byte(nil), golden...)
+	binary.BigEndian.PutUint64(mismatch[16:], 960)
+	bad := map[string][]byte{
+		"short header": golden[:10],
+		"truncated":    golden[:len(golden)-1],
+		"length":       mismatch,
+	}
+	for name, data := range bad {
+		if _, err := ImportBitsAndBlooms(bytes.NewReader(data)); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: expected ErrCorrupt, got %v", name, err)
+		}
+	}
+
+	if err := New(64, 3).ExportBitsAndBlooms(&bytes.Buffer{}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("expected ErrUnsupportedFormat for an fnv filter, got %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"unsafe"
+)
+
+// blockedBlockBits is the size of one Blocked block: a 64-byte cache line.
+const blockedBlockBits = 512
+
+// blockedBlock is one cache line of a Blocked filter.
+type blockedBlock [blockedBlockBits / 64]uint64
+
+// Blocked is a blocked Bloom filter (Putze et al., 2007). The first hash
+// picks a 64-byte block and all k probes fall inside it, so Add and
+// MightContain touch exactly one cache line per key instead of k. The
+// block array is cache-line aligned.
+//
+// Confining a key to one block raises the false positive rate, because
+// blocks fill unevenly. NewBlocked compensates by growing m until the
+// predicted rate meets the target: about 6% more memory than a standard
+// filter at 1%, and 10% at 0.1%.
+//
+// Note: This type is not safe for concurrent use without external locking.
+type Blocked struct {
+	k       uint64
+	blocks  []blockedBlock // aligned view into the allocation
+	inserts uint64
+}
+
+// NewBlocked creates a blocked filter for n items at fpRate, sized so
+// that its predicted false positive rate, blocking included, is at most
+// fpRate. It panics if n == 0 or fpRate is not in (0, 1).
+func NewBlocked(n uint64, fpRate float64) *Blocked {
+	m, k := EstimateParameters(n, fpRate)
+	k = min(k, blockedBlockBits)
+	blocks := (m + blockedBlockBits - 1) / blockedBlockBits
+	for blockedFalsePositiveRate(blocks, k, n) > fpRate {
+		blocks += max(blocks/32, 1)
+	}
+	return newBlocked(blocks, k)
+}
+
+// NewBlockedWithSize creates a blocked filter with m bits, rounded up to
+// a whole number of 512-bit blocks, and k hash functions (at most 512).
+// m and k ==> must be >0.
+func NewBlockedWithSize(m, k uint64) *Blocked {
+	if m == 0 {
+		panic("bloom: m (no. of bits) must be > 0")
+	}
+	if k == 0 || k > blockedBlockBits {
+		panic("bloom: k (no. of hash fucntions) must be in [1, 512]")
+	}
+	return newBlocked((m+blockedBlockBits-1)/blockedBlockBits, k)
+}
+
+func newBlocked(blocks, k uint64) *Blocked {
+	// Over-allocate by one block and start at the first cache-line
+	// boundary.
+	raw := make([]blockedBlock, blocks+1)
+	skip := (64 - uintptr(unsafe.Pointer(&raw[0]))%64) % 64 / 8
+	words := unsafe.Slice((*uint64)(unsafe.Pointer(&raw[0])), len(raw)*8)[skip:]
+	aligned := unsafe.Slice((*blockedBlock)(unsafe.Pointer(&words[0])), blocks)
+	return &Blocked{k: k, blocks: aligned}
+}
+
+// blockedFalsePositiveRate predicts the false positive rate of a blocked
+// filter holding n keys. The keys per block follow a Poisson distribution
+// with mean λ = n/blocks, and a block holding j keys gives a false
+// positive with probability (1 - (1 - 1/512)^(jk))^k.
+func blockedFalsePositiveRate(blocks, k, n uint64) float64 {
+	lambda := float64(n) / float64(blocks)
+	miss := math.Log1p(-1.0 / blockedBlockBits)
+	var rate float64
+	limit := int(lambda + 10*math.Sqrt(lambda) + 20)
+	logPMF := -lambda // log P(j = 0)
+	for j := 0; j <= limit; j++ {
+		if j > 0 {
+			logPMF += math.Log(lambda) - math.Log(float64(j))
+		}
+		fill := -math.Expm1(float64(j) * float64(k) * miss)
+		rate += math.Exp(logPMF) * math.Pow(fill, float64(k))
+	}
+	return rate
+}
+
+// Add inserts data into the filter.
+// It panics with ErrUninitialized on a zero-value or nil filter.
+func (b *Blocked) Add(data []byte) {
+	if b == nil || len(b.blocks) == 0 {
+		panic(ErrUninitialized)
+	}
+	block, seed := b.probe(data)
+	h := seed
+	for i := uint64(0); i < b.k; i++ {
+		var pos uint64
+		pos, h = nextBlockedPosition(seed, h, i)
+		block[pos/64] |= 1 << (pos % 64)
+	}
+	b.inserts++
+}
+
+// MightContain checks if data might be in the filter.
+// Returns false -> definitely not present.
+// Returns true  -> might be present (subject to false positives).
+// A zero-value or nil filter contains nothing.
+func (b *Blocked) MightContain(data []byte) bool {
+	if b == nil || len(b.blocks) == 0 {
+		return false
+	}
+	block, seed := b.probe(data)
+	h := seed
+	for i := uint64(0); i < b.k; i++ {
+		var pos uint64
+		pos, h = nextBlockedPosition(seed, h, i)
+		if block[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// probe returns data's block and the hash its in-block positions are
+// drawn from. The FNV hashes go through the murmur3 finalizer first, since
+// block selection uses the high bits of a multiply and in-block positions
+// the low bits, and raw FNV-1a low bits are weak.
+func (b *Blocked) probe(data []byte) (*blockedBlock, uint64) {
+	h1, h2 := fnvHashes(data)
+	i, _ := bits.Mul64(mix64(h1), uint64(len(b.blocks)))
+	return &b.blocks[i], mix64(h2)
+}
+
+// blockedPositionsPerHash is how many 9-bit positions one 64-bit hash
+// yields.
+const blockedPositionsPerHash = 64 / 9
+
+// nextBlockedPosition returns the i-th in-block position, taken as the
+// next 9 bits of h, and what remains of h. Every seven positions h is
+// used up and replaced by a fresh mix of the key's seed hash. Unlike
+// double hashing, which can only produce 512*256 distinct probe patterns
+// in a block and measurably raises the false positive rate, this gives
+// independent uniform positions.
+func nextBlockedPosition(seed, h, i uint64) (uint64, uint64) {
+	if i > 0 && i%blockedPositionsPerHash == 0 {
+		h = mix64(seed + i)
+	}
+	return h % blockedBlockBits, h / blockedBlockBits
+}
+
+// M returns the number of bits, a multiple of 512.
+func (b *Blocked) M() uint64 {
+	if b == nil {
+		return 0
+	}
+	return uint64(len(b.blocks)) * blockedBlockBits
+}
+
+// K returns the number of hash functions.
+func (b *Blocked) K() uint64 {
+	if b == nil {
+		return 0
+	}
+	return b.k
+}
+
+// Reset clears all bits in the filter.
+func (b *Blocked) Reset() {
+	if b == nil {
+		return
+	}
+	clear(b.blocks)
+	b.inserts = 0
+}
+
+// SizeInBytes reports the memory held by the filter, including the
+// alignment slack.
+func (b *Blocked) SizeInBytes() uint64 {
+	if b == nil {
+		return 0
+	}
+	return uint64(len(b.blocks)+1)*64 + uint64(unsafe.Sizeof(*b))
+}
+
+// Info returns a small description of the filter's configuration.
+func (b *Blocked) Info() string {
+	if b == nil {
+		return "Blocked{nil}"
+	}
+	return fmt.Sprintf("Blocked{m=%d bits in %d blocks, k=%d}", b.M(), len(b.blocks), b.k)
+}
+
+// Blocked binary format (all integers little-endian):
+//
+//	version  uint8   blockedVersion
+//	blocks   uint64  no. of 512-bit blocks
+//	k        uint64
+//	inserts  uint64
+//	bits     blocks * 8 * uint64
+const (
+	blockedVersion   = 1
+	blockedHeaderLen = 1 + 8 + 8 + 8
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (b *Blocked) MarshalBinary() ([]byte, error) {
+	if b == nil || len(b.blocks) == 0 {
+		return nil, ErrUninitialized
+	}
+	buf := make([]byte, 0, blockedHeaderLen+len(b.blocks)*64)
+	buf = append(buf, blockedVersion)
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(b.blocks)))
+	buf = binary.LittleEndian.AppendUint64(buf, b.k)
+	buf = binary.LittleEndian.AppendUint64(buf, b.inserts)
+	for i := range b.blocks {
+		for _, w := range b.blocks[i] {
+			buf = binary.LittleEndian.AppendUint64(buf, w)
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, fully replacing
+// the receiver's contents. Malformed data fails with ErrCorrupt, an
+// unknown version with ErrUnsupportedVersion; the receiver is left
+// untouched on error.
+func (b *Blocked) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	if data[0] != blockedVersion {
+		return fmt.Errorf("%w: blocked version %d", ErrUnsupportedVersion, data[0])
+	}
+	if len(data) < blockedHeaderLen {
+		return fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	blocks := binary.LittleEndian.Uint64(data[1:])
+	k := binary.LittleEndian.Uint64(data[9:])
+	payload := data[blockedHeaderLen:]
+	if blocks == 0 || k == 0 || k > blockedBlockBits {
+		return fmt.Errorf("%w: %d blocks, k=%d", ErrCorrupt, blocks, k)
+	}
+	if uint64(len(payload))/64 != blocks || len(payload)%64 != 0 {
+		return fmt.Errorf("%w: payload is %d bytes, want %d blocks", ErrCorrupt, len(payload), blocks)
+	}
+	decoded := newBlocked(blocks, k)
+	decoded.inserts = binary.LittleEndian.Uint64(data[17:])
+	for i := range decoded.blocks {
+		for j := range decoded.blocks[i] {
+			decoded.blocks[i][j] = binary.LittleEndian.Uint64(payload[(i*8+j)*8:])
+		}
+	}
+	*b = *decoded
+	return nil
+}
@@ -0,0 +1,149 @@
+package bloom
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"unsafe"
+)
+
+func TestBlocked_NoFalseNegatives(t *testing.T) {
+	b := NewBlocked(50_000, 0.01)
+	for i := 0; i < 50_000; i++ {
+		b.Add([]byte("key-" + strconv.Itoa(i)))
+	}
+	for i := 0; i < 50_000; i++ {
+		if !b.MightContain([]byte("key-" + strconv.Itoa(i))) {
+			t.Fatalf("false negative for key-%d", i)
+		}
+	}
+}
+
+func TestBlocked_FalsePositiveRateMeetsTarget(t *testing.T) {
+	for _, target := range []float64{0.01, 0.001} {
+		b := NewBlocked(50_000, target)
+		for _, key := range randomKeys("in-", 50_000, 21) {
+			b.Add(key)
+		}
+		predicted := blockedFalsePositiveRate(uint64(len(b.blocks)), b.k, 50_000)
+		if predicted > target {
+			t.Fatalf("target %g: sized for a predicted rate of %g", target, predicted)
+		}
+
+		falsePositives := 0
+		const probes = 500_000
+		for _, key := range randomKeys("out-", probes, 22) {
+			if b.MightContain(key) {
+				falsePositives++
+			}
+		}
+		rate := float64(falsePositives) / probes
+		if rate > target*1.15 {
+			t.Errorf("target %g: measured %.5f (predicted %.5f)", target, rate, predicted)
+		}
+
+		plain := NewWithEstimates(50_000, target)
+		t.Logf("target %g: %d bits against %d unblocked (+%.1f%%), measured %.5f",
+			target, b.M(), plain.m, 100*(float64(b.M())/float64(plain.m)-1), rate)
+	}
+}
+
+func TestBlocked_Aligned(t *testing.T) {
+	for _, n := range []uint64{1, 100, 10_000} {
+		b := NewBlocked(n, 0.01)
+		if addr := uintptr(unsafe.Pointer(&b.blocks[0])); addr%64 != 0 {
+			t.Fatalf("n=%d: blocks start at %#x, not cache-line aligned", n, addr)
+		}
+	}
+}
+
+func TestBlocked_OneBlockPerKey(t *testing.T) {
+	b := NewBlockedWithSize(512*64, 7)
+	b.Add([]byte("only"))
+	touched := 0
+	for i := range b.blocks {
+		if b.blocks[i] != (blockedBlock{}) {
+			touched++
+			if popcount(b.blocks[i][:]) != 7 {
+				t.Fatalf("block holds %d bits, want all 7 probes", popcount(b.blocks[i][:]))
+			}
+		}
+	}
+	if touched != 1 {
+		t.Fatalf("one key touched %d blocks", touched)
+	}
+}
+
+func TestBlocked_BinaryRoundTrip(t *testing.T) {
+	b := NewBlocked(5000, 0.01)
+	for i := 0; i < 5000; i++ {
+		b.Add([]byte("key-" + strconv.Itoa(i)))
+	}
+	data, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Blocked
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got.Info() != b.Info() || got.inserts != b.inserts {
+		t.Fatalf("decoded %s, want %s", got.Info(), b.Info())
+	}
+	for i := 0; i < 10_000; i++ {
+		key := []byte("key-" + strconv.Itoa(i))
+		if got.MightContain(key) != b.MightContain(key) {
+			t.Fatalf("key %d: decoded filter disagrees", i)
+		}
+	}
+
+	for name, bad := range map[string][]byte{
+		"empty":     nil,
+		"truncated": data[:len(data)-1],
+		"k":         append(append(append([]byte(nil), data[:9]...), make([]byte, 8)...), data[17:]...),
+	} {
+		if err := got.UnmarshalBinary(bad); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: expected ErrCorrupt, got %v", name, err)
+		}
+	}
+	if err := got.UnmarshalBinary([]byte{2}); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+// The benchmarks below query filters sized for 50M keys at 1% (about
+// 60 MB, beyond a typical last-level cache) holding 1M keys, so every
+// probe of the standard filter is likely a cache miss.
+const benchLargeItems = 50_000_000
+
+func benchLargeKeys() [][]byte {
+	keys := make([][]byte, 1_000_000)
+	for i := range keys {
+		keys[i] = []byte("key-" + strconv.Itoa(i))
+	}
+	return keys
+}
+
+func BenchmarkBlocked_MightContainLarge(b *testing.B) {
+	bl := NewBlocked(benchLargeItems, 0.01)
+	keys := benchLargeKeys()
+	for _, key := range keys {
+		bl.Add(key)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bl.MightContain(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkBloom_MightContainLarge(b *testing.B) {
+	bf := NewWithEstimates(benchLargeItems, 0.01)
+	keys := benchLargeKeys()
+	for _, key := range keys {
+		bf.Add(key)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.MightContain(keys[i%len(keys)])
+	}
+}
@@ -1,34 +1,79 @@
 package bloom
 
 import (
-	"fmt"
-	"math"
+	"errors"
+	"math/bits"
+	"unsafe"
+)
+
+// ErrUninitialized is returned (or raised by Add) when inserting into a
+// zero-value or nil BloomFilter, which has no bits to set.
+var ErrUninitialized = errors.New("bloom: filter not initialized")
+
+// Errors returned by the Try constructors, and raised by the panicking
+// ones, for invalid parameters.
+var (
+	// ErrZeroBits is returned when m, the no. of bits, is zero.
+	ErrZeroBits = errors.New("bloom: m (no. of bits) must be > 0")
+
+	// ErrZeroHashes is returned when k, the no. of hash functions, is zero.
+	ErrZeroHashes = errors.New("bloom: k (no. of hash functions) must be > 0")
+
+	// ErrInvalidFPRate is returned when a false positive rate is not in
+	// (0, 1).
+	ErrInvalidFPRate = errors.New("bloom: fpRate must be between 0 and 1 (exclusive)")
+
+	// ErrFilterTooLarge is returned when the requested filter needs more
+	// memory than can be allocated.
+	ErrFilterTooLarge = errors.New("bloom: filter too large to allocate")
 )
 
 // Bloomfilter is a standard Bloom Filter implementation.
 // Note: This type is not safe for concurrent use without external locking
+//
+// The zero value (and a nil *BloomFilter) is an empty filter: MightContain
+// reports false, and Info, Reset and SizeInBytes are safe to call. It has no
+// storage, so Add panics with ErrUninitialized and AddChecked returns it;
+// use New or NewWithEstimates to build a usable filter.
 type BloomFilter struct {
-	m    uint64   // no. of bits
-	k    uint64   // no. of hash functions
-	bits []uint64 //bitset storage
+	m       uint64 // no. of bits
+	k       uint64 // no. of hash functions
+	storage        // bitset words, contiguous or chunked (see chunked.go)
+
+	scheme scheme        // how keys map to probe positions
+	hasher *hasherConfig // non-default Hasher for native schemes; nil = FNVHasher
+	seed   uint64        // hash seed for native schemes; 0 = unseeded (see seed.go)
+	mapped *mapping      // backing file when bits are memory-mapped (see NewMmap)
+
+	setBits   uint64  // no. of bits currently set; every write path keeps it exact
+	inserts   uint64  // no. of Add calls since construction or Reset
+	capacity  uint64  // designed no. of insertions (0 = unknown)
+	threshold float64 // fill ratio at which the filter is saturated (0 = default)
+
+	onOverfill func(count, capacity uint64) // see WithOverfillCallback
+	overfilled bool                         // onOverfill has fired since construction or Reset
+
+	dirty      []uint64 // per-block generation of the last change (nil = not tracking; see DeltaSince)
+	generation uint64   // current dirty-tracking generation
 }
 
 // New creates a bloom filter wiht an explicit no. of bits (m) and hash functions (k).
 // m and k ==> must be >0.
+//
+// This panics with the error TryNew would return.
 func New(m, k uint64) *BloomFilter {
-	if m == 0 {
-		panic("bloom: m (no. of bits) must be > 0")
-	}
-	if k == 0 {
-		panic("bloom: k (no. of hash fucntions) must be > 0")
+	bf, err := TryNew(m, k)
+	if err != nil {
+		panic(err)
 	}
+	return bf
+}
 
-	wordCount := (m + 63) / 64 // round up to whole 63-bit words
-	return &BloomFilter{
-		m:    m,
-		k:    k,
-		bits: make([]uint64, wordCount),
-	}
+// TryNew is New for parameters that come from configuration: instead of
+// panicking it returns ErrZeroBits, ErrZeroHashes or, for m beyond what
+// can be allocated, ErrFilterTooLarge.
+func TryNew(m, k uint64) (*BloomFilter, error) {
+	return NewWithOptions(0, 0, WithExplicitSize(m, k))
 }
 
 // NewWithEstimates constructs a Bloom filter for an expected number of items (n)
@@ -37,91 +82,313 @@ func New(m, k uint64) *BloomFilter {
 // m = - (n * ln(fpRate)) / (ln 2)^2
 // k = (m / n) * ln 2
 //
-// This panics if n == 0 or fpRate is not in (0, 1).
+// This panics with the error TryNewWithEstimates would return.
 func NewWithEstimates(n uint64, fpRate float64) *BloomFilter {
-	if n == 0 {
-		panic("bloom: n (expected insertions) must be > 0")
-	}
-	if fpRate <= 0.0 || fpRate >= 1.0 {
-		panic("bloom: fpRate must be between 0 and 1 (exclusive)")
-	}
-
-	ln2 := math.Ln2
-
-	mFloat := -float64(n) * math.Log(fpRate) / (ln2 * ln2)
-	m := uint64(math.Ceil(mFloat))
-	if m == 0 {
-		m = 1
-	}
-
-	kFloat := (float64(m) / float64(n)) * ln2
-	k := uint64(math.Ceil(kFloat))
-	if k == 0 {
-		k = 1
+	bf, err := TryNewWithEstimates(n, fpRate)
+	if err != nil {
+		panic(err)
 	}
+	return bf
+}
 
-	return New(m, k)
+// TryNewWithEstimates is NewWithEstimates returning an error instead of
+// panicking: ErrZeroInsertions for n == 0, ErrInvalidFPRate for fpRate
+// outside (0, 1), and ErrFilterTooLarge if the resulting filter cannot be
+// allocated.
+func TryNewWithEstimates(n uint64, fpRate float64) (*BloomFilter, error) {
+	return NewWithOptions(n, fpRate)
 }
 
 // Add inserts data into the Bloom filter.
+// It panics with ErrUninitialized on a zero-value or nil filter.
 func (bf *BloomFilter) Add(data []byte) {
-	if bf.m == 0 || bf.k == 0 {
-		panic("bloom: filter not initialized")
-	}
-
-	h1, h2 := hash128(data)
-	if h2 == 0 {
-		// avoid degenerate double-hash sequence
-		h2 = 0x9e3779b97f4a7c15 // some odd constant
+	if !bf.initialized() {
+		panic(ErrUninitialized)
 	}
 
-	for i := uint64(0); i < bf.k; i++ {
-		// double hashing: position = (h1 + i*h2) mod m
-		pos := (h1 + i*h2) % bf.m
-		bf.setBit(pos)
-	}
+	bf.addHashes(bf.hashes(data))
 }
 
 // MightContain checks if data might be in the filter.
 // Returns false -> definitely not present.
 // Returns true  -> might be present (subject to false positives).
+// A zero-value or nil filter contains nothing.
 func (bf *BloomFilter) MightContain(data []byte) bool {
-	if bf.m == 0 || bf.k == 0 {
-		panic("bloom: filter not initialized")
+	if !bf.initialized() {
+		return false
 	}
 
-	h1, h2 := hash128(data)
-	if h2 == 0 {
-		h2 = 0x9e3779b97f4a7c15
+	return bf.containsHashes(bf.hashes(data))
+}
+
+// TestAndAdd reports whether data might already have been in the filter,
+// then adds it. It hashes data once, so it is the cheap way to deduplicate
+// a stream: "if !bf.TestAndAdd(k) { process(k) }". Like MightContain, a
+// true result may be a false positive, so a new key is occasionally
+// reported as seen; a false result is always correct.
+// It panics with ErrUninitialized on a zero-value or nil filter.
+func (bf *BloomFilter) TestAndAdd(data []byte) bool {
+	if !bf.initialized() {
+		panic(ErrUninitialized)
 	}
 
+	before := bf.setBits
+	bf.addHashes(bf.hashes(data))
+	return bf.setBits == before
+}
+
+// AddString inserts s. It is equivalent to Add([]byte(s)) but does not
+// allocate a copy of s.
+// It panics with ErrUninitialized on a zero-value or nil filter.
+func (bf *BloomFilter) AddString(s string) {
+	bf.Add(stringBytes(s))
+}
+
+// MightContainString checks if s might be in the filter. It is equivalent
+// to MightContain([]byte(s)) but does not allocate a copy of s.
+func (bf *BloomFilter) MightContainString(s string) bool {
+	return bf.MightContain(stringBytes(s))
+}
+
+// stringBytes returns a read-only view of s's bytes without copying. The
+// result must never be written to; the hashing paths only read it.
+func stringBytes(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
+
+// containsHashes reports whether every probe position of the key with base
+// hashes h is set.
+func (bf *BloomFilter) containsHashes(h baseHashes) bool {
 	for i := uint64(0); i < bf.k; i++ {
-		pos := (h1 + i*h2) % bf.m
-		if !bf.getBit(pos) {
+		if pos := bf.location(h, i); !bf.getBit(pos) || bf.stale() && !bf.live(pos) {
 			return false
 		}
 	}
 	return true
 }
 
-// Reset clears all bits in the filter.
+// Reset clears all bits in the filter. It takes time proportional to the
+// filter's size unless the filter was built WithLazyReset.
 func (bf *BloomFilter) Reset() {
-	for i := range bf.bits {
-		bf.bits[i] = 0
+	if bf == nil {
+		return
 	}
+	if bf.lazy() {
+		bf.lazyReset()
+	} else {
+		bf.eachSpan(func(_ int, words []uint64) { clear(words) })
+	}
+	bf.resetCounters()
 }
 
-// Info returns a small description of the filter's configuration.
+// resetCounters brings everything but the words back to the state of a
+// new filter, once the words have been cleared.
+func (bf *BloomFilter) resetCounters() {
+	bf.setBits = 0
+	bf.inserts = 0
+	bf.overfilled = false
+	bf.stopTracking()
+}
+
+// SizeInBytes reports the memory held by the filter: the bitset storage,
+// the chunk table of chunked storage, the generation stamps of a lazy
+// filter, any dirty-tracking state and the fixed struct overhead.
+func (bf *BloomFilter) SizeInBytes() uint64 {
+	if bf == nil {
+		return 0
+	}
+	var chunkTable uint64
+	if bf.chunked() {
+		chunkTable = uint64(cap(bf.chunks)) * uint64(unsafe.Sizeof([]uint64(nil)))
+	}
+	var stamps uint64
+	if bf.lazy() {
+		stamps = uint64(len(bf.gen.stamps))*2 + uint64(unsafe.Sizeof(*bf.gen))
+	}
+	return uint64(bf.wordCount()+len(bf.dirty))*8 + chunkTable + stamps + filterOverhead
+}
+
+// Info returns a one-line description of the filter's configuration and
+// fill, Stats().String(). Prefer Stats for anything machine-read.
 func (bf *BloomFilter) Info() string {
-	return fmt.Sprintf("BloomFilter{m=%d bits, k=%d}", bf.m, bf.k)
+	if bf == nil {
+		return "BloomFilter{nil}"
+	}
+	return bf.Stats().String()
+}
+
+// addHashes inserts the key with base hashes h.
+func (bf *BloomFilter) addHashes(h baseHashes) {
+	for i := uint64(0); i < bf.k; i++ {
+		pos := bf.location(h, i)
+		if bf.stale() {
+			bf.freshen(pos)
+		}
+		bf.setBit(pos)
+	}
+	bf.inserts++
+	if bf.onOverfill != nil {
+		bf.checkOverfill()
+	}
+}
+
+// Clone returns a deep copy of bf with its own heap storage, or nil if bf
+// is nil. Changes to either filter never affect the other.
+func (bf *BloomFilter) Clone() *BloomFilter {
+	if bf == nil {
+		return nil
+	}
+	c := *bf
+	c.storage = newStorage(bf.wordCount(), bf.chunkShift)
+	copyWords(&c.storage, &bf.storage)
+	c.setLazy(bf.lazy())
+	c.mapped = nil
+	c.dirty = nil
+	return &c
+}
+
+// CopyFrom overwrites bf with the contents of other. bf's storage is
+// reused when it is large enough, so refreshing a same-sized copy does not
+// allocate. A memory-mapped bf keeps its file and can only copy a filter
+// with the same m, k and probe scheme; otherwise bf takes other's storage
+// layout, chunked or contiguous.
+func (bf *BloomFilter) CopyFrom(other *BloomFilter) error {
+	if bf == nil || !other.initialized() {
+		return ErrUninitialized
+	}
+	if bf == other {
+		return nil
+	}
+	if bf.mapped != nil {
+		if err := bf.checkCompatible(other); err != nil {
+			return err
+		}
+	}
+	var st storage
+	switch n := other.wordCount(); {
+	case bf.mapped != nil || bf.sameLayout(&other.storage):
+		st = bf.storage
+	case !other.chunked() && !bf.chunked() && cap(bf.bits) >= n:
+		st = contiguous(bf.bits[:n])
+	default:
+		st = newStorage(n, other.chunkShift)
+	}
+	copyWords(&st, &other.storage)
+	st.setLazy(other.lazy())
+
+	mapped, generation := bf.mapped, bf.generation
+	*bf = *other
+	bf.storage = st
+	bf.mapped = mapped
+	bf.dirty, bf.generation = nil, generation
+	return nil
+}
+
+// initialized reports whether the filter has storage to operate on.
+func (bf *BloomFilter) initialized() bool {
+	return bf != nil && bf.m != 0 && bf.k != 0
+}
+
+// baseHashes holds the hashes a key's probe positions are derived from.
+// Most schemes use only the first two.
+type baseHashes [4]uint64
+
+// hashes returns the base hashes used to derive data's probe positions.
+func (bf *BloomFilter) hashes(data []byte) baseHashes {
+	switch bf.scheme {
+	case schemeGuava32, schemeGuava64:
+		h1, h2 := murmur3x64_128(data, 0)
+		return baseHashes{h1, h2}
+	case schemeBitsAndBlooms:
+		return bitsAndBloomsHashes(data)
+	case schemeCassandra:
+		h1, h2 := cassandraMurmur3(data)
+		return baseHashes{h1, h2}
+	}
+	if bf.hasher != nil {
+		h1, h2 := bf.hasher.hash128(data)
+		if h2 == 0 && !bf.hasher.exact {
+			h2 = 0x9e3779b97f4a7c15
+		}
+		return baseHashes{h1, h2}
+	}
+	if bf.seed != 0 {
+		h1, h2 := seededFNVHashes(data, bf.seed)
+		return baseHashes{h1, h2}
+	}
+	h1, h2 := fnvHashes(data)
+	return baseHashes{h1, h2}
+}
+
+// fnvHashes returns the default scheme's base hashes for data.
+func fnvHashes(data []byte) (uint64, uint64) {
+	h1, h2 := hash128(data)
+	if h2 == 0 {
+		// avoid degenerate double-hash sequence
+		h2 = 0x9e3779b97f4a7c15 // some odd constant
+	}
+	return h1, h2
+}
+
+// location returns the i-th probe position for the base hashes h.
+func (bf *BloomFilter) location(h baseHashes, i uint64) uint64 {
+	switch bf.scheme {
+	case schemeGuava64:
+		return guava64Location(h[0], h[1], i, bf.m)
+	case schemeGuava32:
+		return guava32Location(h[0], i, bf.m)
+	case schemeBitsAndBlooms:
+		return bitsAndBloomsLocation(h, i) % bf.m
+	case schemeCassandra:
+		return cassandraLocation(h[0], h[1], i, bf.m)
+	case schemeEnhanced:
+		return (h[0] + i*h[1] + (i*i*i-i)/6) % bf.m
+	case schemeSplit64:
+		return split64Location(h[0], i, bf.m)
+	case schemeFastRange:
+		return fastRange(h[0]+i*h[1], bf.m)
+	}
+	// double hashing: position = (h1 + i*h2) mod m
+	return (h[0] + i*h[1]) % bf.m
+}
+
+// fastRange maps h to [0, m) as floor(h * m / 2^64), Lemire's
+// multiply-shift reduction: one multiplication instead of a 64-bit
+// division, and as uniform as h mod m for uniform h, each position taking
+// floor or ceil of 2^64/m of the hash values.
+func fastRange(h, m uint64) uint64 {
+	hi, _ := bits.Mul64(h, m)
+	return hi
+}
+
+// split64Location returns the i-th probe position of WithSplit64Hashing
+// for the 64-bit hash h, in the same 64-bit arithmetic as the C code it
+// mirrors.
+func split64Location(h, i, m uint64) uint64 {
+	g1 := h & 0xffffffff
+	g2 := h>>32 | 1
+	return (g1 + i*g2) % m
 }
 
 // setBit sets the bit at position pos (0 <= pos < m).
 func (bf *BloomFilter) setBit(pos uint64) {
+	if w, mask := bf.word(pos/64), uint64(1)<<(pos%64); *w&mask == 0 {
+		*w |= mask
+		bf.setBits++
+		bf.markDirty(pos / 64)
+	}
+}
+
+// clearBit clears the bit at position pos (0 <= pos < m).
+func (bf *BloomFilter) clearBit(pos uint64) {
 	wordIndex := pos / 64
 	bitIndex := pos % 64
 	mask := uint64(1) << bitIndex
-	bf.bits[wordIndex] |= mask
+	if w := bf.word(wordIndex); *w&mask != 0 {
+		*w &^= mask
+		bf.setBits--
+		bf.stopTracking()
+	}
 }
 
 // getBit returns true if the bit at position pos is set.
@@ -129,7 +396,7 @@ func (bf *BloomFilter) getBit(pos uint64) bool {
 	wordIndex := pos / 64
 	bitIndex := pos % 64
 	mask := uint64(1) << bitIndex
-	return (bf.bits[wordIndex] & mask) != 0
+	return (*bf.word(wordIndex) & mask) != 0
 }
 
 // --- Hashing helpers ---
@@ -153,21 +420,17 @@ func fnv64a(data []byte) uint64 {
 	return hash
 }
 
-// fnv64aSalted is the same as fnv64a but uses a different offset basis
-// so that we get an independent second hash.
-func fnv64aSalted(data []byte, salt uint64) uint64 {
-	var hash uint64 = fnv64Offset ^ salt
-	for _, b := range data {
-		hash ^= uint64(b)
-		hash *= fnv64Prime
-	}
-	return hash
-}
-
-// hash128 produces two 64-bit hashes from the same input.
+// hash128 produces two 64-bit hashes from the same input: FNV-1a, and
+// FNV-1a from a salted offset basis so that the second is independent.
+// Both are computed in one loop over data; the two multiply chains do not
+// depend on each other, so the CPU overlaps them and a long key costs
+// little more than a single FNV pass.
 func hash128(data []byte) (uint64, uint64) {
-	h1 := fnv64a(data)
 	const salt = 0x9e3779b97f4a7c15 // arbitrary odd 64-bit constant
-	h2 := fnv64aSalted(data, salt)
+	h1, h2 := uint64(fnv64Offset), uint64(fnv64Offset^salt)
+	for _, b := range data {
+		h1 = (h1 ^ uint64(b)) * fnv64Prime
+		h2 = (h2 ^ uint64(b)) * fnv64Prime
+	}
 	return h1, h2
 }
@@ -1,28 +1,80 @@
 package bloom
 
-import "sync"
+import (
+	"context"
+	"io"
+	"sync"
+	"unsafe"
+)
 
 // SafeBloom wraps BloomFilter with a mutex to allow safe concurrent use.
+//
+// The zero value behaves like a zero-value BloomFilter: it contains nothing,
+// Add panics with ErrUninitialized and AddChecked returns it.
 type SafeBloom struct {
 	mu sync.RWMutex
 	bf *BloomFilter
+
+	// resetMu serializes Reset and guards the fields below. It is taken
+	// before mu, never while holding it.
+	resetMu   sync.Mutex
+	spare     storage // zeroed words for the next Reset (see SetResetSpare)
+	keepSpare bool
 }
 
 // NewSafe creates a concurrency-safe Bloom filter using explicit m and k.
 func NewSafe(m, k uint64) *SafeBloom {
-	return &SafeBloom{bf: New(m, k)}
+	s, err := TryNewSafe(m, k)
+	if err != nil {
+		panic(err)
+	}
+	return s
 }
 
 // NewSafeWithEstimates creates a concurrency-safe Bloom filter using n and fpRate.
 func NewSafeWithEstimates(n uint64, fpRate float64) *SafeBloom {
-	return &SafeBloom{bf: NewWithEstimates(n, fpRate)}
+	s, err := TryNewSafeWithEstimates(n, fpRate)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// TryNewSafe is NewSafe returning an error instead of panicking. See TryNew.
+func TryNewSafe(m, k uint64) (*SafeBloom, error) {
+	return NewSafeWithOptions(0, 0, WithExplicitSize(m, k))
+}
+
+// TryNewSafeWithEstimates is NewSafeWithEstimates returning an error instead
+// of panicking. See TryNewWithEstimates.
+func TryNewSafeWithEstimates(n uint64, fpRate float64) (*SafeBloom, error) {
+	return NewSafeWithOptions(n, fpRate)
+}
+
+// NewSafeOptimalForMemory creates a concurrency-safe Bloom filter sized to a
+// memory budget. See NewOptimalForMemory.
+func NewSafeOptimalForMemory(budgetBytes uint64, n uint64) (*SafeBloom, float64, error) {
+	bf, fpRate, err := NewOptimalForMemory(budgetBytes, n)
+	if err != nil {
+		return nil, fpRate, err
+	}
+	return &SafeBloom{bf: bf}, fpRate, nil
 }
 
 // Add inserts data safely.
 func (s *SafeBloom) Add(data []byte) {
 	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.bf.Add(data)
-	s.mu.Unlock()
+}
+
+// TestAndAdd reports whether data might already have been present and adds
+// it, under a single write lock. Of several goroutines racing to insert the
+// same new key, exactly one sees false. See BloomFilter.TestAndAdd.
+func (s *SafeBloom) TestAndAdd(data []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bf.TestAndAdd(data)
 }
 
 // MightContain checks membership safely.
@@ -32,11 +84,404 @@ func (s *SafeBloom) MightContain(data []byte) bool {
 	return s.bf.MightContain(data)
 }
 
-// Reset clears the filter safely.
+// AddString inserts s safely without copying it. See
+// BloomFilter.AddString.
+func (s *SafeBloom) AddString(str string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bf.AddString(str)
+}
+
+// MightContainString checks membership of s safely without copying it.
+// See BloomFilter.MightContainString.
+func (s *SafeBloom) MightContainString(str string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.MightContainString(str)
+}
+
+// AddChecked inserts data safely. See BloomFilter.AddChecked.
+func (s *SafeBloom) AddChecked(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bf.AddChecked(data)
+}
+
+// IsSaturated reports saturation safely.
+func (s *SafeBloom) IsSaturated() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.IsSaturated()
+}
+
+// Count returns the insert count safely. See BloomFilter.Count.
+func (s *SafeBloom) Count() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.Count()
+}
+
+// Capacity returns the designed capacity safely.
+func (s *SafeBloom) Capacity() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.Capacity()
+}
+
+// OverCapacity reports safely whether the insert count exceeds the
+// capacity.
+func (s *SafeBloom) OverCapacity() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.OverCapacity()
+}
+
+// SetCapacity records the designed capacity safely.
+func (s *SafeBloom) SetCapacity(n uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bf.SetCapacity(n)
+}
+
+// SetSaturationThreshold sets the saturation fill ratio safely.
+func (s *SafeBloom) SetSaturationThreshold(ratio float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bf.SetSaturationThreshold(ratio)
+}
+
+// Rebuild re-initializes the filter in place for n expected insertions at
+// fpRate, under the write lock, so every holder of s sees the new, empty
+// filter and no reader observes it half-built. A zero-value SafeBloom gets
+// a new filter. See BloomFilter.Rebuild.
+func (s *SafeBloom) Rebuild(n uint64, fpRate float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bf == nil {
+		bf, err := TryNewWithEstimates(n, fpRate)
+		if err != nil {
+			return err
+		}
+		s.bf = bf
+		return nil
+	}
+	return s.bf.Rebuild(n, fpRate)
+}
+
+// RebuildSize is Rebuild with an explicit m and k. See
+// BloomFilter.RebuildSize.
+func (s *SafeBloom) RebuildSize(m, k uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bf == nil {
+		bf, err := TryNew(m, k)
+		if err != nil {
+			return err
+		}
+		s.bf = bf
+		return nil
+	}
+	return s.bf.RebuildSize(m, k)
+}
+
+// ParallelAddAll drains keys on `workers` goroutines. Hashing happens
+// outside the lock; each worker then applies its batch of positions under a
+// single write lock acquisition, so readers are only blocked briefly.
+// See BloomFilter.ParallelAddAll for the return values.
+func (s *SafeBloom) ParallelAddAll(ctx context.Context, keys <-chan []byte, workers int) (uint64, error) {
+	apply, err := s.parallelApply()
+	if err != nil {
+		return 0, err
+	}
+	return parallelLoad(ctx, keys, workers, apply)
+}
+
+// parallelApply returns the batch function of SafeBloom's parallel
+// loaders: it hashes a batch without the lock, then sets the positions
+// under one write lock acquisition.
+func (s *SafeBloom) parallelApply() (func(batch [][]byte), error) {
+	s.mu.RLock()
+	bf := s.bf
+	var geom BloomFilter // m, k, scheme, hasher and seed only, safe to read unlocked
+	if bf.initialized() {
+		geom = BloomFilter{m: bf.m, k: bf.k, scheme: bf.scheme, hasher: bf.hasher, seed: bf.seed}
+	}
+	s.mu.RUnlock()
+	if !geom.initialized() {
+		return nil, ErrUninitialized
+	}
+
+	return func(batch [][]byte) {
+		positions := make([]uint64, 0, len(batch)*int(geom.k))
+		for _, key := range batch {
+			h := geom.hashes(key)
+			for i := uint64(0); i < geom.k; i++ {
+				positions = append(positions, geom.location(h, i))
+			}
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.bf != bf || bf.m != geom.m || bf.k != geom.k {
+			// The filter was swapped or rebuilt mid-load; the precomputed
+			// positions belong to the old geometry.
+			for _, key := range batch {
+				s.bf.Add(key)
+			}
+			return
+		}
+		for _, pos := range positions {
+			bf.setBit(pos)
+		}
+		bf.inserts += uint64(len(batch))
+		bf.checkOverfill()
+	}, nil
+}
+
+// Swap atomically replaces the wrapped filter with newBF and returns the
+// previous one. Concurrent readers observe either the old or the new filter,
+// never a mix. It panics with ErrUninitialized if newBF is nil or a zero value.
+//
+// The returned filter is no longer guarded by s; the caller owns it.
+func (s *SafeBloom) Swap(newBF *BloomFilter) *BloomFilter {
+	if !newBF.initialized() {
+		panic(ErrUninitialized)
+	}
+	s.mu.Lock()
+	old := s.bf
+	s.bf = newBF
+	s.mu.Unlock()
+	return old
+}
+
+// ReplaceFromReader decodes a filter from r and swaps it in. Decoding happens
+// outside the lock; on error the current filter is left untouched.
+func (s *SafeBloom) ReplaceFromReader(r io.Reader) error {
+	bf, _, err := readFilter(r)
+	if err != nil {
+		return err
+	}
+	s.Swap(bf)
+	return nil
+}
+
+// Reset clears the filter safely. The cleared words are prepared outside
+// the lock and swapped in, so readers and writers are blocked only for the
+// swap, however large the filter. The replaced words are then dropped, so
+// memory use briefly doubles during Reset; with SetResetSpare(true) they are
+// instead cleared and kept for the next Reset, which then allocates
+// nothing, at the cost of holding twice the filter's memory from then on.
+// A memory-mapped filter is cleared in place under the write lock.
 func (s *SafeBloom) Reset() {
+	s.resetMu.Lock()
+	defer s.resetMu.Unlock()
+
+	s.mu.RLock()
+	bf := s.bf
+	var layout storage
+	mapped := false
+	if bf != nil {
+		layout, mapped = bf.storage, bf.mapped != nil
+	}
+	s.mu.RUnlock()
+	if bf == nil || mapped {
+		s.mu.Lock()
+		s.bf.Reset()
+		s.mu.Unlock()
+		return
+	}
+
+	fresh := s.spare
+	s.spare = storage{}
+	if !fresh.sameLayout(&layout) {
+		fresh = newStorage(layout.wordCount(), layout.chunkShift)
+	}
+
 	s.mu.Lock()
-	s.bf.Reset()
+	if s.bf != bf || !bf.sameLayout(&fresh) {
+		// Swapped or rebuilt since the words were prepared.
+		s.bf.Reset()
+		s.mu.Unlock()
+		return
+	}
+	old := bf.storage
+	bf.storage = fresh
+	bf.resetCounters()
 	s.mu.Unlock()
+
+	if s.keepSpare {
+		old.eachSpan(func(_ int, words []uint64) { clear(words) })
+		s.spare = old
+	}
+}
+
+// SetResetSpare sets whether Reset keeps the words it replaces as a
+// cleared spare for the next Reset. Keeping one makes repeated Resets
+// allocation-free but holds a second copy of the filter's words between
+// them; SizeInBytes includes it. Turning it off drops any spare. The
+// default is off.
+func (s *SafeBloom) SetResetSpare(keep bool) {
+	s.resetMu.Lock()
+	defer s.resetMu.Unlock()
+	s.keepSpare = keep
+	if !keep {
+		s.spare = storage{}
+	}
+}
+
+// spareBytes returns the memory held by the Reset spare. It takes resetMu,
+// so callers must not hold mu.
+func (s *SafeBloom) spareBytes() uint64 {
+	s.resetMu.Lock()
+	defer s.resetMu.Unlock()
+	return uint64(s.spare.wordCount()) * 8
+}
+
+// Stats returns a consistent snapshot of the filter's statistics.
+func (s *SafeBloom) Stats() Stats {
+	spare := s.spareBytes()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st := s.bf.Stats()
+	st.SizeBytes = uint64(unsafe.Sizeof(*s)) + s.bf.SizeInBytes() + spare
+	return st
+}
+
+// GobEncode implements gob.GobEncoder, encoding the filter under the read lock.
+func (s *SafeBloom) GobEncode() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder. The decoded filter replaces the
+// current one atomically, and the wrapper is immediately usable.
+func (s *SafeBloom) GobDecode(data []byte) error {
+	bf, err := unmarshal(data)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.bf = bf
+	s.mu.Unlock()
+	return nil
+}
+
+// Merge ORs other's bits into the filter under the write lock. See
+// BloomFilter.Merge. other must not be modified concurrently; merge a
+// Snapshot to combine two SafeBlooms.
+func (s *SafeBloom) Merge(other *BloomFilter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bf.Merge(other)
+}
+
+// Intersect ANDs other's bits into the filter under the write lock. See
+// BloomFilter.Intersect. other must not be modified concurrently.
+func (s *SafeBloom) Intersect(other *BloomFilter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bf.Intersect(other)
+}
+
+// Equal reports under the read lock whether the filter equals other. See
+// BloomFilter.Equal.
+func (s *SafeBloom) Equal(other *BloomFilter) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.Equal(other)
+}
+
+// CompatibleWith reports under the read lock whether the filter could be
+// merged with other. See BloomFilter.CompatibleWith.
+func (s *SafeBloom) CompatibleWith(other *BloomFilter) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.CompatibleWith(other)
+}
+
+// DeltaSince returns the words changed since generation under the write
+// lock, since it advances the generation. See BloomFilter.DeltaSince.
+func (s *SafeBloom) DeltaSince(generation uint64) (Delta, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bf.DeltaSince(generation)
+}
+
+// ApplyDelta applies d under the write lock. See BloomFilter.ApplyDelta.
+func (s *SafeBloom) ApplyDelta(d Delta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bf.ApplyDelta(d)
+}
+
+// Params returns the current filter's parameters. See BloomFilter.Params.
+func (s *SafeBloom) Params() Params {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.Params()
+}
+
+// EstimatedFalsePositiveRate returns the current false positive rate under
+// the read lock. See BloomFilter.EstimatedFalsePositiveRate.
+func (s *SafeBloom) EstimatedFalsePositiveRate() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.EstimatedFalsePositiveRate()
+}
+
+// RemainingCapacity estimates the keys left before targetFP, under the
+// read lock. See BloomFilter.RemainingCapacity.
+func (s *SafeBloom) RemainingCapacity(targetFP float64) (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.RemainingCapacity(targetFP)
+}
+
+// ProjectedFPRate projects the false positive rate under the read lock.
+// See BloomFilter.ProjectedFPRate.
+func (s *SafeBloom) ProjectedFPRate(additionalItems uint64) float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.ProjectedFPRate(additionalItems)
+}
+
+// ApproximateItemCount estimates the number of distinct keys added, under
+// the read lock. See BloomFilter.ApproximateItemCount.
+func (s *SafeBloom) ApproximateItemCount() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.ApproximateItemCount()
+}
+
+// JaccardEstimate estimates the Jaccard similarity of the key sets in s and
+// other from a Snapshot of each, so neither is locked while estimating and
+// the two locks are never held together. See the package-level
+// JaccardEstimate.
+func (s *SafeBloom) JaccardEstimate(other *SafeBloom) (float64, error) {
+	return JaccardEstimate(s.Snapshot(), other.Snapshot())
+}
+
+// Snapshot returns a private copy of the current filter, or nil if s holds
+// none. Only the copy of the words happens under the read lock, so writers
+// are blocked for a memcpy rather than for however long the caller spends
+// using the copy.
+func (s *SafeBloom) Snapshot() *BloomFilter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.Clone()
+}
+
+// Clone is Snapshot, named to match BloomFilter.Clone.
+func (s *SafeBloom) Clone() *BloomFilter {
+	return s.Snapshot()
+}
+
+// SnapshotTo writes the binary encoding of a Snapshot to w. The encoding
+// and I/O happen outside the lock.
+func (s *SafeBloom) SnapshotTo(w io.Writer) error {
+	_, err := s.Snapshot().WriteTo(w)
+	return err
 }
 
 // Info returns metadata safely.
@@ -45,3 +490,11 @@ func (s *SafeBloom) Info() string {
 	defer s.mu.RUnlock()
 	return s.bf.Info()
 }
+
+// SizeInBytes reports the memory held by the wrapper and its filter.
+func (s *SafeBloom) SizeInBytes() uint64 {
+	spare := s.spareBytes()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return uint64(unsafe.Sizeof(*s)) + s.bf.SizeInBytes() + spare
+}
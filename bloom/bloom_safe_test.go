@@ -0,0 +1,331 @@
+package bloom
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestSafeBloom_SwapRace(t *testing.T) {
+	s := NewSafe(1<<12, 4)
+	s.Add([]byte("common"))
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if !s.MightContain([]byte("common")) {
+					t.Error(`"common" must be present in every generation`)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		next := New(1<<12+uint64(i), 4)
+		next.Add([]byte("common"))
+		next.Add([]byte("gen-" + strconv.Itoa(i)))
+		old := s.Swap(next)
+		if i > 0 && !old.MightContain([]byte("gen-"+strconv.Itoa(i-1))) {
+			t.Fatalf("Swap returned the wrong filter at generation %d", i)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if !s.MightContain([]byte("gen-99")) {
+		t.Fatal(`expected "gen-99" to be present after the final swap`)
+	}
+}
+
+func TestSafeBloom_SwapRejectsUninitialized(t *testing.T) {
+	s := NewSafe(64, 1)
+	expectPanic(t, ErrUninitialized, func() { s.Swap(nil) })
+	expectPanic(t, ErrUninitialized, func() { s.Swap(&BloomFilter{}) })
+}
+
+func TestSafeBloom_ReplaceFromReader(t *testing.T) {
+	src := New(1000, 3)
+	src.Add([]byte("fresh"))
+	var buf bytes.Buffer
+	if _, err := src.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+
+	s := NewSafe(512, 2)
+	s.Add([]byte("stale"))
+
+	// A truncated stream must leave the current filter in place.
+	if err := s.ReplaceFromReader(bytes.NewReader(encoded[:len(encoded)-3])); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+	if !s.MightContain([]byte("stale")) {
+		t.Fatal("failed replace must not modify the filter")
+	}
+
+	if err := s.ReplaceFromReader(bytes.NewReader(encoded)); err != nil {
+		t.Fatal(err)
+	}
+	if !s.MightContain([]byte("fresh")) {
+		t.Fatal(`expected "fresh" to be present after replace`)
+	}
+}
+
+func TestSafeBloom_Snapshot(t *testing.T) {
+	s := NewSafe(1<<12, 4)
+	s.Add([]byte("before"))
+
+	snap := s.Snapshot()
+	s.Add([]byte("after"))
+	if !snap.MightContain([]byte("before")) || snap.MightContain([]byte("after")) {
+		t.Fatal("snapshot should hold exactly the keys added before it was taken")
+	}
+	snap.Add([]byte("private"))
+	if s.MightContain([]byte("private")) {
+		t.Fatal("adding to a snapshot must not affect the live filter")
+	}
+
+	var buf bytes.Buffer
+	if err := s.SnapshotTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var got BloomFilter
+	if err := got.UnmarshalBinary(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if !got.MightContain([]byte("before")) || !got.MightContain([]byte("after")) {
+		t.Fatal("SnapshotTo lost keys")
+	}
+
+	var zero SafeBloom
+	if zero.Snapshot() != nil {
+		t.Fatal("zero SafeBloom should snapshot to nil")
+	}
+	if err := zero.SnapshotTo(&buf); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("expected ErrUninitialized, got %v", err)
+	}
+}
+
+// slowWriter stands in for a network or disk sink.
+type slowWriter struct{}
+
+func (slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	return len(p), nil
+}
+
+// BenchmarkSafeBloom_AddDuringSnapshot reports Add's p99 and worst-case
+// latency while a
+// 1 MiB filter is serialized every 10ms, either entirely under the read
+// lock ("locked") or via SnapshotTo ("snapshot").
+func BenchmarkSafeBloom_AddDuringSnapshot(b *testing.B) {
+	serialize := map[string]func(s *SafeBloom){
+		"locked": func(s *SafeBloom) {
+			s.mu.RLock()
+			s.bf.WriteTo(slowWriter{})
+			s.mu.RUnlock()
+		},
+		"snapshot": func(s *SafeBloom) {
+			s.SnapshotTo(slowWriter{})
+		},
+	}
+	for _, name := range []string{"locked", "snapshot"} {
+		b.Run(name, func(b *testing.B) {
+			s := NewSafe(1<<23, 7)
+			keys := benchmarkKeys(4096)
+			stop := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for {
+					select {
+					case <-stop:
+						return
+					case <-time.After(10 * time.Millisecond):
+						serialize[name](s)
+					}
+				}
+			}()
+
+			latencies := make([]time.Duration, b.N)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				start := time.Now()
+				s.Add(keys[i%len(keys)])
+				latencies[i] = time.Since(start)
+			}
+			b.StopTimer()
+			close(stop)
+			<-done
+
+			slices.Sort(latencies)
+			b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns")
+			b.ReportMetric(float64(latencies[len(latencies)-1].Nanoseconds()), "max-ns")
+		})
+	}
+}
+
+// TestSafeBloom_TestAndAddRace has many goroutines insert the same fresh
+// keys at once: for each key exactly one must see it as new.
+func TestSafeBloom_TestAndAddRace(t *testing.T) {
+	s := NewSafeWithEstimates(1000, 0.001)
+	const goroutines, keys = 16, 200
+	var firsts [keys]int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < keys; i++ {
+				if !s.TestAndAdd([]byte("race-" + strconv.Itoa(i))) {
+					mu.Lock()
+					firsts[i]++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for i, n := range firsts {
+		// A key colliding entirely with earlier keys would see 0; at this
+		// load and rate that is far below one in a million runs.
+		if n != 1 {
+			t.Fatalf("race-%d: %d goroutines saw it as new, want exactly 1", i, n)
+		}
+	}
+}
+
+func TestSafeBloom_ResetSwapsWords(t *testing.T) {
+	s := NewSafe(1<<16, 4)
+	keys := randomKeys("reset", 1000, 3)
+	s.AddBatch(keys)
+	size := s.SizeInBytes()
+
+	s.Reset()
+	if s.Count() != 0 || s.Stats().BitsSet != 0 || s.MightContain(keys[0]) {
+		t.Fatal("Reset left keys behind")
+	}
+	if s.SizeInBytes() != size {
+		t.Fatalf("SizeInBytes() = %d after Reset without a spare, want %d", s.SizeInBytes(), size)
+	}
+
+	// With a spare, the replaced words are kept, cleared, and reused.
+	s.SetResetSpare(true)
+	s.AddBatch(keys)
+	before := unsafe.SliceData(s.bf.bits)
+	s.Reset()
+	if s.SizeInBytes() != size+uint64(len(s.bf.bits))*8 || unsafe.SliceData(s.spare.bits) != before {
+		t.Fatal("Reset did not keep the replaced words as a spare")
+	}
+	if popcount(s.spare.bits) != 0 {
+		t.Fatal("the spare was not cleared")
+	}
+	s.AddBatch(keys)
+	s.Reset()
+	if unsafe.SliceData(s.bf.bits) != before || s.MightContain(keys[0]) {
+		t.Fatal("Reset did not reuse the spare")
+	}
+	s.SetResetSpare(false)
+	if s.SizeInBytes() != size {
+		t.Fatal("SetResetSpare(false) kept the spare")
+	}
+
+	// A filter rebuilt to another size gets words of the new size.
+	s.SetResetSpare(true)
+	s.Reset()
+	if err := s.RebuildSize(1<<10, 3); err != nil {
+		t.Fatal(err)
+	}
+	s.Add(keys[0])
+	s.Reset()
+	if len(s.bf.bits) != 1<<10/64 || s.MightContain(keys[0]) {
+		t.Fatalf("Reset after RebuildSize: %d words", len(s.bf.bits))
+	}
+
+	var zero SafeBloom
+	zero.Reset()
+}
+
+// TestSafeBloom_ResetWithStats runs Reset against the methods that report
+// the spare's size. Those used to take resetMu while holding the read lock,
+// the opposite order to Reset, and could deadlock.
+func TestSafeBloom_ResetWithStats(t *testing.T) {
+	s := NewSafe(1<<16, 4)
+	s.SetResetSpare(true)
+	keys := randomKeys("stats", 100, 4)
+	var wg sync.WaitGroup
+	for g := range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20000 {
+				switch g {
+				case 0:
+					s.AddBatch(keys)
+					s.Reset()
+				case 1:
+					s.Stats()
+				case 2:
+					s.SizeInBytes()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestSafeBloom_ResetDoesNotStallReaders resets a 256 MiB filter while
+// another goroutine reads continuously. Clearing that much memory under the
+// write lock takes tens of milliseconds; swapping in fresh words blocks the
+// reader only briefly.
+func TestSafeBloom_ResetDoesNotStallReaders(t *testing.T) {
+	if testing.Short() || raceEnabled {
+		t.Skip("allocates 512 MiB and measures wall-clock latency")
+	}
+	if runtime.NumCPU() < 2 {
+		t.Skip("needs a second CPU so the reader runs while Reset does")
+	}
+	s := NewSafe(1<<31, 3)
+	s.Add([]byte("k"))
+	stop := make(chan struct{})
+	worst := make(chan time.Duration)
+	go func() {
+		var stall time.Duration
+		for {
+			select {
+			case <-stop:
+				worst <- stall
+				return
+			default:
+			}
+			start := time.Now()
+			s.MightContain([]byte("k"))
+			stall = max(stall, time.Since(start))
+		}
+	}()
+	for i := 0; i < 3; i++ {
+		s.Reset()
+		s.Add([]byte("k"))
+	}
+	close(stop)
+	if stall := <-worst; stall > 20*time.Millisecond {
+		t.Fatalf("a read stalled for %v during Reset", stall)
+	}
+}
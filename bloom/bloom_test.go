@@ -1,6 +1,8 @@
 package bloom
 
 import (
+	"errors"
+	"math"
 	"strconv"
 	"testing"
 )
@@ -61,3 +63,218 @@ func TestBloom_Reset(t *testing.T) {
 		t.Fatal(`expected "foo" to be absent after reset`)
 	}
 }
+
+func TestBloom_NilReceiver(t *testing.T) {
+	var bf *BloomFilter
+
+	if bf.MightContain([]byte("foo")) {
+		t.Fatal("nil filter must not contain anything")
+	}
+	bf.Reset()
+	if bf.Info() == "" || bf.SizeInBytes() != 0 || bf.IsSaturated() {
+		t.Fatal("unexpected metadata from nil filter")
+	}
+	if err := bf.AddChecked([]byte("foo")); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("expected ErrUninitialized, got %v", err)
+	}
+	expectPanic(t, ErrUninitialized, func() { bf.Add([]byte("foo")) })
+}
+
+func TestBloom_ZeroValue(t *testing.T) {
+	var holder struct {
+		name string
+		bf   BloomFilter
+	}
+
+	if holder.bf.MightContain([]byte("foo")) {
+		t.Fatal("zero-value filter must not contain anything")
+	}
+	holder.bf.Reset()
+	_ = holder.bf.Info()
+	if err := holder.bf.AddChecked([]byte("foo")); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("expected ErrUninitialized, got %v", err)
+	}
+	expectPanic(t, ErrUninitialized, func() { holder.bf.Add([]byte("foo")) })
+}
+
+func TestSafeBloom_ZeroValue(t *testing.T) {
+	var s SafeBloom
+
+	if s.MightContain([]byte("foo")) {
+		t.Fatal("zero-value SafeBloom must not contain anything")
+	}
+	s.Reset()
+	_ = s.Info()
+	if err := s.AddChecked([]byte("foo")); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("expected ErrUninitialized, got %v", err)
+	}
+	expectPanic(t, ErrUninitialized, func() { s.Add([]byte("foo")) })
+
+	// The lock must not be left held after the panic.
+	if s.MightContain([]byte("foo")) {
+		t.Fatal("zero-value SafeBloom must not contain anything")
+	}
+}
+
+func expectPanic(t *testing.T, want error, fn func()) {
+	t.Helper()
+	defer func() {
+		r := recover()
+		if err, ok := r.(error); !ok || !errors.Is(err, want) {
+			t.Fatalf("expected panic with %v, got %v", want, r)
+		}
+	}()
+	fn()
+}
+
+func TestBloom_StringMatchesBytes(t *testing.T) {
+	for s := schemeFNV; s.valid(); s++ {
+		byBytes, byString := New(4099, 5), New(4099, 5)
+		byBytes.scheme, byString.scheme = s, s
+		for i := 0; i < 500; i++ {
+			key := "user-" + strconv.Itoa(i)
+			byBytes.Add([]byte(key))
+			byString.AddString(key)
+		}
+		if !byBytes.Equal(byString) {
+			t.Fatalf("%s: AddString set different bits than Add", s)
+		}
+		for i := 0; i < 1000; i++ {
+			key := "user-" + strconv.Itoa(i)
+			if byString.MightContainString(key) != byBytes.MightContain([]byte(key)) {
+				t.Fatalf("%s: MightContainString(%q) disagrees with MightContain", s, key)
+			}
+		}
+	}
+	if (*BloomFilter)(nil).MightContainString("x") || NewSafe(64, 3).MightContainString("x") {
+		t.Fatal("empty filters must not contain a string")
+	}
+}
+
+func TestBloom_StringPathsDoNotAllocate(t *testing.T) {
+	bf := NewWithEstimates(1000, 0.01)
+	s := NewSafeWithEstimates(1000, 0.01)
+	key := "a-string-key-long-enough-to-matter"
+	allocs := testing.AllocsPerRun(100, func() {
+		bf.AddString(key)
+		bf.MightContainString(key)
+		s.AddString(key)
+		s.MightContainString(key)
+	})
+	if allocs != 0 {
+		t.Fatalf("string paths allocate %.1f times per call set, want 0", allocs)
+	}
+}
+
+func BenchmarkBloom_AddString(b *testing.B) {
+	bf := NewWithEstimates(1<<20, 0.01)
+	key := "user-1234567890"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		bf.AddString(key)
+	}
+}
+
+func BenchmarkBloom_MightContainString(b *testing.B) {
+	bf := NewWithEstimates(1<<20, 0.01)
+	key := "user-1234567890"
+	bf.AddString(key)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		bf.MightContainString(key)
+	}
+}
+
+func TestBloom_TestAndAdd(t *testing.T) {
+	bf := NewWithEstimates(1000, 0.01)
+	keys := randomKeys("tad", 1000, 7)
+	for i, key := range keys {
+		want := bf.MightContain(key)
+		if got := bf.TestAndAdd(key); got != want {
+			t.Fatalf("key %d: TestAndAdd = %v, MightContain said %v", i, got, want)
+		}
+		if !bf.TestAndAdd(key) {
+			t.Fatalf("key %d: second TestAndAdd reported new", i)
+		}
+	}
+	ref := NewWithEstimates(1000, 0.01)
+	for _, key := range keys {
+		ref.Add(key)
+	}
+	if !bf.Equal(ref) {
+		t.Fatal("TestAndAdd set different bits than Add")
+	}
+	expectPanic(t, ErrUninitialized, func() { new(BloomFilter).TestAndAdd([]byte("x")) })
+}
+
+// BenchmarkDedup compares TestAndAdd with the check-then-add pattern it
+// replaces, over a stream where every key repeats once.
+func BenchmarkDedup(b *testing.B) {
+	keys := benchmarkKeys(1 << 16)
+	b.Run("TestAndAdd", func(b *testing.B) {
+		bf := NewWithEstimates(uint64(len(keys)), 0.01)
+		for i := 0; i < b.N; i++ {
+			bf.TestAndAdd(keys[(i/2)%len(keys)])
+		}
+	})
+	b.Run("MightContain+Add", func(b *testing.B) {
+		bf := NewWithEstimates(uint64(len(keys)), 0.01)
+		for i := 0; i < b.N; i++ {
+			if key := keys[(i/2)%len(keys)]; !bf.MightContain(key) {
+				bf.Add(key)
+			}
+		}
+	})
+	b.Run("SafeBloom/TestAndAdd", func(b *testing.B) {
+		s := NewSafeWithEstimates(uint64(len(keys)), 0.01)
+		for i := 0; i < b.N; i++ {
+			s.TestAndAdd(keys[(i/2)%len(keys)])
+		}
+	})
+	b.Run("SafeBloom/MightContain+Add", func(b *testing.B) {
+		s := NewSafeWithEstimates(uint64(len(keys)), 0.01)
+		for i := 0; i < b.N; i++ {
+			if key := keys[(i/2)%len(keys)]; !s.MightContain(key) {
+				s.Add(key)
+			}
+		}
+	})
+}
+
+func TestTryNew_Errors(t *testing.T) {
+	cases := []struct {
+		name string
+		fn   func() error
+		want error
+	}{
+		{"m zero", func() error { _, err := TryNew(0, 3); return err }, ErrZeroBits},
+		{"k zero", func() error { _, err := TryNew(64, 0); return err }, ErrZeroHashes},
+		{"m huge", func() error { _, err := TryNew(math.MaxUint64, 3); return err }, ErrFilterTooLarge},
+		{"n zero", func() error { _, err := TryNewWithEstimates(0, 0.01); return err }, ErrZeroInsertions},
+		{"fpRate 1", func() error { _, err := TryNewWithEstimates(100, 1.0); return err }, ErrInvalidFPRate},
+		{"fpRate 0", func() error { _, err := TryNewWithEstimates(100, 0); return err }, ErrInvalidFPRate},
+		{"fpRate NaN", func() error { _, err := TryNewWithEstimates(100, math.NaN()); return err }, ErrInvalidFPRate},
+		{"n huge", func() error { _, err := TryNewWithEstimates(math.MaxUint64, 1e-9); return err }, ErrFilterTooLarge},
+		{"safe m zero", func() error { _, err := TryNewSafe(0, 3); return err }, ErrZeroBits},
+		{"safe fpRate", func() error { _, err := TryNewSafeWithEstimates(100, 1.5); return err }, ErrInvalidFPRate},
+	}
+	for _, c := range cases {
+		if err := c.fn(); !errors.Is(err, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, err)
+		}
+	}
+
+	bf, err := TryNewWithEstimates(1000, 0.01)
+	if err != nil || bf.Info() != NewWithEstimates(1000, 0.01).Info() || bf.Stats().Capacity != 1000 {
+		t.Fatalf("TryNewWithEstimates differs from NewWithEstimates: %v", err)
+	}
+	if s, err := TryNewSafe(64, 3); err != nil || s.Params().M != 64 {
+		t.Fatalf("TryNewSafe: %v", err)
+	}
+
+	// The panicking constructors raise the same errors.
+	expectPanic(t, ErrZeroBits, func() { New(0, 3) })
+	expectPanic(t, ErrZeroHashes, func() { NewSafe(64, 0) })
+	expectPanic(t, ErrInvalidFPRate, func() { NewWithEstimates(100, 1.0) })
+	expectPanic(t, ErrZeroInsertions, func() { NewSafeWithEstimates(0, 0.01) })
+}
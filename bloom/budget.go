@@ -0,0 +1,71 @@
+package bloom
+
+import "errors"
+
+// MaxBudgetFPRate is the highest expected false positive rate
+// NewOptimalForMemory accepts. Beyond it the filter answers "maybe" for
+// most queries and is almost certainly misconfigured.
+const MaxBudgetFPRate = 0.5
+
+// maxBudgetK caps k for budgets far larger than n needs. The optimal k grows
+// linearly with m/n, but past this point the false positive rate is already
+// negligible and every extra probe only slows Add and MightContain down.
+const maxBudgetK = 64
+
+var (
+	// ErrBudgetTooSmall is returned when a memory budget cannot hold a single
+	// 64-bit word, or yields a false positive rate above MaxBudgetFPRate.
+	ErrBudgetTooSmall = errors.New("bloom: memory budget too small")
+
+	// ErrBudgetTooLarge is returned when a memory budget exceeds the largest
+	// filter that can be allocated, 2^48 bytes of words.
+	ErrBudgetTooLarge = errors.New("bloom: memory budget too large")
+
+	// ErrZeroInsertions is returned when the expected insertion count is zero.
+	ErrZeroInsertions = errors.New("bloom: n (expected insertions) must be > 0")
+)
+
+// NewOptimalForMemory sizes a filter to fit budgetBytes of bitset storage for
+// n expected insertions. m is the largest whole number of 64-bit words that
+// fits the budget, and k = (m / n) * ln 2 (at least 1, at most 64).
+//
+// It returns the filter together with its expected false positive rate after
+// n insertions. If that rate exceeds MaxBudgetFPRate the filter is not built
+// and ErrBudgetTooSmall is returned along with the rate that would have resulted.
+func NewOptimalForMemory(budgetBytes uint64, n uint64) (*BloomFilter, float64, error) {
+	m, k, fpRate, err := budgetParameters(budgetBytes, n)
+	if err != nil {
+		return nil, fpRate, err
+	}
+	bf, err := TryNew(m, k)
+	if err != nil {
+		return nil, fpRate, err
+	}
+	return bf, fpRate, nil
+}
+
+// budgetParameters derives m, k and the expected false positive rate for
+// NewOptimalForMemory without allocating anything.
+func budgetParameters(budgetBytes uint64, n uint64) (m, k uint64, fpRate float64, err error) {
+	if n == 0 {
+		return 0, 0, 0, ErrZeroInsertions
+	}
+	words := budgetBytes / 8
+	if words == 0 {
+		return 0, 0, 1, ErrBudgetTooSmall
+	}
+	if words > maxBits/64 {
+		return 0, 0, 0, ErrBudgetTooLarge
+	}
+
+	m = words * 64
+	k = OptimalK(m, n)
+	if k > maxBudgetK {
+		k = maxBudgetK
+	}
+	fpRate = EstimateFalsePositiveRate(m, k, n)
+	if fpRate > MaxBudgetFPRate {
+		return 0, 0, fpRate, ErrBudgetTooSmall
+	}
+	return m, k, fpRate, nil
+}
@@ -0,0 +1,100 @@
+package bloom
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNewOptimalForMemory_TinyBudget(t *testing.T) {
+	for _, budget := range []uint64{0, 1, 7} {
+		bf, _, err := NewOptimalForMemory(budget, 10)
+		if !errors.Is(err, ErrBudgetTooSmall) || bf != nil {
+			t.Fatalf("budget %d: got (%v, %v), want ErrBudgetTooSmall", budget, bf, err)
+		}
+	}
+
+	// 16 bytes for 100 keys fits in memory but would be useless.
+	_, fpRate, err := NewOptimalForMemory(16, 100)
+	if !errors.Is(err, ErrBudgetTooSmall) {
+		t.Fatalf("expected ErrBudgetTooSmall, got %v", err)
+	}
+	if fpRate <= MaxBudgetFPRate {
+		t.Fatalf("expected reported rate above %v, got %v", MaxBudgetFPRate, fpRate)
+	}
+}
+
+func TestNewOptimalForMemory_ZeroN(t *testing.T) {
+	if _, _, err := NewOptimalForMemory(1024, 0); !errors.Is(err, ErrZeroInsertions) {
+		t.Fatalf("expected ErrZeroInsertions, got %v", err)
+	}
+}
+
+func TestNewOptimalForMemory_HugeBudget(t *testing.T) {
+	// 64 MB for 50M keys: sized without allocating.
+	m, k, fpRate, err := budgetParameters(64<<20, 50_000_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != 64<<23 || k != 8 {
+		t.Fatalf("got m=%d k=%d, want m=%d k=8", m, k, uint64(64<<23))
+	}
+	if math.Abs(fpRate-0.0058) > 0.0001 {
+		t.Fatalf("unexpected fpRate %v", fpRate)
+	}
+
+	// Far more memory than n needs: k is capped.
+	_, k, fpRate, err = budgetParameters(1<<40, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k != maxBudgetK || fpRate > 1e-12 {
+		t.Fatalf("got k=%d fpRate=%v, want k=%d and negligible rate", k, fpRate, maxBudgetK)
+	}
+
+	if _, _, _, err := budgetParameters(math.MaxUint64, 1000); !errors.Is(err, ErrBudgetTooLarge) {
+		t.Fatalf("expected ErrBudgetTooLarge, got %v", err)
+	}
+
+	// The largest filter that can be allocated is 2^48 bytes; beyond it
+	// NewOptimalForMemory fails instead of panicking.
+	if m, _, _, err := budgetParameters(1<<48, 1000); err != nil || m != maxBits {
+		t.Fatalf("2^48-byte budget: m=%d, %v", m, err)
+	}
+	if _, _, err := NewOptimalForMemory(1<<48+8, 1000); !errors.Is(err, ErrBudgetTooLarge) {
+		t.Fatalf("expected ErrBudgetTooLarge, got %v", err)
+	}
+}
+
+func TestNewOptimalForMemory_SingleItem(t *testing.T) {
+	bf, fpRate, err := NewOptimalForMemory(8, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bf.m != 64 || bf.k != 45 {
+		t.Fatalf("got %s, want m=64 k=45", bf.Info())
+	}
+	if fpRate > 1e-12 {
+		t.Fatalf("unexpected fpRate %v", fpRate)
+	}
+
+	bf.Add([]byte("only"))
+	if !bf.MightContain([]byte("only")) {
+		t.Fatal(`expected "only" to be present`)
+	}
+}
+
+func TestNewSafeOptimalForMemory(t *testing.T) {
+	s, _, err := NewSafeOptimalForMemory(1<<10, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Add([]byte("foo"))
+	if !s.MightContain([]byte("foo")) {
+		t.Fatal(`expected "foo" to be present`)
+	}
+
+	if _, _, err := NewSafeOptimalForMemory(4, 100); !errors.Is(err, ErrBudgetTooSmall) {
+		t.Fatalf("expected ErrBudgetTooSmall, got %v", err)
+	}
+}
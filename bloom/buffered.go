@@ -0,0 +1,107 @@
+package bloom
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// defaultBufferedKeys is the number of keys a BufferedWriter holds before
+// flushing when NewBufferedWriter is given 0.
+const defaultBufferedKeys = 1024
+
+// BufferedWriter batches Adds to an Atomic filter for one goroutine. Add
+// only hashes the key and appends its bit positions to a private buffer;
+// the bits are set when the buffer fills, on Flush and on Close. A flush
+// sets its bits with atomic OR operations as Atomic.Add does, but updates
+// the filter's shared set-bit and insert counters once for the whole batch
+// rather than once per bit and key, so many writers stop bouncing those
+// cache lines between cores.
+//
+// A key is visible to MightContain on the filter only once the buffer
+// holding it has been flushed: up to the buffer size of the most recent
+// keys of each writer may be missing. Call Flush where a caller needs
+// every key added so far to be visible, and Close when done.
+//
+// A BufferedWriter is not safe for concurrent use; give each goroutine its
+// own. Keys still buffered when the filter is Reset are flushed into the
+// new, empty one.
+type BufferedWriter struct {
+	a       *Atomic
+	bf      *BloomFilter // the filter the buffered positions were hashed for
+	pending []uint64     // bit positions not yet set
+	keys    uint64       // keys whose positions are in pending
+	closed  bool
+}
+
+// NewBufferedWriter returns a writer that buffers size keys before
+// flushing to a; size <= 0 means 1024. It panics with ErrUninitialized on
+// a zero-value Atomic.
+func (a *Atomic) NewBufferedWriter(size int) *BufferedWriter {
+	bf := a.bf.Load()
+	if !bf.initialized() {
+		panic(ErrUninitialized)
+	}
+	if size <= 0 {
+		size = defaultBufferedKeys
+	}
+	return &BufferedWriter{a: a, bf: bf, pending: make([]uint64, 0, size*int(bf.k))}
+}
+
+// Add buffers data, flushing first if the buffer is full. It panics with
+// os.ErrClosed after Close.
+func (w *BufferedWriter) Add(data []byte) {
+	if w.closed {
+		panic(os.ErrClosed)
+	}
+	bf := w.bf
+	if len(w.pending)+int(bf.k) > cap(w.pending) {
+		w.Flush()
+	}
+	h := bf.hashes(data)
+	for i := uint64(0); i < bf.k; i++ {
+		w.pending = append(w.pending, bf.location(h, i))
+	}
+	w.keys++
+}
+
+// AddString buffers s without copying it. See BloomFilter.AddString.
+func (w *BufferedWriter) AddString(s string) {
+	w.Add(stringBytes(s))
+}
+
+// Flush sets the bits of every buffered key in the filter. Once it
+// returns, MightContain reports true for every key added through w.
+func (w *BufferedWriter) Flush() {
+	if w.keys == 0 {
+		return
+	}
+	// Reset swaps in a new filter of the same geometry, so positions
+	// hashed for the old one are valid in it.
+	bf := w.a.bf.Load()
+	var set uint64
+	for _, pos := range w.pending {
+		mask := uint64(1) << (pos % 64)
+		if atomic.OrUint64(bf.word(pos/64), mask)&mask == 0 {
+			set++
+		}
+	}
+	atomic.AddUint64(&bf.setBits, set)
+	atomic.AddUint64(&bf.inserts, w.keys)
+	w.pending, w.keys = w.pending[:0], 0
+}
+
+// Buffered returns the number of keys added but not yet flushed.
+func (w *BufferedWriter) Buffered() int {
+	return int(w.keys)
+}
+
+// Close flushes the buffer. Later Adds panic; a second Close returns
+// os.ErrClosed.
+func (w *BufferedWriter) Close() error {
+	if w.closed {
+		return os.ErrClosed
+	}
+	w.Flush()
+	w.closed = true
+	return nil
+}
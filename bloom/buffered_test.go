@@ -0,0 +1,119 @@
+package bloom
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestBufferedWriter_ConcurrentWriters(t *testing.T) {
+	const writers, perWriter = 8, 5000
+	keys := randomKeys("buffered", writers*perWriter, 41)
+	a := NewAtomicWithEstimates(uint64(len(keys)), 0.01)
+
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func(keys [][]byte) {
+			defer wg.Done()
+			bw := a.NewBufferedWriter(100)
+			defer bw.Close()
+			for i, key := range keys {
+				bw.Add(key)
+				if i%1000 == 999 {
+					bw.Flush()
+				}
+			}
+		}(keys[w*perWriter : (w+1)*perWriter])
+	}
+	wg.Wait()
+
+	for i, key := range keys {
+		if !a.MightContain(key) {
+			t.Fatalf("key %d missing after every writer closed", i)
+		}
+	}
+	want := NewWithEstimates(uint64(len(keys)), 0.01)
+	want.AddAll(keys)
+	got := a.Snapshot()
+	if !got.Equal(want) || got.Stats() != want.Stats() {
+		t.Fatal("buffered writers left a different filter than AddAll")
+	}
+}
+
+func TestBufferedWriter_Visibility(t *testing.T) {
+	a := NewAtomic(1<<16, 4)
+	bw := a.NewBufferedWriter(3)
+	keys := randomKeys("visible", 4, 42)
+	for _, key := range keys[:3] {
+		bw.Add(key)
+	}
+	if bw.Buffered() != 3 || a.MightContain(keys[0]) || a.Count() != 0 {
+		t.Fatal("keys visible before the buffer flushed")
+	}
+	// The fourth key does not fit, so the first three are flushed.
+	bw.Add(keys[3])
+	if bw.Buffered() != 1 || !a.MightContain(keys[2]) || a.MightContain(keys[3]) || a.Count() != 3 {
+		t.Fatal("a full buffer was not flushed")
+	}
+	bw.Flush()
+	if !a.MightContain(keys[3]) || a.Count() != 4 {
+		t.Fatal("Flush left a key buffered")
+	}
+
+	// Keys buffered across a Reset land in the new filter.
+	bw.AddString("after")
+	a.Reset()
+	if err := bw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !a.MightContainString("after") || a.MightContain(keys[0]) || a.Count() != 1 {
+		t.Fatal("Close did not flush into the reset filter")
+	}
+	if err := bw.Close(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("second Close: %v", err)
+	}
+	expectPanic(t, os.ErrClosed, func() { bw.AddString("closed") })
+	expectPanic(t, ErrUninitialized, func() { new(Atomic).NewBufferedWriter(0) })
+}
+
+func BenchmarkBufferedWriter(b *testing.B) {
+	keys := randomKeys("bench", 1<<16, 1)
+	for _, g := range []int{1, 8, 32} {
+		b.Run("Atomic/goroutines="+strconv.Itoa(g), func(b *testing.B) {
+			a := NewAtomicWithEstimates(1<<20, 0.01)
+			benchmarkAdds(b, g, keys, func() (func([]byte), func()) { return a.Add, func() {} })
+		})
+		b.Run("BufferedWriter/goroutines="+strconv.Itoa(g), func(b *testing.B) {
+			a := NewAtomicWithEstimates(1<<20, 0.01)
+			benchmarkAdds(b, g, keys, func() (func([]byte), func()) {
+				bw := a.NewBufferedWriter(0)
+				return bw.Add, func() { bw.Close() }
+			})
+		})
+	}
+}
+
+// benchmarkAdds splits b.N Adds of keys across g goroutines, each with the
+// add and done functions newWriter returns.
+func benchmarkAdds(b *testing.B, g int, keys [][]byte, newWriter func() (add func([]byte), done func())) {
+	var wg sync.WaitGroup
+	for w := range g {
+		n := b.N / g
+		if w < b.N%g {
+			n++
+		}
+		add, done := newWriter()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer done()
+			for i := range n {
+				add(keys[(w*7919+i)&(len(keys)-1)])
+			}
+		}()
+	}
+	wg.Wait()
+}
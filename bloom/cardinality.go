@@ -0,0 +1,93 @@
+package bloom
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// ApproximateItemCount estimates the number of distinct keys added to the
+// filter from the bits it has set, using n ≈ -(m/k)·ln(1 - X/m). X is the
+// running set-bit count, so this is O(1). Re-adding a key does not change
+// the estimate, unlike Stats().Inserts. It is 0 for an empty or zero-value
+// filter and +Inf once every bit is set, when the filter can say nothing
+// more.
+//
+// The estimate is within a few percent up to the design capacity and
+// loses precision as the fill ratio approaches 1.
+func (bf *BloomFilter) ApproximateItemCount() float64 {
+	if !bf.initialized() {
+		return 0
+	}
+	return estimateItems(bf.m, bf.k, bf.setBits)
+}
+
+// EstimateUnionCount estimates the number of distinct keys added to a or b
+// from the bits set in their union, using the Swamidass & Baldi estimate
+// n ≈ -(m/k)·ln(1 - X/m). The filters must be compatible and are not
+// modified. The result is +Inf if the union has every bit set.
+func EstimateUnionCount(a, b *BloomFilter) (float64, error) {
+	if err := a.checkCompatible(b); err != nil {
+		return 0, err
+	}
+	return estimateItems(a.m, a.k, unionBits(a, b)), nil
+}
+
+// EstimateIntersectionCount estimates the number of distinct keys added to
+// both a and b by inclusion-exclusion: n(a) + n(b) - n(a ∪ b), clamped at
+// zero. The filters must be compatible and are not modified. If any of the
+// three estimates is unbounded because every bit is set, it fails with
+// ErrOverCapacity.
+//
+// The estimate is a difference of estimates, so its absolute error is
+// roughly that of the union estimate; it is least reliable when the
+// overlap is small relative to the sets.
+func EstimateIntersectionCount(a, b *BloomFilter) (float64, error) {
+	if err := a.checkCompatible(b); err != nil {
+		return 0, err
+	}
+	na := estimateItems(a.m, a.k, a.onesCount())
+	nb := estimateItems(b.m, b.k, b.onesCount())
+	nu := estimateItems(a.m, a.k, unionBits(a, b))
+	if math.IsInf(na, 1) || math.IsInf(nb, 1) || math.IsInf(nu, 1) {
+		return 0, fmt.Errorf("%w: every bit is set, cardinality cannot be estimated", ErrOverCapacity)
+	}
+	return max(na+nb-nu, 0), nil
+}
+
+// unionBits returns the number of bits set in a OR b, ignoring padding.
+func unionBits(a, b *BloomFilter) uint64 {
+	var n uint64
+	eachSpanPair(&a.storage, &b.storage, func(_ int, x, y []uint64) {
+		for i := range x {
+			n += uint64(bits.OnesCount64(x[i] | y[i]))
+		}
+	})
+	last := uint64(a.wordCount() - 1)
+	padding := (*a.word(last) | *b.word(last)) &^ lastWordMask(a.m)
+	return n - uint64(bits.OnesCount64(padding))
+}
+
+// JaccardEstimate estimates the Jaccard similarity |A∩B| / |A∪B| of the key
+// sets behind two compatible filters, from EstimateIntersectionCount and
+// EstimateUnionCount. Neither filter is modified. Identical filters,
+// including two empty ones, give exactly 1.
+//
+// Accuracy falls off quickly as the union fills up: past a fill ratio of
+// about 0.9 a handful of bits swings the estimates by large amounts, and
+// the result should not be trusted. Once every bit of either filter or of
+// the union is set it fails with ErrOverCapacity.
+func JaccardEstimate(a, b *BloomFilter) (float64, error) {
+	if err := a.checkCompatible(b); err != nil {
+		return 0, err
+	}
+	inter, err := EstimateIntersectionCount(a, b)
+	if err != nil {
+		return 0, err
+	}
+	union := estimateItems(a.m, a.k, unionBits(a, b))
+	if union == 0 {
+		return 1, nil
+	}
+	return min(inter/union, 1), nil
+}
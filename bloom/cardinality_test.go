@@ -0,0 +1,171 @@
+package bloom
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+)
+
+// cardinalityTolerance is the documented relative error for filters loaded
+// up to their design capacity with arbitrary keys: the estimates below
+// stay within 5% of the true counts.
+//
+// The estimate assumes well-spread probe positions. The default FNV
+// double hashing sets noticeably fewer bits than that for runs of keys
+// differing only in a trailing counter ("user-1", "user-2", ...), and
+// estimates for such keys can fall 15-20% short.
+const cardinalityTolerance = 0.05
+
+// cardinalityKeys are distinct keys with no shared structure beyond a
+// prefix.
+var cardinalityKeys = func() [][]byte {
+	r := rand.New(rand.NewPCG(1, 2))
+	keys := make([][]byte, 12_000)
+	for i := range keys {
+		keys[i] = []byte("user-" + strconv.FormatUint(r.Uint64(), 16))
+	}
+	return keys
+}()
+
+func withinTolerance(got, want float64) bool {
+	return math.Abs(got-want) <= cardinalityTolerance*want
+}
+
+func cardinalityFilter(from, to int) *BloomFilter {
+	bf := NewWithEstimates(20_000, 0.01)
+	for _, key := range cardinalityKeys[from:to] {
+		bf.Add(key)
+	}
+	return bf
+}
+
+func TestEstimateCounts(t *testing.T) {
+	cases := []struct {
+		name              string
+		a, b              [2]int
+		union, intersects float64
+	}{
+		{"disjoint", [2]int{0, 5000}, [2]int{5000, 10_000}, 10_000, 0},
+		{"overlapping", [2]int{0, 8000}, [2]int{4000, 12_000}, 12_000, 4000},
+		{"identical", [2]int{0, 6000}, [2]int{0, 6000}, 6000, 6000},
+	}
+	for _, c := range cases {
+		a := cardinalityFilter(c.a[0], c.a[1])
+		b := cardinalityFilter(c.b[0], c.b[1])
+		aBits := a.setBits
+
+		union, err := EstimateUnionCount(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !withinTolerance(union, c.union) {
+			t.Errorf("%s: union estimate %.0f, want %.0f ±%.0f%%", c.name, union, c.union, cardinalityTolerance*100)
+		}
+
+		inter, err := EstimateIntersectionCount(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A relative bound is meaningless around zero; allow 5% of the union.
+		if math.Abs(inter-c.intersects) > cardinalityTolerance*c.union {
+			t.Errorf("%s: intersection estimate %.0f, want %.0f", c.name, inter, c.intersects)
+		}
+		if a.setBits != aBits || popcount(a.bits) != aBits {
+			t.Fatalf("%s: estimating modified an input", c.name)
+		}
+	}
+}
+
+func TestEstimateCounts_Errors(t *testing.T) {
+	if _, err := EstimateUnionCount(New(64, 3), New(128, 3)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible, got %v", err)
+	}
+
+	full := New(64, 1)
+	for i := range full.bits {
+		full.bits[i] = ^uint64(0)
+	}
+	full.setBits = 64
+	if n, err := EstimateUnionCount(full, New(64, 1)); err != nil || !math.IsInf(n, 1) {
+		t.Fatalf("union of a full filter = %v, %v; want +Inf", n, err)
+	}
+	if _, err := EstimateIntersectionCount(full, New(64, 1)); !errors.Is(err, ErrOverCapacity) {
+		t.Fatalf("expected ErrOverCapacity, got %v", err)
+	}
+}
+
+func TestJaccardEstimate(t *testing.T) {
+	a := cardinalityFilter(0, 6000)
+	if j, err := JaccardEstimate(a, a.Clone()); err != nil || j != 1 {
+		t.Fatalf("identical filters: J = %v, %v; want exactly 1", j, err)
+	}
+	if j, err := JaccardEstimate(New(64, 3), New(64, 3)); err != nil || j != 1 {
+		t.Fatalf("empty filters: J = %v, %v; want exactly 1", j, err)
+	}
+
+	cases := []struct {
+		name string
+		a, b [2]int
+		want float64
+	}{
+		{"disjoint", [2]int{0, 6000}, [2]int{6000, 12_000}, 0},
+		{"half", [2]int{0, 8000}, [2]int{4000, 12_000}, 1.0 / 3},
+	}
+	for _, c := range cases {
+		a, b := cardinalityFilter(c.a[0], c.a[1]), cardinalityFilter(c.b[0], c.b[1])
+		j, err := JaccardEstimate(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(j-c.want) > 0.03 {
+			t.Errorf("%s: J = %.3f, want %.3f ±0.03", c.name, j, c.want)
+		}
+	}
+
+	sa, sb := NewSafeWithEstimates(20_000, 0.01), NewSafeWithEstimates(20_000, 0.01)
+	for _, key := range cardinalityKeys[:6000] {
+		sa.Add(key)
+		sb.Add(key)
+	}
+	if j, err := sa.JaccardEstimate(sb); err != nil || j != 1 {
+		t.Fatalf("SafeBloom identical: J = %v, %v", j, err)
+	}
+	if _, err := JaccardEstimate(a, New(64, 3)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible, got %v", err)
+	}
+}
+
+func TestApproximateItemCount(t *testing.T) {
+	bf := NewWithEstimates(10_000, 0.01)
+	if got := bf.ApproximateItemCount(); got != 0 {
+		t.Fatalf("empty filter estimates %v items", got)
+	}
+	added := 0
+	for _, n := range []int{100, 1000, 5000, 10_000, 12_000} {
+		for ; added < n; added++ {
+			bf.Add(cardinalityKeys[added])
+		}
+		bf.Add(cardinalityKeys[0]) // repeats are not counted
+		got := bf.ApproximateItemCount()
+		if !withinTolerance(got, float64(n)) {
+			t.Errorf("%d keys (fill %.2f): estimate %.0f", n, bf.fillRatio(), got)
+		}
+	}
+
+	s := NewSafe(10_000, 7)
+	s.Swap(bf.Clone())
+	if s.ApproximateItemCount() != bf.ApproximateItemCount() {
+		t.Fatal("SafeBloom estimate differs")
+	}
+
+	full := New(64, 3)
+	full.bits[0], full.setBits = math.MaxUint64, 64
+	if got := full.ApproximateItemCount(); !math.IsInf(got, 1) {
+		t.Fatalf("saturated filter estimates %v, want +Inf", got)
+	}
+	if got := new(BloomFilter).ApproximateItemCount(); got != 0 {
+		t.Fatalf("zero-value filter estimates %v", got)
+	}
+}
@@ -0,0 +1,255 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"unsafe"
+)
+
+const (
+	// cascadeDeepRate is the false positive rate of every level after the
+	// first. Levels past the first hold fast-shrinking sets, and 0.5 is the
+	// rate that minimizes their total size (Larisch et al., CRLite, 2017).
+	cascadeDeepRate = 0.5
+
+	// maxCascadeLevels bounds the cascade. Every two levels past the first
+	// halve the keys still misclassified, so even billions of keys need
+	// well under a hundred levels; only a key listed as both a positive and
+	// a negative gets anywhere near the bound.
+	maxCascadeLevels = 256
+)
+
+// Cascade is a filter cascade that answers membership exactly for every
+// key of a labelled universe, as used by CRLite for certificate
+// revocation. Level 0 holds the positives; level 1 holds the negatives
+// level 0 wrongly reports; level 2 the positives level 1 wrongly reports,
+// and so on until a level makes no mistakes.
+//
+// Contains is exact for any key passed to BuildCascade. For keys outside
+// that universe it behaves like a Bloom filter of the positives, with
+// about the first level's false positive rate.
+//
+// Each level hashes keys with its own seed, so a collision at one level
+// does not repeat at the next.
+type Cascade struct {
+	seed   uint64
+	levels []*BloomFilter
+	stats  []CascadeLevelStats
+}
+
+// CascadeLevelStats describes one level of a Cascade, for tuning.
+type CascadeLevelStats struct {
+	Keys           uint64  `json:"keys"`            // keys inserted at this level
+	FalsePositives uint64  `json:"false_positives"` // keys of the other label it wrongly reports; the next level's Keys
+	FPRate         float64 `json:"fp_rate"`         // designed false positive rate
+	M              uint64  `json:"m"`               // no. of bits
+	K              uint64  `json:"k"`               // no. of hash functions
+	SizeBytes      uint64  `json:"size_bytes"`      // see BloomFilter.SizeInBytes
+}
+
+// BuildCascade builds a Cascade with seed 0. See BuildCascadeWithSeed.
+func BuildCascade(positives, negatives [][]byte, fpRate float64) (*Cascade, error) {
+	return BuildCascadeWithSeed(positives, negatives, fpRate, 0)
+}
+
+// BuildCascadeWithSeed builds a Cascade that reports every key in positives
+// present and every key in negatives absent. The first level is sized for
+// fpRate, which trades its size against how many negatives the later
+// levels must correct; later levels use a rate of 0.5.
+//
+// The result depends only on the keys, their order, fpRate and seed, so
+// two builders given the same inputs produce byte-identical encodings.
+// A key listed in both sets cannot be classified and fails the build with
+// ErrStaticBuildFailed once the cascade reaches its level bound.
+//
+// This panics if fpRate is not in (0, 1).
+func BuildCascadeWithSeed(positives, negatives [][]byte, fpRate float64, seed uint64) (*Cascade, error) {
+	if fpRate <= 0.0 || fpRate >= 1.0 {
+		panic("bloom: fpRate must be between 0 and 1 (exclusive)")
+	}
+	c := &Cascade{seed: seed}
+	include, exclude := positives, negatives
+	rate := fpRate
+	for level := 0; len(include) > 0; level++ {
+		if level == maxCascadeLevels {
+			return nil, fmt.Errorf("%w: cascade still has %d misclassified keys after %d levels; is a key both positive and negative?",
+				ErrStaticBuildFailed, len(include), maxCascadeLevels)
+		}
+		bf := NewWithEstimates(uint64(len(include)), rate)
+		bf.scheme = schemeGuava64
+		for _, key := range include {
+			bf.addHashes(c.hashes(level, key))
+		}
+
+		var wrong [][]byte
+		for _, key := range exclude {
+			if bf.containsHashes(c.hashes(level, key)) {
+				wrong = append(wrong, key)
+			}
+		}
+		c.levels = append(c.levels, bf)
+		c.stats = append(c.stats, CascadeLevelStats{
+			Keys:           uint64(len(include)),
+			FalsePositives: uint64(len(wrong)),
+			FPRate:         rate,
+			M:              bf.m,
+			K:              bf.k,
+			SizeBytes:      bf.SizeInBytes(),
+		})
+		include, exclude = wrong, include
+		rate = cascadeDeepRate
+	}
+	return c, nil
+}
+
+// Contains reports whether data is one of the positives the cascade was
+// built from. It is exact for keys of the labelled universe; see Cascade.
+func (c *Cascade) Contains(data []byte) bool {
+	if c == nil || len(c.levels) == 0 {
+		return false
+	}
+	for level, bf := range c.levels {
+		if !bf.containsHashes(c.hashes(level, data)) {
+			// Absent from an even level means negative, from an odd level
+			// positive.
+			return level%2 == 1
+		}
+	}
+	return (len(c.levels)-1)%2 == 0
+}
+
+// MightContain is Contains, under the name ColdTier and the other
+// filters use.
+func (c *Cascade) MightContain(data []byte) bool {
+	return c.Contains(data)
+}
+
+// Levels returns per-level statistics, from level 0 down.
+func (c *Cascade) Levels() []CascadeLevelStats {
+	if c == nil {
+		return nil
+	}
+	return append([]CascadeLevelStats(nil), c.stats...)
+}
+
+// SizeInBytes reports the memory held by all levels.
+func (c *Cascade) SizeInBytes() uint64 {
+	if c == nil {
+		return 0
+	}
+	size := uint64(unsafe.Sizeof(*c)) + uint64(len(c.stats))*uint64(unsafe.Sizeof(CascadeLevelStats{}))
+	for _, bf := range c.levels {
+		size += bf.SizeInBytes()
+	}
+	return size
+}
+
+// Info returns a small description of the cascade.
+func (c *Cascade) Info() string {
+	if c == nil {
+		return "Cascade{nil}"
+	}
+	var bits uint64
+	for _, bf := range c.levels {
+		bits += bf.m
+	}
+	return fmt.Sprintf("Cascade{levels=%d, m=%d bits}", len(c.levels), bits)
+}
+
+// hashes returns the base hashes of data at level, seeded per level.
+func (c *Cascade) hashes(level int, data []byte) baseHashes {
+	seed := uint32(mix64(c.seed + uint64(level)))
+	h1, h2 := murmur3x64_128(data, seed)
+	return baseHashes{h1, h2}
+}
+
+// Cascade binary format (all integers little-endian):
+//
+//	version  uint8   cascadeVersion
+//	seed     uint64
+//	levels   uint64
+//	per level:
+//	  keys            uint64
+//	  falsePositives  uint64
+//	  fpRate          float64 bits
+//	  filter          BloomFilter binary encoding
+const (
+	cascadeVersion   = 1
+	cascadeHeaderLen = 1 + 8 + 8
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (c *Cascade) MarshalBinary() ([]byte, error) {
+	if c == nil {
+		return nil, ErrUninitialized
+	}
+	buf := make([]byte, 0, cascadeHeaderLen)
+	buf = append(buf, cascadeVersion)
+	buf = binary.LittleEndian.AppendUint64(buf, c.seed)
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(c.levels)))
+	for i, bf := range c.levels {
+		st := c.stats[i]
+		buf = binary.LittleEndian.AppendUint64(buf, st.Keys)
+		buf = binary.LittleEndian.AppendUint64(buf, st.FalsePositives)
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(st.FPRate))
+		level, err := bf.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, level...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, fully replacing
+// the receiver's contents. Malformed data fails with ErrCorrupt, an
+// unknown version with ErrUnsupportedVersion; the receiver is left
+// untouched on error.
+func (c *Cascade) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	if data[0] != cascadeVersion {
+		return fmt.Errorf("%w: cascade version %d", ErrUnsupportedVersion, data[0])
+	}
+	if len(data) < cascadeHeaderLen {
+		return fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	decoded := &Cascade{seed: binary.LittleEndian.Uint64(data[1:])}
+	levels := binary.LittleEndian.Uint64(data[9:])
+	if levels > maxCascadeLevels {
+		return fmt.Errorf("%w: %d cascade levels", ErrCorrupt, levels)
+	}
+
+	r := bytes.NewReader(data[cascadeHeaderLen:])
+	var stat [24]byte
+	for i := uint64(0); i < levels; i++ {
+		if _, err := io.ReadFull(r, stat[:]); err != nil {
+			return fmt.Errorf("%w: level %d truncated", ErrCorrupt, i)
+		}
+		bf, _, err := readFilter(r)
+		if err != nil {
+			return fmt.Errorf("level %d: %w", i, err)
+		}
+		if bf.scheme != schemeGuava64 {
+			return fmt.Errorf("%w: level %d uses probe scheme %s", ErrCorrupt, i, bf.scheme)
+		}
+		decoded.levels = append(decoded.levels, bf)
+		decoded.stats = append(decoded.stats, CascadeLevelStats{
+			Keys:           binary.LittleEndian.Uint64(stat[0:]),
+			FalsePositives: binary.LittleEndian.Uint64(stat[8:]),
+			FPRate:         math.Float64frombits(binary.LittleEndian.Uint64(stat[16:])),
+			M:              bf.m,
+			K:              bf.k,
+			SizeBytes:      bf.SizeInBytes(),
+		})
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrCorrupt, r.Len())
+	}
+	*c = *decoded
+	return nil
+}
@@ -0,0 +1,119 @@
+package bloom
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func cascadeUniverse() (positives, negatives [][]byte) {
+	return randomKeys("revoked-", 2000, 71), randomKeys("valid-", 60_000, 72)
+}
+
+// TestCascade_ExactOverUniverse builds with a loose first level, so many
+// negatives collide with positives at level 0 and the corrections need
+// several levels, and checks every labelled key.
+func TestCascade_ExactOverUniverse(t *testing.T) {
+	positives, negatives := cascadeUniverse()
+	positives = append(positives, positives[:100]...) // duplicates are harmless
+	c, err := BuildCascade(positives, negatives, 0.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	levels := c.Levels()
+	if len(levels) < 4 || levels[0].FalsePositives < 1000 {
+		t.Fatalf("expected heavy level-0 collisions and a deep cascade, got %+v", levels)
+	}
+	for i, st := range levels {
+		if i+1 < len(levels) && st.FalsePositives != levels[i+1].Keys {
+			t.Fatalf("level %d: %d false positives but level %d holds %d keys", i, st.FalsePositives, i+1, levels[i+1].Keys)
+		}
+	}
+	if last := levels[len(levels)-1]; last.FalsePositives != 0 {
+		t.Fatalf("last level still misclassifies %d keys", last.FalsePositives)
+	}
+
+	for i, key := range positives {
+		if !c.Contains(key) {
+			t.Fatalf("positive %d reported absent", i)
+		}
+	}
+	for i, key := range negatives {
+		if c.Contains(key) {
+			t.Fatalf("negative %d reported present", i)
+		}
+	}
+}
+
+func TestCascade_Deterministic(t *testing.T) {
+	positives, negatives := cascadeUniverse()
+	encode := func(seed uint64) []byte {
+		c, err := BuildCascadeWithSeed(positives, negatives, 0.01, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := c.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	a, b := encode(7), encode(7)
+	if !bytes.Equal(a, b) {
+		t.Fatal("two builds with the same seed differ")
+	}
+	if bytes.Equal(a, encode(8)) {
+		t.Fatal("a different seed produced the same cascade")
+	}
+}
+
+func TestCascade_BinaryRoundTrip(t *testing.T) {
+	positives, negatives := cascadeUniverse()
+	c, err := BuildCascade(positives, negatives, 0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Cascade
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got.Info() != c.Info() || len(got.Levels()) != len(c.Levels()) || got.Levels()[1] != c.Levels()[1] {
+		t.Fatalf("decoded %s, want %s", got.Info(), c.Info())
+	}
+	for _, key := range append(positives[:500:500], negatives[:5000]...) {
+		if got.Contains(key) != c.Contains(key) {
+			t.Fatalf("%q: decoded cascade disagrees", key)
+		}
+	}
+
+	for name, bad := range map[string][]byte{
+		"truncated": data[:len(data)-1],
+		"trailing":  append(append([]byte(nil), data...), 0),
+		"header":    data[:cascadeHeaderLen-1],
+	} {
+		if err := got.UnmarshalBinary(bad); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: expected ErrCorrupt, got %v", name, err)
+		}
+	}
+	if err := got.UnmarshalBinary(append([]byte{9}, data[1:]...)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+func TestCascade_Contradiction(t *testing.T) {
+	positives, negatives := cascadeUniverse()
+	negatives = append(negatives, positives[0])
+	if _, err := BuildCascade(positives, negatives, 0.01); !errors.Is(err, ErrStaticBuildFailed) {
+		t.Fatalf("expected ErrStaticBuildFailed for a key in both sets, got %v", err)
+	}
+
+	empty, err := BuildCascade(nil, negatives, 0.01)
+	if err != nil || empty.Contains(negatives[0]) || len(empty.Levels()) != 0 {
+		t.Fatalf("an empty positive set must report nothing: %v", err)
+	}
+}
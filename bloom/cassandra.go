@@ -0,0 +1,90 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Cassandra -Filter.db layout (BloomFilterSerializer), integers big-endian
+// as written by Java's DataOutput:
+//
+//	hashCount  int32
+//	words      int32   no. of 64-bit words that follow
+//	data       words * 8 bytes
+//
+// Bit i of the filter is bit i%8 of byte i/8 of OffHeapBitSet's memory.
+// SSTable versions "na" and later write that memory as raw bytes; "ma"
+// through "me" write it as big-endian longs, each assembled from eight
+// memory bytes in little-endian order. Either way bit i ends up at
+// data[i/64] & 1<<(i%64), the layout this package uses.
+const cassandraHeaderSize = 4 + 4
+
+// cassandraRawBytes lists the SSTable format versions ReadCassandra
+// supports, and whether each stores the bitset as raw bytes (true) or as
+// big-endian longs (false). Versions before "ma" used a different probe
+// order and are not supported.
+var cassandraRawBytes = map[string]bool{
+	"ma": false, "mb": false, "mc": false, "md": false, "me": false, // 3.x
+	"na": true, "nb": true, // 4.x
+	"oa": true, "da": true, // 5.0 BIG and BTI
+}
+
+// ReadCassandra decodes a Cassandra or ScyllaDB -Filter.db file written by
+// the SSTable format version (the two-letter prefix of the SSTable file
+// names, e.g. "nb"). Keys are partition keys in their serialized form; the
+// result answers MightContain the way Cassandra's BloomFilter.isPresent
+// does. Unknown or pre-3.0 versions fail with ErrUnsupportedVersion.
+//
+// The layouts and hashing are implemented from Cassandra's source. No
+// -Filter.db file written by Cassandra or ScyllaDB is among the test
+// fixtures yet, so compatibility with either is unverified.
+func ReadCassandra(r io.Reader, version string) (*BloomFilter, error) {
+	rawBytes, ok := cassandraRawBytes[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: cassandra sstable version %q", ErrUnsupportedVersion, version)
+	}
+
+	var hbuf [cassandraHeaderSize]byte
+	if _, err := io.ReadFull(r, hbuf[:]); err != nil {
+		return nil, fmt.Errorf("%w: short cassandra filter header", ErrCorrupt)
+	}
+	k := int32(binary.BigEndian.Uint32(hbuf[0:]))
+	count := int32(binary.BigEndian.Uint32(hbuf[4:]))
+	if k <= 0 || count <= 0 {
+		return nil, fmt.Errorf("%w: cassandra hashCount=%d words=%d", ErrCorrupt, k, count)
+	}
+
+	order := binary.ByteOrder(binary.BigEndian)
+	if rawBytes {
+		order = binary.LittleEndian
+	}
+	words := make([]uint64, 0, min(uint64(count), streamChunkWords))
+	buf := make([]byte, streamChunkWords*8)
+	for remaining := uint64(count); remaining > 0; {
+		chunk := min(remaining, streamChunkWords)
+		if _, err := io.ReadFull(r, buf[:chunk*8]); err != nil {
+			return nil, fmt.Errorf("%w: cassandra filter ended %d words short", ErrCorrupt, remaining)
+		}
+		for i := uint64(0); i < chunk; i++ {
+			words = append(words, order.Uint64(buf[i*8:]))
+		}
+		remaining -= chunk
+	}
+
+	bf := &BloomFilter{m: uint64(count) * 64, k: uint64(k), storage: contiguous(words), scheme: schemeCassandra}
+	bf.setBits = popcount(words)
+	return bf, nil
+}
+
+// cassandraLocation mirrors BloomFilter.setIndexes with the post-3.0 hash
+// order: the sequence starts at the second murmur3 half, advances by the
+// first, and each value is reduced with Java's signed % and made
+// non-negative.
+func cassandraLocation(h1, h2, i, m uint64) uint64 {
+	pos := int64(h2+i*h1) % int64(m)
+	if pos < 0 {
+		pos = -pos
+	}
+	return uint64(pos)
+}
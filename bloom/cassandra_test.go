@@ -0,0 +1,79 @@
+package bloom
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// cassandraFox is a hand-built -Filter.db body in the "na" layout: k=3 over
+// one word after adding "The quick brown fox jumps over the lazy dog".
+// murmur3 gives h1=0xe34bbc7bbc071b6c and h2=0x7a433ca9c49a9347; the probes
+// h2, h2+h1 and h2+2*h1 are all positive as int64 and land on bits 7, 51
+// and 31. Like the legacy variant below, it is worked out from Cassandra's
+// source rather than dumped from a cluster, so these tests check the
+// package against that reading of the source, not against Cassandra.
+var cassandraFox = []byte{
+	0x00, 0x00, 0x00, 0x03, // hashCount
+	0x00, 0x00, 0x00, 0x01, // words
+	0x80, 0x00, 0x00, 0x80, 0x00, 0x00, 0x08, 0x00,
+}
+
+const foxSentence = "The quick brown fox jumps over the lazy dog"
+
+func TestReadCassandra_KnownVector(t *testing.T) {
+	bf, err := ReadCassandra(bytes.NewReader(cassandraFox), "nb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bf.m != 64 || bf.k != 3 || bf.setBits != 3 {
+		t.Fatalf("got %s with %d bits set, want m=64 k=3 and 3 bits", bf.Info(), bf.setBits)
+	}
+	if !bf.MightContain([]byte(foxSentence)) {
+		t.Fatal("expected the fox sentence to be present")
+	}
+
+	// Pre-4.0 versions store the same word as a big-endian long.
+	legacy := append(append([]byte(nil), cassandraFox[:8]...), 0x00, 0x08, 0x00, 0x00, 0x80, 0x00, 0x00, 0x80)
+	bf, err = ReadCassandra(bytes.NewReader(legacy), "mc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bf.MightContain([]byte(foxSentence)) {
+		t.Fatal("expected the fox sentence to be present in the 3.x layout")
+	}
+}
+
+func TestReadCassandra_Errors(t *testing.T) {
+	for _, version := range []string{"la", "ka", "zz", ""} {
+		_, err := ReadCassandra(bytes.NewReader(cassandraFox), version)
+		if !errors.Is(err, ErrUnsupportedVersion) {
+			t.Fatalf("version %q: expected ErrUnsupportedVersion, got %v", version, err)
+		}
+	}
+	if _, err := ReadCassandra(bytes.NewReader(cassandraFox[:12]), "na"); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt for truncated data, got %v", err)
+	}
+}
+
+func TestCassandra_HashQuirks(t *testing.T) {
+	// Java's signed bytes only matter in the tail: a high byte inside a full
+	// 16-byte block hashes like the reference, one in the tail does not.
+	block := []byte("\xffbcdefghijklmnop")
+	a1, a2 := cassandraMurmur3(block)
+	b1, b2 := murmur3x64_128(block, 0)
+	if a1 != b1 || a2 != b2 {
+		t.Fatal("high byte in a full block should hash like the reference")
+	}
+	tail := []byte("abc\xff")
+	a1, _ = cassandraMurmur3(tail)
+	b1, _ = murmur3x64_128(tail, 0)
+	if a1 == b1 {
+		t.Fatal("high byte in the tail should be sign-extended")
+	}
+
+	// Negative int64 sums are reduced with Java's signed % and then abs.
+	if got := cassandraLocation(0, 1<<64-1, 0, 64); got != 1 {
+		t.Fatalf("cassandraLocation(-1) = %d, want 1", got)
+	}
+}
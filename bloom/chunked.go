@@ -0,0 +1,250 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"unsafe"
+)
+
+// Chunked storage. A filter's words normally live in one []uint64, which
+// for very large filters means one huge allocation: it can fail on a
+// fragmented heap long before memory runs out, the GC has to treat it as a
+// single object, and on 32-bit platforms the address space rarely has room
+// for it. Chunked storage splits the words into fixed-size chunks of
+// 1<<chunkShift words, and word i lives at chunks[i>>chunkShift][i&mask].
+//
+// Contiguous storage keeps bits set, and the probe path indexes it
+// directly; only chunked storage pays for the second lookup. It is also
+// described as a single chunk with a shift of 63, so span arithmetic works
+// on either layout without special cases.
+//
+// Chunking is a property of the in-memory layout only. Every operation
+// sees the same words in the same order, the encodings are byte-for-byte
+// identical, and chunked and contiguous filters of the same parameters
+// can be merged and compared freely.
+//
+// Code that walks the words uses eachSpan, or eachSpanPair for two filters,
+// rather than bf.bits, so it works with either layout.
+
+// storage holds a filter's words in one of the two layouts.
+type storage struct {
+	bits       []uint64     // contiguous words; nil when chunked
+	chunks     [][]uint64   // chunks of 1<<chunkShift words, the last possibly shorter; {bits} when contiguous
+	chunkShift uint         // log2 of the words per chunk; contiguousShift when contiguous
+	chunkMask  uint64       // 1<<chunkShift - 1, kept so word need not compute it
+	gen        *generations // nil unless Reset is lazy (see lazyreset.go)
+}
+
+const (
+	// defaultChunkShift makes chunks of 1<<23 words, 64 MiB.
+	defaultChunkShift = 23
+
+	// contiguousShift is the chunkShift of contiguous storage: every word
+	// index is below 1<<63, so all of them fall in the one chunk.
+	contiguousShift = 63
+)
+
+// Filters larger than chunkedThresholdWords are chunked without
+// WithChunkedStorage, in chunks of 1<<autoChunkShift words. The threshold
+// is 1 GiB on 64-bit platforms and a single chunk on 32-bit ones, where
+// address space is scarce and large contiguous allocations often fail.
+// They are variables so tests can exercise the default chunking on small
+// filters.
+var (
+	autoChunkShift        uint = defaultChunkShift
+	chunkedThresholdWords      = 1 << (defaultChunkShift + 4*(bits.UintSize/64))
+)
+
+// WithChunkedStorage stores the filter's words in 64 MiB chunks instead of
+// one contiguous allocation, whatever its size. Filters larger than 1 GiB
+// (64 MiB on 32-bit platforms) are chunked without it. Chunked filters
+// behave exactly like contiguous ones, encode identically, and can be
+// combined with them; lookups cost one extra indirection.
+func WithChunkedStorage() Option {
+	return withChunkShift(defaultChunkShift)
+}
+
+// withChunkShift forces chunked storage with chunks of 1<<shift words, so
+// tests can build many-chunk filters cheaply.
+func withChunkShift(shift uint) Option {
+	return func(o *options) error {
+		if shift == 0 || shift > defaultChunkShift {
+			return fmt.Errorf("%w: chunk shift %d", ErrInvalidOption, shift)
+		}
+		if o.chunkShift != 0 && o.chunkShift != shift {
+			return fmt.Errorf("%w: chunk size given twice", ErrConflictingOptions)
+		}
+		o.chunkShift = shift
+		return nil
+	}
+}
+
+// contiguous returns storage holding words as one slice.
+func contiguous(words []uint64) storage {
+	return storage{bits: words, chunks: [][]uint64{words}, chunkShift: contiguousShift, chunkMask: 1<<contiguousShift - 1}
+}
+
+// chunkedStorage returns storage over chunks of 1<<shift words.
+func chunkedStorage(chunks [][]uint64, shift uint) storage {
+	return storage{chunks: chunks, chunkShift: shift, chunkMask: 1<<shift - 1}
+}
+
+// newStorage returns zeroed storage of n words laid out for shift: chunks
+// of 1<<shift words, or one slice for contiguousShift. Shift 0 picks
+// chunks of the default size if n is above chunkedThresholdWords and one
+// slice otherwise.
+func newStorage(n int, shift uint) storage {
+	if shift == 0 {
+		shift = contiguousShift
+		if n > chunkedThresholdWords {
+			shift = autoChunkShift
+		}
+	}
+	if shift == contiguousShift {
+		return contiguous(make([]uint64, n))
+	}
+	size := 1 << shift
+	chunks := make([][]uint64, 0, (n+size-1)/size)
+	for start := 0; start < n; start += size {
+		chunks = append(chunks, make([]uint64, min(size, n-start)))
+	}
+	return chunkedStorage(chunks, shift)
+}
+
+// chunkTableBytes returns the size of the chunk table newStorage gives a
+// filter of the given word count by default.
+func chunkTableBytes(words uint64) uint64 {
+	if words <= uint64(chunkedThresholdWords) {
+		return 0
+	}
+	return (words + 1<<autoChunkShift - 1) >> autoChunkShift * uint64(unsafe.Sizeof([]uint64(nil)))
+}
+
+// sameLayout reports whether st and other have the same word count and
+// chunk size.
+func (st *storage) sameLayout(other *storage) bool {
+	return st.chunkShift == other.chunkShift && st.wordCount() == other.wordCount()
+}
+
+// chunked reports whether the words are chunked.
+func (st *storage) chunked() bool {
+	return st.bits == nil && st.chunks != nil
+}
+
+// wordCount returns the number of words.
+func (st *storage) wordCount() int {
+	if !st.chunked() {
+		return len(st.bits)
+	}
+	last := len(st.chunks) - 1
+	return last<<st.chunkShift + len(st.chunks[last])
+}
+
+// spanWords returns the length of the longest run of words that is always
+// contiguous in memory: a chunk, or all of contiguous storage.
+func (st *storage) spanWords() int {
+	if !st.chunked() {
+		return max(len(st.bits), 1)
+	}
+	return 1 << st.chunkShift
+}
+
+// span returns words [start, start+n), which must not cross a chunk
+// boundary.
+func (st *storage) span(start, n int) []uint64 {
+	if !st.chunked() {
+		return st.bits[start : start+n]
+	}
+	off := start & (1<<st.chunkShift - 1)
+	return st.chunks[start>>st.chunkShift][off : off+n]
+}
+
+// word returns a pointer to word i.
+func (st *storage) word(i uint64) *uint64 {
+	if st.bits != nil {
+		return &st.bits[i]
+	}
+	return &st.chunks[i>>st.chunkShift][i&st.chunkMask]
+}
+
+// eachSpan calls fn with each contiguous run of the words, in order, and
+// the index of its first word. A lazy filter is settled first.
+func (st *storage) eachSpan(fn func(start int, words []uint64)) {
+	st.settle()
+	if !st.chunked() {
+		fn(0, st.bits)
+		return
+	}
+	for c, words := range st.chunks {
+		fn(c<<st.chunkShift, words)
+	}
+}
+
+// eachSpanPair calls fn with matching runs of a's and b's words, which
+// must have the same word count, whatever the layout of either. Chunk
+// sizes are powers of two, so runs of the smaller size never cross a
+// chunk boundary of either.
+func eachSpanPair(a, b *storage, fn func(start int, x, y []uint64)) {
+	a.settle()
+	b.settle()
+	n := a.wordCount()
+	step := min(a.spanWords(), b.spanWords())
+	for start := 0; start < n; start += step {
+		end := min(n, start+step)
+		fn(start, a.span(start, end-start), b.span(start, end-start))
+	}
+}
+
+// copyWords copies src's words into dst, which has the same word count.
+func copyWords(dst, src *storage) {
+	eachSpanPair(dst, src, func(_ int, x, y []uint64) { copy(x, y) })
+}
+
+// appendWords appends the words to buf in the given byte order.
+func (st *storage) appendWords(buf []byte, order binary.AppendByteOrder) []byte {
+	st.eachSpan(func(_ int, words []uint64) {
+		for _, word := range words {
+			buf = order.AppendUint64(buf, word)
+		}
+	})
+	return buf
+}
+
+// writeWords streams the words to w in the given byte order after the
+// bytes already in buf, writing every streamChunkWords words so the whole
+// payload is never held in memory. It returns the number of bytes
+// written.
+func (st *storage) writeWords(w io.Writer, buf []byte, order binary.AppendByteOrder) (int64, error) {
+	var (
+		written int64
+		err     error
+	)
+	st.eachSpan(func(start int, words []uint64) {
+		for i, word := range words {
+			if err != nil {
+				return
+			}
+			buf = order.AppendUint64(buf, word)
+			if (start+i+1)%streamChunkWords == 0 {
+				var n int
+				n, err = w.Write(buf)
+				written += int64(n)
+				buf = buf[:0]
+			}
+		}
+	})
+	if err != nil {
+		return written, err
+	}
+	n, err := w.Write(buf)
+	return written + int64(n), err
+}
+
+// onesCount returns the number of set bits in the words.
+func (st *storage) onesCount() uint64 {
+	var n uint64
+	st.eachSpan(func(_ int, words []uint64) { n += popcount(words) })
+	return n
+}
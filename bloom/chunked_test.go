@@ -0,0 +1,263 @@
+package bloom
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// chunkedPair returns a contiguous filter and one of 8-word chunks with the
+// same parameters, both holding keys.
+func chunkedPair(t *testing.T, m, k uint64, keys [][]byte) (flat, chunked *BloomFilter) {
+	t.Helper()
+	flat = New(m, k)
+	chunked, err := NewWithOptions(0, 0, WithExplicitSize(m, k), withChunkShift(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	flat.AddAll(keys)
+	chunked.AddAll(keys)
+	return flat, chunked
+}
+
+func TestChunked_MatchesContiguous(t *testing.T) {
+	// 102 words: 12 full chunks and a short last one, with padding bits.
+	const m = 101*64 + 17
+	keys := randomKeys("chunked", 400, 21)
+	flat, chunked := chunkedPair(t, m, 5, keys)
+	if !chunked.chunked() || len(chunked.chunks) != 13 || len(chunked.chunks[12]) != 6 || chunked.wordCount() != 102 {
+		t.Fatalf("storage is %d chunks of %d words", len(chunked.chunks), 1<<chunked.chunkShift)
+	}
+
+	for i, key := range randomKeys("probe", 2000, 22) {
+		if chunked.MightContain(key) != flat.MightContain(key) {
+			t.Fatalf("probe %d: chunked and contiguous filters disagree", i)
+		}
+	}
+	if !chunked.Equal(flat) || !flat.Equal(chunked) {
+		t.Fatal("chunked filter differs from the contiguous one")
+	}
+	// Only the size differs, by the chunk table.
+	st, flatSt := chunked.Stats(), flat.Stats()
+	if st.SizeBytes <= flatSt.SizeBytes {
+		t.Fatalf("SizeBytes %d does not count the chunk table", st.SizeBytes)
+	}
+	if st.SizeBytes = flatSt.SizeBytes; st != flatSt {
+		t.Fatalf("Stats() = %+v, want %+v", st, flatSt)
+	}
+	if countBits(chunked) != chunked.setBits || !slices.Equal(chunked.BitWords(), flat.BitWords()) {
+		t.Fatal("BitWords or the set-bit count differ")
+	}
+	if !slices.Equal(chunked.SetBitPositions(), flat.SetBitPositions()) || !slices.Equal(chunked.BitDistribution(7), flat.BitDistribution(7)) {
+		t.Fatal("set-bit positions differ")
+	}
+	if a, b, err := chunked.Diff(flat); err != nil || len(a)+len(b) != 0 {
+		t.Fatalf("Diff = %v, %v, %v", a, b, err)
+	}
+
+	// Every encoding is byte-for-byte the same.
+	for name, enc := range map[string]func(*BloomFilter) ([]byte, error){
+		"binary": (*BloomFilter).MarshalBinary,
+		"json":   (*BloomFilter).MarshalJSON,
+		"text":   (*BloomFilter).MarshalText,
+		"sparse": (*BloomFilter).MarshalSparse,
+		"stream": func(bf *BloomFilter) ([]byte, error) {
+			var buf bytes.Buffer
+			_, err := bf.WriteTo(&buf)
+			return buf.Bytes(), err
+		},
+	} {
+		got, err := enc(chunked)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want, _ := enc(flat); !bytes.Equal(got, want) {
+			t.Fatalf("%s encoding differs", name)
+		}
+	}
+
+	// Combining works across layouts in both directions.
+	more := randomKeys("more", 300, 23)
+	otherFlat, otherChunked := chunkedPair(t, m, 5, more)
+	if err := flat.Merge(otherChunked); err != nil {
+		t.Fatal(err)
+	}
+	if err := chunked.Merge(otherFlat); err != nil {
+		t.Fatal(err)
+	}
+	if !chunked.Equal(flat) || chunked.setBits != flat.setBits || chunked.Count() != 700 {
+		t.Fatal("Merge across layouts differs")
+	}
+	inter, err := chunked.IntersectNew(otherFlat)
+	if err != nil || !inter.Equal(otherFlat) || inter.setBits != otherFlat.setBits {
+		t.Fatalf("IntersectNew: %v", err)
+	}
+	want, _ := EstimateIntersectionCount(flat, otherFlat)
+	if got, _ := EstimateIntersectionCount(chunked, otherFlat); got != want {
+		t.Fatalf("EstimateIntersectionCount = %v, want %v", got, want)
+	}
+
+	d, _ := chunked.DeltaSince(0)
+	applied := New(m, 5)
+	if err := applied.ApplyDelta(d); err != nil || !applied.Equal(chunked) {
+		t.Fatalf("ApplyDelta of a chunked delta: %v", err)
+	}
+
+	c := chunked.Clone()
+	c.AddString("only in the clone")
+	if !c.chunked() || chunked.MightContainString("only in the clone") {
+		t.Fatal("Clone shares or lost chunked storage")
+	}
+	if err := flat.CopyFrom(c); err != nil || !flat.Equal(c) || !flat.chunked() {
+		t.Fatalf("CopyFrom a chunked filter: %v", err)
+	}
+	if err := c.CopyFrom(otherFlat); err != nil || !c.Equal(otherFlat) || c.chunked() {
+		t.Fatalf("CopyFrom a contiguous filter: %v", err)
+	}
+
+	chunked.Reset()
+	if chunked.Count() != 0 || chunked.Stats().BitsSet != 0 || countBits(chunked) != 0 || !chunked.chunked() {
+		t.Fatal("Reset left bits behind")
+	}
+}
+
+func TestChunked_Fold(t *testing.T) {
+	keys := randomKeys("fold", 200, 24)
+	flat, chunked := chunkedPair(t, 1<<14, 4, keys)
+	want, _ := flat.Fold(4)
+	got, err := chunked.Fold(4)
+	if err != nil || !got.Equal(want) || got.setBits != want.setBits {
+		t.Fatalf("Fold: %v", err)
+	}
+}
+
+// lowerChunkThreshold makes filters above 64 words chunk by default, in
+// chunks of 16 words, until the test ends.
+func lowerChunkThreshold(t *testing.T) {
+	shift, threshold := autoChunkShift, chunkedThresholdWords
+	autoChunkShift, chunkedThresholdWords = 4, 64
+	t.Cleanup(func() { autoChunkShift, chunkedThresholdWords = shift, threshold })
+}
+
+func TestChunked_Default(t *testing.T) {
+	lowerChunkThreshold(t)
+	keys := randomKeys("default", 1000, 25)
+	bf := NewWithEstimates(1000, 0.01)
+	bf.AddAll(keys)
+	if !bf.chunked() || bf.chunkShift != 4 {
+		t.Fatal("a filter above the threshold is not chunked")
+	}
+	if small := NewWithEstimates(10, 0.01); small.chunked() {
+		t.Fatal("a filter below the threshold is chunked")
+	}
+	if got, want := bf.SizeInBytes(), MemoryRequired(1000, 0.01); got != want {
+		t.Fatalf("SizeInBytes() = %d, MemoryRequired = %d", got, want)
+	}
+
+	// Decoding a large filter gives chunked storage, whichever way.
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var unmarshaled, read BloomFilter
+	if err := unmarshaled.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := read.ReadFrom(bytes.NewReader(data)); err != nil || n != int64(len(data)) {
+		t.Fatalf("ReadFrom = %d, %v", n, err)
+	}
+	for name, got := range map[string]*BloomFilter{"UnmarshalBinary": &unmarshaled, "ReadFrom": &read} {
+		if !got.chunked() || !got.Equal(bf) || got.setBits != bf.setBits {
+			t.Fatalf("%s: decoded filter differs", name)
+		}
+	}
+	if _, err := read.ReadFrom(bytes.NewReader(data[:len(data)-8])); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("truncated stream: %v", err)
+	}
+}
+
+func TestChunked_Concurrent(t *testing.T) {
+	lowerChunkThreshold(t)
+	keys := randomKeys("concurrent", 1000, 26)
+	want := NewWithEstimates(1000, 0.01)
+	want.AddAll(keys)
+
+	a := NewAtomicWithEstimates(1000, 0.01)
+	for _, key := range keys {
+		a.Add(key)
+	}
+	snap := a.Snapshot()
+	if !snap.chunked() || !snap.Equal(want) || snap.setBits != want.setBits {
+		t.Fatal("Atomic over chunked storage differs")
+	}
+	a.Reset()
+	if a.MightContain(keys[0]) || !a.bf.Load().chunked() {
+		t.Fatal("Atomic.Reset lost the layout or the keys")
+	}
+
+	// SafeBloom.Reset swaps chunked storage too, and reuses it as a spare.
+	s := NewSafeWithEstimates(1000, 0.01)
+	s.SetResetSpare(true)
+	s.AddBatch(keys)
+	before := &s.bf.chunks[0][0]
+	s.Reset()
+	if s.MightContain(keys[0]) || &s.spare.chunks[0][0] != before || s.spare.onesCount() != 0 {
+		t.Fatal("Reset did not swap the chunks out")
+	}
+	s.AddBatch(keys)
+	s.Reset()
+	if &s.bf.chunks[0][0] != before || s.Count() != 0 {
+		t.Fatal("Reset did not reuse the spare chunks")
+	}
+}
+
+func TestChunked_Options(t *testing.T) {
+	bf, err := NewWithOptions(1000, 0.01, WithChunkedStorage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bf.chunked() || len(bf.chunks) != 1 || bf.chunkShift != defaultChunkShift {
+		t.Fatalf("WithChunkedStorage: %d chunks, shift %d", len(bf.chunks), bf.chunkShift)
+	}
+	bf.AddString("x")
+	if !bf.MightContainString("x") {
+		t.Fatal("lost a key")
+	}
+	if _, err := NewWithOptions(1000, 0.01, withChunkShift(0)); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("zero shift: %v", err)
+	}
+	if _, err := NewWithOptions(1000, 0.01, WithChunkedStorage(), withChunkShift(3)); !errors.Is(err, ErrConflictingOptions) {
+		t.Fatalf("two chunk sizes: %v", err)
+	}
+}
+
+func BenchmarkChunked(b *testing.B) {
+	keys := benchmarkKeys(100_000)
+	flat := NewWithEstimates(10_000_000, 0.01)
+	// 8 MiB chunks, so the filter spans two of them.
+	chunked, err := NewWithOptions(10_000_000, 0.01, withChunkShift(20))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		bf   *BloomFilter
+	}{{"contiguous", flat}, {"chunked", chunked}} {
+		name, bf := c.name, c.bf
+		b.Run("Add/"+name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.AddAll(keys)
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/key")
+		})
+		b.Run("MightContain/"+name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, key := range keys {
+					bf.MightContain(key)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/key")
+		})
+	}
+}
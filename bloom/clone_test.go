@@ -0,0 +1,91 @@
+package bloom
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestClone_Independent(t *testing.T) {
+	bf := NewWithEstimates(1000, 0.01)
+	bf.Add([]byte("shared"))
+
+	c := bf.Clone()
+	c.Add([]byte("clone-only"))
+	bf.Add([]byte("original-only"))
+
+	if bf.MightContain([]byte("clone-only")) || c.MightContain([]byte("original-only")) {
+		t.Fatal("a clone must not share storage with its original")
+	}
+	if !c.MightContain([]byte("shared")) || c.inserts != 2 || bf.inserts != 2 {
+		t.Fatal("clone lost state from the original")
+	}
+	if (*BloomFilter)(nil).Clone() != nil {
+		t.Fatal("cloning nil should return nil")
+	}
+
+	s := NewSafeWithEstimates(1000, 0.01)
+	s.Add([]byte("safe"))
+	sc := s.Clone()
+	sc.Add([]byte("safe-clone-only"))
+	if s.MightContain([]byte("safe-clone-only")) || !sc.MightContain([]byte("safe")) {
+		t.Fatal("SafeBloom.Clone must return an independent copy")
+	}
+}
+
+func TestCopyFrom_ReusesStorage(t *testing.T) {
+	live := NewWithEstimates(10_000, 0.01)
+	snap := NewWithEstimates(10_000, 0.01)
+	for i := 0; i < 100; i++ {
+		live.Add([]byte(strconv.Itoa(i)))
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		if err := snap.CopyFrom(live); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("CopyFrom of a same-sized filter allocated %v times", allocs)
+	}
+	live.Add([]byte("after"))
+	if snap.MightContain([]byte("after")) || !snap.MightContain([]byte("42")) || snap.setBits == live.setBits {
+		t.Fatal("CopyFrom result should be an independent copy of the source")
+	}
+
+	small := New(64, 2)
+	if err := small.CopyFrom(live); err != nil {
+		t.Fatal(err)
+	}
+	if small.m != live.m || !small.MightContain([]byte("after")) {
+		t.Fatal("CopyFrom should grow a smaller receiver")
+	}
+	if err := small.CopyFrom(&BloomFilter{}); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("expected ErrUninitialized, got %v", err)
+	}
+}
+
+func TestCopyFrom_Mapped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.mmap")
+	mapped, err := NewMmap(path, 4096, 3)
+	if err != nil {
+		if errors.Is(err, ErrMmapUnsupported) {
+			t.Skip(err)
+		}
+		t.Fatal(err)
+	}
+	defer mapped.Close()
+
+	src := New(4096, 3)
+	src.Add([]byte("x"))
+	if err := mapped.CopyFrom(src); err != nil {
+		t.Fatal(err)
+	}
+	if mapped.mapped == nil || !mapped.MightContain([]byte("x")) {
+		t.Fatal("CopyFrom into a mapped filter should keep the mapping")
+	}
+	if err := mapped.CopyFrom(New(8192, 3)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible resizing a mapped filter, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"sync"
+	"unsafe"
+)
+
+// MaxCompactBits is the largest m a Compact filter supports, so that every
+// probe position fits in a uint16.
+const MaxCompactBits = 1 << 16
+
+// compactInlineBytes is the bitset size stored inline in the Compact value
+// itself, avoiding a second allocation for the smallest filters.
+const compactInlineBytes = 512
+
+// Compact is a small Bloom filter (m <= MaxCompactBits) laid out for
+// workloads holding millions of instances, such as one filter per data
+// block. Its bitset lives inline for m <= 4096 and is byte-addressed, and
+// FilterPool recycles instances so steady-state construction allocates
+// nothing.
+//
+// Compact hashes exactly like a BloomFilter with the same m and k, and its
+// binary encoding is interchangeable with BloomFilter's.
+//
+// Note: This type is not safe for concurrent use without external locking.
+type Compact struct {
+	m       uint32
+	k       uint32
+	setBits uint32
+	inserts uint32 // Add calls since init or Reset, saturating
+	bits    []byte // len is a multiple of 8; may alias inline
+	inline  [compactInlineBytes]byte
+}
+
+// NewCompact creates a compact filter with m bits and k hash functions.
+// m must be in (0, MaxCompactBits] and k > 0.
+func NewCompact(m, k uint64) *Compact {
+	c := &Compact{}
+	c.init(m, k)
+	return c
+}
+
+// NewCompactWithEstimates creates a compact filter sized like
+// NewWithEstimates(n, fpRate). It panics if the result exceeds MaxCompactBits.
+func NewCompactWithEstimates(n uint64, fpRate float64) *Compact {
+	m, k := EstimateParameters(n, fpRate)
+	return NewCompact(m, k)
+}
+
+// init (re)configures c for m bits and k hash functions, reusing the
+// existing backing array when it is large enough. The bitset is cleared.
+func (c *Compact) init(m, k uint64) {
+	if m == 0 || m > MaxCompactBits {
+		panic("bloom: compact m (no. of bits) must be in (0, 65536]")
+	}
+	if k == 0 {
+		panic("bloom: k (no. of hash fucntions) must be > 0")
+	}
+
+	size := int(wordsFor(m) * 8)
+	switch {
+	case size <= compactInlineBytes:
+		c.bits = c.inline[:size]
+	case cap(c.bits) >= size && !c.isInline():
+		c.bits = c.bits[:size]
+	default:
+		c.bits = make([]byte, size)
+	}
+	c.m = uint32(m)
+	c.k = uint32(k)
+	c.Reset()
+}
+
+func (c *Compact) isInline() bool {
+	return cap(c.bits) > 0 && unsafe.SliceData(c.bits) == &c.inline[0]
+}
+
+// Add inserts data into the filter.
+// It panics with ErrUninitialized on a zero-value or nil filter.
+func (c *Compact) Add(data []byte) {
+	if c == nil || c.m == 0 {
+		panic(ErrUninitialized)
+	}
+	h1, h2 := fnvHashes(data)
+	m := uint64(c.m)
+	for i := uint64(0); i < uint64(c.k); i++ {
+		pos := uint16((h1 + i*h2) % m)
+		mask := byte(1) << (pos % 8)
+		if c.bits[pos/8]&mask == 0 {
+			c.bits[pos/8] |= mask
+			c.setBits++
+		}
+	}
+	if c.inserts < math.MaxUint32 {
+		c.inserts++
+	}
+}
+
+// MightContain checks if data might be in the filter.
+// Returns false -> definitely not present.
+// Returns true  -> might be present (subject to false positives).
+func (c *Compact) MightContain(data []byte) bool {
+	if c == nil || c.m == 0 {
+		return false
+	}
+	h1, h2 := fnvHashes(data)
+	m := uint64(c.m)
+	for i := uint64(0); i < uint64(c.k); i++ {
+		pos := uint16((h1 + i*h2) % m)
+		if c.bits[pos/8]&(1<<(pos%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Reset clears all bits in the filter.
+func (c *Compact) Reset() {
+	clear(c.bits)
+	c.setBits = 0
+	c.inserts = 0
+}
+
+// SizeInBytes reports the memory held by the filter. Inline storage is
+// part of the fixed struct size.
+func (c *Compact) SizeInBytes() uint64 {
+	size := uint64(unsafe.Sizeof(*c))
+	if !c.isInline() {
+		size += uint64(cap(c.bits))
+	}
+	return size
+}
+
+// Info returns a small description of the filter's configuration.
+func (c *Compact) Info() string {
+	return fmt.Sprintf("Compact{m=%d bits, k=%d}", c.m, c.k)
+}
+
+// MarshalBinary encodes the filter in the same format as a BloomFilter with
+// identical m and k, including the insert count; a Compact has no
+// capacity. A little-endian word layout is exactly the byte-wise bitset,
+// so the bits are copied verbatim.
+func (c *Compact) MarshalBinary() ([]byte, error) {
+	h := header{version: encodingVersion, m: uint64(c.m), k: uint64(c.k), words: uint64(len(c.bits) / 8), inserts: uint64(c.inserts)}
+	buf := h.append(make([]byte, 0, headerLen(encodingVersion)+len(c.bits)))
+	return append(buf, c.bits...), nil
+}
+
+// UnmarshalBinary decodes a filter written by Compact.MarshalBinary or by
+// a default-scheme BloomFilter with m <= MaxCompactBits, replacing the
+// receiver's contents.
+func (c *Compact) UnmarshalBinary(data []byte) error {
+	h, err := parseHeader(data)
+	if err != nil {
+		return err
+	}
+	if h.scheme != schemeFNV {
+		return fmt.Errorf("%w: compact filters only support the %s scheme", ErrUnsupportedFormat, schemeFNV)
+	}
+	if h.hasher != 0 || h.seed != 0 {
+		return fmt.Errorf("%w: compact filters only support the default unseeded hasher", ErrUnsupportedFormat)
+	}
+	if h.m > MaxCompactBits {
+		return fmt.Errorf("%w: m=%d exceeds MaxCompactBits", ErrUnsupportedFormat, h.m)
+	}
+	payload := data[headerLen(h.version):]
+	if uint64(len(payload)) != h.words*8 {
+		return fmt.Errorf("%w: payload is %d bytes, want %d", ErrCorrupt, len(payload), h.words*8)
+	}
+	last := binary.LittleEndian.Uint64(payload[len(payload)-8:])
+	if last&^lastWordMask(h.m) != 0 {
+		return fmt.Errorf("%w: padding bits set beyond m", ErrCorrupt)
+	}
+
+	c.init(h.m, h.k)
+	copy(c.bits, payload)
+	c.setBits = uint32(popcountBytes(c.bits))
+	c.inserts = uint32(min(h.inserts, math.MaxUint32))
+	return nil
+}
+
+// FilterPool recycles Compact filters and their backing arrays. It is safe
+// for concurrent use.
+type FilterPool struct {
+	pool sync.Pool
+}
+
+// Get returns an empty compact filter sized like NewWithEstimates(n, fpRate),
+// reusing a pooled instance when one is available.
+func (p *FilterPool) Get(n uint64, fpRate float64) *Compact {
+	m, k := EstimateParameters(n, fpRate)
+	c, _ := p.pool.Get().(*Compact)
+	if c == nil {
+		c = &Compact{}
+	}
+	c.init(m, k)
+	return c
+}
+
+// Put returns c to the pool. c must not be used afterwards.
+func (p *FilterPool) Put(c *Compact) {
+	if c != nil {
+		p.pool.Put(c)
+	}
+}
+
+// popcountBytes returns the number of set bits in b, whose length is a
+// multiple of 8.
+func popcountBytes(b []byte) uint64 {
+	var n uint64
+	for i := 0; i < len(b); i += 8 {
+		n += uint64(bits.OnesCount64(binary.LittleEndian.Uint64(b[i:])))
+	}
+	return n
+}
@@ -0,0 +1,142 @@
+package bloom
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func TestCompact_MatchesBloomFilter(t *testing.T) {
+	for _, m := range []uint64{100, 4096, 10000, MaxCompactBits} {
+		c := NewCompact(m, 5)
+		bf := New(m, 5)
+		for i := 0; i < 300; i++ {
+			key := []byte("key-" + strconv.Itoa(i))
+			c.Add(key)
+			bf.Add(key)
+		}
+
+		got, err := c.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want bytes.Buffer
+		if _, err := bf.WriteTo(&want); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want.Bytes()) {
+			t.Fatalf("m=%d: compact encoding differs from BloomFilter", m)
+		}
+
+		for i := 0; i < 600; i++ {
+			key := []byte("key-" + strconv.Itoa(i))
+			if c.MightContain(key) != bf.MightContain(key) {
+				t.Fatalf("m=%d key %d: compact disagrees with BloomFilter", m, i)
+			}
+		}
+	}
+}
+
+func TestCompact_UnmarshalBinary(t *testing.T) {
+	src := NewCompact(5000, 4)
+	src.Add([]byte("foo"))
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var c Compact
+	if err := c.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !c.MightContain([]byte("foo")) || c.setBits != src.setBits {
+		t.Fatal("decoded compact filter lost its contents")
+	}
+
+	big := New(MaxCompactBits+1, 3)
+	var buf bytes.Buffer
+	if _, err := big.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UnmarshalBinary(buf.Bytes()); err == nil {
+		t.Fatal("expected an error decoding a filter larger than MaxCompactBits")
+	}
+}
+
+func TestCompact_ZeroValue(t *testing.T) {
+	var zero Compact
+	if zero.MightContain([]byte("a")) || (*Compact)(nil).MightContain([]byte("a")) {
+		t.Fatal("zero-value Compact contains a key")
+	}
+	expectPanic(t, ErrUninitialized, func() { zero.Add([]byte("a")) })
+	expectPanic(t, ErrUninitialized, func() { (*Compact)(nil).Add([]byte("a")) })
+}
+
+func TestCompact_Inline(t *testing.T) {
+	small := NewCompact(4096, 3)
+	if !small.isInline() {
+		t.Fatal("4096-bit filter should use inline storage")
+	}
+	large := NewCompact(4097, 3)
+	if large.isInline() {
+		t.Fatal("4097-bit filter should not use inline storage")
+	}
+	if large.SizeInBytes() <= small.SizeInBytes() {
+		t.Fatal("heap-backed filter should report its backing array")
+	}
+}
+
+func TestFilterPool_ReusesStorage(t *testing.T) {
+	var p FilterPool
+
+	c := p.Get(1000, 0.01)
+	c.Add([]byte("stale"))
+	p.Put(c)
+
+	c = p.Get(1000, 0.01)
+	if c.MightContain([]byte("stale")) {
+		t.Fatal("pooled filter must come back empty")
+	}
+	p.Put(c)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		p.Put(p.Get(1000, 0.01))
+	})
+	if allocs > 0 {
+		t.Fatalf("pooled Get/Put allocated %v times per run", allocs)
+	}
+}
+
+func BenchmarkCompact_New(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewCompactWithEstimates(1000, 0.01)
+	}
+}
+
+func BenchmarkFilterPool_Get(b *testing.B) {
+	var p FilterPool
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.Put(p.Get(1000, 0.01))
+	}
+}
+
+func BenchmarkBloom_New(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewWithEstimates(1000, 0.01)
+	}
+}
+
+func BenchmarkCompact_MightContain(b *testing.B) {
+	keys := benchmarkKeys(1024)
+	c := NewCompactWithEstimates(uint64(len(keys)), 0.01)
+	for _, key := range keys {
+		c.Add(key)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.MightContain(keys[i&(len(keys)-1)])
+	}
+}
@@ -0,0 +1,362 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// Counting is a counting Bloom filter: each of its m positions holds a
+// small saturating counter instead of a bit, so elements can be removed.
+// Counters are packed into 64-bit words, 4 bits wide by default.
+//
+// Keys map to positions exactly as in a BloomFilter with the same m and k,
+// so NewWithEstimates-style sizing applies unchanged; the cost is width
+// times the memory.
+//
+// A counter that reaches its maximum sticks there: neither Add nor Remove
+// changes it again. Overflow therefore never wraps a counter to zero, and
+// never causes a false negative, at the price of keys on that position
+// never fully leaving. With 4-bit counters this needs 15 insertions on
+// one position, which is rare for a filter within its capacity.
+//
+// Note: This type is not safe for concurrent use without external locking.
+// See SafeCounting.
+type Counting struct {
+	m, k     uint64
+	width    uint64   // bits per counter: 2, 4, 8 or 16
+	counters []uint64 // packed counters, 64/width per word
+	inserts  uint64   // no. of Add calls minus successful Removes
+}
+
+// DefaultCounterWidth is the counter width used by NewCounting.
+const DefaultCounterWidth = 4
+
+// NewCounting creates a counting filter with m 4-bit counters and k hash
+// functions. m and k ==> must be >0.
+func NewCounting(m, k uint64) *Counting {
+	return NewCountingWithWidth(m, k, DefaultCounterWidth)
+}
+
+// NewCountingWithWidth creates a counting filter whose counters are width
+// bits wide; width must be 2, 4, 8 or 16.
+func NewCountingWithWidth(m, k, width uint64) *Counting {
+	if m == 0 {
+		panic("bloom: m (no. of counters) must be > 0")
+	}
+	if k == 0 {
+		panic("bloom: k (no. of hash fucntions) must be > 0")
+	}
+	if !validCounterWidth(width) {
+		panic("bloom: counter width must be 2, 4, 8 or 16")
+	}
+	return &Counting{
+		m:        m,
+		k:        k,
+		width:    width,
+		counters: make([]uint64, countingWords(m, width)),
+	}
+}
+
+// NewCountingWithEstimates creates a counting filter sized for n items at
+// fpRate, like NewWithEstimates, with 4-bit counters.
+func NewCountingWithEstimates(n uint64, fpRate float64) *Counting {
+	m, k := EstimateParameters(n, fpRate)
+	return NewCounting(m, k)
+}
+
+func validCounterWidth(width uint64) bool {
+	return width == 2 || width == 4 || width == 8 || width == 16
+}
+
+func countingWords(m, width uint64) uint64 {
+	perWord := 64 / width
+	return (m + perWord - 1) / perWord
+}
+
+// Add inserts data, incrementing its k counters.
+// It panics with ErrUninitialized on a zero-value or nil filter.
+func (c *Counting) Add(data []byte) {
+	if c == nil || c.m == 0 {
+		panic(ErrUninitialized)
+	}
+	h1, h2 := fnvHashes(data)
+	limit := c.maxCount()
+	for i := uint64(0); i < c.k; i++ {
+		pos := (h1 + i*h2) % c.m
+		if n := c.get(pos); n < limit {
+			c.set(pos, n+1)
+		}
+	}
+	c.inserts++
+}
+
+// Remove deletes one occurrence of data by decrementing its k counters,
+// and reports whether it did. If any counter is zero, data was never added
+// and Remove returns false without changing anything.
+//
+// Removing a key that was never added but is a false positive is
+// undefined: it decrements counters that belong to other keys, which can
+// later turn into false negatives for them. Only remove keys known to have
+// been added.
+func (c *Counting) Remove(data []byte) bool {
+	if !c.MightContain(data) {
+		return false
+	}
+	h1, h2 := fnvHashes(data)
+	limit := c.maxCount()
+	for i := uint64(0); i < c.k; i++ {
+		pos := (h1 + i*h2) % c.m
+		// A probe can repeat a position; MightContain saw it non-zero, but
+		// an earlier probe may have taken it to zero already.
+		if n := c.get(pos); n > 0 && n < limit {
+			c.set(pos, n-1)
+		}
+	}
+	if c.inserts > 0 {
+		c.inserts--
+	}
+	return true
+}
+
+// MightContain checks if data might be in the filter.
+// Returns false -> definitely not present.
+// Returns true  -> might be present (subject to false positives).
+// A zero-value or nil filter contains nothing.
+func (c *Counting) MightContain(data []byte) bool {
+	return c.EstimateCount(data) > 0
+}
+
+// EstimateCount returns an upper bound on how many times data is present:
+// the smallest of its k counters. Collisions with other keys can only
+// raise it, and it caps at the counter maximum (15 for 4-bit counters).
+func (c *Counting) EstimateCount(data []byte) uint64 {
+	if c == nil || c.m == 0 {
+		return 0
+	}
+	h1, h2 := fnvHashes(data)
+	count := c.maxCount()
+	for i := uint64(0); i < c.k && count > 0; i++ {
+		count = min(count, c.get((h1+i*h2)%c.m))
+	}
+	return count
+}
+
+// Reset sets every counter to zero.
+func (c *Counting) Reset() {
+	if c == nil {
+		return
+	}
+	clear(c.counters)
+	c.inserts = 0
+}
+
+// ToBloom returns a BloomFilter with a bit set wherever a counter is
+// non-zero. It answers MightContain exactly like c, in 1/width of the
+// space, for shipping to readers that never remove.
+func (c *Counting) ToBloom() *BloomFilter {
+	bf := New(c.m, c.k)
+	for pos := uint64(0); pos < c.m; pos++ {
+		if c.get(pos) != 0 {
+			bf.setBit(pos)
+		}
+	}
+	bf.inserts = c.inserts
+	return bf
+}
+
+// SizeInBytes reports the memory held by the filter: the counter storage
+// plus the fixed struct overhead.
+func (c *Counting) SizeInBytes() uint64 {
+	if c == nil {
+		return 0
+	}
+	return uint64(len(c.counters))*8 + uint64(unsafe.Sizeof(*c))
+}
+
+// Info returns a small description of the filter's configuration.
+func (c *Counting) Info() string {
+	if c == nil {
+		return "Counting{nil}"
+	}
+	return fmt.Sprintf("Counting{m=%d counters, k=%d, width=%d}", c.m, c.k, c.width)
+}
+
+func (c *Counting) maxCount() uint64 {
+	return 1<<c.width - 1
+}
+
+func (c *Counting) get(pos uint64) uint64 {
+	return getCell(c.counters, c.width, pos)
+}
+
+func (c *Counting) set(pos, n uint64) {
+	setCell(c.counters, c.width, pos, n)
+}
+
+// getCell returns the width-bit cell at pos in words. width must divide 64.
+func getCell(words []uint64, width, pos uint64) uint64 {
+	bit := pos * width
+	return words[bit/64] >> (bit % 64) & (1<<width - 1)
+}
+
+// setCell stores n, which must fit in width bits, in the cell at pos.
+func setCell(words []uint64, width, pos, n uint64) {
+	bit := pos * width
+	w := &words[bit/64]
+	*w = *w&^((1<<width-1)<<(bit%64)) | n<<(bit%64)
+}
+
+// Counting binary format (all integers little-endian):
+//
+//	version  uint8   countingVersion
+//	m        uint64  no. of counters
+//	k        uint64  no. of hash functions
+//	width    uint8   bits per counter
+//	inserts  uint64
+//	words    uint64  no. of 64-bit words that follow
+//	counters words * uint64
+const (
+	countingVersion   = 1
+	countingHeaderLen = 1 + 8 + 8 + 1 + 8 + 8
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (c *Counting) MarshalBinary() ([]byte, error) {
+	if c == nil || c.m == 0 {
+		return nil, ErrUninitialized
+	}
+	buf := make([]byte, 0, countingHeaderLen+len(c.counters)*8)
+	buf = append(buf, countingVersion)
+	buf = binary.LittleEndian.AppendUint64(buf, c.m)
+	buf = binary.LittleEndian.AppendUint64(buf, c.k)
+	buf = append(buf, byte(c.width))
+	buf = binary.LittleEndian.AppendUint64(buf, c.inserts)
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(c.counters)))
+	for _, w := range c.counters {
+		buf = binary.LittleEndian.AppendUint64(buf, w)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, fully replacing
+// the receiver's contents. Malformed data fails with ErrCorrupt, an unknown
+// version with ErrUnsupportedVersion; the receiver is left untouched on
+// error.
+func (c *Counting) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	if data[0] != countingVersion {
+		return fmt.Errorf("%w: counting filter version %d", ErrUnsupportedVersion, data[0])
+	}
+	if len(data) < countingHeaderLen {
+		return fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	decoded := Counting{
+		m:       binary.LittleEndian.Uint64(data[1:]),
+		k:       binary.LittleEndian.Uint64(data[9:]),
+		width:   uint64(data[17]),
+		inserts: binary.LittleEndian.Uint64(data[18:]),
+	}
+	words := binary.LittleEndian.Uint64(data[26:])
+	if decoded.m == 0 || decoded.k == 0 || !validCounterWidth(decoded.width) {
+		return fmt.Errorf("%w: m=%d k=%d width=%d", ErrCorrupt, decoded.m, decoded.k, decoded.width)
+	}
+	if words != countingWords(decoded.m, decoded.width) {
+		return fmt.Errorf("%w: %d words for m=%d", ErrCorrupt, words, decoded.m)
+	}
+	payload := data[countingHeaderLen:]
+	if uint64(len(payload)) != words*8 {
+		return fmt.Errorf("%w: payload is %d bytes, want %d", ErrCorrupt, len(payload), words*8)
+	}
+	decoded.counters = make([]uint64, words)
+	for i := range decoded.counters {
+		decoded.counters[i] = binary.LittleEndian.Uint64(payload[i*8:])
+	}
+	if used := decoded.m * decoded.width % 64; used != 0 && decoded.counters[words-1]>>used != 0 {
+		return fmt.Errorf("%w: padding bits set beyond m", ErrCorrupt)
+	}
+	*c = decoded
+	return nil
+}
+
+// SafeCounting wraps Counting with a mutex to allow safe concurrent use.
+type SafeCounting struct {
+	mu sync.RWMutex
+	c  *Counting
+}
+
+// NewSafeCounting creates a concurrency-safe counting filter using explicit
+// m and k.
+func NewSafeCounting(m, k uint64) *SafeCounting {
+	return &SafeCounting{c: NewCounting(m, k)}
+}
+
+// NewSafeCountingWithEstimates creates a concurrency-safe counting filter
+// using n and fpRate.
+func NewSafeCountingWithEstimates(n uint64, fpRate float64) *SafeCounting {
+	return &SafeCounting{c: NewCountingWithEstimates(n, fpRate)}
+}
+
+// Add inserts data safely.
+func (s *SafeCounting) Add(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c.Add(data)
+}
+
+// Remove deletes data safely. See Counting.Remove.
+func (s *SafeCounting) Remove(data []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Remove(data)
+}
+
+// MightContain checks membership safely.
+func (s *SafeCounting) MightContain(data []byte) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.c.MightContain(data)
+}
+
+// EstimateCount returns data's count estimate safely.
+func (s *SafeCounting) EstimateCount(data []byte) uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.c.EstimateCount(data)
+}
+
+// Reset clears the filter safely.
+func (s *SafeCounting) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c.Reset()
+}
+
+// MarshalBinary encodes the filter under the read lock.
+func (s *SafeCounting) MarshalBinary() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.c.MarshalBinary()
+}
+
+// UnmarshalBinary decodes data and replaces the current filter atomically.
+func (s *SafeCounting) UnmarshalBinary(data []byte) error {
+	var c Counting
+	if err := c.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.c = &c
+	s.mu.Unlock()
+	return nil
+}
+
+// Info returns metadata safely.
+func (s *SafeCounting) Info() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.c.Info()
+}
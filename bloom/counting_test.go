@@ -0,0 +1,187 @@
+package bloom
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestCounting_AddRemove(t *testing.T) {
+	c := NewCountingWithEstimates(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		c.Add([]byte("session-" + strconv.Itoa(i)))
+	}
+	for i := 0; i < 500; i++ {
+		if !c.Remove([]byte("session-" + strconv.Itoa(i))) {
+			t.Fatalf("Remove(session-%d) = false for an added key", i)
+		}
+	}
+	for i := 500; i < 1000; i++ {
+		if !c.MightContain([]byte("session-" + strconv.Itoa(i))) {
+			t.Fatalf("false negative for session-%d after removing others", i)
+		}
+	}
+	removedStillPresent := 0
+	for i := 0; i < 500; i++ {
+		if c.MightContain([]byte("session-" + strconv.Itoa(i))) {
+			removedStillPresent++
+		}
+	}
+	// Removed keys linger only as ordinary false positives.
+	if removedStillPresent > 25 {
+		t.Fatalf("%d of 500 removed keys still reported present", removedStillPresent)
+	}
+	if c.inserts != 500 {
+		t.Fatalf("inserts = %d, want 500", c.inserts)
+	}
+}
+
+func TestCounting_RemoveAbsent(t *testing.T) {
+	c := NewCounting(1024, 4)
+	c.Add([]byte("present"))
+	before := append([]uint64(nil), c.counters...)
+	if c.Remove([]byte("absent")) {
+		t.Fatal("Remove reported success for a key that was never added")
+	}
+	for i := range before {
+		if before[i] != c.counters[i] {
+			t.Fatal("Remove of an absent key changed counters")
+		}
+	}
+}
+
+func TestCounting_MatchesBloomFilter(t *testing.T) {
+	c := NewCounting(2000, 5)
+	bf := New(2000, 5)
+	for i := 0; i < 300; i++ {
+		key := []byte("key-" + strconv.Itoa(i))
+		c.Add(key)
+		bf.Add(key)
+	}
+	if !c.ToBloom().Equal(bf) {
+		t.Fatal("counting filter probes differ from BloomFilter")
+	}
+	for i := 0; i < 3000; i++ {
+		key := []byte("key-" + strconv.Itoa(i))
+		if c.MightContain(key) != bf.MightContain(key) {
+			t.Fatalf("key %d: counting and plain filters disagree", i)
+		}
+	}
+}
+
+func TestCounting_SaturatesWithoutWrapping(t *testing.T) {
+	for _, width := range []uint64{2, 4, 8, 16} {
+		c := NewCountingWithWidth(64, 3, width)
+		key := []byte("hot")
+		limit := uint64(1)<<width - 1
+		n := min(limit+5, 1000)
+		for i := uint64(0); i < n; i++ {
+			c.Add(key)
+		}
+		want := min(n, limit)
+		if got := c.EstimateCount(key); got != want {
+			t.Fatalf("width %d: EstimateCount = %d, want %d", width, got, want)
+		}
+		for i := uint64(0); i < n; i++ {
+			c.Remove(key)
+		}
+		// Saturated counters stick, so the key cannot become a false
+		// negative through overflow.
+		if n > limit && !c.MightContain(key) {
+			t.Fatalf("width %d: saturated key lost after removals", width)
+		}
+	}
+}
+
+func TestCounting_EstimateCount(t *testing.T) {
+	c := NewCounting(4096, 4)
+	for i := 0; i < 3; i++ {
+		c.Add([]byte("triple"))
+	}
+	c.Add([]byte("single"))
+	if got := c.EstimateCount([]byte("triple")); got != 3 {
+		t.Fatalf("EstimateCount(triple) = %d, want 3", got)
+	}
+	if got := c.EstimateCount([]byte("single")); got != 1 {
+		t.Fatalf("EstimateCount(single) = %d, want 1", got)
+	}
+	if got := (*Counting)(nil).EstimateCount([]byte("x")); got != 0 {
+		t.Fatalf("nil filter EstimateCount = %d", got)
+	}
+}
+
+func TestCounting_BinaryRoundTrip(t *testing.T) {
+	c := NewCountingWithWidth(1001, 4, 8)
+	for i := 0; i < 100; i++ {
+		c.Add([]byte("key-" + strconv.Itoa(i)))
+	}
+	data, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Counting
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got.Info() != c.Info() || got.inserts != c.inserts {
+		t.Fatalf("decoded %s with %d inserts, want %s with %d", got.Info(), got.inserts, c.Info(), c.inserts)
+	}
+	for i := 0; i < 100; i++ {
+		key := []byte("key-" + strconv.Itoa(i))
+		if got.EstimateCount(key) != c.EstimateCount(key) {
+			t.Fatalf("key %d: counts differ after round trip", i)
+		}
+	}
+
+	for name, bad := range map[string][]byte{
+		"empty":     nil,
+		"truncated": data[:len(data)-1],
+		"width":     append(append(append([]byte(nil), data[:17]...), 3), data[18:]...),
+	} {
+		if err := got.UnmarshalBinary(bad); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: expected ErrCorrupt, got %v", name, err)
+		}
+	}
+	if err := got.UnmarshalBinary([]byte{9}); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+func TestSafeCounting_Concurrent(t *testing.T) {
+	s := NewSafeCountingWithEstimates(10_000, 0.01)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := []byte(strconv.Itoa(w) + "-" + strconv.Itoa(i))
+				s.Add(key)
+				if i%2 == 0 {
+					s.Remove(key)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	for w := 0; w < 4; w++ {
+		for i := 1; i < 1000; i += 2 {
+			if !s.MightContain([]byte(strconv.Itoa(w) + "-" + strconv.Itoa(i))) {
+				t.Fatalf("false negative for %d-%d", w, i)
+			}
+		}
+	}
+
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored SafeCounting
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !restored.MightContain([]byte("0-1")) {
+		t.Fatal("restored filter lost a key")
+	}
+}
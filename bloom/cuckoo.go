@@ -0,0 +1,350 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+	"unsafe"
+)
+
+// ErrFilterFull is returned by Cuckoo.Add when no slot can be freed for a
+// new key within the displacement bound, and by fixed-size filters in
+// subpackages when they have no room left. The filter is left unchanged.
+var ErrFilterFull = errors.New("bloom: filter full")
+
+const (
+	// cuckooBucketSize is the number of fingerprint slots per bucket.
+	cuckooBucketSize = 4
+
+	// cuckooMaxKicks bounds the relocation chain of a single Add.
+	cuckooMaxKicks = 500
+
+	// cuckooMaxLoad is the load factor 4-way buckets reliably reach; it
+	// sizes the table in NewCuckoo.
+	cuckooMaxLoad = 0.95
+)
+
+// Cuckoo is a cuckoo filter (Fan et al., 2014) with 4-way buckets and
+// 8, 12 or 16-bit fingerprints. Unlike a Bloom filter it supports Delete,
+// and a query reads two buckets instead of k scattered bits.
+//
+// Space depends on how well the target fits the fingerprint widths and
+// on the table, whose bucket count is a power of two. It beats a Bloom
+// filter near the rates the widths give exactly (8 / 2^f: about 0.012%
+// for 16 bits), where it takes about f / 0.95 bits per item against
+// 1.44·log2(1/ε). Between them it pays for the next width up: at 0.1%
+// it needs 16 bits and, for 100k items, about 45% more memory than a
+// Bloom filter, while answering queries faster. The benchmarks
+// BenchmarkCuckoo_Contains and BenchmarkBloom_MightContainAtCuckooTarget
+// report both.
+//
+// Each key is stored as a fingerprint in one of two candidate buckets.
+// Inserting into two full buckets relocates existing fingerprints to
+// their alternate bucket, up to a bound; past it Add fails with
+// ErrFilterFull instead of looping.
+//
+// Note: This type is not safe for concurrent use without external locking.
+type Cuckoo struct {
+	fpBits  uint64   // bits per fingerprint: 8, 12 or 16
+	buckets uint64   // no. of buckets, a power of two
+	slots   []uint64 // buckets*cuckooBucketSize packed fingerprints; 0 = empty
+	count   uint64
+	rng     *rand.Rand
+}
+
+// NewCuckoo creates a cuckoo filter for capacity items with fpBits-bit
+// fingerprints (8, 12 or 16). The false positive rate is about
+// 8 / 2^fpBits: 3% for 8 bits, 0.2% for 12 and 0.012% for 16.
+func NewCuckoo(capacity, fpBits uint64) *Cuckoo {
+	if capacity == 0 {
+		panic("bloom: n (expected insertions) must be > 0")
+	}
+	if fpBits != 8 && fpBits != 12 && fpBits != 16 {
+		panic("bloom: fingerprint bits must be 8, 12 or 16")
+	}
+	want := uint64(math.Ceil(float64(capacity) / cuckooBucketSize / cuckooMaxLoad))
+	buckets := uint64(1) << bits.Len64(max(want, 1)-1)
+	return newCuckoo(fpBits, buckets)
+}
+
+// NewCuckooWithEstimates creates a cuckoo filter for n items with the
+// smallest fingerprint that meets fpRate. It panics if fpRate needs more
+// than 16 bits (below about 0.012%).
+func NewCuckooWithEstimates(n uint64, fpRate float64) *Cuckoo {
+	if fpRate <= 0 || fpRate >= 1 {
+		panic("bloom: fpRate must be in (0, 1)")
+	}
+	need := math.Log2(2 * cuckooBucketSize / fpRate)
+	for _, fpBits := range []uint64{8, 12, 16} {
+		if float64(fpBits) >= need {
+			return NewCuckoo(n, fpBits)
+		}
+	}
+	panic("bloom: fpRate below what 16-bit fingerprints can reach")
+}
+
+func newCuckoo(fpBits, buckets uint64) *Cuckoo {
+	return &Cuckoo{
+		fpBits:  fpBits,
+		buckets: buckets,
+		slots:   make([]uint64, (buckets*cuckooBucketSize*fpBits+63)/64),
+		rng:     rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+}
+
+// Add inserts data. It returns ErrFilterFull, leaving the filter
+// unchanged, if no room can be made within cuckooMaxKicks relocations.
+// Adding the same key again stores another copy; a key fits at most
+// 2*4 times.
+// It panics with ErrUninitialized on a zero-value or nil filter.
+func (c *Cuckoo) Add(data []byte) error {
+	if c == nil || c.buckets == 0 {
+		panic(ErrUninitialized)
+	}
+	fp, i1 := c.hashes(data)
+	i2 := c.altIndex(i1, fp)
+	if c.insert(i1, fp) || c.insert(i2, fp) {
+		c.count++
+		return nil
+	}
+
+	type kick struct{ slot, prev uint64 }
+	var path [cuckooMaxKicks]kick
+	i := i1
+	if c.rng.IntN(2) == 1 {
+		i = i2
+	}
+	for n := 0; n < cuckooMaxKicks; n++ {
+		slot := i*cuckooBucketSize + c.rng.Uint64N(cuckooBucketSize)
+		prev := c.get(slot)
+		c.set(slot, fp)
+		path[n] = kick{slot, prev}
+		fp = prev
+		i = c.altIndex(i, fp)
+		if c.insert(i, fp) {
+			c.count++
+			return nil
+		}
+	}
+	// Undo the relocations so no stored fingerprint is lost.
+	for n := cuckooMaxKicks - 1; n >= 0; n-- {
+		c.set(path[n].slot, path[n].prev)
+	}
+	return ErrFilterFull
+}
+
+// Contains reports whether data might be in the filter.
+// Returns false -> definitely not present.
+// Returns true  -> might be present (subject to false positives).
+// A zero-value or nil filter contains nothing.
+func (c *Cuckoo) Contains(data []byte) bool {
+	if c == nil || c.buckets == 0 {
+		return false
+	}
+	fp, i1 := c.hashes(data)
+	return c.find(i1, fp) >= 0 || c.find(c.altIndex(i1, fp), fp) >= 0
+}
+
+// MightContain is Contains, under the name ColdTier and the other
+// filters use.
+func (c *Cuckoo) MightContain(data []byte) bool {
+	return c.Contains(data)
+}
+
+// Delete removes one copy of data and reports whether one was found.
+//
+// Only delete keys that were added. A key that was never added but
+// shares a fingerprint and bucket with one that was (a false positive)
+// deletes that key's copy instead, which then becomes a false negative.
+// Each Add of a duplicate key needs its own Delete.
+func (c *Cuckoo) Delete(data []byte) bool {
+	if c == nil || c.buckets == 0 {
+		return false
+	}
+	fp, i1 := c.hashes(data)
+	for _, i := range [2]uint64{i1, c.altIndex(i1, fp)} {
+		if slot := c.find(i, fp); slot >= 0 {
+			c.set(uint64(slot), 0)
+			c.count--
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of stored fingerprints: keys added minus keys
+// deleted.
+func (c *Cuckoo) Count() uint64 {
+	if c == nil {
+		return 0
+	}
+	return c.count
+}
+
+// Load returns the fraction of slots in use. Adds start failing as it
+// nears cuckooMaxLoad.
+func (c *Cuckoo) Load() float64 {
+	if c == nil || c.buckets == 0 {
+		return 0
+	}
+	return float64(c.count) / float64(c.buckets*cuckooBucketSize)
+}
+
+// Reset removes every key.
+func (c *Cuckoo) Reset() {
+	if c == nil {
+		return
+	}
+	clear(c.slots)
+	c.count = 0
+}
+
+// SizeInBytes reports the memory held by the filter.
+func (c *Cuckoo) SizeInBytes() uint64 {
+	if c == nil {
+		return 0
+	}
+	return uint64(len(c.slots))*8 + uint64(unsafe.Sizeof(*c))
+}
+
+// Info returns a small description of the filter's configuration.
+func (c *Cuckoo) Info() string {
+	if c == nil {
+		return "Cuckoo{nil}"
+	}
+	return fmt.Sprintf("Cuckoo{buckets=%d, fingerprint=%d bits}", c.buckets, c.fpBits)
+}
+
+// hashes returns data's non-zero fingerprint and primary bucket, from the
+// two halves of its murmur3 hash.
+func (c *Cuckoo) hashes(data []byte) (fp, bucket uint64) {
+	h1, h2 := murmur3x64_128(data, 0)
+	fp = h2 & (1<<c.fpBits - 1)
+	if fp == 0 {
+		fp = 1
+	}
+	return fp, h1 & (c.buckets - 1)
+}
+
+// altIndex returns the other candidate bucket for fp stored in bucket i.
+// It is an involution: altIndex(altIndex(i, fp), fp) == i.
+func (c *Cuckoo) altIndex(i, fp uint64) uint64 {
+	return (i ^ mix64(fp)) & (c.buckets - 1)
+}
+
+// insert stores fp in an empty slot of bucket i, if there is one.
+func (c *Cuckoo) insert(i, fp uint64) bool {
+	if slot := c.find(i, 0); slot >= 0 {
+		c.set(uint64(slot), fp)
+		return true
+	}
+	return false
+}
+
+// find returns the first slot of bucket i holding fp, or -1.
+func (c *Cuckoo) find(i, fp uint64) int64 {
+	for slot := i * cuckooBucketSize; slot < (i+1)*cuckooBucketSize; slot++ {
+		if c.get(slot) == fp {
+			return int64(slot)
+		}
+	}
+	return -1
+}
+
+// get returns the fingerprint in slot, which may straddle two words.
+func (c *Cuckoo) get(slot uint64) uint64 {
+	bit := slot * c.fpBits
+	word, shift := bit/64, bit%64
+	v := c.slots[word] >> shift
+	if shift+c.fpBits > 64 {
+		v |= c.slots[word+1] << (64 - shift)
+	}
+	return v & (1<<c.fpBits - 1)
+}
+
+func (c *Cuckoo) set(slot, fp uint64) {
+	bit := slot * c.fpBits
+	word, shift := bit/64, bit%64
+	mask := uint64(1)<<c.fpBits - 1
+	c.slots[word] = c.slots[word]&^(mask<<shift) | fp<<shift
+	if shift+c.fpBits > 64 {
+		spill := 64 - shift
+		c.slots[word+1] = c.slots[word+1]&^(mask>>spill) | fp>>spill
+	}
+}
+
+// Cuckoo binary format (all integers little-endian):
+//
+//	version  uint8   cuckooVersion
+//	fpBits   uint8
+//	buckets  uint64  a power of two
+//	count    uint64
+//	slots    ceil(buckets*4*fpBits/64) * uint64
+const (
+	cuckooVersion   = 1
+	cuckooHeaderLen = 1 + 1 + 8 + 8
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (c *Cuckoo) MarshalBinary() ([]byte, error) {
+	if c == nil || c.buckets == 0 {
+		return nil, ErrUninitialized
+	}
+	buf := make([]byte, 0, cuckooHeaderLen+len(c.slots)*8)
+	buf = append(buf, cuckooVersion, byte(c.fpBits))
+	buf = binary.LittleEndian.AppendUint64(buf, c.buckets)
+	buf = binary.LittleEndian.AppendUint64(buf, c.count)
+	for _, w := range c.slots {
+		buf = binary.LittleEndian.AppendUint64(buf, w)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, fully replacing
+// the receiver's contents. Malformed data fails with ErrCorrupt, an
+// unknown version with ErrUnsupportedVersion; the receiver is left
+// untouched on error.
+func (c *Cuckoo) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	if data[0] != cuckooVersion {
+		return fmt.Errorf("%w: cuckoo version %d", ErrUnsupportedVersion, data[0])
+	}
+	if len(data) < cuckooHeaderLen {
+		return fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	fpBits := uint64(data[1])
+	buckets := binary.LittleEndian.Uint64(data[2:])
+	count := binary.LittleEndian.Uint64(data[10:])
+	if fpBits != 8 && fpBits != 12 && fpBits != 16 || buckets == 0 || buckets&(buckets-1) != 0 || buckets > 1<<58 {
+		return fmt.Errorf("%w: fingerprint bits %d, %d buckets", ErrCorrupt, fpBits, buckets)
+	}
+	slotBits := buckets * cuckooBucketSize * fpBits
+	payload := data[cuckooHeaderLen:]
+	if uint64(len(payload)) != (slotBits+63)/64*8 {
+		return fmt.Errorf("%w: payload is %d bytes, want %d", ErrCorrupt, len(payload), (slotBits+63)/64*8)
+	}
+	decoded := newCuckoo(fpBits, buckets)
+	for i := range decoded.slots {
+		decoded.slots[i] = binary.LittleEndian.Uint64(payload[i*8:])
+	}
+	if used := slotBits % 64; used != 0 && decoded.slots[len(decoded.slots)-1]>>used != 0 {
+		return fmt.Errorf("%w: padding bits set beyond the last slot", ErrCorrupt)
+	}
+	var used uint64
+	for slot := uint64(0); slot < buckets*cuckooBucketSize; slot++ {
+		if decoded.get(slot) != 0 {
+			used++
+		}
+	}
+	if used != count {
+		return fmt.Errorf("%w: %d fingerprints stored, header says %d", ErrCorrupt, used, count)
+	}
+	decoded.count = count
+	*c = *decoded
+	return nil
+}
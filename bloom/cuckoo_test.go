@@ -0,0 +1,198 @@
+package bloom
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestCuckoo_AddContainsDelete(t *testing.T) {
+	for _, fpBits := range []uint64{8, 12, 16} {
+		c := NewCuckoo(10_000, fpBits)
+		for i := 0; i < 10_000; i++ {
+			if err := c.Add([]byte("key-" + strconv.Itoa(i))); err != nil {
+				t.Fatalf("%d bits: Add %d: %v", fpBits, i, err)
+			}
+		}
+		if c.Count() != 10_000 {
+			t.Fatalf("%d bits: Count = %d", fpBits, c.Count())
+		}
+		for i := 0; i < 10_000; i++ {
+			if !c.Contains([]byte("key-" + strconv.Itoa(i))) {
+				t.Fatalf("%d bits: false negative for key-%d", fpBits, i)
+			}
+		}
+		for i := 0; i < 5000; i++ {
+			if !c.Delete([]byte("key-" + strconv.Itoa(i))) {
+				t.Fatalf("%d bits: Delete(key-%d) = false", fpBits, i)
+			}
+		}
+		for i := 5000; i < 10_000; i++ {
+			if !c.Contains([]byte("key-" + strconv.Itoa(i))) {
+				t.Fatalf("%d bits: false negative for key-%d after deletes", fpBits, i)
+			}
+		}
+		if c.Count() != 5000 {
+			t.Fatalf("%d bits: Count = %d after deletes", fpBits, c.Count())
+		}
+	}
+}
+
+func TestCuckoo_FalsePositiveRate(t *testing.T) {
+	for _, fpBits := range []uint64{8, 12, 16} {
+		c := NewCuckoo(20_000, fpBits)
+		for _, key := range randomKeys("in-", 20_000, 11) {
+			if err := c.Add(key); err != nil {
+				t.Fatal(err)
+			}
+		}
+		falsePositives := 0
+		const probes = 200_000
+		for _, key := range randomKeys("out-", probes, 12) {
+			if c.Contains(key) {
+				falsePositives++
+			}
+		}
+		// The bound 8/2^f assumes full buckets; at lower load it is lower.
+		rate, bound := float64(falsePositives)/probes, 8/float64(uint64(1)<<fpBits)
+		if rate > bound {
+			t.Errorf("%d bits: false positive rate %.5f over %.5f", fpBits, rate, bound)
+		}
+	}
+}
+
+func TestCuckoo_FullFailsCleanly(t *testing.T) {
+	c := NewCuckoo(1000, 12)
+	var added []string
+	var err error
+	for i := 0; err == nil; i++ {
+		key := "key-" + strconv.Itoa(i)
+		if err = c.Add([]byte(key)); err == nil {
+			added = append(added, key)
+		}
+		if i > 10_000 {
+			t.Fatal("Add never reported the filter full")
+		}
+	}
+	if !errors.Is(err, ErrFilterFull) {
+		t.Fatalf("expected ErrFilterFull, got %v", err)
+	}
+	if load := c.Load(); load < 0.9 {
+		t.Fatalf("filter reported full at load %.2f", load)
+	}
+	if c.Count() != uint64(len(added)) {
+		t.Fatalf("Count = %d after %d successful adds", c.Count(), len(added))
+	}
+	// The failed Add must not have displaced anything.
+	for _, key := range added {
+		if !c.Contains([]byte(key)) {
+			t.Fatalf("%s lost after a failed Add", key)
+		}
+	}
+}
+
+func TestCuckoo_Duplicates(t *testing.T) {
+	c := NewCuckoo(100, 16)
+	for i := 0; i < 3; i++ {
+		if err := c.Add([]byte("dup")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if !c.Delete([]byte("dup")) {
+			t.Fatalf("Delete %d of 3 copies failed", i+1)
+		}
+	}
+	if c.Contains([]byte("dup")) || c.Delete([]byte("dup")) {
+		t.Fatal("key still present after deleting every copy")
+	}
+	if c.Delete([]byte("never-added")) {
+		t.Fatal("Delete succeeded for a key that was never added")
+	}
+}
+
+func TestCuckoo_BinaryRoundTrip(t *testing.T) {
+	c := NewCuckooWithEstimates(5000, 0.001)
+	if c.fpBits != 16 {
+		t.Fatalf("0.1%% needs 16-bit fingerprints (8/2^13 > 0.001), got %d", c.fpBits)
+	}
+	c = NewCuckoo(5000, 12)
+	for i := 0; i < 4000; i++ {
+		if err := c.Add([]byte("key-" + strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Cuckoo
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got.Info() != c.Info() || got.Count() != c.Count() {
+		t.Fatalf("decoded %s with %d items, want %s with %d", got.Info(), got.Count(), c.Info(), c.Count())
+	}
+	for i := 0; i < 8000; i++ {
+		key := []byte("key-" + strconv.Itoa(i))
+		if got.Contains(key) != c.Contains(key) {
+			t.Fatalf("key %d: decoded filter disagrees", i)
+		}
+	}
+	if err := got.Add([]byte("after-decode")); err != nil {
+		t.Fatal(err)
+	}
+
+	badCount := append([]byte(nil), data...)
+	badCount[10]++
+	for name, bad := range map[string][]byte{
+		"truncated": data[:len(data)-1],
+		"count":     badCount,
+		"buckets":   append([]byte{cuckooVersion, 12, 3, 0, 0, 0, 0, 0, 0, 0}, data[10:]...),
+		"huge":      append([]byte{cuckooVersion, 12, 0, 0, 0, 0, 0, 0, 0, 0x01}, data[10:]...),
+	} {
+		if err := got.UnmarshalBinary(bad); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: expected ErrCorrupt, got %v", name, err)
+		}
+	}
+}
+
+// The Cuckoo and Bloom benchmarks below are sized for 100k items at a
+// 0.1% target so their bytes/item and ns/op can be compared directly.
+const benchCompareItems = 100_000
+
+func benchCompareKeys() [][]byte {
+	keys := make([][]byte, benchCompareItems)
+	for i := range keys {
+		keys[i] = []byte("key-" + strconv.Itoa(i))
+	}
+	return keys
+}
+
+func BenchmarkCuckoo_Contains(b *testing.B) {
+	c := NewCuckooWithEstimates(benchCompareItems, 0.001)
+	keys := benchCompareKeys()
+	for _, key := range keys {
+		if err := c.Add(key); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Contains(keys[i%len(keys)])
+	}
+	b.ReportMetric(float64(c.SizeInBytes())/benchCompareItems, "bytes/item")
+}
+
+func BenchmarkBloom_MightContainAtCuckooTarget(b *testing.B) {
+	bf := NewWithEstimates(benchCompareItems, 0.001)
+	keys := benchCompareKeys()
+	for _, key := range keys {
+		bf.Add(key)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.MightContain(keys[i%len(keys)])
+	}
+	b.ReportMetric(float64(bf.SizeInBytes())/benchCompareItems, "bytes/item")
+}
@@ -0,0 +1,131 @@
+package bloom
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Deletable is a Deletable Bloom Filter (Rothenberg et al.). The m bits are
+// split into r regions, and a bitmap of r bits records which regions have
+// seen two insertions set the same bit. Bits in collision-free regions
+// belong to exactly one element and can be cleared safely, so Remove never
+// introduces false negatives for the remaining elements. The overhead is
+// r bits, instead of the 4-8x of a counting filter.
+//
+// Elements whose bits all fall in collided regions cannot be removed; the
+// fraction of removable elements shrinks as the filter fills.
+//
+// Note: This type is not safe for concurrent use without external locking.
+type Deletable struct {
+	bf         *BloomFilter
+	regions    uint64
+	regionSize uint64   // bits per region; the last region may be shorter
+	collisions []uint64 // one bit per region
+}
+
+// NewDeletable creates a deletable filter with m bits, k hash functions and
+// the given number of collision-tracking regions.
+// m, k and regions ==> must be >0, and regions <= m.
+func NewDeletable(m, k, regions uint64) *Deletable {
+	if regions == 0 || regions > m {
+		panic("bloom: regions must be in [1, m]")
+	}
+	bf := New(m, k)
+	return &Deletable{
+		bf:         bf,
+		regions:    regions,
+		regionSize: (m + regions - 1) / regions,
+		collisions: make([]uint64, wordsFor(regions)),
+	}
+}
+
+// NewDeletableWithEstimates creates a deletable filter sized for n items at
+// fpRate (as NewWithEstimates), split into the given number of regions.
+func NewDeletableWithEstimates(n uint64, fpRate float64, regions uint64) *Deletable {
+	bf := NewWithEstimates(n, fpRate)
+	d := NewDeletable(bf.m, bf.k, regions)
+	d.bf.capacity = n
+	return d
+}
+
+// Add inserts data. Any probe that lands on an already-set bit marks that
+// bit's region as collided.
+func (d *Deletable) Add(data []byte) {
+	bf := d.bf
+	h := bf.hashes(data)
+	for i := uint64(0); i < bf.k; i++ {
+		pos := bf.location(h, i)
+		if bf.getBit(pos) {
+			r := pos / d.regionSize
+			d.collisions[r/64] |= 1 << (r % 64)
+		} else {
+			bf.setBit(pos)
+		}
+	}
+	bf.inserts++
+}
+
+// MightContain checks if data might be in the filter.
+// Returns false -> definitely not present.
+// Returns true  -> might be present (subject to false positives).
+func (d *Deletable) MightContain(data []byte) bool {
+	return d.bf.MightContain(data)
+}
+
+// Remove deletes data by clearing its bits that lie in collision-free
+// regions. It reports whether the element was removed, i.e. at least one
+// such bit existed; false means data was absent or not removable.
+//
+// Removing a key that was never added is harmless when MightContain reports
+// it absent. If it is a false positive, its collision-free bits belong to
+// another element, which Remove would then delete.
+func (d *Deletable) Remove(data []byte) bool {
+	if !d.MightContain(data) {
+		return false
+	}
+
+	bf := d.bf
+	h := bf.hashes(data)
+	removed := false
+	for i := uint64(0); i < bf.k; i++ {
+		pos := bf.location(h, i)
+		if !d.collided(pos / d.regionSize) {
+			bf.clearBit(pos)
+			removed = true
+		}
+	}
+	if removed && bf.inserts > 0 {
+		bf.inserts--
+	}
+	return removed
+}
+
+// Reset clears all bits and collision marks.
+func (d *Deletable) Reset() {
+	d.bf.Reset()
+	for i := range d.collisions {
+		d.collisions[i] = 0
+	}
+}
+
+// SizeInBytes reports the memory held by the filter, including the
+// collision bitmap.
+func (d *Deletable) SizeInBytes() uint64 {
+	return uint64(unsafe.Sizeof(*d)) + d.bf.SizeInBytes() + uint64(len(d.collisions))*8
+}
+
+// Stats returns a snapshot of the filter's configuration and fill.
+func (d *Deletable) Stats() Stats {
+	st := d.bf.Stats()
+	st.SizeBytes = d.SizeInBytes()
+	return st
+}
+
+// Info returns a small description of the filter's configuration.
+func (d *Deletable) Info() string {
+	return fmt.Sprintf("Deletable{m=%d bits, k=%d, regions=%d}", d.bf.m, d.bf.k, d.regions)
+}
+
+func (d *Deletable) collided(region uint64) bool {
+	return d.collisions[region/64]&(1<<(region%64)) != 0
+}
@@ -0,0 +1,77 @@
+package bloom
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestDeletable_NoFalseNegativesAfterRemove(t *testing.T) {
+	d := NewDeletableWithEstimates(2000, 0.01, 1024)
+
+	present := make(map[string]bool)
+	for i := 0; i < 2000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		d.Add([]byte(key))
+		present[key] = true
+
+		// Interleave removals of earlier keys.
+		if i%3 == 0 && i > 0 {
+			victim := "key-" + strconv.Itoa(i/2)
+			if present[victim] && d.Remove([]byte(victim)) {
+				delete(present, victim)
+			}
+		}
+	}
+
+	for key := range present {
+		if !d.MightContain([]byte(key)) {
+			t.Fatalf("false negative for %q after removals", key)
+		}
+	}
+}
+
+func TestDeletable_RemoveSingle(t *testing.T) {
+	d := NewDeletable(1<<12, 4, 64)
+	d.Add([]byte("alpha"))
+
+	if !d.Remove([]byte("alpha")) {
+		t.Fatal(`expected "alpha" to be removable from an otherwise empty filter`)
+	}
+	if d.MightContain([]byte("alpha")) {
+		t.Fatal(`expected "alpha" to be absent after Remove`)
+	}
+}
+
+func TestDeletable_RemoveNeverAdded(t *testing.T) {
+	d := NewDeletable(1<<12, 4, 64)
+	d.Add([]byte("alpha"))
+
+	if d.MightContain([]byte("never-added")) {
+		t.Skip(`"never-added" is a false positive for this configuration`)
+	}
+	if d.Remove([]byte("never-added")) {
+		t.Fatal("Remove of an absent key must report false")
+	}
+	if !d.MightContain([]byte("alpha")) {
+		t.Fatal(`Remove of an absent key must not affect "alpha"`)
+	}
+}
+
+func TestDeletable_CollidedRegionNotRemovable(t *testing.T) {
+	d := NewDeletable(1<<10, 3, 1) // a single region collides on any overlap
+	d.Add([]byte("alpha"))
+	d.Add([]byte("alpha")) // re-adding collides with itself
+
+	if d.Remove([]byte("alpha")) {
+		t.Fatal("element in a collided region must not be removable")
+	}
+	if !d.MightContain([]byte("alpha")) {
+		t.Fatal(`expected "alpha" to remain present`)
+	}
+
+	d.Reset()
+	d.Add([]byte("alpha"))
+	if !d.Remove([]byte("alpha")) {
+		t.Fatal("Reset must clear collision marks")
+	}
+}
@@ -0,0 +1,207 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"sync/atomic"
+)
+
+// deltaBlockWords is the granularity of dirty tracking: one generation
+// stamp covers this many words, so tracking costs 1/16 of the bitset.
+const deltaBlockWords = 16
+
+// Delta holds the words of a filter that changed over some interval, for
+// replicating a filter incrementally. See DeltaSince and ApplyDelta.
+type Delta struct {
+	// Fingerprint identifies the source's parameters; ApplyDelta refuses
+	// deltas from a different family.
+	Fingerprint uint64
+
+	// Full reports that the delta holds every non-zero word of the source
+	// and replaces the replica's bits instead of adding to them. It is set
+	// when the source cannot say what changed, because tracking had not
+	// started or bits were cleared (Reset, Intersect, CopyFrom, or removal
+	// from a Deletable).
+	Full bool
+
+	// Inserts is the source's insert count when the delta was taken.
+	Inserts uint64
+
+	// Indexes and Words are parallel: Words[i] is the value of word
+	// Indexes[i], in ascending index order.
+	Indexes []uint64
+	Words   []uint64
+}
+
+// DeltaSince returns the words that changed since generation, together
+// with the generation to pass next time. Pass 0 to start; the first call
+// returns a Full delta of the whole filter and turns on dirty tracking,
+// after which each call returns only the changed words.
+//
+// Words are tracked in blocks of 16, and a delta carries every non-zero
+// word of a dirty block, so it can hold unchanged words next to changed
+// ones. Since bits only ever turn on between Full deltas, applying the
+// same delta twice or deltas out of order gives the same result.
+func (bf *BloomFilter) DeltaSince(generation uint64) (Delta, uint64) {
+	if !bf.initialized() {
+		return Delta{}, generation
+	}
+	bf.settle()
+	d := Delta{Fingerprint: bf.Fingerprint(), Inserts: bf.inserts}
+	if bf.dirty == nil {
+		bf.dirty = make([]uint64, (bf.wordCount()+deltaBlockWords-1)/deltaBlockWords)
+		if bf.generation == 0 {
+			bf.generation = 1
+		}
+		d.Full = true
+	}
+	for block, stamp := range bf.dirty {
+		if !d.Full && stamp <= generation {
+			continue
+		}
+		start := block * deltaBlockWords
+		for i := start; i < min(start+deltaBlockWords, bf.wordCount()); i++ {
+			if w := *bf.word(uint64(i)); w != 0 {
+				d.Indexes = append(d.Indexes, uint64(i))
+				d.Words = append(d.Words, w)
+			}
+		}
+	}
+	current := bf.generation
+	bf.generation++
+	return d, current
+}
+
+// ApplyDelta brings bf up to date with a delta taken from another filter
+// of the same family: the words are ORed in, or replace bf's bits if the
+// delta is Full. Deltas from another family fail with ErrIncompatible, and
+// malformed ones with ErrCorrupt; bf is unchanged on error.
+func (bf *BloomFilter) ApplyDelta(d Delta) error {
+	if !bf.initialized() {
+		return ErrUninitialized
+	}
+	if d.Fingerprint != bf.Fingerprint() {
+		return fmt.Errorf("%w: delta fingerprint %#x != %#x", ErrIncompatible, d.Fingerprint, bf.Fingerprint())
+	}
+	if len(d.Indexes) != len(d.Words) {
+		return fmt.Errorf("%w: %d indexes for %d words", ErrCorrupt, len(d.Indexes), len(d.Words))
+	}
+	last := uint64(bf.wordCount() - 1)
+	for i, idx := range d.Indexes {
+		if idx > last || (idx == last && d.Words[i]&^lastWordMask(bf.m) != 0) {
+			return fmt.Errorf("%w: delta word %d out of range", ErrCorrupt, idx)
+		}
+	}
+
+	if d.Full {
+		bf.Reset()
+	}
+	bf.settle()
+	for i, idx := range d.Indexes {
+		bf.orWord(int(idx), d.Words[i])
+	}
+	bf.inserts = max(bf.inserts, d.Inserts)
+	bf.checkOverfill()
+	return nil
+}
+
+// orWord ORs w into word i, keeping the set-bit count and dirty tracking
+// up to date.
+func (bf *BloomFilter) orWord(i int, w uint64) {
+	p := bf.word(uint64(i))
+	old := *p
+	if old|w == old {
+		return
+	}
+	*p = old | w
+	bf.setBits += uint64(bits.OnesCount64(w &^ old))
+	bf.markDirty(uint64(i))
+}
+
+// markDirty records that word i changed in the current generation.
+func (bf *BloomFilter) markDirty(i uint64) {
+	if bf.dirty != nil {
+		bf.dirty[i/deltaBlockWords] = bf.generation
+	}
+}
+
+// markDirtyAtomic is markDirty for concurrent loaders. Only DeltaSince
+// changes the generation, and it does not run during a load.
+func (bf *BloomFilter) markDirtyAtomic(i uint64) {
+	if bf.dirty != nil {
+		atomic.StoreUint64(&bf.dirty[i/deltaBlockWords], bf.generation)
+	}
+}
+
+// stopTracking forgets dirty state after bits were cleared, so the next
+// DeltaSince sends a Full delta.
+func (bf *BloomFilter) stopTracking() {
+	bf.dirty = nil
+}
+
+// Delta binary format (integers are uvarints unless noted):
+//
+//	fingerprint  uint64 little-endian
+//	full         uint8
+//	inserts
+//	count
+//	count × (index delta from the previous index, word as uint64 LE)
+//
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (d Delta) MarshalBinary() ([]byte, error) {
+	buf := binary.LittleEndian.AppendUint64(make([]byte, 0, 16+len(d.Words)*10), d.Fingerprint)
+	if d.Full {
+		buf = append(buf, 1)
+	} else {
+		buf = append(buf, 0)
+	}
+	buf = binary.AppendUvarint(buf, d.Inserts)
+	buf = binary.AppendUvarint(buf, uint64(len(d.Indexes)))
+	var prev uint64
+	for i, idx := range d.Indexes {
+		buf = binary.AppendUvarint(buf, idx-prev)
+		buf = binary.LittleEndian.AppendUint64(buf, d.Words[i])
+		prev = idx
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. Malformed data
+// fails with ErrCorrupt.
+func (d *Delta) UnmarshalBinary(data []byte) error {
+	if len(data) < 9 || data[8] > 1 {
+		return fmt.Errorf("%w: short delta header", ErrCorrupt)
+	}
+	decoded := Delta{Fingerprint: binary.LittleEndian.Uint64(data), Full: data[8] == 1}
+	data = data[9:]
+	var count uint64
+	for _, v := range []*uint64{&decoded.Inserts, &count} {
+		n, size := binary.Uvarint(data)
+		if size <= 0 {
+			return fmt.Errorf("%w: bad delta header", ErrCorrupt)
+		}
+		*v, data = n, data[size:]
+	}
+	if count > uint64(len(data))/9 {
+		return fmt.Errorf("%w: delta claims %d words", ErrCorrupt, count)
+	}
+	decoded.Indexes = make([]uint64, 0, count)
+	decoded.Words = make([]uint64, 0, count)
+	var prev uint64
+	for i := uint64(0); i < count; i++ {
+		gap, size := binary.Uvarint(data)
+		if size <= 0 || len(data) < size+8 || (i > 0 && gap == 0) {
+			return fmt.Errorf("%w: bad delta entry %d", ErrCorrupt, i)
+		}
+		prev += gap
+		decoded.Indexes = append(decoded.Indexes, prev)
+		decoded.Words = append(decoded.Words, binary.LittleEndian.Uint64(data[size:]))
+		data = data[size+8:]
+	}
+	if len(data) != 0 {
+		return fmt.Errorf("%w: %d trailing bytes after delta", ErrCorrupt, len(data))
+	}
+	*d = decoded
+	return nil
+}
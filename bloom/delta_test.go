@@ -0,0 +1,147 @@
+package bloom
+
+import (
+	"errors"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+)
+
+func TestDelta_ReplicaStaysInSync(t *testing.T) {
+	source := NewWithEstimates(200_000, 0.01)
+	replica := NewWithEstimates(200_000, 0.01)
+	r := rand.New(rand.NewPCG(5, 6))
+
+	var gen uint64
+	added := 0
+	for round := 0; round < 20; round++ {
+		for i := 0; i < 100; i++ {
+			source.Add([]byte("key-" + strconv.Itoa(added)))
+			added++
+		}
+		d, next := source.DeltaSince(gen)
+		if round == 0 && !d.Full {
+			t.Fatal("first delta should be full")
+		}
+		if round > 0 && (d.Full || len(d.Words) > len(source.bits)/2) {
+			t.Fatalf("round %d: expected an incremental delta, got full=%v with %d words", round, d.Full, len(d.Words))
+		}
+		// Ship it through the wire format.
+		data, err := d.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var received Delta
+		if err := received.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := replica.ApplyDelta(received); err != nil {
+			t.Fatal(err)
+		}
+		gen = next
+	}
+
+	if !replica.Equal(source) || replica.setBits != source.setBits || replica.inserts != source.inserts {
+		t.Fatal("replica diverged from the source")
+	}
+	for i := 0; i < 100_000; i++ {
+		key := []byte("key-" + strconv.FormatUint(r.Uint64()%4000, 10))
+		if replica.MightContain(key) != source.MightContain(key) {
+			t.Fatalf("replica and source disagree on %q", key)
+		}
+	}
+}
+
+func TestDelta_IdempotentAndOrderIndependent(t *testing.T) {
+	source := New(1<<16, 4)
+	_, gen := source.DeltaSince(0)
+	var deltas []Delta
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 200; i++ {
+			source.Add([]byte(strconv.Itoa(round) + "-" + strconv.Itoa(i)))
+		}
+		var d Delta
+		d, gen = source.DeltaSince(gen)
+		deltas = append(deltas, d)
+	}
+
+	replica := New(1<<16, 4)
+	for i := len(deltas) - 1; i >= 0; i-- {
+		for range 2 {
+			if err := replica.ApplyDelta(deltas[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if !replica.Equal(source) {
+		t.Fatal("reversed, duplicated deltas did not reproduce the source")
+	}
+}
+
+func TestDelta_FullAfterClearing(t *testing.T) {
+	source := New(4096, 3)
+	source.Add([]byte("old"))
+	_, gen := source.DeltaSince(0)
+	replica := source.Clone()
+
+	source.Reset()
+	source.Add([]byte("new"))
+	d, gen := source.DeltaSince(gen)
+	if !d.Full {
+		t.Fatal("a delta after Reset must be full")
+	}
+	if err := replica.ApplyDelta(d); err != nil {
+		t.Fatal(err)
+	}
+	if !replica.Equal(source) || replica.MightContain([]byte("old")) {
+		t.Fatal("full delta did not replace the replica's bits")
+	}
+
+	if d, _ := source.DeltaSince(gen); d.Full || len(d.Words) != 0 {
+		t.Fatalf("no changes since the last delta, got %d words", len(d.Words))
+	}
+}
+
+func TestDelta_Errors(t *testing.T) {
+	source := New(1024, 3)
+	source.Add([]byte("x"))
+	d, _ := source.DeltaSince(0)
+
+	if err := New(1024, 4).ApplyDelta(d); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible, got %v", err)
+	}
+	bad := d
+	bad.Indexes = []uint64{16}
+	bad.Words = []uint64{1}
+	if err := New(1024, 3).ApplyDelta(bad); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt for an out-of-range word, got %v", err)
+	}
+	if err := (&BloomFilter{}).ApplyDelta(d); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("expected ErrUninitialized, got %v", err)
+	}
+
+	data, _ := d.MarshalBinary()
+	var decoded Delta
+	for _, truncated := range [][]byte{data[:5], data[:len(data)-1], append(data, 0)} {
+		if err := decoded.UnmarshalBinary(truncated); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("expected ErrCorrupt for %d bytes, got %v", len(truncated), err)
+		}
+	}
+}
+
+func TestSafeBloom_Delta(t *testing.T) {
+	source, replica := NewSafe(2048, 3), NewSafe(2048, 3)
+	source.Add([]byte("a"))
+	d, gen := source.DeltaSince(0)
+	if err := replica.ApplyDelta(d); err != nil {
+		t.Fatal(err)
+	}
+	source.Add([]byte("b"))
+	d, _ = source.DeltaSince(gen)
+	if err := replica.ApplyDelta(d); err != nil {
+		t.Fatal(err)
+	}
+	if !replica.MightContain([]byte("a")) || !replica.MightContain([]byte("b")) {
+		t.Fatal("replica missed keys shipped by delta")
+	}
+}
@@ -0,0 +1,105 @@
+package bloom
+
+import "fmt"
+
+// Digest is the pair of 64-bit base hashes a key's probe positions are
+// derived from. Hashing a key once with Hash and handing the Digest to
+// AddHash or ContainsHash on any number of filters gives exactly the
+// results of Add and MightContain on the key, without hashing it again.
+//
+// Stability: the Digest of a key under Hash is part of the package's
+// compatibility promise, like the binary encoding. H1 is 64-bit FNV-1a of
+// the key and H2 is FNV-1a with the offset basis xored with
+// 0x9e3779b97f4a7c15, replaced by that constant if it is 0; neither will
+// change in future releases, so digests may be stored or sent between
+// processes built from different versions.
+//
+// A Digest is not a secret-preserving form of the key: FNV is fast to
+// invert by guessing, so a client that sends digests instead of keys hides
+// only keys drawn from a space too large to enumerate.
+type Digest struct {
+	H1, H2 uint64
+}
+
+// Hash returns the Digest of data for filters using the default hashing,
+// with no WithHasher and no seed, under any of the package's own probe
+// schemes. For any other filter use that filter's Hash method.
+func Hash(data []byte) Digest {
+	h1, h2 := fnvHashes(data)
+	return Digest{h1, h2}
+}
+
+// Hash returns the Digest of data under bf's own hashing, honouring its
+// Hasher and seed. It panics with ErrUninitialized on a zero-value or nil
+// filter, and with ErrIncompatible for a foreign probe scheme.
+func (bf *BloomFilter) Hash(data []byte) Digest {
+	bf.checkDigests()
+	h := bf.hashes(data)
+	return Digest{h[0], h[1]}
+}
+
+// AddHash inserts the key whose Digest is d, as Add would. d must come from
+// Hash, or from bf.Hash for a filter with a Hasher or seed; a digest made
+// for other hashing inserts a different key. It panics with
+// ErrUninitialized on a zero-value or nil filter, and with ErrIncompatible
+// for a foreign probe scheme (Guava, bits-and-blooms, Cassandra), whose
+// positions are not derived from a Digest.
+func (bf *BloomFilter) AddHash(d Digest) {
+	bf.checkDigests()
+	bf.addHashes(bf.digestBase(d))
+}
+
+// ContainsHash reports whether the key whose Digest is d might be in the
+// filter, as MightContain would. A zero-value or nil filter contains
+// nothing; a foreign probe scheme panics as in AddHash.
+func (bf *BloomFilter) ContainsHash(d Digest) bool {
+	if !bf.initialized() {
+		return false
+	}
+	bf.checkDigests()
+	return bf.containsHashes(bf.digestBase(d))
+}
+
+// checkDigests panics unless bf's probe positions come from a Digest.
+func (bf *BloomFilter) checkDigests() {
+	if !bf.initialized() {
+		panic(ErrUninitialized)
+	}
+	if !bf.scheme.native() {
+		panic(fmt.Errorf("%w: probe scheme %s does not use digests", ErrIncompatible, bf.scheme))
+	}
+}
+
+// digestBase returns d as base hashes for bf, with the same h2 fix-up as
+// hashes so a hand-made digest cannot degenerate into a single probe
+// position, unless bf's Hasher keeps an h2 of 0.
+func (bf *BloomFilter) digestBase(d Digest) baseHashes {
+	if d.H2 == 0 && (bf.hasher == nil || !bf.hasher.exact) {
+		d.H2 = 0x9e3779b97f4a7c15
+	}
+	return baseHashes{d.H1, d.H2}
+}
+
+// AddHash inserts the key whose Digest is d under the write lock. See
+// BloomFilter.AddHash.
+func (s *SafeBloom) AddHash(d Digest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bf.AddHash(d)
+}
+
+// ContainsHash reports, under the read lock, whether the key whose Digest
+// is d might be in the filter. See BloomFilter.ContainsHash.
+func (s *SafeBloom) ContainsHash(d Digest) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.ContainsHash(d)
+}
+
+// Hash returns the Digest of data under the current filter's hashing.
+// See BloomFilter.Hash.
+func (s *SafeBloom) Hash(data []byte) Digest {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.Hash(data)
+}
@@ -0,0 +1,97 @@
+package bloom
+
+import "testing"
+
+func TestDigest_MatchesAddAndMightContain(t *testing.T) {
+	seeded, _ := NewWithOptions(2000, 0.01, WithSeed(42))
+	hashed, _ := NewWithOptions(2000, 0.01, WithHasher(XXHasher{}))
+	for name, pair := range map[string][2]*BloomFilter{
+		"default": {NewWithEstimates(2000, 0.01), NewWithEstimates(2000, 0.01)},
+		"seeded":  {seeded, seeded.Clone()},
+		"hasher":  {hashed, hashed.Clone()},
+	} {
+		byKey, byDigest := pair[0], pair[1]
+		keys := randomKeys(name, 2000, 11)
+		for _, k := range keys[:1000] {
+			byKey.Add(k)
+			byDigest.AddHash(byDigest.Hash(k))
+		}
+		if !byKey.Equal(byDigest) || byKey.Count() != byDigest.Count() {
+			t.Fatalf("%s: AddHash set different bits from Add", name)
+		}
+		for _, k := range keys {
+			if byKey.MightContain(k) != byDigest.ContainsHash(byKey.Hash(k)) {
+				t.Fatalf("%s: ContainsHash disagrees with MightContain for %q", name, k)
+			}
+		}
+	}
+
+	// The package-level Hash is the default filter's digest.
+	bf := NewWithEstimates(100, 0.01)
+	if Hash([]byte("k")) != bf.Hash([]byte("k")) {
+		t.Fatal("Hash differs from a default filter's Hash")
+	}
+}
+
+func TestDigest_Stable(t *testing.T) {
+	// Pinned: digests may be stored or exchanged across releases.
+	// The empty key's digest is the two offset bases.
+	cases := []struct {
+		key string
+		d   Digest
+	}{
+		{"", Digest{0xcbf29ce484222325, 0x55c5e55dfb685f30}},
+		{"alice", Digest{0x508b2abb65a03907, 0x6fbbcaeaaa4c1398}},
+	}
+	for _, c := range cases {
+		if got := Hash([]byte(c.key)); got != c.d {
+			t.Errorf("Hash(%q) = %#x, want %#x", c.key, got, c.d)
+		}
+	}
+}
+
+func TestDigest_SafeBloomAndErrors(t *testing.T) {
+	s := NewSafeWithEstimates(100, 0.01)
+	d := Hash([]byte("shared"))
+	s.AddHash(d)
+	if !s.ContainsHash(d) || !s.MightContain([]byte("shared")) || s.Hash([]byte("shared")) != d {
+		t.Fatal("SafeBloom digest methods disagree with the key methods")
+	}
+
+	var zero BloomFilter
+	if zero.ContainsHash(d) {
+		t.Fatal("zero-value filter contains a digest")
+	}
+	expectPanic(t, ErrUninitialized, func() { zero.AddHash(d) })
+	expectPanic(t, ErrIncompatible, func() { NewGuavaWithEstimates(100, 0.01).AddHash(d) })
+
+	// A hand-made digest with H2 == 0 still probes k distinct positions.
+	bf := New(1024, 5)
+	bf.AddHash(Digest{H1: 7})
+	if bf.setBits < 2 {
+		t.Fatalf("zero H2 set %d bits", bf.setBits)
+	}
+}
+
+func BenchmarkDigest_TwelveFilters(b *testing.B) {
+	filters := make([]*BloomFilter, 12)
+	for i := range filters {
+		filters[i] = NewWithEstimates(100000, 0.01)
+	}
+	key := make([]byte, 600)
+	b.Run("MightContain", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, f := range filters {
+				f.MightContain(key)
+			}
+		}
+	})
+	b.Run("ContainsHash", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			d := Hash(key)
+			for _, f := range filters {
+				f.ContainsHash(d)
+			}
+		}
+	})
+}
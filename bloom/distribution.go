@@ -0,0 +1,98 @@
+package bloom
+
+import "math/bits"
+
+// BitDistribution splits the m bits into buckets contiguous ranges and
+// returns the fill ratio of each, in order. With a good hash every range
+// fills at about the overall FillRatio; ranges well above or below it
+// mean keys cluster, as from a weak hash or a key format the hash handles
+// badly. When buckets does not divide m, the ranges differ in size by at
+// most one bit.
+//
+// buckets is capped at m. It returns nil for buckets < 1 or an
+// uninitialized filter. The filter is only read, apart from settling a
+// lazy one (see WithLazyReset).
+func (bf *BloomFilter) BitDistribution(buckets int) []float64 {
+	counts, sizes := bf.bucketCounts(buckets)
+	if counts == nil {
+		return nil
+	}
+	fills := make([]float64, len(counts))
+	for i := range counts {
+		fills[i] = float64(counts[i]) / float64(sizes[i])
+	}
+	return fills
+}
+
+// ChiSquare summarizes BitDistribution(buckets) as Pearson's chi-square
+// statistic: the sum over ranges of (set - expected)² / expected, where a
+// range is expected to hold set bits in proportion to its size. For
+// uniformly spread bits it is around buckets-1, give or take
+// sqrt(2*(buckets-1)), and usually less on a filter past half full, where
+// the ranges fill more evenly than independent draws would. Values many
+// times buckets-1 indicate skew. It returns 0 for an empty filter, and
+// for buckets < 1 or an uninitialized filter.
+func (bf *BloomFilter) ChiSquare(buckets int) float64 {
+	counts, sizes := bf.bucketCounts(buckets)
+	if counts == nil || bf.setBits == 0 {
+		return 0
+	}
+	fill := bf.fillRatio()
+	var chi2 float64
+	for i := range counts {
+		expected := fill * float64(sizes[i])
+		d := float64(counts[i]) - expected
+		chi2 += d * d / expected
+	}
+	return chi2
+}
+
+// bucketCounts returns the number of set bits and the size of each of
+// buckets contiguous ranges of the bitset, or nils if there are none.
+func (bf *BloomFilter) bucketCounts(buckets int) (counts, sizes []uint64) {
+	if !bf.initialized() || buckets < 1 {
+		return nil, nil
+	}
+	bf.settle()
+	b := min(uint64(buckets), bf.m)
+	counts, sizes = make([]uint64, b), make([]uint64, b)
+	lo := uint64(0)
+	for i := uint64(0); i < b; i++ {
+		// hi = (i+1)*m/b without overflowing for large m.
+		hi, rem := bits.Mul64(i+1, bf.m)
+		hi, _ = bits.Div64(hi, rem, b)
+		counts[i], sizes[i] = bf.countRange(lo, hi), hi-lo
+		lo = hi
+	}
+	return counts, sizes
+}
+
+// countRange returns the number of set bits at positions [lo, hi).
+func (bf *BloomFilter) countRange(lo, hi uint64) uint64 {
+	if lo >= hi {
+		return 0
+	}
+	first, last := lo/64, (hi-1)/64
+	loMask := ^uint64(0) << (lo % 64)
+	hiMask := ^uint64(0) >> (63 - (hi-1)%64)
+	if first == last {
+		return uint64(bits.OnesCount64(*bf.word(first) & loMask & hiMask))
+	}
+	n := uint64(bits.OnesCount64(*bf.word(first) & loMask))
+	for i := first + 1; i < last; i++ {
+		n += uint64(bits.OnesCount64(*bf.word(i)))
+	}
+	return n + uint64(bits.OnesCount64(*bf.word(last)&hiMask))
+}
+
+// BitDistribution returns the bit distribution of a Snapshot, so the lock
+// is held only for the copy. See BloomFilter.BitDistribution.
+func (s *SafeBloom) BitDistribution(buckets int) []float64 {
+	return s.Snapshot().BitDistribution(buckets)
+}
+
+// ChiSquare returns the chi-square statistic of a Snapshot. See
+// BloomFilter.ChiSquare.
+func (s *SafeBloom) ChiSquare(buckets int) float64 {
+	return s.Snapshot().ChiSquare(buckets)
+}
@@ -0,0 +1,86 @@
+package bloom
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBitDistribution_Uniform(t *testing.T) {
+	bf := NewWithEstimates(20_000, 0.01)
+	for _, key := range randomKeys("dist", 10_000, 21) {
+		bf.Add(key)
+	}
+	fill := bf.fillRatio()
+	for i, f := range bf.BitDistribution(16) {
+		if math.Abs(f-fill) > 0.03 {
+			t.Fatalf("bucket %d: fill %.3f, overall %.3f", i, f, fill)
+		}
+	}
+	if chi2 := bf.ChiSquare(64); chi2 > 3*63 {
+		t.Fatalf("chi-square %.1f for random keys, want around 63", chi2)
+	}
+}
+
+// TestBitDistribution_BrokenHasher feeds keys through a hash whose values
+// span only a small range, as a hash that ignores most of the key would,
+// and checks that the diagnostic flags it.
+func TestBitDistribution_BrokenHasher(t *testing.T) {
+	bf := NewWithEstimates(20_000, 0.01)
+	for i := uint64(0); i < 10_000; i++ {
+		bf.addHashes(baseHashes{i % 50_000, 1 + i%7})
+	}
+	dist := bf.BitDistribution(16)
+	if dist[0] < 0.5 || dist[15] != 0 {
+		t.Fatalf("broken hasher not visible: first bucket %.3f, last %.3f", dist[0], dist[15])
+	}
+	if chi2 := bf.ChiSquare(64); chi2 < 100*63 {
+		t.Fatalf("chi-square %.1f for a broken hasher, want far above 63", chi2)
+	}
+}
+
+func TestBitDistribution_UnevenBuckets(t *testing.T) {
+	bf := New(1000, 3)
+	for _, key := range randomKeys("uneven", 200, 22) {
+		bf.Add(key)
+	}
+	for _, buckets := range []int{1, 3, 7, 64, 999, 1000, 5000} {
+		counts, sizes := bf.bucketCounts(buckets)
+		if len(counts) != min(buckets, 1000) {
+			t.Fatalf("%d buckets: got %d", buckets, len(counts))
+		}
+		var total, set, pos uint64
+		for i := range counts {
+			if sizes[i] < 1000/uint64(len(sizes)) || sizes[i] > 1000/uint64(len(sizes))+1 {
+				t.Fatalf("%d buckets: bucket %d has %d bits", buckets, i, sizes[i])
+			}
+			var want uint64
+			for p := pos; p < pos+sizes[i]; p++ {
+				if bf.getBit(p) {
+					want++
+				}
+			}
+			if counts[i] != want {
+				t.Fatalf("%d buckets: bucket %d counts %d set bits, want %d", buckets, i, counts[i], want)
+			}
+			pos += sizes[i]
+			total += sizes[i]
+			set += counts[i]
+		}
+		if total != 1000 || set != bf.setBits {
+			t.Fatalf("%d buckets cover %d bits holding %d set, want 1000 and %d", buckets, total, set, bf.setBits)
+		}
+	}
+
+	if bf.BitDistribution(0) != nil || new(BloomFilter).BitDistribution(4) != nil {
+		t.Fatal("expected nil for no buckets or a zero filter")
+	}
+	if New(64, 3).ChiSquare(8) != 0 {
+		t.Fatal("empty filter has a non-zero chi-square")
+	}
+
+	s := NewSafe(1000, 3)
+	s.Swap(bf.Clone())
+	if got, want := s.BitDistribution(7), bf.BitDistribution(7); len(got) != 7 || got[3] != want[3] || s.ChiSquare(7) != bf.ChiSquare(7) {
+		t.Fatal("SafeBloom distribution differs from its filter's")
+	}
+}
@@ -0,0 +1,336 @@
+package bloom
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// Binary format (all integers little-endian):
+//
+//	version  uint8
+//	m        uint64  no. of bits
+//	k        uint64  no. of hash functions
+//	words    uint64  no. of 64-bit words that follow, always (m+63)/64
+//	scheme   uint8   probe scheme (version >= 2; version 1 implies FNV)
+//	fp       uint64  parameter fingerprint (version >= 3; see Fingerprint)
+//	inserts  uint64  insert count, see Count (version >= 4; older data decodes as 0)
+//	capacity uint64  designed capacity, see Capacity (version >= 4)
+//	hasher   uint64  id of a registered Hasher, 0 for the default (version >= 5)
+//	seed     uint64  hash seed, 0 for unseeded (version >= 6)
+//	bits     words * uint64
+//
+// Fields are only ever appended, so newer versions can read older data.
+const encodingVersion = 6
+
+// headerLen returns the encoded header length for version, or 0 if the
+// version is unknown.
+func headerLen(version uint8) int {
+	switch version {
+	case 1:
+		return 1 + 8 + 8 + 8
+	case 2:
+		return 1 + 8 + 8 + 8 + 1
+	case 3:
+		return 1 + 8 + 8 + 8 + 1 + 8
+	case 4:
+		return 1 + 8 + 8 + 8 + 1 + 8 + 8 + 8
+	case 5:
+		return 1 + 8 + 8 + 8 + 1 + 8 + 8 + 8 + 8
+	case 6:
+		return 1 + 8 + 8 + 8 + 1 + 8 + 8 + 8 + 8 + 8
+	}
+	return 0
+}
+
+// streamChunkWords is the number of words buffered per read or write when
+// streaming, so encoding never materializes the whole payload.
+const streamChunkWords = 4096
+
+var (
+	// ErrUnsupportedVersion is returned when decoding data written by an
+	// unknown version of the format.
+	ErrUnsupportedVersion = errors.New("bloom: unsupported encoding version")
+
+	// ErrCorrupt is returned when encoded data is truncated or inconsistent.
+	ErrCorrupt = errors.New("bloom: corrupt encoding")
+)
+
+// header is the fixed-size prefix of the binary format.
+type header struct {
+	version uint8
+	m, k    uint64
+	words   uint64
+	scheme  scheme
+
+	inserts, capacity uint64 // version >= 4
+	hasher            uint64 // version >= 5; see hasherConfig.id
+	seed              uint64 // version >= 6
+}
+
+func (bf *BloomFilter) header() header {
+	return header{
+		version:  encodingVersion,
+		m:        bf.m,
+		k:        bf.k,
+		words:    uint64(bf.wordCount()),
+		scheme:   bf.scheme,
+		inserts:  bf.inserts,
+		capacity: bf.capacity,
+		hasher:   bf.hasher.hasherID(),
+		seed:     bf.seed,
+	}
+}
+
+func (h header) append(buf []byte) []byte {
+	buf = append(buf, h.version)
+	buf = binary.LittleEndian.AppendUint64(buf, h.m)
+	buf = binary.LittleEndian.AppendUint64(buf, h.k)
+	buf = binary.LittleEndian.AppendUint64(buf, h.words)
+	buf = append(buf, byte(h.scheme))
+	if h.version >= 3 {
+		buf = binary.LittleEndian.AppendUint64(buf, h.fingerprint())
+	}
+	if h.version >= 4 {
+		buf = binary.LittleEndian.AppendUint64(buf, h.inserts)
+		buf = binary.LittleEndian.AppendUint64(buf, h.capacity)
+	}
+	if h.version >= 5 {
+		buf = binary.LittleEndian.AppendUint64(buf, h.hasher)
+	}
+	if h.version >= 6 {
+		buf = binary.LittleEndian.AppendUint64(buf, h.seed)
+	}
+	return buf
+}
+
+// parseHeader decodes and validates a header from the start of buf.
+func parseHeader(buf []byte) (header, error) {
+	if len(buf) == 0 {
+		return header{}, fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	size := headerLen(buf[0])
+	if size == 0 {
+		return header{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, buf[0])
+	}
+	if len(buf) < size {
+		return header{}, fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	h := header{
+		version: buf[0],
+		m:       binary.LittleEndian.Uint64(buf[1:]),
+		k:       binary.LittleEndian.Uint64(buf[9:]),
+		words:   binary.LittleEndian.Uint64(buf[17:]),
+	}
+	if h.version >= 2 {
+		h.scheme = scheme(buf[25])
+	}
+	if !h.scheme.valid() {
+		return header{}, fmt.Errorf("%w: unknown probe scheme %d", ErrCorrupt, h.scheme)
+	}
+	if h.m == 0 || h.k == 0 {
+		return header{}, fmt.Errorf("%w: m=%d k=%d", ErrCorrupt, h.m, h.k)
+	}
+	if h.words != wordsFor(h.m) {
+		return header{}, fmt.Errorf("%w: %d words for m=%d", ErrCorrupt, h.words, h.m)
+	}
+	if h.version >= 5 {
+		h.hasher = binary.LittleEndian.Uint64(buf[50:])
+	}
+	if h.version >= 6 {
+		h.seed = binary.LittleEndian.Uint64(buf[58:])
+	}
+	if h.version >= 3 {
+		if fp := binary.LittleEndian.Uint64(buf[26:]); fp != h.fingerprint() {
+			return header{}, fmt.Errorf("%w: fingerprint %#x does not match parameters (want %#x)", ErrCorrupt, fp, h.fingerprint())
+		}
+	}
+	if h.version >= 4 {
+		h.inserts = binary.LittleEndian.Uint64(buf[34:])
+		h.capacity = binary.LittleEndian.Uint64(buf[42:])
+	}
+	return h, nil
+}
+
+// newFromHeader builds a filter from a decoded header and its words,
+// rejecting set padding bits beyond m and hashers that are not registered.
+func newFromHeader(h header, words storage) (*BloomFilter, error) {
+	if n := words.wordCount(); n > 0 && *words.word(uint64(n - 1))&^lastWordMask(h.m) != 0 {
+		return nil, fmt.Errorf("%w: padding bits set beyond m", ErrCorrupt)
+	}
+	hc, err := configForID(h.hasher)
+	if err != nil {
+		return nil, err
+	}
+	bf := &BloomFilter{m: h.m, k: h.k, storage: words, scheme: h.scheme, hasher: hc, seed: h.seed, inserts: h.inserts, capacity: h.capacity}
+	bf.setBits = words.onesCount()
+	return bf, nil
+}
+
+var (
+	_ encoding.BinaryMarshaler   = (*BloomFilter)(nil)
+	_ encoding.BinaryUnmarshaler = (*BloomFilter)(nil)
+	_ io.WriterTo                = (*BloomFilter)(nil)
+	_ io.ReaderFrom              = (*BloomFilter)(nil)
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler. The encoding is a
+// versioned header carrying m, k and the word count, followed by the bit
+// words; see the format description above.
+func (bf *BloomFilter) MarshalBinary() ([]byte, error) {
+	if !bf.initialized() {
+		return nil, ErrUninitialized
+	}
+	if err := bf.checkEncodable(); err != nil {
+		return nil, err
+	}
+	buf := bf.header().append(make([]byte, 0, headerLen(encodingVersion)+bf.wordCount()*8))
+	return bf.appendWords(buf, binary.LittleEndian), nil
+}
+
+// unmarshal decodes a filter from its complete binary encoding.
+func unmarshal(data []byte) (*BloomFilter, error) {
+	h, err := parseHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	payload := data[headerLen(h.version):]
+	if uint64(len(payload)) != h.words*8 {
+		return nil, fmt.Errorf("%w: payload is %d bytes, want %d", ErrCorrupt, len(payload), h.words*8)
+	}
+	words := newStorage(int(h.words), 0)
+	words.eachSpan(func(start int, span []uint64) {
+		for i := range span {
+			span[i] = binary.LittleEndian.Uint64(payload[(start+i)*8:])
+		}
+	})
+	return newFromHeader(h, words)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, fully replacing
+// the receiver's contents. Truncated, oversized or inconsistent data fails
+// with ErrCorrupt, and data from an unknown format version with
+// ErrUnsupportedVersion; the receiver is left untouched on error.
+func (bf *BloomFilter) UnmarshalBinary(data []byte) error {
+	decoded, err := unmarshal(data)
+	if err != nil {
+		return err
+	}
+	*bf = *decoded
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder using the binary format, so filters
+// embedded in gob-encoded structs keep their contents.
+func (bf *BloomFilter) GobEncode() ([]byte, error) {
+	return bf.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder. The receiver's contents are fully
+// replaced by the decoded filter.
+func (bf *BloomFilter) GobDecode(data []byte) error {
+	return bf.UnmarshalBinary(data)
+}
+
+// WriteTo implements io.WriterTo, streaming the binary encoding of bf to w
+// in fixed-size chunks so the full payload is never held in memory. The
+// output is identical to MarshalBinary, and the returned count is the
+// exact number of bytes written.
+func (bf *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	if !bf.initialized() {
+		return 0, ErrUninitialized
+	}
+	if err := bf.checkEncodable(); err != nil {
+		return 0, err
+	}
+	buf := bf.header().append(make([]byte, 0, streamChunkWords*8))
+	return bf.writeWords(w, buf, binary.LittleEndian)
+}
+
+// ReadFrom implements io.ReaderFrom, decoding a filter streamed by WriteTo
+// (or written by MarshalBinary) and replacing the receiver's contents. It
+// returns the number of bytes consumed. A stream that ends before the
+// declared number of words fails with ErrCorrupt and leaves the receiver
+// untouched. ReadFrom does not read past the end of the encoding.
+func (bf *BloomFilter) ReadFrom(r io.Reader) (int64, error) {
+	decoded, n, err := readFilter(r)
+	if err != nil {
+		return n, err
+	}
+	*bf = *decoded
+	return n, nil
+}
+
+// readFilter decodes a filter streamed by WriteTo. Words are read in chunks
+// and storage grows as data arrives, so a header claiming more words than
+// the stream holds fails with ErrCorrupt instead of allocating up front.
+// Filters large enough to be chunked get a storage chunk at a time.
+func readFilter(r io.Reader) (*BloomFilter, int64, error) {
+	hbuf := make([]byte, 1, headerLen(encodingVersion))
+	n, err := io.ReadFull(r, hbuf)
+	read := int64(n)
+	if err != nil {
+		return nil, read, fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	if size := headerLen(hbuf[0]); size > 1 {
+		hbuf = hbuf[:size]
+		n, err = io.ReadFull(r, hbuf[1:])
+		read += int64(n)
+		if err != nil {
+			return nil, read, fmt.Errorf("%w: short header", ErrCorrupt)
+		}
+	}
+	h, err := parseHeader(hbuf)
+	if err != nil {
+		return nil, read, err
+	}
+
+	chunkWords := 1 << autoChunkShift
+	chunked := h.words > uint64(chunkedThresholdWords)
+	var chunks [][]uint64
+	words := make([]uint64, 0, min(h.words, streamChunkWords))
+	buf := make([]byte, streamChunkWords*8)
+	for remaining := h.words; remaining > 0; {
+		chunk := min(remaining, streamChunkWords)
+		n, err := io.ReadFull(r, buf[:chunk*8])
+		read += int64(n)
+		if err != nil {
+			return nil, read, fmt.Errorf("%w: stream ended %d words short", ErrCorrupt, remaining)
+		}
+		for i := uint64(0); i < chunk; i++ {
+			if chunked && len(words) == chunkWords {
+				chunks = append(chunks, words)
+				words = make([]uint64, 0, min(remaining-i, uint64(chunkWords)))
+			}
+			words = append(words, binary.LittleEndian.Uint64(buf[i*8:]))
+		}
+		remaining -= chunk
+	}
+
+	st := contiguous(words)
+	if chunked {
+		st = chunkedStorage(append(chunks, words), autoChunkShift)
+	}
+	bf, err := newFromHeader(h, st)
+	return bf, read, err
+}
+
+// lastWordMask returns the mask of valid bits in the final word for m bits.
+func lastWordMask(m uint64) uint64 {
+	if m%64 == 0 {
+		return ^uint64(0)
+	}
+	return uint64(1)<<(m%64) - 1
+}
+
+// popcount returns the number of set bits in words.
+func popcount(words []uint64) uint64 {
+	var n uint64
+	for _, w := range words {
+		n += uint64(bits.OnesCount64(w))
+	}
+	return n
+}
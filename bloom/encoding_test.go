@@ -0,0 +1,443 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type gobSnapshot struct {
+	Name  string
+	Seen  *BloomFilter
+	Users *SafeBloom
+	Count int
+}
+
+func TestGob_NestedRoundTrip(t *testing.T) {
+	in := gobSnapshot{
+		Name:  "node-1",
+		Seen:  New(1000, 4),
+		Users: NewSafeWithEstimates(500, 0.01),
+		Count: 42,
+	}
+	for i := 0; i < 100; i++ {
+		in.Seen.Add([]byte("seen-" + strconv.Itoa(i)))
+		in.Users.Add([]byte("user-" + strconv.Itoa(i)))
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatal(err)
+	}
+	var out gobSnapshot
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Name != in.Name || out.Count != in.Count {
+		t.Fatalf("surrounding fields lost: %+v", out)
+	}
+	if out.Seen.Info() != in.Seen.Info() || out.Seen.setBits != in.Seen.setBits {
+		t.Fatalf("decoded filter %s differs from %s", out.Seen.Info(), in.Seen.Info())
+	}
+
+	// The decoded SafeBloom must be usable concurrently straight away.
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if !out.Users.MightContain([]byte("user-" + strconv.Itoa(i))) {
+					t.Errorf("user %d missing after gob round trip", i)
+					return
+				}
+				out.Users.Add([]byte("new-" + strconv.Itoa(g) + "-" + strconv.Itoa(i)))
+			}
+		}(g)
+	}
+	wg.Wait()
+	for i := 0; i < 100; i++ {
+		if !out.Seen.MightContain([]byte("seen-" + strconv.Itoa(i))) {
+			t.Fatalf("seen %d missing after gob round trip", i)
+		}
+	}
+}
+
+func TestGob_DecodeReplacesContents(t *testing.T) {
+	src := New(256, 3)
+	src.Add([]byte("fresh"))
+	data, err := src.GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := New(4096, 7)
+	dst.Add([]byte("stale"))
+	dst.SetCapacity(10)
+	if err := dst.GobDecode(data); err != nil {
+		t.Fatal(err)
+	}
+	if dst.m != 256 || dst.k != 3 || dst.capacity != 0 {
+		t.Fatalf("decode did not replace the filter: %s", dst.Info())
+	}
+	if !dst.MightContain([]byte("fresh")) {
+		t.Fatal(`expected "fresh" to be present after decode`)
+	}
+}
+
+func TestMarshalBinary_RoundTrip(t *testing.T) {
+	for _, m := range []uint64{1, 63, 64, 65, 1000, 4097} {
+		bf := New(m, 3)
+		for i := 0; i < 50; i++ {
+			bf.Add([]byte("key-" + strconv.Itoa(i)))
+		}
+
+		data, err := bf.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := headerLen(encodingVersion) + int(wordsFor(m))*8; len(data) != want {
+			t.Fatalf("m=%d: encoded %d bytes, want %d", m, len(data), want)
+		}
+
+		got := &BloomFilter{}
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("m=%d: %v", m, err)
+		}
+		if got.m != bf.m || got.k != bf.k || got.setBits != bf.setBits {
+			t.Fatalf("m=%d: decoded %s with %d bits set, want %s with %d", m, got.Info(), got.setBits, bf.Info(), bf.setBits)
+		}
+		for i := 0; i < 100; i++ {
+			key := []byte("key-" + strconv.Itoa(i))
+			if got.MightContain(key) != bf.MightContain(key) {
+				t.Fatalf("m=%d key %d: decoded filter disagrees with original", m, i)
+			}
+		}
+	}
+}
+
+func TestUnmarshalBinary_Rejects(t *testing.T) {
+	bf := New(100, 3)
+	bf.Add([]byte("foo"))
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	padding := append([]byte(nil), data...)
+	padding[len(padding)-1] |= 0x80 // bit 127, beyond m=100
+
+	badVersion := append([]byte(nil), data...)
+	badVersion[0] = 99
+
+	badWords := append([]byte(nil), data...)
+	badWords[17]++ // word count no longer matches m
+
+	cases := map[string]struct {
+		data []byte
+		want error
+	}{
+		"empty":          {nil, ErrCorrupt},
+		"short header":   {data[:10], ErrCorrupt},
+		"truncated bits": {data[:len(data)-1], ErrCorrupt},
+		"trailing bytes": {append(append([]byte(nil), data...), 0), ErrCorrupt},
+		"padding bits":   {padding, ErrCorrupt},
+		"word count":     {badWords, ErrCorrupt},
+		"version":        {badVersion, ErrUnsupportedVersion},
+	}
+	for name, c := range cases {
+		dst := New(64, 1)
+		dst.Add([]byte("keep"))
+		err := dst.UnmarshalBinary(c.data)
+		if !errors.Is(err, c.want) {
+			t.Fatalf("%s: got %v, want %v", name, err, c.want)
+		}
+		if !dst.MightContain([]byte("keep")) {
+			t.Fatalf("%s: failed decode modified the receiver", name)
+		}
+	}
+
+	if _, err := (&BloomFilter{}).MarshalBinary(); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("expected ErrUninitialized marshaling a zero filter, got %v", err)
+	}
+}
+
+func TestUnmarshalBinary_Version1(t *testing.T) {
+	bf := New(130, 2)
+	bf.Add([]byte("legacy"))
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Version 1 had no scheme byte.
+	v1 := append([]byte{1}, data[1:25]...)
+	v1 = append(v1, data[headerLen(encodingVersion):]...)
+
+	var got BloomFilter
+	if err := got.UnmarshalBinary(v1); err != nil {
+		t.Fatal(err)
+	}
+	if got.scheme != schemeFNV || !got.MightContain([]byte("legacy")) {
+		t.Fatal("version 1 data decoded incorrectly")
+	}
+}
+
+func TestUnmarshalBinary_Version2(t *testing.T) {
+	bf := NewGuavaWithEstimates(100, 0.01)
+	bf.Add([]byte("legacy"))
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Version 2 had no fingerprint.
+	v2 := append([]byte{2}, data[1:headerLen(2)]...)
+	v2 = append(v2, data[headerLen(encodingVersion):]...)
+
+	var got BloomFilter
+	if err := got.UnmarshalBinary(v2); err != nil {
+		t.Fatal(err)
+	}
+	if got.scheme != schemeGuava64 || !got.MightContain([]byte("legacy")) {
+		t.Fatal("version 2 data decoded incorrectly")
+	}
+	if got.Fingerprint() != bf.Fingerprint() {
+		t.Fatal("version 2 data decoded with a different fingerprint")
+	}
+}
+
+func TestUnmarshalBinary_Version3(t *testing.T) {
+	bf := NewWithEstimates(100, 0.01)
+	bf.Add([]byte("legacy"))
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Version 3 had no insert count or capacity.
+	v3 := append([]byte{3}, data[1:headerLen(3)]...)
+	v3 = append(v3, data[headerLen(encodingVersion):]...)
+
+	var got BloomFilter
+	if err := got.UnmarshalBinary(v3); err != nil {
+		t.Fatal(err)
+	}
+	if !got.MightContain([]byte("legacy")) || got.Count() != 0 || got.Capacity() != 0 {
+		t.Fatal("version 3 data decoded incorrectly")
+	}
+}
+
+func TestUnmarshalBinary_Version4(t *testing.T) {
+	bf := NewWithEstimates(100, 0.01)
+	bf.Add([]byte("legacy"))
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Version 4 had no hasher id; the fingerprint is unchanged for the
+	// default hasher.
+	v4 := append([]byte{4}, data[1:headerLen(4)]...)
+	v4 = append(v4, data[headerLen(encodingVersion):]...)
+
+	var got BloomFilter
+	if err := got.UnmarshalBinary(v4); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(bf) || got.Count() != 1 || got.Hasher() != "fnv" {
+		t.Fatal("version 4 data decoded incorrectly")
+	}
+}
+
+func TestUnmarshalBinary_Version5(t *testing.T) {
+	bf, _ := NewWithOptions(100, 0.01, WithHasher(XXHasher{}))
+	bf.Add([]byte("legacy"))
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Version 5 had no seed.
+	v5 := append([]byte{5}, data[1:headerLen(5)]...)
+	v5 = append(v5, data[headerLen(encodingVersion):]...)
+
+	var got BloomFilter
+	if err := got.UnmarshalBinary(v5); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(bf) || got.Seed() != 0 || got.Hasher() != "xxh64" {
+		t.Fatal("version 5 data decoded incorrectly")
+	}
+}
+
+func TestMarshalBinary_KeepsCounts(t *testing.T) {
+	bf := NewWithEstimates(100, 0.01)
+	for i := 0; i < 150; i++ {
+		bf.Add([]byte(strconv.Itoa(i)))
+	}
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var viaBinary, viaStream, viaJSON BloomFilter
+	if err := viaBinary.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := viaStream.ReadFrom(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(bf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(js, &viaJSON); err != nil {
+		t.Fatal(err)
+	}
+	for name, got := range map[string]*BloomFilter{"binary": &viaBinary, "stream": &viaStream, "json": &viaJSON} {
+		if got.Count() != 150 || got.Capacity() != 100 || !got.OverCapacity() {
+			t.Fatalf("%s: decoded count=%d capacity=%d, want 150 and 100", name, got.Count(), got.Capacity())
+		}
+	}
+}
+
+func TestWriteTo_ReadFromPipe(t *testing.T) {
+	// Large enough to span several stream chunks.
+	bf := New(streamChunkWords*64*3+17, 4)
+	for i := 0; i < 10000; i++ {
+		bf.Add([]byte("key-" + strconv.Itoa(i)))
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan int64)
+	go func() {
+		n, err := bf.WriteTo(pw)
+		pw.CloseWithError(err)
+		done <- n
+	}()
+
+	var got BloomFilter
+	read, err := got.ReadFrom(pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := <-done
+	want := int64(headerLen(encodingVersion)) + int64(len(bf.bits))*8
+	if read != want || written != want {
+		t.Fatalf("read %d and wrote %d bytes, want %d", read, written, want)
+	}
+	for i := 0; i < 10000; i++ {
+		if !got.MightContain([]byte("key-" + strconv.Itoa(i))) {
+			t.Fatalf("key %d missing after streaming round trip", i)
+		}
+	}
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := bf.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("WriteTo output differs from MarshalBinary")
+	}
+}
+
+func TestReadFrom_ShortStream(t *testing.T) {
+	bf := New(1<<16, 3)
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got BloomFilter
+	n, err := got.ReadFrom(bytes.NewReader(data[:len(data)-100]))
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+	if n != int64(len(data)-100) {
+		t.Fatalf("reported %d bytes read, want %d", n, len(data)-100)
+	}
+	if got.initialized() {
+		t.Fatal("failed ReadFrom must leave the receiver untouched")
+	}
+
+	// Two filters back to back: ReadFrom must stop at the end of the first.
+	stream := bytes.NewReader(append(append([]byte(nil), data...), data...))
+	if _, err := got.ReadFrom(stream); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := got.ReadFrom(stream); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGob_ThousandsOfKeys(t *testing.T) {
+	const count = 5000
+	src := NewWithEstimates(count, 0.01)
+	safe := NewSafeWithEstimates(count, 0.01)
+	for i := 0; i < count; i++ {
+		key := []byte("worker-key-" + strconv.Itoa(i))
+		src.Add(key)
+		safe.Add(key)
+	}
+
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	if err := enc.Encode(src); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(safe); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		dst     *BloomFilter
+		dstSafe SafeBloom
+	)
+	dec := gob.NewDecoder(&buf)
+	if err := dec.Decode(&dst); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(&dstSafe); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < count; i++ {
+		key := []byte("worker-key-" + strconv.Itoa(i))
+		if !dst.MightContain(key) || !dstSafe.MightContain(key) {
+			t.Fatalf("key %d missing after gob round trip", i)
+		}
+	}
+}
+
+func TestGob_RejectsCorruption(t *testing.T) {
+	src := New(1000, 3)
+	data, err := src.GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[17]-- // word count no longer matches m
+
+	var dst BloomFilter
+	if err := dst.GobDecode(data); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+	var dstSafe SafeBloom
+	if err := dstSafe.GobDecode(data); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+
+	// A zero-value filter cannot be encoded: it would decode into a filter
+	// that panics on Add.
+	if err := gob.NewEncoder(io.Discard).Encode(&BloomFilter{}); err == nil {
+		t.Fatal("expected an error encoding an uninitialized filter")
+	}
+}
@@ -0,0 +1,119 @@
+package bloom
+
+import (
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+// filterOverhead is the fixed in-memory cost of a BloomFilter value,
+// excluding the bitset backing array.
+const filterOverhead = uint64(unsafe.Sizeof(BloomFilter{}))
+
+// maxBits is the largest m a filter may have: 2^48 bytes of words, the
+// most the Go runtime can allocate on 64-bit platforms. Larger filters
+// fail with ErrFilterTooLarge instead of overflowing the word count or
+// crashing in make.
+const maxBits = 1 << 51
+
+// optimalBits returns the exact no. of bits for n expected insertions at
+// false positive rate fpRate: - (n * ln(fpRate)) / (ln 2)^2.
+func optimalBits(n uint64, fpRate float64) float64 {
+	return -float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)
+}
+
+// estimateParameters derives m and k for n expected insertions at the
+// given false positive probability. Callers must validate n and fpRate;
+// EstimateParameters is the checked form.
+func estimateParameters(n uint64, fpRate float64) (m, k uint64) {
+	m = uint64(math.Ceil(optimalBits(n, fpRate)))
+	if m == 0 {
+		m = 1
+	}
+	return m, OptimalK(m, n)
+}
+
+// validEstimates validates n and fpRate like TryNewWithEstimates and
+// returns the resulting parameters.
+func validEstimates(n uint64, fpRate float64) (m, k uint64, err error) {
+	if n == 0 {
+		return 0, 0, ErrZeroInsertions
+	}
+	if !(fpRate > 0.0 && fpRate < 1.0) {
+		return 0, 0, fmt.Errorf("%w: got %v", ErrInvalidFPRate, fpRate)
+	}
+	if bits := optimalBits(n, fpRate); bits > maxBits {
+		return 0, 0, fmt.Errorf("%w: n=%d at fpRate %v needs %.3g bits", ErrFilterTooLarge, n, fpRate, bits)
+	}
+	m, k = estimateParameters(n, fpRate)
+	return m, k, nil
+}
+
+// EstimateParameters returns the no. of bits m and hash functions k that
+// NewWithEstimates uses for n expected insertions at false positive rate
+// fpRate, without allocating anything:
+//
+// m = - (n * ln(fpRate)) / (ln 2)^2, rounded up
+// k = OptimalK(m, n)
+//
+// This panics with the error TryNewWithEstimates would return.
+func EstimateParameters(n uint64, fpRate float64) (m, k uint64) {
+	m, k, err := validEstimates(n, fpRate)
+	if err != nil {
+		panic(err)
+	}
+	return m, k
+}
+
+// wordsFor returns the number of 64-bit words needed to hold m bits.
+func wordsFor(m uint64) uint64 {
+	return (m + 63) / 64
+}
+
+// MemoryRequired reports how many bytes a filter built with
+// NewWithEstimates(n, fpRate) would occupy, so a deployment can check a
+// memory limit before committing to a size. It only runs the parameter
+// estimation and allocates nothing. The result matches SizeInBytes of the
+// constructed filter; a SafeBloom adds a few dozen bytes of wrapper.
+//
+// This panics with the error TryNewWithEstimates would return.
+func MemoryRequired(n uint64, fpRate float64) uint64 {
+	m, _ := EstimateParameters(n, fpRate)
+	words := wordsFor(m)
+	return words*8 + chunkTableBytes(words) + filterOverhead
+}
+
+// EstimateSizeForEstimates reports the bytes a filter built with
+// NewWithEstimates(n, fpRate) would occupy. It is the same function as
+// MemoryRequired under a name that pairs with EstimateParameters.
+//
+// This panics with the error TryNewWithEstimates would return.
+func EstimateSizeForEstimates(n uint64, fpRate float64) uint64 {
+	return MemoryRequired(n, fpRate)
+}
+
+// OptimalK returns the no. of hash functions minimising the false positive
+// rate of m bits after n insertions: k = (m / n) * ln 2, rounded up and at
+// least 1.
+//
+// This panics with ErrZeroInsertions if n == 0.
+func OptimalK(m, n uint64) uint64 {
+	if n == 0 {
+		panic(ErrZeroInsertions)
+	}
+	k := uint64(math.Ceil((float64(m) / float64(n)) * math.Ln2))
+	if k == 0 {
+		k = 1
+	}
+	return k
+}
+
+// EstimateFalsePositiveRate returns the theoretical false positive rate of
+// a filter with m bits and k hash functions after n distinct insertions:
+// (1 - e^(-k*n/m))^k. It is 0 for n == 0 and 1 for m == 0.
+func EstimateFalsePositiveRate(m, k, n uint64) float64 {
+	if m == 0 {
+		return 1
+	}
+	return math.Pow(1-math.Exp(-float64(k)*float64(n)/float64(m)), float64(k))
+}
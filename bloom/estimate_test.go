@@ -0,0 +1,137 @@
+package bloom
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestEstimateSizeForEstimates(t *testing.T) {
+	cases := []struct {
+		n         uint64
+		fpRate    float64
+		wantBytes uint64 // bitset bytes, excluding struct overhead
+	}{
+		{1, 0.5, 8},
+		{1000, 0.01, 1200},
+		{1000000, 0.01, 1198136},
+		{50000000, 0.001, 89859928},
+	}
+
+	for _, c := range cases {
+		got := EstimateSizeForEstimates(c.n, c.fpRate)
+		if want := c.wantBytes + filterOverhead; got != want {
+			t.Fatalf("EstimateSizeForEstimates(%d, %v) = %d, want %d", c.n, c.fpRate, got, want)
+		}
+	}
+}
+
+func TestSizeInBytes_MatchesEstimate(t *testing.T) {
+	bf := NewWithEstimates(1000, 0.01)
+	if got, want := bf.SizeInBytes(), EstimateSizeForEstimates(1000, 0.01); got != want {
+		t.Fatalf("SizeInBytes = %d, want %d", got, want)
+	}
+
+	// m not a multiple of 64 rounds up to whole words.
+	bf = New(65, 3)
+	if got, want := bf.SizeInBytes(), 16+filterOverhead; got != want {
+		t.Fatalf("SizeInBytes for m=65 = %d, want %d", got, want)
+	}
+
+	s := NewSafe(65, 3)
+	if s.SizeInBytes() <= bf.SizeInBytes() {
+		t.Fatalf("SafeBloom SizeInBytes %d should exceed inner filter size %d", s.SizeInBytes(), bf.SizeInBytes())
+	}
+}
+
+func TestMemoryRequired_MatchesConstructed(t *testing.T) {
+	cases := []struct {
+		n      uint64
+		fpRate float64
+	}{
+		{1, 0.5},
+		{1000, 0.01},
+		{12345, 0.001},
+		{1_000_000, 0.01},
+		{3_000_000, 1e-6},
+		{50, 0.3},
+	}
+	for _, c := range cases {
+		want := MemoryRequired(c.n, c.fpRate)
+		if got := NewWithEstimates(c.n, c.fpRate).SizeInBytes(); got != want {
+			t.Fatalf("n=%d p=%v: constructed filter holds %d bytes, MemoryRequired said %d", c.n, c.fpRate, got, want)
+		}
+		s := NewSafeWithEstimates(c.n, c.fpRate)
+		if got := s.SizeInBytes(); got <= want || got > want+128 {
+			t.Fatalf("n=%d p=%v: SafeBloom holds %d bytes, want a little over %d", c.n, c.fpRate, got, want)
+		}
+	}
+
+	// Planning a 2-billion-key filter allocates nothing.
+	var size uint64
+	allocs := testing.AllocsPerRun(10, func() { size = MemoryRequired(2_000_000_000, 0.01) })
+	if allocs != 0 {
+		t.Fatalf("MemoryRequired allocates %.1f times", allocs)
+	}
+	if size < 2_300_000_000 || size > 2_500_000_000 {
+		t.Fatalf("MemoryRequired(2e9, 0.01) = %d bytes, want about 2.4 GB", size)
+	}
+}
+
+func TestEstimateParameters(t *testing.T) {
+	cases := []struct {
+		n      uint64
+		fpRate float64
+		m, k   uint64
+		rate   float64 // EstimateFalsePositiveRate(m, k, n)
+	}{
+		{1_000_000, 0.01, 9_585_059, 7, 0.010039},
+		{1000, 0.01, 9586, 7, 0.010035},
+		{1_000_000, 0.001, 14_377_588, 10, 0.0010000},
+		{10_000, 1e-6, 287_552, 20, 1.00004e-6},
+		{1, 0.5, 2, 2, 0.39958},
+	}
+	for _, c := range cases {
+		m, k := EstimateParameters(c.n, c.fpRate)
+		if m != c.m || k != c.k {
+			t.Errorf("EstimateParameters(%d, %v) = %d, %d; want %d, %d", c.n, c.fpRate, m, k, c.m, c.k)
+		}
+		if got := OptimalK(c.m, c.n); got != c.k {
+			t.Errorf("OptimalK(%d, %d) = %d, want %d", c.m, c.n, got, c.k)
+		}
+		if got := EstimateFalsePositiveRate(m, k, c.n); math.Abs(got-c.rate) > 1e-4*c.rate {
+			t.Errorf("EstimateFalsePositiveRate(%d, %d, %d) = %v, want %v", m, k, c.n, got, c.rate)
+		}
+		if bf := NewWithEstimates(c.n, c.fpRate); bf.m != m || bf.k != k {
+			t.Errorf("NewWithEstimates(%d, %v) built m=%d k=%d", c.n, c.fpRate, bf.m, bf.k)
+		}
+	}
+
+	if got := OptimalK(10, 1000); got != 1 {
+		t.Errorf("OptimalK for a tiny m = %d, want 1", got)
+	}
+	if got := EstimateFalsePositiveRate(1024, 3, 0); got != 0 {
+		t.Errorf("rate of an empty filter = %v", got)
+	}
+	if got := EstimateFalsePositiveRate(0, 3, 10); got != 1 {
+		t.Errorf("rate of a zero-bit filter = %v", got)
+	}
+	expectPanic(t, ErrZeroInsertions, func() { OptimalK(64, 0) })
+	expectPanic(t, ErrInvalidFPRate, func() { EstimateParameters(10, 0) })
+}
+
+func TestSizeInBytes_Variants(t *testing.T) {
+	tiered := NewTiered(1000, 0.01)
+	if got, hot := tiered.SizeInBytes(), MemoryRequired(1000, 0.01); got <= hot {
+		t.Fatalf("Tiered SizeInBytes %d should exceed its hot tier's %d", got, hot)
+	}
+
+	w, err := NewWithWAL(filepath.Join(t.TempDir(), "wal"), 1000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if got, bf := w.SizeInBytes(), w.Filter().SizeInBytes(); got <= bf {
+		t.Fatalf("WALFilter SizeInBytes %d should exceed its filter's %d", got, bf)
+	}
+}
@@ -0,0 +1,144 @@
+// Package ethbloom builds and queries Ethereum's logsBloom, the 2048-bit
+// Bloom filter in every block header and transaction receipt.
+//
+// The scheme is fixed by the Yellow Paper (section 4.4.1) and shared by
+// every client: an entry is the Keccak-256 hash of a log's emitting
+// address or of one of its topics; each of the hash's first three byte
+// pairs, read big-endian and taken mod 2048, selects a bit; and bit i
+// lives in byte 255-i/8 of the 256-byte header field, at position i%8.
+// A Bloom here is bit-for-bit what go-ethereum's types.Bloom holds for
+// the same logs, so header fields can be compared and stored directly.
+//
+// The Bloom filters of the bloom package use their own hashing and
+// layout and are not interchangeable with this one. Decoding errors are
+// the bloom package's bloom.ErrCorrupt.
+package ethbloom
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/Abhisheklearn12/bloom-filter/bloom"
+)
+
+const (
+	// ByteLength is the size of a logsBloom in bytes.
+	ByteLength = 256
+	// BitLength is the size of a logsBloom in bits, the filter's m.
+	BitLength = 8 * ByteLength
+	// probes is the number of bits each entry sets, the filter's k.
+	probes = 3
+)
+
+// Bloom is a logsBloom in its header representation. The zero value is
+// an empty filter, and a Bloom converts directly to and from a [256]byte
+// such as go-ethereum's types.Bloom.
+type Bloom [ByteLength]byte
+
+// positions returns the byte index and mask of each bit data sets.
+func positions(data []byte) (idx [probes]int, mask [probes]byte) {
+	h := keccak256(data)
+	for i := range idx {
+		bit := (int(h[2*i])<<8 | int(h[2*i+1])) & (BitLength - 1)
+		idx[i] = ByteLength - 1 - bit/8
+		mask[i] = 1 << (bit % 8)
+	}
+	return idx, mask
+}
+
+// Add inserts one raw entry: a 20-byte address or a 32-byte topic.
+func (b *Bloom) Add(data []byte) {
+	idx, mask := positions(data)
+	for i := range idx {
+		b[idx[i]] |= mask[i]
+	}
+}
+
+// AddLog inserts a log: its emitting contract address and each of its
+// topics. This is what a receipt's bloom holds for each of its logs; log
+// data is not included.
+func (b *Bloom) AddLog(address []byte, topics [][]byte) {
+	b.Add(address)
+	for _, topic := range topics {
+		b.Add(topic)
+	}
+}
+
+// Contains reports whether an address or topic might have been added.
+// Returns false -> definitely not present.
+// Returns true  -> might be present (subject to false positives).
+func (b *Bloom) Contains(addressOrTopic []byte) bool {
+	idx, mask := positions(addressOrTopic)
+	for i := range idx {
+		if b[idx[i]]&mask[i] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Or merges other into b. A block's logsBloom is the Or of its receipts'
+// blooms.
+func (b *Bloom) Or(other *Bloom) {
+	for i := range b {
+		b[i] |= other[i]
+	}
+}
+
+// Merge returns the Or of blooms, such as a block's logsBloom from its
+// receipts' blooms.
+func Merge(blooms ...*Bloom) Bloom {
+	var out Bloom
+	for _, other := range blooms {
+		out.Or(other)
+	}
+	return out
+}
+
+// IsEmpty reports whether no bit is set.
+func (b *Bloom) IsEmpty() bool {
+	return *b == Bloom{}
+}
+
+// Bytes returns a copy of the 256-byte header representation.
+func (b *Bloom) Bytes() []byte {
+	return append([]byte(nil), b[:]...)
+}
+
+// FromBytes decodes a 256-byte header representation. Any other length
+// fails with bloom.ErrCorrupt.
+func FromBytes(data []byte) (Bloom, error) {
+	var b Bloom
+	if len(data) != ByteLength {
+		return b, fmt.Errorf("%w: logsBloom is %d bytes, want %d", bloom.ErrCorrupt, len(data), ByteLength)
+	}
+	copy(b[:], data)
+	return b, nil
+}
+
+// MarshalText implements encoding.TextMarshaler with the 0x-prefixed hex
+// used by JSON-RPC, as in eth_getBlockByNumber's logsBloom field.
+func (b Bloom) MarshalText() ([]byte, error) {
+	out := make([]byte, 2+hex.EncodedLen(ByteLength))
+	copy(out, "0x")
+	hex.Encode(out[2:], b[:])
+	return out, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting 512 hex
+// digits with or without a 0x prefix. Malformed input fails with
+// bloom.ErrCorrupt and leaves b untouched.
+func (b *Bloom) UnmarshalText(text []byte) error {
+	if len(text) >= 2 && text[0] == '0' && (text[1] == 'x' || text[1] == 'X') {
+		text = text[2:]
+	}
+	if len(text) != hex.EncodedLen(ByteLength) {
+		return fmt.Errorf("%w: logsBloom has %d hex digits, want %d", bloom.ErrCorrupt, len(text), hex.EncodedLen(ByteLength))
+	}
+	var decoded Bloom
+	if _, err := hex.Decode(decoded[:], text); err != nil {
+		return fmt.Errorf("%w: %v", bloom.ErrCorrupt, err)
+	}
+	*b = decoded
+	return nil
+}
@@ -0,0 +1,153 @@
+package ethbloom
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Abhisheklearn12/bloom-filter/bloom"
+)
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var (
+	weth     = mustHex("c02aaa39b223fe8d0a0e5c4f27ead9083c756cc2")
+	usdt     = mustHex("dac17f958d2ee523a2206206994597c13d831ec7")
+	usdc     = mustHex("a0b86991c6218b36c1d19d4a2e9eb0ce3606eb48")
+	transfer = mustHex("ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef") // Transfer(address,address,uint256)
+)
+
+func TestKeccak256(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"abc", "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+		{"Transfer(address,address,uint256)", hex.EncodeToString(transfer)},
+		// Around the 136-byte rate, checked against x/crypto's
+		// NewLegacyKeccak256.
+		{strings.Repeat("\x00", 135), "29e3704feeca7fb9ba229f0fa04d9b36449cf3ad6e1d85d9cfff3a10df9abc3e"},
+		{strings.Repeat("\x00", 136), "3a5912a7c5faa06ee4fe906253e339467a9ce87d533c65be3c15cb231cdb25f9"},
+		{strings.Repeat("\x00", 1000), "ae72e2bf2302ebcd309e003e5be58830f96deddaf87bb89eeea159388bfe3ec1"},
+	}
+	for _, c := range cases {
+		if got := keccak256([]byte(c.in)); hex.EncodeToString(got[:]) != c.want {
+			t.Errorf("keccak256 of %d bytes = %x, want %s", len(c.in), got, c.want)
+		}
+	}
+}
+
+// TestMatchesGoEthereum replays go-ethereum's core/types bloom tests and
+// a receipt bloom computed with its types.Bloom.
+func TestMatchesGoEthereum(t *testing.T) {
+	var b Bloom
+	for _, s := range []string{"testtest", "test", "hallo", "other"} {
+		b.Add([]byte(s))
+	}
+	for _, s := range []string{"testtest", "test", "hallo", "other"} {
+		if !b.Contains([]byte(s)) {
+			t.Errorf("%q missing", s)
+		}
+	}
+	for _, s := range []string{"tes", "lo"} {
+		if b.Contains([]byte(s)) {
+			t.Errorf("%q reported present", s)
+		}
+	}
+
+	// TestBloomExtensively: the Keccak-256 of the bloom of 100 strings.
+	b = Bloom{}
+	for i := 0; i < 100; i++ {
+		b.Add([]byte(fmt.Sprintf("xxxxxxxxxx data %d yyyyyyyyyyyyyy", i)))
+	}
+	if got := keccak256(b[:]); hex.EncodeToString(got[:]) != "c8d3ca65cdb4874300a9e39475508f23ed6da09fdbc487f89a2dcf50b09eb263" {
+		t.Errorf("extensive bloom hashes to %x", got)
+	}
+
+	// A WETH Transfer log to 0x4838b1...5f97, hashed into a types.Bloom.
+	b = Bloom{}
+	b.AddLog(weth, [][]byte{transfer, mustHex("0000000000000000000000004838b106fce9647bdf1e7877bf73ce8b0bad5f97")})
+	if got := hex.EncodeToString(b[:]); got != wethTransferBloom {
+		t.Errorf("receipt bloom\n%s\nwant\n%s", got, wethTransferBloom)
+	}
+}
+
+// wethTransferBloom is go-ethereum's types.Bloom for the log above.
+const wethTransferBloom = "00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000002000000080000000000000000000000000000000000000000000008000000000000000000010000000000000000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000002000000000"
+
+// TestMainnetBlooms decodes the logsBloom of two mainnet blocks, as
+// served by JSON-RPC, and checks the entries any busy block contains:
+// the WETH, USDT and USDC contracts and the ERC-20 Transfer topic. The
+// fixtures are the execution payloads' logs_bloom fields from
+// go-ethereum's beacon/types/testdata (blocks 18189758 and 19431837).
+func TestMainnetBlooms(t *testing.T) {
+	for _, block := range []string{"18189758", "19431837"} {
+		text, err := os.ReadFile("testdata/mainnet_" + block + ".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		text = bytes.TrimSpace(text)
+		var b Bloom
+		if err := b.UnmarshalText(text); err != nil {
+			t.Fatalf("block %s: %v", block, err)
+		}
+		for name, entry := range map[string][]byte{"WETH": weth, "USDT": usdt, "USDC": usdc, "Transfer": transfer} {
+			if !b.Contains(entry) {
+				t.Errorf("block %s: %s missing", block, name)
+			}
+		}
+		if out, _ := b.MarshalText(); !bytes.Equal(out, text) {
+			t.Errorf("block %s: MarshalText does not round-trip", block)
+		}
+		raw, err := FromBytes(b.Bytes())
+		if err != nil || raw != b {
+			t.Errorf("block %s: FromBytes does not round-trip: %v", block, err)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	var r1, r2, all Bloom
+	r1.AddLog(weth, [][]byte{transfer})
+	r2.AddLog(usdc, [][]byte{transfer, mustHex("00000000000000000000000095222290dd7278aa3ddd389cc1e1d165cc4bafe5")})
+	all.AddLog(weth, [][]byte{transfer})
+	all.AddLog(usdc, [][]byte{transfer, mustHex("00000000000000000000000095222290dd7278aa3ddd389cc1e1d165cc4bafe5")})
+	if got := Merge(&r1, &r2); got != all {
+		t.Fatal("merged receipt blooms differ from the bloom of all logs")
+	}
+	if empty := Merge(); !empty.IsEmpty() || all.IsEmpty() {
+		t.Fatal("IsEmpty is wrong")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	var b Bloom
+	b.Add(weth)
+	before := b
+	for name, text := range map[string]string{
+		"short":  "0x00",
+		"long":   "0x" + strings.Repeat("00", ByteLength+1),
+		"nonhex": "0x" + strings.Repeat("zz", ByteLength),
+	} {
+		if err := b.UnmarshalText([]byte(text)); !errors.Is(err, bloom.ErrCorrupt) {
+			t.Errorf("%s: expected ErrCorrupt, got %v", name, err)
+		}
+	}
+	if b != before {
+		t.Fatal("a failed decode modified the receiver")
+	}
+	if err := b.UnmarshalText([]byte(strings.Repeat("00", ByteLength))); err != nil || !b.IsEmpty() {
+		t.Fatalf("unprefixed hex: %v", err)
+	}
+	if _, err := FromBytes(make([]byte, ByteLength-1)); !errors.Is(err, bloom.ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+}
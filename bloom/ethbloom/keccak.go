@@ -0,0 +1,84 @@
+package ethbloom
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// keccakRate is the sponge rate of Keccak-256 in bytes.
+const keccakRate = 136
+
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000,
+	0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a,
+	0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// keccakRotations and keccakPi drive the combined rho and pi steps: lane
+// keccakPi[i] receives the previous lane rotated by keccakRotations[i].
+var (
+	keccakRotations = [24]int{1, 3, 6, 10, 15, 21, 28, 36, 45, 55, 2, 14, 27, 41, 56, 8, 25, 43, 62, 18, 39, 61, 20, 44}
+	keccakPi        = [24]int{10, 7, 11, 17, 18, 3, 5, 16, 8, 21, 24, 4, 15, 23, 19, 13, 12, 2, 20, 14, 22, 9, 6, 1}
+)
+
+// keccakF1600 applies the Keccak-f[1600] permutation to st.
+func keccakF1600(st *[25]uint64) {
+	var bc [5]uint64
+	for _, rc := range keccakRoundConstants {
+		// theta
+		for i := 0; i < 5; i++ {
+			bc[i] = st[i] ^ st[i+5] ^ st[i+10] ^ st[i+15] ^ st[i+20]
+		}
+		for i := 0; i < 5; i++ {
+			t := bc[(i+4)%5] ^ bits.RotateLeft64(bc[(i+1)%5], 1)
+			for j := 0; j < 25; j += 5 {
+				st[j+i] ^= t
+			}
+		}
+		// rho and pi
+		t := st[1]
+		for i, j := range keccakPi {
+			st[j], t = bits.RotateLeft64(t, keccakRotations[i]), st[j]
+		}
+		// chi
+		for j := 0; j < 25; j += 5 {
+			copy(bc[:], st[j:j+5])
+			for i := 0; i < 5; i++ {
+				st[j+i] ^= ^bc[(i+1)%5] & bc[(i+2)%5]
+			}
+		}
+		// iota
+		st[0] ^= rc
+	}
+}
+
+// keccak256 returns the Keccak-256 digest of data as Ethereum uses it: the
+// original Keccak padding (0x01), not the FIPS 202 SHA3-256 padding
+// (0x06), so the standard library's crypto/sha3 gives different results.
+func keccak256(data []byte) [32]byte {
+	var st [25]uint64
+	for len(data) >= keccakRate {
+		for i := 0; i < keccakRate/8; i++ {
+			st[i] ^= binary.LittleEndian.Uint64(data[i*8:])
+		}
+		keccakF1600(&st)
+		data = data[keccakRate:]
+	}
+	var block [keccakRate]byte
+	copy(block[:], data)
+	block[len(data)] ^= 0x01
+	block[keccakRate-1] ^= 0x80
+	for i := 0; i < keccakRate/8; i++ {
+		st[i] ^= binary.LittleEndian.Uint64(block[i*8:])
+	}
+	keccakF1600(&st)
+
+	var out [32]byte
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint64(out[i*8:], st[i])
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package bloom_test
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/Abhisheklearn12/bloom-filter/bloom"
+)
+
+func ExampleTyped() {
+	users := bloom.NewTyped(bloom.NewWithEstimates(1000, 0.01), bloom.IntKey[uint64])
+	users.AddAll([]uint64{1001, 1002, 1003})
+
+	fmt.Println(users.MightContain(1002))
+	fmt.Println(users.MightContain(9999))
+	// Output:
+	// true
+	// false
+}
+
+func ExampleTyped_structKey() {
+	type orderID struct {
+		Region string
+		Seq    uint32
+	}
+	encode := func(o orderID) []byte {
+		key := binary.LittleEndian.AppendUint32(nil, o.Seq)
+		return append(key, o.Region...)
+	}
+
+	orders := bloom.NewTyped(bloom.NewWithEstimates(1000, 0.01), encode)
+	orders.Add(orderID{"eu", 42})
+
+	fmt.Println(orders.MightContain(orderID{"eu", 42}))
+	fmt.Println(orders.MightContain(orderID{"us", 42}))
+	// Output:
+	// true
+	// false
+}
+
+// dedupe returns the entries of events not seen before, recording them in
+// seen. It works with any bloom.Filter.
+func dedupe(seen bloom.Filter, events []string) []string {
+	var fresh []string
+	for _, e := range events {
+		if !seen.MightContain([]byte(e)) {
+			seen.Add([]byte(e))
+			fresh = append(fresh, e)
+		}
+	}
+	return fresh
+}
+
+func ExampleFilter() {
+	events := []string{"login:ann", "login:bob", "login:ann", "logout:bob", "login:bob"}
+
+	for _, seen := range []bloom.Filter{
+		bloom.NewWithEstimates(1000, 0.001),
+		bloom.NewCountingWithEstimates(1000, 0.001),
+	} {
+		fmt.Printf("%T: %v\n", seen, dedupe(seen, events))
+	}
+	// Output:
+	// *bloom.BloomFilter: [login:ann login:bob logout:bob]
+	// *bloom.Counting: [login:ann login:bob logout:bob]
+}
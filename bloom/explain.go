@@ -0,0 +1,103 @@
+package bloom
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Explanation traces how a filter answers MightContain for one key: the
+// base hashes computed from it, and for each of the k probes the bit
+// position and whether that bit is set. Present, the MightContain result,
+// is true exactly when every probe's bit is set.
+//
+// Since it pins down every intermediate value, an Explanation doubles as
+// a conformance vector for implementations in other languages.
+type Explanation struct {
+	Key    []byte `json:"key"`
+	Scheme string `json:"scheme"`
+	M      uint64 `json:"m"`
+	K      uint64 `json:"k"`
+
+	// Hashes are the base hashes the positions are derived from: h1 and
+	// h2 for most schemes, four for bitsandblooms.
+	Hashes []uint64 `json:"hashes"`
+
+	Probes  []Probe `json:"probes"`
+	Present bool    `json:"present"`
+}
+
+// Probe is one of a key's k bit positions.
+type Probe struct {
+	Position uint64 `json:"position"` // bit index in [0, m)
+	Word     uint64 `json:"word"`     // index of the 64-bit word holding it
+	Mask     uint64 `json:"mask"`     // the bit within that word
+	Set      bool   `json:"set"`
+}
+
+// Explain traces MightContain(data) without modifying the filter. An
+// uninitialized filter gives an Explanation with no probes and Present
+// false.
+func (bf *BloomFilter) Explain(data []byte) Explanation {
+	e := Explanation{Key: append([]byte(nil), data...)}
+	if !bf.initialized() {
+		return e
+	}
+	e.Scheme, e.M, e.K = bf.scheme.String(), bf.m, bf.k
+	h := bf.hashes(data)
+	e.Hashes = append([]uint64(nil), h[:2]...)
+	if bf.scheme == schemeBitsAndBlooms {
+		e.Hashes = append([]uint64(nil), h[:]...)
+	}
+	e.Present = true
+	for i := uint64(0); i < bf.k; i++ {
+		pos := bf.location(h, i)
+		p := Probe{Position: pos, Word: pos / 64, Mask: uint64(1) << (pos % 64), Set: bf.hasBit(pos)}
+		e.Probes = append(e.Probes, p)
+		e.Present = e.Present && p.Set
+	}
+	return e
+}
+
+// String renders the trace, one line per probe:
+//
+//	key "alice" (fnv, m=1000, k=3) hashes [0x508b2abb65a03907 0x6fbbcaeaaa4c1398]
+//	  probe 0: bit 183 (word 2 mask 0x0080000000000000) set
+//	  probe 1: bit 239 (word 3 mask 0x0000800000000000) set
+//	  probe 2: bit 679 (word 10 mask 0x0000008000000000) set
+//	  => might be present
+func (e Explanation) String() string {
+	var b strings.Builder
+	if e.M == 0 {
+		fmt.Fprintf(&b, "key %q (uninitialized filter)\n  => absent", e.Key)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "key %q (%s, m=%d, k=%d) hashes [", e.Key, e.Scheme, e.M, e.K)
+	for i, h := range e.Hashes {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%#016x", h)
+	}
+	b.WriteString("]\n")
+	for i, p := range e.Probes {
+		state := "clear"
+		if p.Set {
+			state = "set"
+		}
+		fmt.Fprintf(&b, "  probe %d: bit %d (word %d mask %#016x) %s\n", i, p.Position, p.Word, p.Mask, state)
+	}
+	if e.Present {
+		b.WriteString("  => might be present")
+	} else {
+		b.WriteString("  => absent")
+	}
+	return b.String()
+}
+
+// Explain traces MightContain(data) under the read lock. See
+// BloomFilter.Explain.
+func (s *SafeBloom) Explain(data []byte) Explanation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.Explain(data)
+}
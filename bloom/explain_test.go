@@ -0,0 +1,64 @@
+package bloom
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	for _, bf := range []*BloomFilter{
+		New(1000, 3),
+		NewGuavaWithEstimates(100, 0.01),
+		NewBitsAndBloomsWithEstimates(100, 0.01),
+	} {
+		for _, key := range randomKeys("explain", 300, 31) {
+			bf.Add(key)
+		}
+		before := bf.Clone()
+		for _, key := range randomKeys("probe", 2000, 32) {
+			e := bf.Explain(key)
+			if e.Present != bf.MightContain(key) {
+				t.Fatalf("%s: Explain says %v, MightContain %v", e.Scheme, e.Present, bf.MightContain(key))
+			}
+			all := true
+			for i, p := range e.Probes {
+				if p.Position != bf.location(bf.hashes(key), uint64(i)) || p.Word != p.Position/64 || p.Mask != 1<<(p.Position%64) {
+					t.Fatalf("%s: probe %d is %+v", e.Scheme, i, p)
+				}
+				if p.Set != (bf.bits[p.Word]&p.Mask != 0) {
+					t.Fatalf("%s: probe %d set=%v disagrees with the bitset", e.Scheme, i, p.Set)
+				}
+				all = all && p.Set
+			}
+			if len(e.Probes) != int(bf.k) || all != e.Present {
+				t.Fatalf("%s: %d probes, all set %v, present %v", e.Scheme, len(e.Probes), all, e.Present)
+			}
+		}
+		if !bf.Equal(before) || bf.inserts != before.inserts {
+			t.Fatal("Explain modified the filter")
+		}
+	}
+}
+
+func TestExplain_String(t *testing.T) {
+	bf := New(1000, 3)
+	bf.Add([]byte("alice"))
+	e := bf.Explain([]byte("alice"))
+	if len(e.Hashes) != 2 || e.Hashes[0] == 0 {
+		t.Fatalf("hashes %v", e.Hashes)
+	}
+	got := e.String()
+	lines := strings.Split(got, "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], `key "alice" (fnv, m=1000, k=3) hashes [0x`) ||
+		!strings.HasSuffix(lines[1], ") set") || lines[4] != "  => might be present" {
+		t.Fatalf("unexpected trace:\n%s", got)
+	}
+
+	s := NewSafe(1000, 3)
+	if e := s.Explain([]byte("alice")); e.Present || !strings.HasSuffix(e.String(), "=> absent") {
+		t.Fatalf("SafeBloom trace for a missing key:\n%s", e)
+	}
+	if got := new(BloomFilter).Explain([]byte("x")).String(); got != "key \"x\" (uninitialized filter)\n  => absent" {
+		t.Fatalf("zero filter trace %q", got)
+	}
+}
@@ -0,0 +1,139 @@
+package bloom
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// chiSquare returns Pearson's statistic for counts against a uniform
+// expectation.
+func chiSquare(counts []uint64) float64 {
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	expected := float64(total) / float64(len(counts))
+	var x float64
+	for _, c := range counts {
+		d := float64(c) - expected
+		x += d * d / expected
+	}
+	return x
+}
+
+func TestFastRange_Uniform(t *testing.T) {
+	// m not a power of two, nor close to one.
+	const m, k = 12_345, 7
+	bf, err := NewWithOptions(0, 0, WithExplicitSize(m, k), WithFastRange())
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make([]uint64, m)
+	lowBits := make([]uint64, 64) // position within its word
+	for _, key := range randomKeys("uniform", 300_000, 61) {
+		h := bf.hashes(key)
+		for i := uint64(0); i < k; i++ {
+			pos := bf.location(h, i)
+			if pos >= m {
+				t.Fatalf("position %d out of range", pos)
+			}
+			counts[pos]++
+			lowBits[pos%64]++
+		}
+	}
+	// Chi-square with n-1 degrees of freedom has mean n-1 and standard
+	// deviation sqrt(2(n-1)); allow five.
+	for name, c := range map[string][]uint64{"positions": counts, "bit within word": lowBits} {
+		df := float64(len(c) - 1)
+		if x := chiSquare(c); math.Abs(x-df) > 5*math.Sqrt(2*df) {
+			t.Errorf("%s: chi-square %.0f for %.0f degrees of freedom", name, x, df)
+		}
+	}
+
+	// A realistic filter fills its ranges as evenly as the default.
+	keys := randomKeys("fill", 20_000, 62)
+	fast, _ := NewWithOptions(20_000, 0.01, WithFastRange())
+	fast.AddAll(keys)
+	if x := fast.ChiSquare(100); x > 99+5*math.Sqrt(2*99) {
+		t.Errorf("filter ChiSquare(100) = %.1f", x)
+	}
+}
+
+func TestFastRange_Compatibility(t *testing.T) {
+	bf, _ := NewWithOptions(1000, 0.01, WithFastRange(), WithSeed(5))
+	keys := randomKeys("fastrange", 1000, 63)
+	bf.AddAll(keys)
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got BloomFilter
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got.scheme != schemeFastRange || got.Params().Scheme != "fnv-fastrange" || !got.Equal(bf) {
+		t.Fatal("fastrange scheme lost in round trip")
+	}
+	for _, k := range keys {
+		if !got.MightContain(k) || !got.ContainsHash(got.Hash(k)) {
+			t.Fatalf("false negative for %q", k)
+		}
+	}
+
+	// Filters encoded without the flag keep the modulo reduction.
+	plain := NewWithEstimates(1000, 0.01)
+	plain.AddAll(keys)
+	data, _ = plain.MarshalBinary()
+	var legacy BloomFilter
+	if err := legacy.UnmarshalBinary(data); err != nil || legacy.scheme != schemeFNV {
+		t.Fatalf("default filter decoded as %v: %v", legacy.scheme, err)
+	}
+	h1, h2 := fnvHashes(keys[0])
+	for i := uint64(0); i < legacy.k; i++ {
+		if pos, want := legacy.location(baseHashes{h1, h2}, i), (h1+i*h2)%legacy.m; pos != want {
+			t.Fatalf("probe %d of a default filter at %d, want %d", i, pos, want)
+		}
+	}
+
+	if err := plain.Merge(bf); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("merging default with fastrange: %v", err)
+	}
+	if _, err := plain.MarshalText(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bf.MarshalText(); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("text encoding of a fastrange filter: %v", err)
+	}
+	m, err := NewMatching(bf.Params())
+	if err != nil || m.checkCompatible(bf) != nil {
+		t.Fatalf("NewMatching: %v", err)
+	}
+	if _, err := NewWithOptions(1000, 0.01, WithFastRange(), WithSplit64Hashing()); !errors.Is(err, ErrConflictingOptions) {
+		t.Fatalf("conflicting schemes: %v", err)
+	}
+}
+
+func BenchmarkFastRange(b *testing.B) {
+	keys := benchmarkKeys(1 << 16)
+	for _, c := range []struct {
+		name string
+		opts []Option
+	}{{"modulo", nil}, {"fastrange", []Option{WithFastRange()}}} {
+		bf, err := NewWithOptions(1_000_000, 0.01, c.opts...)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run("Add/"+c.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.Add(keys[i&(len(keys)-1)])
+			}
+		})
+		b.Run("MightContain/"+c.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.MightContain(keys[i&(len(keys)-1)])
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package bloom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// File format:
+//
+//	magic    [4]byte "BLMF"
+//	version  uint8   file format version
+//	payload          the binary encoding (see MarshalBinary)
+//	crc      uint32  little-endian CRC-32 (IEEE) of payload
+const fileVersion = 1
+
+var fileMagic = [4]byte{'B', 'L', 'M', 'F'}
+
+// SaveFile writes the filter to path atomically: the data goes to a
+// temporary file in the same directory, which is synced and renamed over
+// path only once complete, so a crash never leaves a half-written filter
+// at path.
+func (bf *BloomFilter) SaveFile(path string) (err error) {
+	if !bf.initialized() {
+		return ErrUninitialized
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(tmp)
+	w.Write(fileMagic[:])
+	w.WriteByte(fileVersion)
+	sum := crc32.NewIEEE()
+	if _, err = bf.WriteTo(io.MultiWriter(w, sum)); err != nil {
+		return err
+	}
+	if err = binary.Write(w, binary.LittleEndian, sum.Sum32()); err != nil {
+		return err
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(0o644); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile reads a filter written by SaveFile. A file from an unknown
+// format version fails with ErrUnsupportedVersion; a bad magic number,
+// truncation or checksum mismatch fails with ErrCorrupt.
+func LoadFile(path string) (*BloomFilter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(fileMagic)+1+4 {
+		return nil, fmt.Errorf("%w: %s: file too short", ErrCorrupt, path)
+	}
+	if !bytes.Equal(data[:len(fileMagic)], fileMagic[:]) {
+		return nil, fmt.Errorf("%w: %s: bad magic number", ErrCorrupt, path)
+	}
+	if v := data[len(fileMagic)]; v != fileVersion {
+		return nil, fmt.Errorf("%w: %s: file version %d", ErrUnsupportedVersion, path, v)
+	}
+
+	payload := data[len(fileMagic)+1 : len(data)-4]
+	want := binary.LittleEndian.Uint32(data[len(data)-4:])
+	if got := crc32.ChecksumIEEE(payload); got != want {
+		return nil, fmt.Errorf("%w: %s: checksum mismatch (got %08x, want %08x)", ErrCorrupt, path, got, want)
+	}
+
+	bf, err := unmarshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return bf, nil
+}
@@ -0,0 +1,76 @@
+package bloom
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestFile_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.bf")
+	bf := NewWithEstimates(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		bf.Add([]byte(strconv.Itoa(i)))
+	}
+
+	if err := bf.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		if !got.MightContain([]byte(strconv.Itoa(i))) {
+			t.Fatalf("false negative for %d after LoadFile", i)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only the saved file, found %d entries", len(entries))
+	}
+}
+
+func TestFile_RejectsCorruption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.bf")
+	bf := New(4096, 3)
+	bf.Add([]byte("x"))
+	if err := bf.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corrupt := append([]byte(nil), data...)
+	corrupt[len(corrupt)/2] ^= 0x01
+	if err := os.WriteFile(path, corrupt, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt for a flipped byte, got %v", err)
+	}
+
+	if err := os.WriteFile(path, data[:len(data)-1], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt for a truncated file, got %v", err)
+	}
+
+	future := append([]byte(nil), data...)
+	future[4] = fileVersion + 1
+	if err := os.WriteFile(path, future, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); !errors.Is(err, ErrUnsupportedVersion) || errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected only ErrUnsupportedVersion, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package bloom
+
+// Filter is the common interface of the package's general-purpose filters,
+// for code that should not depend on which one it is given. A function
+// taking a Filter works unchanged with a BloomFilter, a SafeBloom, a
+// Counting or Scalable filter, and the rest listed below.
+//
+// Filters whose Add can fail or returns a result (Cuckoo, WALFilter,
+// Layered) do not implement it, nor does Tiered, which has no Reset. The
+// build-once filters (StaticFilter, Ribbon, GCS, Cascade, Frozen) only
+// answer queries and satisfy ColdTier instead. RocksDBFilter, queried by
+// precomputed hash, implements neither.
+type Filter interface {
+	Add(data []byte)
+	MightContain(data []byte) bool
+	Reset()
+}
+
+// StatsProvider is implemented by every filter in the package, for
+// uniform logging and memory accounting. Richer, type-specific figures
+// are available from each type's Stats method where it has one.
+type StatsProvider interface {
+	Info() string
+	SizeInBytes() uint64
+}
+
+var (
+	_ Filter = (*BloomFilter)(nil)
+	_ Filter = (*SafeBloom)(nil)
+	_ Filter = (*Blocked)(nil)
+	_ Filter = (*Compact)(nil)
+	_ Filter = (*Counting)(nil)
+	_ Filter = (*Deletable)(nil)
+	_ Filter = (*Partitioned)(nil)
+	_ Filter = (*Rotating)(nil)
+	_ Filter = (*Scalable)(nil)
+	_ Filter = (*SplitBlock)(nil)
+	_ Filter = (*Stable)(nil)
+
+	_ ColdTier = (*BloomFilter)(nil)
+	_ ColdTier = (*Cascade)(nil)
+	_ ColdTier = (*Cuckoo)(nil)
+	_ ColdTier = (*Frozen)(nil)
+	_ ColdTier = (*GCS)(nil)
+	_ ColdTier = (*ReaderAtFilter)(nil)
+	_ ColdTier = (*Ribbon)(nil)
+	_ ColdTier = (*StaticFilter)(nil)
+
+	_ StatsProvider = (*BloomFilter)(nil)
+	_ StatsProvider = (*SafeBloom)(nil)
+	_ StatsProvider = (*Blocked)(nil)
+	_ StatsProvider = (*Cascade)(nil)
+	_ StatsProvider = (*Compact)(nil)
+	_ StatsProvider = (*Counting)(nil)
+	_ StatsProvider = (*Cuckoo)(nil)
+	_ StatsProvider = (*Deletable)(nil)
+	_ StatsProvider = (*Frozen)(nil)
+	_ StatsProvider = (*GCS)(nil)
+	_ StatsProvider = (*Inverse)(nil)
+	_ StatsProvider = (*Layered)(nil)
+	_ StatsProvider = (*Partitioned)(nil)
+	_ StatsProvider = (*ReaderAtFilter)(nil)
+	_ StatsProvider = (*Ribbon)(nil)
+	_ StatsProvider = (*Rotating)(nil)
+	_ StatsProvider = (*Scalable)(nil)
+	_ StatsProvider = (*SplitBlock)(nil)
+	_ StatsProvider = (*Stable)(nil)
+	_ StatsProvider = (*StaticFilter)(nil)
+	_ StatsProvider = (*Tiered)(nil)
+	_ StatsProvider = (*WALFilter)(nil)
+)
@@ -0,0 +1,60 @@
+package bloom
+
+import "testing"
+
+func TestFilter_Implementations(t *testing.T) {
+	filters := map[string]Filter{
+		"BloomFilter": NewWithEstimates(500, 0.01),
+		"SafeBloom":   NewSafeWithEstimates(500, 0.01),
+		"Blocked":     NewBlocked(500, 0.01),
+		"Compact":     NewCompactWithEstimates(500, 0.01),
+		"Counting":    NewCountingWithEstimates(500, 0.01),
+		"SplitBlock":  NewSplitBlock(SplitBlockBytesFor(500, 0.01)),
+	}
+	keys := randomKeys("iface", 500, 41)
+	for name, f := range filters {
+		for _, key := range keys {
+			f.Add(key)
+		}
+		for _, key := range keys {
+			if !f.MightContain(key) {
+				t.Fatalf("%s: added key missing", name)
+			}
+		}
+		f.Reset()
+		if f.MightContain(keys[0]) {
+			t.Fatalf("%s: key present after Reset", name)
+		}
+		if sp, ok := f.(StatsProvider); !ok || sp.Info() == "" || sp.SizeInBytes() == 0 {
+			t.Fatalf("%s: not a usable StatsProvider", name)
+		}
+	}
+}
+
+func TestColdTier_MightContainMatchesContains(t *testing.T) {
+	keys := randomKeys("cold", 300, 42)
+	cuckoo := NewCuckooWithEstimates(1000, 0.01)
+	for _, key := range keys[:150] {
+		cuckoo.Add(key)
+	}
+	ribbon, err := BuildRibbon(keys[:150], 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcs := BuildGCS(keys[:150], 7)
+	tiers := map[string]struct {
+		tier     ColdTier
+		contains func([]byte) bool
+	}{
+		"Cuckoo": {cuckoo, cuckoo.Contains},
+		"Ribbon": {ribbon, ribbon.Contains},
+		"GCS":    {gcs, gcs.Contains},
+	}
+	for name, c := range tiers {
+		for _, key := range keys {
+			if c.tier.MightContain(key) != c.contains(key) {
+				t.Fatalf("%s: MightContain and Contains disagree", name)
+			}
+		}
+	}
+}
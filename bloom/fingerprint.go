@@ -0,0 +1,44 @@
+package bloom
+
+import "encoding/binary"
+
+// Fingerprint returns a stable 64-bit digest of the parameters that decide
+// where a key's bits land: m, k, the probe scheme and the hash seed. Two
+// filters can only be merged or compared when their fingerprints match.
+// The value depends on nothing process-specific, so it is safe to persist
+// or exchange; it is carried in the binary encoding from version 3 on.
+// A zero-value or nil filter has fingerprint 0.
+func (bf *BloomFilter) Fingerprint() uint64 {
+	if !bf.initialized() {
+		return 0
+	}
+	return bf.header().fingerprint()
+}
+
+// SameFamily reports whether a and b were built with the same parameters,
+// so that Merge, Intersect and the set estimators accept them. It is cheap
+// enough to check before shipping a filter to another service.
+func SameFamily(a, b *BloomFilter) bool {
+	return a.initialized() && b.initialized() && a.Fingerprint() == b.Fingerprint()
+}
+
+// fingerprint is FNV-1a over m, k, the scheme's name and, for a
+// non-default Hasher or a seed, its id and the seed. The name rather than
+// its numeric value is hashed so renumbering schemes never changes a
+// fingerprint, and the defaults add nothing so fingerprints from before
+// hashers and seeds stay valid.
+func (h header) fingerprint() uint64 {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, "bloom/v1"...)
+	buf = binary.LittleEndian.AppendUint64(buf, h.m)
+	buf = binary.LittleEndian.AppendUint64(buf, h.k)
+	buf = append(buf, h.scheme.String()...)
+	if h.hasher != 0 {
+		buf = binary.LittleEndian.AppendUint64(buf, h.hasher)
+	}
+	if h.seed != 0 {
+		buf = append(buf, "seed"...)
+		buf = binary.LittleEndian.AppendUint64(buf, h.seed)
+	}
+	return fnv64a(buf)
+}
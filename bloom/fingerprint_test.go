@@ -0,0 +1,76 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestFingerprint_Stable(t *testing.T) {
+	// Pinned so a change to the fingerprint input is caught: persisted and
+	// exchanged filters rely on it staying the same across releases.
+	bf := New(1024, 7)
+	if got, want := bf.Fingerprint(), New(1024, 7).Fingerprint(); got != want {
+		t.Fatalf("fingerprint differs between equal filters: %#x != %#x", got, want)
+	}
+	if got := bf.Fingerprint(); got != fingerprintFNV1024k7 {
+		t.Fatalf("Fingerprint() = %#x, want %#x", got, uint64(fingerprintFNV1024k7))
+	}
+	if (*BloomFilter)(nil).Fingerprint() != 0 || (&BloomFilter{}).Fingerprint() != 0 {
+		t.Fatal("uninitialized filters should have fingerprint 0")
+	}
+
+	distinct := map[uint64]string{}
+	for name, f := range map[string]*BloomFilter{
+		"fnv":    New(1024, 7),
+		"m":      New(1088, 7),
+		"k":      New(1024, 6),
+		"guava":  {m: 1024, k: 7, storage: contiguous(make([]uint64, 16)), scheme: schemeGuava64},
+		"guava2": {m: 1024, k: 7, storage: contiguous(make([]uint64, 16)), scheme: schemeGuava32},
+	} {
+		if prev, ok := distinct[f.Fingerprint()]; ok {
+			t.Fatalf("%s and %s share a fingerprint", name, prev)
+		}
+		distinct[f.Fingerprint()] = name
+	}
+}
+
+const fingerprintFNV1024k7 = 0x6186d4d8e5fa0d0b
+
+func TestSameFamily(t *testing.T) {
+	a, b := New(1024, 7), New(1024, 7)
+	a.Add([]byte("a"))
+	if !SameFamily(a, b) {
+		t.Fatal("filters with equal parameters should be the same family")
+	}
+	if SameFamily(a, New(1024, 6)) || SameFamily(a, nil) || SameFamily(nil, nil) {
+		t.Fatal("SameFamily accepted mismatched or uninitialized filters")
+	}
+}
+
+func TestFingerprint_Encoding(t *testing.T) {
+	bf := NewWithEstimates(100, 0.01)
+	bf.Add([]byte("x"))
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.LittleEndian.Uint64(data[26:]); got != bf.Fingerprint() {
+		t.Fatalf("header fingerprint %#x, want %#x", got, bf.Fingerprint())
+	}
+	decoded, _, err := readFilter(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Fingerprint() != bf.Fingerprint() {
+		t.Fatal("decoded filter has a different fingerprint")
+	}
+
+	// A header whose fingerprint disagrees with its parameters was written
+	// by a family this version cannot reproduce.
+	data[26] ^= 1
+	if _, err := unmarshal(data); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt for a fingerprint mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package bloom
+
+import (
+	"hash/fnv"
+	"strconv"
+	"testing"
+)
+
+// twoPassHash128 is hash128 as it used to be written, one pass over data
+// per hash. Filters built with either must be identical.
+func twoPassHash128(data []byte) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write(data)
+	h2 := uint64(fnv64Offset ^ 0x9e3779b97f4a7c15)
+	for _, b := range data {
+		h2 ^= uint64(b)
+		h2 *= fnv64Prime
+	}
+	return h.Sum64(), h2
+}
+
+func TestHash128_Stable(t *testing.T) {
+	for _, c := range []struct {
+		key    string
+		h1, h2 uint64
+	}{
+		{"", 0xcbf29ce484222325, 0x55c5e55dfb685f30},
+		{"a", 0xaf63dc4c8601ec8c, 0x27a40fb23259f6a3},
+		{"hello", 0xa430d84680aabd0b, 0xd80e69ef89515aa8},
+		{"the quick brown fox jumps over the lazy dog", 0x7404cea13ff89bb0, 0xacc964ce9528714b},
+	} {
+		if h1, h2 := hash128([]byte(c.key)); h1 != c.h1 || h2 != c.h2 {
+			t.Errorf("hash128(%q) = %#x, %#x, want %#x, %#x", c.key, h1, h2, c.h1, c.h2)
+		}
+	}
+
+	for _, n := range []int{0, 1, 7, 8, 9, 63, 1000, 65536} {
+		data := make([]byte, n)
+		for i := range data {
+			data[i] = byte(i*131 + n)
+		}
+		h1, h2 := hash128(data)
+		if w1, w2 := twoPassHash128(data); h1 != w1 || h2 != w2 {
+			t.Fatalf("%d-byte key: hash128 = %#x, %#x, two passes give %#x, %#x", n, h1, h2, w1, w2)
+		}
+		st := newFNVStream(0)
+		st.write(data[:n/2])
+		st.write(data[n/2:])
+		if s1, s2 := st.sum(); s1 != h1 || (h2 != 0 && s2 != h2) {
+			t.Fatalf("%d-byte key: stream and hash128 disagree", n)
+		}
+	}
+}
+
+func BenchmarkHash128(b *testing.B) {
+	for _, n := range []int{16, 1 << 10, 64 << 10} {
+		data := make([]byte, n)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		for _, c := range []struct {
+			name string
+			hash func([]byte) (uint64, uint64)
+		}{{"twoPass", twoPassHash128}, {"onePass", hash128}} {
+			b.Run(c.name+"/"+strconv.Itoa(n), func(b *testing.B) {
+				b.SetBytes(int64(n))
+				for i := 0; i < b.N; i++ {
+					c.hash(data)
+				}
+			})
+		}
+	}
+}
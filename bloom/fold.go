@@ -0,0 +1,90 @@
+package bloom
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// ErrInvalidFoldFactor is returned by Fold when the factor is not a power
+// of two dividing m.
+var ErrInvalidFoldFactor = errors.New("bloom: invalid fold factor")
+
+// Fold returns a copy of bf shrunk to m/factor bits by ORing every bit p
+// onto bit p mod m/factor, or onto bit p/factor under WithFastRange.
+// factor must be a power of two dividing m, so filters meant to be folded
+// are best given a power-of-two m. bf is left unchanged.
+//
+// Every other probe scheme reduces positions modulo m, and reducing mod m
+// and then mod m/factor is the same as reducing mod m/factor directly.
+// WithFastRange maps h to floor(h*m / 2^64) instead, and dividing that by
+// factor gives floor(h*(m/factor) / 2^64), so it folds contiguous runs of
+// factor bits. Either way the folded filter is an ordinary filter with the
+// smaller m that answers MightContain with no false negatives, and is
+// identical to one built at that size from the same keys.
+//
+// Folding trades bandwidth for accuracy: factor bits collapse into one,
+// so the fill ratio rises towards 1-(1-fill)^factor and the false positive
+// rate, roughly fill^k, with it. A 2^20-bit, k=7 filter holding 10k keys
+// goes from a rate near 5e-9 to ~0.2% folded 8×, and ~5% folded 16×.
+// The default FNV scheme fares worse than that at power-of-two sizes,
+// since FNV-1a's low bits are weak; see TestFold_FalsePositiveInflation.
+func (bf *BloomFilter) Fold(factor uint64) (*BloomFilter, error) {
+	if !bf.initialized() {
+		return nil, ErrUninitialized
+	}
+	if factor == 0 || bits.OnesCount64(factor) != 1 || bf.m%factor != 0 {
+		return nil, fmt.Errorf("%w: %d does not divide m=%d as a power of two", ErrInvalidFoldFactor, factor, bf.m)
+	}
+	folded := &BloomFilter{
+		m:         bf.m / factor,
+		k:         bf.k,
+		storage:   newStorage(int(wordsFor(bf.m/factor)), 0),
+		scheme:    bf.scheme,
+		hasher:    bf.hasher,
+		seed:      bf.seed,
+		inserts:   bf.inserts,
+		capacity:  bf.capacity,
+		threshold: bf.threshold,
+	}
+	folded.orFolded(bf)
+	return folded, nil
+}
+
+// orFolded ORs src into bf, reducing each of src's positions as bf's
+// probe scheme does. src.m must be a multiple of bf.m.
+func (bf *BloomFilter) orFolded(src *BloomFilter) {
+	if bf.scheme == schemeFastRange {
+		factor := src.m / bf.m
+		src.ForEachSetBit(func(pos uint64) bool {
+			bf.setBit(pos / factor)
+			return true
+		})
+	} else if bf.m%64 == 0 {
+		// Whole words line up, so fold a word at a time.
+		n := bf.wordCount()
+		src.eachSpan(func(start int, words []uint64) {
+			for i, w := range words {
+				bf.orWord((start+i)%n, w)
+			}
+		})
+	} else {
+		src.ForEachSetBit(func(pos uint64) bool {
+			bf.setBit(pos % bf.m)
+			return true
+		})
+	}
+}
+
+// foldsInto reports whether src can be folded into bf: the same k and
+// probe scheme, with src.m a power-of-two multiple of bf.m.
+func (bf *BloomFilter) foldsInto(src *BloomFilter) bool {
+	if !bf.initialized() || !src.initialized() || src.m <= bf.m || src.m%bf.m != 0 {
+		return false
+	}
+	if bits.OnesCount64(src.m/bf.m) != 1 {
+		return false
+	}
+	probe := &BloomFilter{m: bf.m, k: src.k, scheme: src.scheme, hasher: src.hasher, seed: src.seed}
+	return probe.Fingerprint() == bf.Fingerprint()
+}
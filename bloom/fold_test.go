@@ -0,0 +1,187 @@
+package bloom
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+)
+
+func TestFold_NoFalseNegatives(t *testing.T) {
+	bf := New(1<<20, 7)
+	for _, key := range cardinalityKeys[:10_000] {
+		bf.Add(key)
+	}
+	for _, factor := range []uint64{1, 2, 8, 64, 512} {
+		folded, err := bf.Fold(factor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if folded.m != bf.m/factor {
+			t.Fatalf("factor %d: m = %d, want %d", factor, folded.m, bf.m/factor)
+		}
+		for i, key := range cardinalityKeys[:10_000] {
+			if !folded.MightContain(key) {
+				t.Fatalf("factor %d: false negative for key %d", factor, i)
+			}
+		}
+	}
+	if bf.m != 1<<20 || bf.setBits != popcount(bf.bits) {
+		t.Fatal("Fold modified the original filter")
+	}
+}
+
+func TestFold_MatchesFilterBuiltSmall(t *testing.T) {
+	for _, m := range []uint64{1 << 12, 480} { // whole-word and bit-by-bit folds
+		for s := schemeFNV; s.valid(); s++ {
+			big := &BloomFilter{m: m, k: 5, storage: contiguous(make([]uint64, wordsFor(m))), scheme: s}
+			small := &BloomFilter{m: m / 16, k: 5, storage: contiguous(make([]uint64, wordsFor(m/16))), scheme: s}
+			for i := 0; i < 40; i++ {
+				key := []byte("key-" + strconv.Itoa(i))
+				big.Add(key)
+				small.Add(key)
+			}
+			folded, err := big.Fold(16)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !folded.Equal(small) || folded.setBits != small.setBits {
+				t.Fatalf("m=%d %s: folded filter differs from one built at m/16", m, s)
+			}
+		}
+	}
+}
+
+// TestFold_EveryScheme checks Fold and a folding Merge for false negatives
+// under every probe scheme, so a scheme that reduces positions some other
+// way than mod m cannot slip past them.
+func TestFold_EveryScheme(t *testing.T) {
+	newFilter := func(m uint64, s scheme) *BloomFilter {
+		return &BloomFilter{m: m, k: 5, storage: contiguous(make([]uint64, wordsFor(m))), scheme: s}
+	}
+	for _, m := range []uint64{1 << 16, 480 * 8} { // whole-word and bit-by-bit folds
+		for s := schemeFNV; s.valid(); s++ {
+			full, other := newFilter(m, s), newFilter(m, s)
+			for i := 0; i < 500; i++ {
+				full.Add([]byte("a-" + strconv.Itoa(i)))
+				other.Add([]byte("b-" + strconv.Itoa(i)))
+			}
+			folded, err := full.Fold(8)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 500; i++ {
+				if !folded.MightContain([]byte("a-" + strconv.Itoa(i))) {
+					t.Fatalf("m=%d %s: false negative for a-%d after Fold", m, s, i)
+				}
+			}
+			if err := folded.Merge(other); err != nil {
+				t.Fatalf("m=%d %s: %v", m, s, err)
+			}
+			for i := 0; i < 500; i++ {
+				for _, key := range []string{"a-" + strconv.Itoa(i), "b-" + strconv.Itoa(i)} {
+					if !folded.MightContain([]byte(key)) {
+						t.Fatalf("m=%d %s: false negative for %s after Merge", m, s, key)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestFold_FalsePositiveInflation documents what folding costs. A 2^20-bit,
+// k=7 filter holding 10k keys is about 6.5% full, with a false positive
+// rate near 5e-9. Folded 8× it is about 41% full and the rate is ~0.2%;
+// folded 16× it is about 66% full and the rate is ~5%. The measured rate
+// tracks fill^k throughout.
+//
+// The murmur-based Guava scheme is used because the default FNV scheme
+// does much worse at power-of-two sizes: FNV-1a's low bits depend only on
+// the low bits of the input, so positions mod 2^j cluster. Folded 16× as
+// above, the FNV filter measures ~13% against a fill^k of 3%.
+func TestFold_FalsePositiveInflation(t *testing.T) {
+	bf := &BloomFilter{m: 1 << 20, k: 7, storage: contiguous(make([]uint64, 1<<14)), scheme: schemeGuava64}
+	for _, key := range cardinalityKeys[:10_000] {
+		bf.Add(key)
+	}
+	for _, factor := range []uint64{4, 8, 16} {
+		folded, err := bf.Fold(factor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fill := float64(folded.setBits) / float64(folded.m)
+		want := math.Pow(fill, 7)
+
+		const probes = 50_000
+		r := rand.New(rand.NewPCG(3, 4))
+		falsePositives := 0
+		for i := 0; i < probes; i++ {
+			if folded.MightContain([]byte("absent-" + strconv.FormatUint(r.Uint64(), 16))) {
+				falsePositives++
+			}
+		}
+		got := float64(falsePositives) / probes
+		t.Logf("factor %d: fill %.3f, FP rate %.4f (fill^k %.4f)", factor, fill, got, want)
+		if math.Abs(got-want) > 0.25*want+0.001 {
+			t.Errorf("factor %d: fill %.3f, FP rate %.4f, want about %.4f", factor, fill, got, want)
+		}
+	}
+}
+
+func TestFold_Errors(t *testing.T) {
+	bf := New(1024, 3)
+	for _, factor := range []uint64{0, 3, 2048} {
+		if _, err := bf.Fold(factor); !errors.Is(err, ErrInvalidFoldFactor) {
+			t.Fatalf("factor %d: expected ErrInvalidFoldFactor, got %v", factor, err)
+		}
+	}
+	if _, err := New(96, 3).Fold(64); !errors.Is(err, ErrInvalidFoldFactor) {
+		t.Fatalf("expected ErrInvalidFoldFactor when factor does not divide m, got %v", err)
+	}
+	if _, err := (&BloomFilter{}).Fold(2); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("expected ErrUninitialized, got %v", err)
+	}
+}
+
+func TestMerge_Folded(t *testing.T) {
+	full, other := New(1<<14, 5), New(1<<14, 5)
+	for i := 0; i < 500; i++ {
+		full.Add([]byte("a-" + strconv.Itoa(i)))
+		other.Add([]byte("b-" + strconv.Itoa(i)))
+	}
+	folded, err := full.Fold(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	union, err := Union(other, folded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := folded.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+	if folded.m != 1<<11 || !folded.Equal(union) {
+		t.Fatal("Merge and Union of folded and full filters disagree")
+	}
+	for i := 0; i < 500; i++ {
+		for _, key := range []string{"a-" + strconv.Itoa(i), "b-" + strconv.Itoa(i)} {
+			if !folded.MightContain([]byte(key)) {
+				t.Fatalf("false negative for %s after merging", key)
+			}
+		}
+	}
+
+	// The full filter cannot absorb a folded one, and a 3× size difference
+	// or a different k is still incompatible.
+	if err := other.Merge(folded); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible merging into the larger filter, got %v", err)
+	}
+	if err := New(1<<14/3+1, 5).Merge(other); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible for a non-power-of-two factor, got %v", err)
+	}
+	if err := New(1<<11, 4).Merge(other); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible for a different k, got %v", err)
+	}
+}
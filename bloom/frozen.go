@@ -0,0 +1,71 @@
+package bloom
+
+import (
+	"io"
+	"unsafe"
+)
+
+// Frozen is an immutable view of a Bloom filter for read-mostly phases.
+// It has no mutating methods at all, so it needs no locking: any number
+// of goroutines may call MightContain concurrently at the cost of a plain
+// BloomFilter read, and an accidental Add is a compile error rather than
+// a data race.
+//
+// A Frozen owns a private copy of the words it was frozen from; later
+// changes to the source filter do not show through. Use Thaw to get a
+// mutable filter back.
+type Frozen struct {
+	bf *BloomFilter
+}
+
+// Freeze returns an immutable copy of bf. Freezing a nil or zero-value
+// filter gives a Frozen that contains nothing.
+func (bf *BloomFilter) Freeze() *Frozen {
+	return &Frozen{bf: bf.Clone()}
+}
+
+// Freeze returns an immutable copy of the current filter, taken as
+// Snapshot under the read lock.
+func (s *SafeBloom) Freeze() *Frozen {
+	return &Frozen{bf: s.Snapshot()}
+}
+
+// MightContain checks if data might be in the filter. It is safe for
+// concurrent use without locking. See BloomFilter.MightContain.
+func (f *Frozen) MightContain(data []byte) bool {
+	return f.bf.MightContain(data)
+}
+
+// Stats returns the frozen filter's statistics.
+func (f *Frozen) Stats() Stats {
+	st := f.bf.Stats()
+	st.SizeBytes = f.SizeInBytes()
+	return st
+}
+
+// Thaw returns a mutable deep copy of the frozen filter, or nil if it was
+// frozen from a nil filter. The Frozen itself is unaffected.
+func (f *Frozen) Thaw() *BloomFilter {
+	return f.bf.Clone()
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, producing the same
+// encoding as BloomFilter.MarshalBinary.
+func (f *Frozen) MarshalBinary() ([]byte, error) {
+	return f.bf.MarshalBinary()
+}
+
+// WriteTo implements io.WriterTo. See BloomFilter.WriteTo.
+func (f *Frozen) WriteTo(w io.Writer) (int64, error) {
+	return f.bf.WriteTo(w)
+}
+
+// Info returns a small description of the filter's configuration.
+func (f *Frozen) Info() string {
+	return f.bf.Info()
+}
+
+// SizeInBytes reports the memory held by the view and its filter.
+func (f *Frozen) SizeInBytes() uint64 {
+	return uint64(unsafe.Sizeof(*f)) + f.bf.SizeInBytes()
+}
@@ -0,0 +1,105 @@
+package bloom
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestFrozen_IsACopy(t *testing.T) {
+	bf := NewWithEstimates(1000, 0.01)
+	bf.Add([]byte("before"))
+	f := bf.Freeze()
+	bf.Add([]byte("after"))
+
+	if !f.MightContain([]byte("before")) || f.MightContain([]byte("after")) {
+		t.Fatal("frozen view must reflect the filter at Freeze time only")
+	}
+	if st := f.Stats(); st.Inserts != 1 || st.SizeBytes != f.SizeInBytes() {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+
+	thawed := f.Thaw()
+	thawed.Add([]byte("thawed"))
+	if f.MightContain([]byte("thawed")) {
+		t.Fatal("mutating a thawed copy changed the frozen view")
+	}
+
+	var want, got bytes.Buffer
+	if _, err := f.WriteTo(&got); err != nil {
+		t.Fatal(err)
+	}
+	bf.Reset()
+	bf.Add([]byte("before"))
+	if _, err := bf.WriteTo(&want); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Fatal("frozen encoding differs from the filter's")
+	}
+
+	var empty *BloomFilter
+	if empty.Freeze().MightContain([]byte("x")) || empty.Freeze().Thaw() != nil {
+		t.Fatal("freezing nil must give an empty view")
+	}
+}
+
+func TestSafeBloom_FreezeConcurrentReads(t *testing.T) {
+	s := NewSafeWithEstimates(1000, 0.01)
+	s.Add([]byte("warm"))
+	f := s.Freeze()
+
+	var wg sync.WaitGroup
+	for r := 0; r < 8; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if !f.MightContain([]byte("warm")) {
+					t.Error(`"warm" missing from the frozen view`)
+					return
+				}
+			}
+		}()
+	}
+	// Writes to the source run alongside the readers without a race.
+	for i := 0; i < 1000; i++ {
+		s.Add([]byte("late"))
+	}
+	wg.Wait()
+}
+
+// The three benchmarks below compare parallel reads of the same filter.
+// Frozen should match the raw BloomFilter; SafeBloom pays for its RWMutex.
+func benchmarkParallelReads(b *testing.B, contains func([]byte) bool) {
+	keys := benchmarkKeys(1024)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			contains(keys[i%len(keys)])
+			i++
+		}
+	})
+}
+
+func benchmarkReadFilter() *BloomFilter {
+	bf := NewWithEstimates(100_000, 0.01)
+	for _, key := range benchmarkKeys(100_000) {
+		bf.Add(key)
+	}
+	return bf
+}
+
+func BenchmarkFrozen_MightContainParallel(b *testing.B) {
+	benchmarkParallelReads(b, benchmarkReadFilter().Freeze().MightContain)
+}
+
+func BenchmarkBloom_MightContainParallel(b *testing.B) {
+	benchmarkParallelReads(b, benchmarkReadFilter().MightContain)
+}
+
+func BenchmarkSafeBloom_MightContainParallel(b *testing.B) {
+	s := &SafeBloom{bf: benchmarkReadFilter()}
+	benchmarkParallelReads(b, s.MightContain)
+}
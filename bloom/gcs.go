@@ -0,0 +1,321 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"sort"
+	"unsafe"
+)
+
+// gcsIndexInterval is how many coded values separate two index entries.
+// Contains decodes at most this many values after a binary search.
+const gcsIndexInterval = 64
+
+// GCS is an immutable Golomb-compressed set: the sorted hashes of a key
+// set, delta-encoded and Golomb-Rice coded. It is the smallest
+// representation in this package, using about fpBits + 1.6 bits per key
+// against 1.23*fpBits for StaticFilter and 1.44*fpBits for a BloomFilter,
+// and is meant for shipping static sets where size matters more than
+// query speed.
+//
+// Like the other filters it never reports a false negative for a key it
+// was built from. Other keys are reported present with probability about
+// 2^-fpBits: each key hashes to [0, N*2^fpBits), so a stranger lands on
+// one of the N stored values with that probability.
+//
+// The encoded bytes are all that MarshalBinary ships. An index of every
+// 64th value and its bit offset is kept in memory alongside them so
+// Contains decodes a short run instead of the whole set; it is rebuilt on
+// decode.
+type GCS struct {
+	n      uint64 // distinct keys; values lie in [0, n<<fpBits)
+	fpBits uint64 // Golomb-Rice parameter and false positive exponent
+	count  uint64 // coded values; below n when key hashes collide
+	data   []byte // Golomb-Rice coded deltas, most significant bit first
+
+	index []gcsIndexEntry
+}
+
+// gcsIndexEntry records the value decoded just before a bit offset.
+type gcsIndexEntry struct {
+	prev   uint64 // value preceding the run that starts at offset
+	offset uint64 // bit offset of the next coded delta
+}
+
+// BuildGCS hashes keys into [0, N*2^fpBits), where N is the number of
+// distinct keys, and codes the sorted values into a GCS. Duplicate keys
+// count once, and keys whose hashes collide in that range are stored once;
+// both are still reported present.
+//
+// This panics if fpBits is not in [1, 32], or if N*2^fpBits overflows a
+// uint64.
+func BuildGCS(keys [][]byte, fpBits uint) *GCS {
+	if fpBits < 1 || fpBits > 32 {
+		panic("bloom: fpBits must be between 1 and 32")
+	}
+
+	hashes := make([]uint64, len(keys))
+	for i, key := range keys {
+		hashes[i], _ = murmur3x64_128(key, 0)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	hashes = dedupeSorted(hashes)
+	if uint64(len(hashes)) > 1<<(64-fpBits)-1 {
+		panic("bloom: too many keys for fpBits")
+	}
+
+	g := &GCS{n: uint64(len(hashes)), fpBits: uint64(fpBits)}
+	// value is monotonic in the hash, so the values are already sorted
+	// and equal values are adjacent.
+	values := hashes[:0]
+	for _, h := range hashes {
+		values = append(values, g.value(h))
+	}
+	values = dedupeSorted(values)
+	g.count = uint64(len(values))
+
+	var w gcsWriter
+	var prev uint64
+	for _, v := range values {
+		w.writeRice(v-prev, g.fpBits)
+		prev = v
+	}
+	g.data = w.buf
+	g.buildIndex()
+	return g
+}
+
+// Contains reports whether data might be in the set the GCS was built from.
+// Returns false -> definitely not present.
+// Returns true  -> might be present (subject to false positives).
+func (g *GCS) Contains(data []byte) bool {
+	if g == nil || g.count == 0 {
+		return false
+	}
+	h, _ := murmur3x64_128(data, 0)
+	target := g.value(h)
+
+	// The last index entry whose run can still reach target.
+	i := sort.Search(len(g.index), func(i int) bool { return g.index[i].prev >= target }) - 1
+	if i < 0 {
+		i = 0
+	}
+	r := gcsReader{data: g.data, pos: g.index[i].offset}
+	v := g.index[i].prev
+	for n := uint64(i) * gcsIndexInterval; n < g.count; n++ {
+		v += r.readRice(g.fpBits)
+		if v >= target {
+			return v == target
+		}
+	}
+	return false
+}
+
+// MightContain is Contains, under the name ColdTier and the other
+// filters use.
+func (g *GCS) MightContain(data []byte) bool {
+	return g.Contains(data)
+}
+
+// Len returns the number of distinct keys the GCS was built from.
+func (g *GCS) Len() uint64 {
+	if g == nil {
+		return 0
+	}
+	return g.n
+}
+
+// SizeInBytes reports the memory held by the GCS: the coded values, the
+// in-memory index and the fixed struct overhead.
+func (g *GCS) SizeInBytes() uint64 {
+	if g == nil {
+		return 0
+	}
+	return uint64(len(g.data)) + uint64(len(g.index))*uint64(unsafe.Sizeof(gcsIndexEntry{})) + uint64(unsafe.Sizeof(*g))
+}
+
+// Info returns a small description of the GCS's configuration.
+func (g *GCS) Info() string {
+	if g == nil {
+		return "GCS{nil}"
+	}
+	return fmt.Sprintf("GCS{n=%d, fpBits=%d, %d bytes}", g.n, g.fpBits, len(g.data))
+}
+
+// value maps a key hash onto [0, n<<fpBits) by multiply-shift.
+func (g *GCS) value(h uint64) uint64 {
+	hi, _ := bits.Mul64(h, g.n<<g.fpBits)
+	return hi
+}
+
+// buildIndex records the position of every gcsIndexInterval-th value.
+// g.data must already be validated.
+func (g *GCS) buildIndex() {
+	g.index = make([]gcsIndexEntry, 0, (g.count+gcsIndexInterval-1)/gcsIndexInterval)
+	r := gcsReader{data: g.data}
+	var v uint64
+	for n := uint64(0); n < g.count; n++ {
+		if n%gcsIndexInterval == 0 {
+			g.index = append(g.index, gcsIndexEntry{prev: v, offset: r.pos})
+		}
+		v += r.readRice(g.fpBits)
+	}
+}
+
+// GCS binary format (all integers little-endian):
+//
+//	version  uint8   gcsVersion
+//	fpBits   uint8
+//	n        uint64  distinct keys
+//	count    uint64  coded values
+//	data     Golomb-Rice coded deltas, padded with zero bits to a byte
+const (
+	gcsVersion   = 1
+	gcsHeaderLen = 1 + 1 + 8 + 8
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (g *GCS) MarshalBinary() ([]byte, error) {
+	if g == nil || g.fpBits == 0 {
+		return nil, ErrUninitialized
+	}
+	buf := make([]byte, 0, gcsHeaderLen+len(g.data))
+	buf = append(buf, gcsVersion, byte(g.fpBits))
+	buf = binary.LittleEndian.AppendUint64(buf, g.n)
+	buf = binary.LittleEndian.AppendUint64(buf, g.count)
+	return append(buf, g.data...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, fully replacing
+// the receiver's contents. The coded values are decoded once to validate
+// them and rebuild the index. Malformed data fails with ErrCorrupt, an
+// unknown version with ErrUnsupportedVersion; the receiver is left
+// untouched on error.
+func (g *GCS) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	if data[0] != gcsVersion {
+		return fmt.Errorf("%w: gcs version %d", ErrUnsupportedVersion, data[0])
+	}
+	if len(data) < gcsHeaderLen {
+		return fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	fpBits := uint64(data[1])
+	n := binary.LittleEndian.Uint64(data[2:])
+	count := binary.LittleEndian.Uint64(data[10:])
+	payload := data[gcsHeaderLen:]
+	if fpBits < 1 || fpBits > 32 || n > 1<<(64-fpBits)-1 || count > n {
+		return fmt.Errorf("%w: fpBits %d, %d keys, %d values", ErrCorrupt, fpBits, n, count)
+	}
+	// Every value takes at least fpBits+1 bits.
+	if count > uint64(len(payload))*8/(fpBits+1) {
+		return fmt.Errorf("%w: %d values cannot fit in %d bytes", ErrCorrupt, count, len(payload))
+	}
+
+	r := gcsReader{data: payload}
+	limit := n << fpBits
+	var v uint64
+	for i := uint64(0); i < count; i++ {
+		d, ok := r.readRiceChecked(fpBits)
+		if !ok || (i > 0 && d == 0) || d >= limit-v {
+			return fmt.Errorf("%w: bad value %d", ErrCorrupt, i)
+		}
+		v += d
+	}
+	if (r.pos+7)/8 != uint64(len(payload)) || r.pos%8 != 0 && payload[len(payload)-1]<<(r.pos%8) != 0 {
+		return fmt.Errorf("%w: trailing data after %d values", ErrCorrupt, count)
+	}
+
+	decoded := &GCS{n: n, fpBits: fpBits, count: count, data: append([]byte(nil), payload...)}
+	decoded.buildIndex()
+	*g = *decoded
+	return nil
+}
+
+// gcsWriter appends bits most significant first.
+type gcsWriter struct {
+	buf  []byte
+	bits uint64 // bits written
+}
+
+func (w *gcsWriter) writeBit(b uint64) {
+	if w.bits%8 == 0 {
+		w.buf = append(w.buf, 0)
+	}
+	w.buf[len(w.buf)-1] |= byte(b << (7 - w.bits%8))
+	w.bits++
+}
+
+// writeRice codes d as d>>p in unary (ones ended by a zero) followed by
+// the low p bits.
+func (w *gcsWriter) writeRice(d, p uint64) {
+	for q := d >> p; q > 0; q-- {
+		w.writeBit(1)
+	}
+	w.writeBit(0)
+	for i := p; i > 0; i-- {
+		w.writeBit(d >> (i - 1) & 1)
+	}
+}
+
+// gcsReader reads bits written by gcsWriter.
+type gcsReader struct {
+	data []byte
+	pos  uint64 // bit offset
+}
+
+func (r *gcsReader) readBit() uint64 {
+	b := uint64(r.data[r.pos/8]>>(7-r.pos%8)) & 1
+	r.pos++
+	return b
+}
+
+// readRice decodes one value from a stream already known to be valid.
+func (r *gcsReader) readRice(p uint64) uint64 {
+	var q uint64
+	for r.readBit() == 1 {
+		q++
+	}
+	var rem uint64
+	for i := uint64(0); i < p; i++ {
+		rem = rem<<1 | r.readBit()
+	}
+	return q<<p | rem
+}
+
+// readRiceChecked is readRice for untrusted data: it reports false instead
+// of reading past the end or overflowing.
+func (r *gcsReader) readRiceChecked(p uint64) (uint64, bool) {
+	end := uint64(len(r.data)) * 8
+	var q uint64
+	for {
+		if r.pos >= end {
+			return 0, false
+		}
+		if r.readBit() == 0 {
+			break
+		}
+		q++
+	}
+	if q > (1<<(64-p))-1 || end-r.pos < p {
+		return 0, false
+	}
+	var rem uint64
+	for i := uint64(0); i < p; i++ {
+		rem = rem<<1 | r.readBit()
+	}
+	return q<<p | rem, true
+}
+
+// dedupeSorted drops adjacent duplicates from a sorted slice in place.
+func dedupeSorted(s []uint64) []uint64 {
+	out := s[:0]
+	for i, v := range s {
+		if i == 0 || v != s[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
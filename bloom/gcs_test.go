@@ -0,0 +1,126 @@
+package bloom
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestGCS_NoFalseNegatives(t *testing.T) {
+	keys := randomKeys("deny-", 20_000, 41)
+	g := BuildGCS(keys, 10)
+	for i, key := range keys {
+		if !g.Contains(key) {
+			t.Fatalf("key %d missing", i)
+		}
+	}
+
+	falsePositives := 0
+	const trials = 200_000
+	for i := 0; i < trials; i++ {
+		if g.Contains([]byte("absent-" + strconv.Itoa(i))) {
+			falsePositives++
+		}
+	}
+	// 2^-10 is ~0.098%.
+	if rate := float64(falsePositives) / trials; rate > 0.0013 || rate < 0.0007 {
+		t.Fatalf("false positive rate %.4f%%, want ~0.098%%", 100*rate)
+	}
+}
+
+// TestGCS_SizeNearBound checks the coded size against the expected
+// fpBits + 1/(1-1/e) ~ fpBits + 1.58 bits per key of Golomb-Rice coding
+// geometric gaps.
+func TestGCS_SizeNearBound(t *testing.T) {
+	keys := randomKeys("deny-", 50_000, 42)
+	for _, fpBits := range []uint{8, 16, 20} {
+		g := BuildGCS(keys, fpBits)
+		perKey := float64(len(g.data)*8) / float64(len(keys))
+		if lo, hi := float64(fpBits)+1.4, float64(fpBits)+1.75; perKey < lo || perKey > hi {
+			t.Errorf("fpBits %d: %.3f bits/key, want within [%.2f, %.2f]", fpBits, perKey, lo, hi)
+		}
+		data, _ := g.MarshalBinary()
+		if len(data) != gcsHeaderLen+len(g.data) {
+			t.Errorf("fpBits %d: encoding is %d bytes, want header plus %d", fpBits, len(data), len(g.data))
+		}
+	}
+}
+
+func TestGCS_Duplicates(t *testing.T) {
+	keys := [][]byte{[]byte("a"), []byte("b"), []byte("a"), []byte("a"), []byte("c")}
+	g := BuildGCS(keys, 1)
+	if g.Len() != 3 {
+		t.Fatalf("Len = %d, want 3 distinct keys", g.Len())
+	}
+	// With 1-bit values in [0, 6) some of the three keys usually collide;
+	// all must still be found.
+	for _, key := range keys {
+		if !g.Contains(key) {
+			t.Fatalf("%q missing", key)
+		}
+	}
+	if g.count > g.n {
+		t.Fatalf("%d values for %d keys", g.count, g.n)
+	}
+
+	empty := BuildGCS(nil, 20)
+	if empty.Contains([]byte("anything")) || empty.Len() != 0 {
+		t.Fatal("empty set reports a key")
+	}
+}
+
+func TestGCS_BinaryRoundTrip(t *testing.T) {
+	keys := randomKeys("deny-", 5000, 43)
+	g := BuildGCS(keys, 12)
+	data, err := g.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got GCS
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got.Info() != g.Info() || len(got.index) != len(g.index) {
+		t.Fatalf("decoded %s, want %s", got.Info(), g.Info())
+	}
+	for i := 0; i < 10_000; i++ {
+		key := []byte("probe-" + strconv.Itoa(i))
+		if i < len(keys) {
+			key = keys[i]
+		}
+		if got.Contains(key) != g.Contains(key) {
+			t.Fatalf("probe %d: decoded set disagrees", i)
+		}
+	}
+
+	badCount := append([]byte(nil), data...)
+	badCount[10]--
+	trailing := append(append([]byte(nil), data...), 0)
+	for name, bad := range map[string][]byte{
+		"truncated": data[:len(data)-1],
+		"count":     badCount,
+		"trailing":  trailing,
+		"fpBits":    append([]byte{gcsVersion, 40}, data[2:]...),
+		"header":    data[:gcsHeaderLen-1],
+	} {
+		if err := got.UnmarshalBinary(bad); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: expected ErrCorrupt, got %v", name, err)
+		}
+	}
+	if err := got.UnmarshalBinary(append([]byte{9}, data[1:]...)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+	if got.Info() != g.Info() {
+		t.Fatal("a failed decode modified the receiver")
+	}
+}
+
+func BenchmarkGCS_Contains(b *testing.B) {
+	g := BuildGCS(benchCompareKeys(), 10)
+	keys := benchmarkKeys(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.Contains(keys[i%len(keys)])
+	}
+	b.ReportMetric(float64(len(g.data))/benchCompareItems, "bytes/item")
+}
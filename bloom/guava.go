@@ -0,0 +1,157 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Guava serialized form (com.google.common.hash.BloomFilter#writeTo), all
+// integers big-endian as written by java.io.DataOutputStream:
+//
+//	strategy  int8    ordinal of BloomFilterStrategies
+//	k         uint8   numHashFunctions
+//	words     int32   no. of longs that follow
+//	data      words * int64
+//
+// Guava's bit array holds words*64 bits, and bit i lives in
+// data[i/64] at 1<<(i%64), the same layout this package uses.
+const (
+	guavaMitz32 = 0 // MURMUR128_MITZ_32
+	guavaMitz64 = 1 // MURMUR128_MITZ_64
+
+	guavaHeaderSize = 1 + 1 + 4
+)
+
+// ErrUnsupportedFormat is returned when foreign filter data uses a layout,
+// strategy or version this package does not implement.
+var ErrUnsupportedFormat = errors.New("bloom: unsupported format")
+
+// NewGuavaWithEstimates creates a filter that hashes like Guava's
+// BloomFilter.create(funnel, n, fpRate) with the default MURMUR128_MITZ_64
+// strategy, so it can be exported with ExportGuava and queried from Java.
+// Keys must be the bytes Guava's funnel would produce (for
+// Funnels.byteArrayFunnel, the array itself; for
+// Funnels.stringFunnel(UTF_8), the UTF-8 encoding).
+//
+// This panics if n == 0 or fpRate is not in (0, 1).
+func NewGuavaWithEstimates(n uint64, fpRate float64) *BloomFilter {
+	if _, _, err := validEstimates(n, fpRate); err != nil {
+		panic(err)
+	}
+
+	// Guava truncates m and rounds k, then allocates whole longs.
+	bitsFloat := -float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)
+	words := wordsFor(uint64(bitsFloat))
+	if words == 0 {
+		words = 1
+	}
+	k := uint64(math.Round(bitsFloat / float64(n) * math.Ln2))
+	if k == 0 {
+		k = 1
+	}
+
+	bf, err := NewWithOptions(n, 0, WithExplicitSize(words*64, k), withScheme(schemeGuava64))
+	if err != nil {
+		panic(err)
+	}
+	return bf
+}
+
+// ImportGuava decodes a filter written by Guava's BloomFilter#writeTo.
+// Both MURMUR128_MITZ_32 and MURMUR128_MITZ_64 strategies are supported,
+// with positions derived as in Guava's BloomFilterStrategies, so
+// membership answers should match the Java side for the same funnelled
+// bytes. The murmur3 hashing is checked against Guava's published test
+// vectors, but no filter written by Guava itself is among the test
+// fixtures yet, so compatibility with the Java side is unverified.
+func ImportGuava(r io.Reader) (*BloomFilter, error) {
+	var hbuf [guavaHeaderSize]byte
+	if _, err := io.ReadFull(r, hbuf[:]); err != nil {
+		return nil, fmt.Errorf("%w: short guava header", ErrCorrupt)
+	}
+
+	var s scheme
+	switch ordinal := int8(hbuf[0]); ordinal {
+	case guavaMitz32:
+		s = schemeGuava32
+	case guavaMitz64:
+		s = schemeGuava64
+	default:
+		return nil, fmt.Errorf("%w: guava strategy ordinal %d", ErrUnsupportedFormat, ordinal)
+	}
+	k := uint64(hbuf[1])
+	count := int32(binary.BigEndian.Uint32(hbuf[2:]))
+	if k == 0 || count <= 0 {
+		return nil, fmt.Errorf("%w: guava k=%d words=%d", ErrCorrupt, k, count)
+	}
+
+	words := make([]uint64, 0, min(uint64(count), streamChunkWords))
+	buf := make([]byte, streamChunkWords*8)
+	for remaining := uint64(count); remaining > 0; {
+		chunk := min(remaining, streamChunkWords)
+		if _, err := io.ReadFull(r, buf[:chunk*8]); err != nil {
+			return nil, fmt.Errorf("%w: guava stream ended %d words short", ErrCorrupt, remaining)
+		}
+		for i := uint64(0); i < chunk; i++ {
+			words = append(words, binary.BigEndian.Uint64(buf[i*8:]))
+		}
+		remaining -= chunk
+	}
+
+	bf := &BloomFilter{m: uint64(count) * 64, k: k, storage: contiguous(words), scheme: s}
+	bf.setBits = popcount(words)
+	return bf, nil
+}
+
+// ReadGuava is ImportGuava, named to pair with Guava's BloomFilter.readFrom.
+func ReadGuava(r io.Reader) (*BloomFilter, error) {
+	return ImportGuava(r)
+}
+
+// ExportGuava writes the filter in Guava's BloomFilter#writeTo form, readable
+// with BloomFilter.readFrom on the Java side. Only filters that hash like
+// Guava (from ImportGuava or NewGuavaWithEstimates) can be exported; for any
+// other filter the Java side would compute different positions.
+func (bf *BloomFilter) ExportGuava(w io.Writer) error {
+	var ordinal byte
+	switch bf.scheme {
+	case schemeGuava32:
+		ordinal = guavaMitz32
+	case schemeGuava64:
+		ordinal = guavaMitz64
+	default:
+		return fmt.Errorf("%w: probe scheme %s is not a guava strategy", ErrUnsupportedFormat, bf.scheme)
+	}
+	if bf.k > math.MaxUint8 || bf.wordCount() > math.MaxInt32 || bf.m != uint64(bf.wordCount())*64 {
+		return fmt.Errorf("%w: m=%d k=%d does not fit guava's layout", ErrUnsupportedFormat, bf.m, bf.k)
+	}
+
+	buf := make([]byte, guavaHeaderSize, streamChunkWords*8)
+	buf[0] = ordinal
+	buf[1] = byte(bf.k)
+	binary.BigEndian.PutUint32(buf[2:], uint32(bf.wordCount()))
+	_, err := bf.writeWords(w, buf, binary.BigEndian)
+	return err
+}
+
+// guava64Location mirrors MURMUR128_MITZ_64: the combined hash starts at h1,
+// advances by h2, and is made non-negative by masking the sign bit.
+func guava64Location(h1, h2, i, m uint64) uint64 {
+	return ((h1 + i*h2) & math.MaxInt64) % m
+}
+
+// guava32Location mirrors MURMUR128_MITZ_32: the low and high 32 bits of h1
+// are combined in int32 arithmetic for i = 1..k, and negative results are
+// bit-flipped.
+func guava32Location(h1, i, m uint64) uint64 {
+	hash1 := int32(h1)
+	hash2 := int32(h1 >> 32)
+	combined := hash1 + int32(i+1)*hash2
+	if combined < 0 {
+		combined = ^combined
+	}
+	return uint64(combined) % m
+}
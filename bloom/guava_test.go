@@ -0,0 +1,179 @@
+package bloom
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// guavaFox is what a Guava MURMUR128_MITZ_64 filter with one 64-bit word
+// and k=3 should write after putting "The quick brown fox jumps over the
+// lazy dog" through Funnels.stringFunnel(UTF_8), worked out by hand rather
+// than captured from Guava. murmur3 gives h1=0xe34bbc7bbc071b6c,
+// h2=0x7a433ca9c49a9347 (one of Guava's own test vectors), so the probes
+// land on bits 44, 51 and 58.
+var guavaFox = []byte{
+	0x01,                   // strategy ordinal: MURMUR128_MITZ_64
+	0x03,                   // numHashFunctions
+	0x00, 0x00, 0x00, 0x01, // data length
+	0x04, 0x08, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
+func TestImportGuava_KnownVector(t *testing.T) {
+	bf, err := ImportGuava(bytes.NewReader(guavaFox))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bf.m != 64 || bf.k != 3 {
+		t.Fatalf("got %s, want m=64 k=3", bf.Info())
+	}
+	if !bf.MightContain([]byte("The quick brown fox jumps over the lazy dog")) {
+		t.Fatal("expected the fox sentence to be present")
+	}
+
+	// Adding the same key to an empty imported filter reproduces the bytes.
+	empty := append([]byte(nil), guavaFox...)
+	copy(empty[6:], make([]byte, 8))
+	bf, err = ImportGuava(bytes.NewReader(empty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf.Add([]byte("The quick brown fox jumps over the lazy dog"))
+	var buf bytes.Buffer
+	if err := bf.ExportGuava(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), guavaFox) {
+		t.Fatalf("ExportGuava = %x, want %x", buf.Bytes(), guavaFox)
+	}
+}
+
+// testdata/guava_mitz64_n100_p001.bin stands in for the output of
+// testdata/guava/GuavaVectors.java: BloomFilter.create(stringFunnel(UTF_8),
+// 100, 0.01) (960 bits, k=7) after putting "key-0" through "key-99". It was
+// written by ExportGuava following the same steps, not by Guava, so this
+// test only pins the package's own output and the 109 false positives it
+// gives among "key-100" through "key-10099". Replacing the file with the
+// Java program's output, and the count with the one it prints, would make
+// it a cross-language check.
+const guavaGolden = "testdata/guava_mitz64_n100_p001.bin"
+
+func TestReadGuava_Golden(t *testing.T) {
+	golden, err := os.ReadFile(guavaGolden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf, err := ReadGuava(bytes.NewReader(golden))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bf.m != 960 || bf.k != 7 || bf.scheme != schemeGuava64 {
+		t.Fatalf("got %s with scheme %s, want m=960 k=7 mitz64", bf.Info(), bf.scheme)
+	}
+	for i := 0; i < 100; i++ {
+		if !bf.MightContain([]byte("key-" + strconv.Itoa(i))) {
+			t.Fatalf("false negative for key-%d", i)
+		}
+	}
+	falsePositives := 0
+	for i := 100; i < 10100; i++ {
+		if bf.MightContain([]byte("key-" + strconv.Itoa(i))) {
+			falsePositives++
+		}
+	}
+	if falsePositives != 109 {
+		t.Fatalf("%d false positives, want 109 as when the fixture was written", falsePositives)
+	}
+
+	built := NewGuavaWithEstimates(100, 0.01)
+	for i := 0; i < 100; i++ {
+		built.Add([]byte("key-" + strconv.Itoa(i)))
+	}
+	var buf bytes.Buffer
+	if err := built.ExportGuava(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Fatal("ExportGuava output differs from the golden file")
+	}
+}
+
+func TestGuava_RoundTrip(t *testing.T) {
+	bf := NewGuavaWithEstimates(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		bf.Add([]byte("key-" + strconv.Itoa(i)))
+	}
+
+	var buf bytes.Buffer
+	if err := bf.ExportGuava(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.Len(), guavaHeaderSize+len(bf.bits)*8; got != want {
+		t.Fatalf("encoded %d bytes, want %d", got, want)
+	}
+
+	got, err := ImportGuava(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2000; i++ {
+		key := []byte("key-" + strconv.Itoa(i))
+		if got.MightContain(key) != bf.MightContain(key) {
+			t.Fatalf("key %d: imported filter disagrees with original", i)
+		}
+	}
+}
+
+func TestImportGuava_Mitz32(t *testing.T) {
+	data := []byte{0x00, 0x02, 0x00, 0x00, 0x00, 0x02}
+	data = append(data, make([]byte, 16)...)
+	bf, err := ImportGuava(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf.Add([]byte("hello"))
+	if !bf.MightContain([]byte("hello")) {
+		t.Fatal(`expected "hello" to be present`)
+	}
+}
+
+func TestImportGuava_Errors(t *testing.T) {
+	if _, err := ImportGuava(bytes.NewReader([]byte{0x07, 0x03, 0, 0, 0, 1})); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("expected ErrUnsupportedFormat for unknown strategy, got %v", err)
+	}
+	if _, err := ImportGuava(bytes.NewReader(guavaFox[:10])); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt for truncated data, got %v", err)
+	}
+	if err := New(128, 3).ExportGuava(&bytes.Buffer{}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("expected ErrUnsupportedFormat exporting an FNV filter, got %v", err)
+	}
+}
+
+func TestGuava_IncompatibleWithFNV(t *testing.T) {
+	g, err := ImportGuava(bytes.NewReader(guavaFox))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.Diff(New(64, 3)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible across probe schemes, got %v", err)
+	}
+}
+
+func TestGuava_SchemeSurvivesBinaryEncoding(t *testing.T) {
+	bf := NewGuavaWithEstimates(100, 0.01)
+	bf.Add([]byte("guava"))
+
+	var buf bytes.Buffer
+	if _, err := bf.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := readFilter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.scheme != schemeGuava64 || !got.MightContain([]byte("guava")) {
+		t.Fatalf("decoded filter lost its probe scheme: %v", got.scheme)
+	}
+}
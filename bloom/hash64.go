@@ -0,0 +1,88 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"sync"
+)
+
+// HasherFromHash64 adapts any 64-bit hash to a Hasher: h1 is the hash of
+// seed1's eight little-endian bytes followed by the key, and h2 the same
+// with seed2, so the seeds must differ. Instances from newHash are pooled,
+// so hashing a key does not allocate once the pool is warm.
+//
+// newHash must be deterministic: every instance it returns, after Reset,
+// must produce the same sum for the same input, in this process and any
+// other that reads filters built with it. Hashes seeded randomly per
+// instance, such as hash/maphash.Hash, break this; WithHasher checks a
+// trial input on two instances and fails with ErrInvalidOption if they
+// disagree, as it does for a nil newHash or equal seeds.
+//
+// Call it once and share the result: filters built from separate calls
+// are treated as having different hashers. Register the result with
+// RegisterHasher to serialize such filters.
+func HasherFromHash64(newHash func() hash.Hash64, seed1, seed2 uint64) Hasher {
+	h := &hash64Hasher{newHash: newHash}
+	binary.LittleEndian.PutUint64(h.seed1[:], seed1)
+	binary.LittleEndian.PutUint64(h.seed2[:], seed2)
+	h.pool.New = func() any { return newHash() }
+	switch {
+	case newHash == nil:
+		h.err = fmt.Errorf("%w: nil hash constructor", ErrInvalidOption)
+	case seed1 == seed2:
+		h.err = fmt.Errorf("%w: HasherFromHash64 seeds must differ", ErrInvalidOption)
+	default:
+		h.err = checkHash64Deterministic(newHash)
+	}
+	return h
+}
+
+// hash64Hasher is the Hasher returned by HasherFromHash64.
+type hash64Hasher struct {
+	newHash      func() hash.Hash64
+	seed1, seed2 [8]byte
+	pool         sync.Pool // of hash.Hash64
+	err          error     // set if newHash is unusable; see validate
+}
+
+// Hash128 implements Hasher.
+func (h *hash64Hasher) Hash128(data []byte) (uint64, uint64) {
+	hh := h.pool.Get().(hash.Hash64)
+	hh.Reset()
+	hh.Write(h.seed1[:])
+	hh.Write(data)
+	h1 := hh.Sum64()
+	hh.Reset()
+	hh.Write(h.seed2[:])
+	hh.Write(data)
+	h2 := hh.Sum64()
+	h.pool.Put(hh)
+	return h1, h2
+}
+
+func (h *hash64Hasher) validate() error {
+	return h.err
+}
+
+// checkHash64Deterministic hashes a fixed input on two fresh instances,
+// and once more after Reset, and reports whether all sums agree.
+func checkHash64Deterministic(newHash func() hash.Hash64) error {
+	trial := []byte("bloom: hash determinism check")
+	a, b := newHash(), newHash()
+	a.Write(trial)
+	b.Write(trial)
+	first := a.Sum64()
+	a.Reset()
+	a.Write(trial)
+	if first != b.Sum64() || first != a.Sum64() {
+		return fmt.Errorf("%w: hash %T is not deterministic across instances", ErrInvalidOption, a)
+	}
+	return nil
+}
+
+// validatingHasher is implemented by hashers that can be constructed in
+// an unusable state, which WithHasher rejects.
+type validatingHasher interface {
+	validate() error
+}
@@ -0,0 +1,71 @@
+package bloom
+
+import (
+	"errors"
+	"hash"
+	"hash/crc64"
+	"hash/fnv"
+	"hash/maphash"
+	"testing"
+)
+
+func TestHasherFromHash64(t *testing.T) {
+	crc := func() hash.Hash64 { return crc64.New(crc64.MakeTable(crc64.ECMA)) }
+	for name, newHash := range map[string]func() hash.Hash64{
+		"fnv64a": fnv.New64a,
+		"crc64":  crc,
+	} {
+		h := HasherFromHash64(newHash, 1, 2)
+		bf, err := NewWithOptions(2000, 0.01, WithHasher(h))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		keys := randomKeys(name, 2000, 15)
+		bf.AddAll(keys)
+		for _, k := range keys {
+			if !bf.MightContain(k) {
+				t.Fatalf("%s: false negative for %q", name, k)
+			}
+		}
+
+		// h1 and h2 are the seeded sums of the standard hash.
+		ref := newHash()
+		ref.Write([]byte{1, 0, 0, 0, 0, 0, 0, 0})
+		ref.Write(keys[0])
+		if h1, _ := h.Hash128(keys[0]); h1 != ref.Sum64() {
+			t.Fatalf("%s: h1 = %#x, want %#x", name, h1, ref.Sum64())
+		}
+
+		if n := testing.AllocsPerRun(100, func() { bf.Add(keys[1]) }); n != 0 && !raceEnabled {
+			t.Fatalf("%s: Add allocates %.1f times per call", name, n)
+		}
+	}
+}
+
+func TestHasherFromHash64_RoundTrip(t *testing.T) {
+	h := HasherFromHash64(fnv.New64a, 11, 12)
+	RegisterHasher("test-fnv64a-11-12", h)
+	bf, _ := NewWithOptions(100, 0.01, WithHasher(h))
+	bf.Add([]byte("k"))
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got BloomFilter
+	if err := got.UnmarshalBinary(data); err != nil || !got.MightContain([]byte("k")) {
+		t.Fatalf("round trip: %v", err)
+	}
+}
+
+func TestHasherFromHash64_Refuses(t *testing.T) {
+	random := func() hash.Hash64 { return new(maphash.Hash) }
+	for name, h := range map[string]Hasher{
+		"nondeterministic": HasherFromHash64(random, 1, 2),
+		"nil":              HasherFromHash64(nil, 1, 2),
+		"equal seeds":      HasherFromHash64(fnv.New64a, 3, 3),
+	} {
+		if _, err := NewWithOptions(100, 0.01, WithHasher(h)); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("%s: got %v, want ErrInvalidOption", name, err)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package bloom
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Hasher computes the two base hashes a key's probe positions are derived
+// from by double hashing: position i is (h1 + i*h2) mod m, plus a cubic
+// term under WithEnhancedDoubleHashing. Implementations must be
+// deterministic, safe for concurrent use, and must not retain data after
+// returning: the slice is reused for later keys. An h2 of zero would
+// give every probe the same position, so the filter replaces it with a
+// fixed odd constant, except for Murmur3Hasher, whose positions must match
+// other languages' filters.
+//
+// A Hasher only applies to the package's own probe schemes; the foreign
+// formats (Guava, bits-and-blooms, Cassandra) always hash as their origin
+// does.
+type Hasher interface {
+	Hash128(data []byte) (h1, h2 uint64)
+}
+
+// FNVHasher is the default Hasher: two FNV-1a passes over the key with
+// different offset bases. It is registered as "fnv", and filters built
+// without WithHasher use it.
+type FNVHasher struct{}
+
+// Hash128 implements Hasher.
+func (FNVHasher) Hash128(data []byte) (uint64, uint64) {
+	return hash128(data)
+}
+
+// hash128 hashes data with the configured Hasher. The package's own
+// hashers are called on their concrete types, which the compiler can see
+// do not retain data. Any other Hasher is handed a copy in a pooled heap
+// buffer instead: passing the key itself through the interface would
+// force every caller's key onto the heap, and a Hasher that keeps the
+// slice despite the rules then holds ordinary heap memory, never a
+// caller's stack.
+func (c *hasherConfig) hash128(data []byte) (uint64, uint64) {
+	switch h := c.h.(type) {
+	case XXHasher:
+		return h.Hash128(data)
+	case Murmur3Hasher:
+		return h.Hash128(data)
+	case IdentityHasher:
+		return h.Hash128(data)
+	case MaphashHasher:
+		return h.Hash128(data)
+	case FNVHasher:
+		return h.Hash128(data)
+	}
+	buf := keyCopies.Get().(*[]byte)
+	*buf = append((*buf)[:0], data...)
+	h1, h2 := c.h.Hash128(*buf)
+	keyCopies.Put(buf)
+	return h1, h2
+}
+
+// keyCopies holds buffers for the copies of keys hash128 hands to
+// hashers from outside the package.
+var keyCopies = sync.Pool{New: func() any { return new([]byte) }}
+
+// hasherConfig is a filter's non-default hasher. Filters share the value
+// and never modify it.
+type hasherConfig struct {
+	h     Hasher
+	name  string // registered name; "" if unregistered
+	id    uint64 // encoded in the binary header: fnv64a(name), or unregisteredHasherID
+	exact bool   // h2 is used as is, even 0 (see exactHasher)
+}
+
+// exactHasher is implemented by hashers whose h2 is used as is, even when
+// it is 0, because filters built with them must set the same bits as
+// other implementations of the same double hashing.
+type exactHasher interface {
+	exactH2()
+}
+
+// newHasherConfig returns the configuration for h under name and id.
+func newHasherConfig(h Hasher, name string, id uint64) *hasherConfig {
+	_, exact := h.(exactHasher)
+	return &hasherConfig{h: h, name: name, id: id, exact: exact}
+}
+
+// unregisteredHasherID stands in for a hasher with no registered name.
+// Such filters cannot be serialized, and their fingerprints do not tell
+// one unregistered hasher from another.
+const unregisteredHasherID = ^uint64(0)
+
+// hasherRegistry maps registered names and their ids to hashers.
+var hasherRegistry = struct {
+	sync.RWMutex
+	byName map[string]*hasherConfig
+	byID   map[uint64]*hasherConfig
+}{
+	byName: map[string]*hasherConfig{},
+	byID:   map[uint64]*hasherConfig{},
+}
+
+func init() {
+	RegisterHasher("fnv", FNVHasher{})
+	RegisterHasher("xxh64", XXHasher{})
+	RegisterHasher("murmur3-128", Murmur3Hasher{})
+	RegisterHasher("identity", IdentityHasher{})
+}
+
+// RegisterHasher makes h available under name, so filters built with
+// WithHasher(h) record the name when serialized and can be decoded again
+// by any process that registered the same hasher under the same name.
+// Register hashers from an init function, before any filter using them is
+// decoded; a name must keep meaning the same function forever, or saved
+// filters will silently answer wrongly.
+//
+// It panics if h is nil, name is empty or already registered, or h is a
+// hasher such as MaphashHasher whose output differs between processes.
+func RegisterHasher(name string, h Hasher) {
+	if h == nil || name == "" {
+		panic("bloom: RegisterHasher needs a name and a non-nil hasher")
+	}
+	if _, ok := h.(processLocalHasher); ok {
+		panic(errProcessLocal(h))
+	}
+	hasherRegistry.Lock()
+	defer hasherRegistry.Unlock()
+	id := fnv64a([]byte(name))
+	if _, dup := hasherRegistry.byName[name]; dup {
+		panic("bloom: hasher " + name + " registered twice")
+	}
+	if other, dup := hasherRegistry.byID[id]; dup || id == 0 || id == unregisteredHasherID {
+		panic(fmt.Sprintf("bloom: hasher name %q collides with %q", name, other.name))
+	}
+	c := newHasherConfig(h, name, id)
+	hasherRegistry.byName[name] = c
+	hasherRegistry.byID[id] = c
+}
+
+// Hashers returns the names of all registered hashers, sorted.
+func Hashers() []string {
+	hasherRegistry.RLock()
+	defer hasherRegistry.RUnlock()
+	names := make([]string, 0, len(hasherRegistry.byName))
+	for name := range hasherRegistry.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// configForHasher returns the configuration a filter built with h uses:
+// nil for the default FNVHasher, the registered entry if h is registered,
+// and an unregistered entry otherwise.
+func configForHasher(h Hasher) *hasherConfig {
+	if sameHasherValue(h, FNVHasher{}) {
+		return nil
+	}
+	hasherRegistry.RLock()
+	defer hasherRegistry.RUnlock()
+	for _, c := range hasherRegistry.byName {
+		if sameHasherValue(c.h, h) {
+			return c
+		}
+	}
+	return newHasherConfig(h, "", unregisteredHasherID)
+}
+
+// configForName returns the registered hasher called name; "" and "fnv"
+// are the default.
+func configForName(name string) (*hasherConfig, error) {
+	if name == "" || name == "fnv" {
+		return nil, nil
+	}
+	hasherRegistry.RLock()
+	defer hasherRegistry.RUnlock()
+	if c, ok := hasherRegistry.byName[name]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("%w: hasher %q is not registered", ErrUnsupportedFormat, name)
+}
+
+// configForID returns the registered hasher with the given encoded id; 0
+// is the default.
+func configForID(id uint64) (*hasherConfig, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	hasherRegistry.RLock()
+	defer hasherRegistry.RUnlock()
+	if c, ok := hasherRegistry.byID[id]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("%w: unknown hasher %#x; register it with RegisterHasher", ErrUnsupportedFormat, id)
+}
+
+// sameHasherValue reports whether a and b are the same hasher value,
+// without panicking on uncomparable types.
+func sameHasherValue(a, b Hasher) bool {
+	ta := reflect.TypeOf(a)
+	return ta == reflect.TypeOf(b) && ta.Comparable() && a == b
+}
+
+// sameHasher reports whether two filters' hasher configurations hash
+// identically.
+func sameHasher(a, b *hasherConfig) bool {
+	switch {
+	case a == b:
+		return true
+	case a == nil || b == nil:
+		return false
+	case a.name != "" || b.name != "":
+		return a.name == b.name
+	}
+	return sameHasherValue(a.h, b.h)
+}
+
+// hasherID returns the id of c for the binary header.
+func (c *hasherConfig) hasherID() uint64 {
+	if c == nil {
+		return 0
+	}
+	return c.id
+}
+
+// String returns c's registered name, "fnv" for the default.
+func (c *hasherConfig) String() string {
+	switch {
+	case c == nil:
+		return "fnv"
+	case c.name == "":
+		return fmt.Sprintf("unregistered %T", c.h)
+	}
+	return c.name
+}
+
+// Hasher returns the name of the filter's hasher: "fnv" by default, the
+// registered name of a WithHasher hasher, or a description of an
+// unregistered one.
+func (bf *BloomFilter) Hasher() string {
+	if bf == nil {
+		return "fnv"
+	}
+	return bf.hasher.String()
+}
+
+// checkEncodable reports whether bf's hasher can be recorded in an
+// encoding.
+func (bf *BloomFilter) checkEncodable() error {
+	if bf.hasher == nil {
+		return nil
+	}
+	if _, ok := bf.hasher.h.(processLocalHasher); ok {
+		return errProcessLocal(bf.hasher.h)
+	}
+	if bf.hasher.name == "" {
+		return fmt.Errorf("%w: %s cannot be serialized; register it with RegisterHasher", ErrUnsupportedFormat, bf.hasher)
+	}
+	return nil
+}
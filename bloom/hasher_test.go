@@ -0,0 +1,240 @@
+package bloom
+
+import (
+	"encoding/json"
+	"errors"
+	"hash/crc64"
+	"strconv"
+	"testing"
+	"unsafe"
+)
+
+// crcHasher derives both base hashes from CRC-64 with different tables.
+type crcHasher struct{}
+
+var (
+	crcISO  = crc64.MakeTable(crc64.ISO)
+	crcECMA = crc64.MakeTable(crc64.ECMA)
+)
+
+func (crcHasher) Hash128(data []byte) (uint64, uint64) {
+	return crc64.Checksum(data, crcISO), crc64.Checksum(data, crcECMA)
+}
+
+// xorHasher is never registered.
+type xorHasher struct{ salt uint64 }
+
+func (h xorHasher) Hash128(data []byte) (uint64, uint64) {
+	h1, h2 := hash128(data)
+	return h1 ^ h.salt, h2
+}
+
+func init() {
+	RegisterHasher("test-crc64", crcHasher{})
+}
+
+func TestWithHasher_Consistent(t *testing.T) {
+	keys := randomKeys("hasher", 2000, 1)
+	bf, err := NewWithOptions(2000, 0.01, WithHasher(crcHasher{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSafeWithOptions(2000, 0.01, WithHasher(crcHasher{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range keys {
+		bf.Add(k)
+		s.Add(k)
+	}
+	for _, k := range keys {
+		if !bf.MightContain(k) || !s.MightContain(k) {
+			t.Fatalf("false negative for %q", k)
+		}
+	}
+	if bf.Hasher() != "test-crc64" || bf.Params().Hasher != "test-crc64" {
+		t.Fatalf("Hasher() = %q, Params().Hasher = %q", bf.Hasher(), bf.Params().Hasher)
+	}
+
+	// The hasher must actually be used: the same keys under FNV set
+	// different bits.
+	def := NewWithEstimates(2000, 0.01)
+	def.AddAll(keys)
+	same := true
+	for i := range def.bits {
+		same = same && def.bits[i] == bf.bits[i]
+	}
+	if same {
+		t.Fatal("custom hasher produced the same bits as FNV")
+	}
+}
+
+func TestWithHasher_RefusesMerge(t *testing.T) {
+	a, _ := NewWithOptions(100, 0.01, WithHasher(crcHasher{}))
+	b := NewWithEstimates(100, 0.01)
+	c, _ := NewWithOptions(100, 0.01, WithHasher(xorHasher{1}))
+	d, _ := NewWithOptions(100, 0.01, WithHasher(xorHasher{2}))
+
+	for name, pair := range map[string][2]*BloomFilter{
+		"registered vs default":      {a, b},
+		"registered vs unregistered": {a, c},
+		"unregistered pair":          {c, d},
+	} {
+		if err := pair[0].Merge(pair[1]); !errors.Is(err, ErrIncompatible) {
+			t.Fatalf("%s: Merge error %v, want ErrIncompatible", name, err)
+		}
+		if pair[0].Equal(pair[1]) {
+			t.Fatalf("%s: filters with different hashers compare equal", name)
+		}
+	}
+	if SameFamily(a, b) {
+		t.Fatal("SameFamily ignored the hasher")
+	}
+
+	a2, _ := NewWithOptions(100, 0.01, WithHasher(crcHasher{}))
+	a2.Add([]byte("x"))
+	if err := a.Merge(a2); err != nil || !a.MightContain([]byte("x")) {
+		t.Fatalf("merging filters with the same hasher: %v", err)
+	}
+}
+
+func TestWithHasher_RoundTrip(t *testing.T) {
+	bf, _ := NewWithOptions(500, 0.01, WithHasher(crcHasher{}))
+	keys := randomKeys("rt", 500, 2)
+	bf.AddAll(keys)
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got BloomFilter
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(bf) || got.Hasher() != "test-crc64" {
+		t.Fatal("binary round trip lost the hasher")
+	}
+
+	js, err := json.Marshal(bf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON BloomFilter
+	if err := json.Unmarshal(js, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range keys {
+		if !got.MightContain(k) || !fromJSON.MightContain(k) {
+			t.Fatalf("decoded filter lost %q", k)
+		}
+	}
+
+	m, err := NewMatching(bf.Params())
+	if err != nil || m.checkCompatible(bf) != nil {
+		t.Fatalf("NewMatching(%+v) is not compatible: %v", bf.Params(), err)
+	}
+
+	// A decoder that never registered the hasher must refuse the data
+	// rather than answer with the wrong hash.
+	unknown := append([]byte(nil), data...)
+	hdr, _ := parseHeader(unknown)
+	hdr.hasher = 12345
+	copy(unknown, hdr.append(nil))
+	if err := got.UnmarshalBinary(unknown); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("unknown hasher: got %v, want ErrUnsupportedFormat", err)
+	}
+	if _, err := NewMatching(Params{M: 64, K: 3, Scheme: "fnv", Hasher: "nope"}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("NewMatching with unknown hasher: %v", err)
+	}
+}
+
+func TestWithHasher_UnregisteredNotEncodable(t *testing.T) {
+	bf, _ := NewWithOptions(100, 0.01, WithHasher(xorHasher{7}))
+	bf.Add([]byte("k"))
+	if !bf.MightContain([]byte("k")) {
+		t.Fatal("unregistered hasher lost a key")
+	}
+	if _, err := bf.MarshalBinary(); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	if _, err := bf.MarshalJSON(); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("MarshalJSON: %v", err)
+	}
+	if _, err := bf.MarshalText(); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("MarshalText: %v", err)
+	}
+}
+
+func TestWithHasher_Options(t *testing.T) {
+	if _, err := NewWithOptions(100, 0.01, WithHasher(nil)); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("nil hasher: %v", err)
+	}
+	if _, err := NewWithOptions(100, 0.01, WithHasher(crcHasher{}), WithHasher(crcHasher{})); !errors.Is(err, ErrConflictingOptions) {
+		t.Fatalf("hasher given twice: %v", err)
+	}
+	if _, err := NewWithOptions(100, 0.01, WithHasher(crcHasher{}), withScheme(schemeGuava64)); !errors.Is(err, ErrConflictingOptions) {
+		t.Fatalf("hasher with foreign scheme: %v", err)
+	}
+
+	// The explicit default is the default: same bits, same encoding.
+	bf, _ := NewWithOptions(100, 0.01, WithHasher(FNVHasher{}))
+	if bf.hasher != nil || bf.Fingerprint() != NewWithEstimates(100, 0.01).Fingerprint() {
+		t.Fatal("WithHasher(FNVHasher{}) differs from the default")
+	}
+}
+
+func TestRegisterHasher(t *testing.T) {
+	for name, fn := range map[string]func(){
+		"duplicate": func() { RegisterHasher("test-crc64", crcHasher{}) },
+		"empty":     func() { RegisterHasher("", crcHasher{}) },
+		"nil":       func() { RegisterHasher("test-nil", nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: RegisterHasher did not panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+	names := Hashers()
+	if len(names) < 2 || names[0] != "fnv" {
+		t.Fatalf("Hashers() = %v", names)
+	}
+}
+
+// retainingHasher breaks the Hasher rules by keeping the last key.
+type retainingHasher struct{ last *[]byte }
+
+func (h retainingHasher) Hash128(data []byte) (uint64, uint64) {
+	*h.last = data
+	return crcHasher{}.Hash128(data)
+}
+
+func TestWithHasher_KeyCopied(t *testing.T) {
+	var last []byte
+	bf, _ := NewWithOptions(1000, 0.01, WithHasher(retainingHasher{&last}))
+	key := []byte("caller's key")
+	bf.Add(key)
+	if unsafe.SliceData(last) == unsafe.SliceData(key) {
+		t.Fatal("a hasher from outside the package was handed the caller's key")
+	}
+	if !bf.MightContainString("caller's key") {
+		t.Fatal("false negative")
+	}
+}
+
+func TestWithHasher_NoAllocs(t *testing.T) {
+	bf, _ := NewWithOptions(1000, 0.01, WithHasher(crcHasher{}))
+	var buf [16]byte
+	key := strconv.AppendInt(buf[:0], 42, 10)
+	// The key is copied into a pooled buffer, which the race detector
+	// sometimes drops.
+	if n := testing.AllocsPerRun(100, func() {
+		bf.Add(key)
+		bf.MightContain(key)
+	}); n != 0 && !raceEnabled {
+		t.Fatalf("custom hasher allocates %.1f times per call", n)
+	}
+}
@@ -0,0 +1,28 @@
+package bloom
+
+import "encoding/binary"
+
+// IdentityHasher is a Hasher for keys that are already uniformly
+// distributed hashes, such as SHA-256 content digests or UUIDv4s: instead
+// of hashing the key again it takes h1 and h2 from the key's first and
+// second little-endian 64-bit words. Keys shorter than 16 bytes have no
+// second word and are hashed with FNV, so they still work, though such
+// keys rarely are uniform hashes.
+//
+// The false positive rate only matches the design target if the first 16
+// bytes of every key are uniformly random. Keys with structure there
+// (counters, timestamps, ASCII text) map to few, correlated positions and
+// can make the filter useless; use the default hasher for them. Select it
+// with WithHasher(IdentityHasher{}); it is registered as "identity".
+type IdentityHasher struct{}
+
+// identityMinLen is the shortest key IdentityHasher uses directly.
+const identityMinLen = 16
+
+// Hash128 implements Hasher.
+func (IdentityHasher) Hash128(data []byte) (uint64, uint64) {
+	if len(data) < identityMinLen {
+		return hash128(data)
+	}
+	return binary.LittleEndian.Uint64(data), binary.LittleEndian.Uint64(data[8:])
+}
@@ -0,0 +1,78 @@
+package bloom
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+	"testing"
+)
+
+func TestIdentityHasher(t *testing.T) {
+	digest := sha256.Sum256([]byte("content"))
+	h1, h2 := IdentityHasher{}.Hash128(digest[:])
+	if h1 != binary.LittleEndian.Uint64(digest[:]) || h2 != binary.LittleEndian.Uint64(digest[8:]) {
+		t.Fatal("IdentityHasher did not use the key's words")
+	}
+	// Short keys fall back to FNV rather than reading past the end.
+	for _, key := range []string{"", "short", "fifteen bytes!!"} {
+		g1, g2 := IdentityHasher{}.Hash128([]byte(key))
+		w1, w2 := hash128([]byte(key))
+		if g1 != w1 || g2 != w2 {
+			t.Fatalf("short key %q not hashed with FNV", key)
+		}
+	}
+	// A zero second word still gets the h2 fix-up.
+	bf, _ := NewWithOptions(0, 0, WithExplicitSize(1024, 5), WithHasher(IdentityHasher{}))
+	bf.Add(make([]byte, 32))
+	if bf.setBits < 2 {
+		t.Fatalf("all-zero digest set %d bits", bf.setBits)
+	}
+}
+
+func TestIdentityHasher_FalsePositiveRate(t *testing.T) {
+	const n, probes = 20000, 200000
+	bf, err := NewWithOptions(n, 0.01, WithHasher(IdentityHasher{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		d := sha256.Sum256([]byte("key-" + strconv.Itoa(i)))
+		bf.Add(d[:])
+	}
+	fp := 0
+	for i := 0; i < probes; i++ {
+		d := sha256.Sum256([]byte("absent-" + strconv.Itoa(i)))
+		if bf.MightContain(d[:]) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / probes; rate > 0.013 {
+		t.Fatalf("false positive rate %.4f over SHA-256 keys, want about 0.01", rate)
+	}
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got BloomFilter
+	if err := got.UnmarshalBinary(data); err != nil || got.Hasher() != "identity" || !got.Equal(bf) {
+		t.Fatalf("round trip: %v", err)
+	}
+}
+
+func BenchmarkIdentityHasher_SHA256Keys(b *testing.B) {
+	key := sha256.Sum256([]byte("content"))
+	for _, h := range []Hasher{FNVHasher{}, IdentityHasher{}} {
+		bf, _ := NewWithOptions(1<<20, 0.01, WithHasher(h))
+		name := "fnv"
+		if _, ok := h.(IdentityHasher); ok {
+			name = "identity"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(key)))
+			for i := 0; i < b.N; i++ {
+				bf.Add(key[:])
+			}
+		})
+	}
+}
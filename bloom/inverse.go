@@ -0,0 +1,109 @@
+package bloom
+
+import (
+	"bytes"
+	"fmt"
+	"math/bits"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Inverse is an inverse Bloom filter: it never reports a key it has not
+// seen (no false positives) but may forget keys it has (false negatives).
+// It suits suppressing duplicates, where letting a repeat through is
+// acceptable but dropping something new is not.
+//
+// Each key hashes to one slot, and Add replaces whatever the slot held. A
+// key is reported only if its slot still holds exactly that key, so it is
+// forgotten once another key lands in the same slot. Slots hold a copy of
+// the full key rather than a fingerprint, because any fixed-size
+// fingerprint can collide and a collision would be a false positive.
+//
+// Inverse is safe for concurrent use without locks: each slot is an
+// atomic pointer, swapped on Add.
+type Inverse struct {
+	slots []atomic.Pointer[[]byte]
+}
+
+// NewInverse creates an inverse filter with capacity slots. The chance a
+// key is forgotten after n later distinct keys is 1 - (1 - 1/capacity)^n.
+// capacity ==> must be >0.
+func NewInverse(capacity uint64) *Inverse {
+	if capacity == 0 {
+		panic("bloom: capacity must be > 0")
+	}
+	return &Inverse{slots: make([]atomic.Pointer[[]byte], capacity)}
+}
+
+// Add records data, evicting whatever key shared its slot.
+func (f *Inverse) Add(data []byte) {
+	key := bytes.Clone(data)
+	f.slot(data).Store(&key)
+}
+
+// Observe reports whether data is currently recorded.
+// Returns true  -> data was definitely added (and not yet evicted).
+// Returns false -> data may or may not have been added.
+func (f *Inverse) Observe(data []byte) bool {
+	if f == nil || len(f.slots) == 0 {
+		return false
+	}
+	key := f.slot(data).Load()
+	return key != nil && bytes.Equal(*key, data)
+}
+
+// ObserveAndAdd records data and reports whether it was already recorded,
+// as a single atomic step: of several concurrent calls with the same new
+// key, exactly one sees false (unless another key evicts it between them).
+func (f *Inverse) ObserveAndAdd(data []byte) bool {
+	key := bytes.Clone(data)
+	old := f.slot(data).Swap(&key)
+	return old != nil && bytes.Equal(*old, data)
+}
+
+// Capacity returns the number of slots.
+func (f *Inverse) Capacity() uint64 {
+	if f == nil {
+		return 0
+	}
+	return uint64(len(f.slots))
+}
+
+// Reset forgets every key. Keys added concurrently with Reset may or may
+// not survive it.
+func (f *Inverse) Reset() {
+	if f == nil {
+		return
+	}
+	for i := range f.slots {
+		f.slots[i].Store(nil)
+	}
+}
+
+// SizeInBytes reports the memory held by the slot array; stored keys are
+// not counted.
+func (f *Inverse) SizeInBytes() uint64 {
+	if f == nil {
+		return 0
+	}
+	return uint64(len(f.slots))*uint64(unsafe.Sizeof(f.slots[0])) + uint64(unsafe.Sizeof(*f))
+}
+
+// Info returns a small description of the filter's configuration.
+func (f *Inverse) Info() string {
+	if f == nil {
+		return "Inverse{nil}"
+	}
+	return fmt.Sprintf("Inverse{slots=%d}", len(f.slots))
+}
+
+// slot returns data's slot.
+// It panics with ErrUninitialized on a zero-value or nil filter.
+func (f *Inverse) slot(data []byte) *atomic.Pointer[[]byte] {
+	if f == nil || len(f.slots) == 0 {
+		panic(ErrUninitialized)
+	}
+	h, _ := murmur3x64_128(data, 0)
+	i, _ := bits.Mul64(h, uint64(len(f.slots)))
+	return &f.slots[i]
+}
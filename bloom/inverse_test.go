@@ -0,0 +1,101 @@
+package bloom
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestInverse_ObserveAndAdd(t *testing.T) {
+	f := NewInverse(1024)
+	if f.ObserveAndAdd([]byte("alert-1")) {
+		t.Fatal("new key reported as seen")
+	}
+	if !f.ObserveAndAdd([]byte("alert-1")) || !f.Observe([]byte("alert-1")) {
+		t.Fatal("repeated key not reported as seen")
+	}
+	if f.Observe([]byte("alert-2")) {
+		t.Fatal("unseen key reported as seen")
+	}
+	f.Reset()
+	if f.Observe([]byte("alert-1")) {
+		t.Fatal("key survived Reset")
+	}
+}
+
+func TestInverse_EvictionIsFalseNegativeOnly(t *testing.T) {
+	f := NewInverse(16)
+	for i := 0; i < 1000; i++ {
+		f.Add([]byte("key-" + strconv.Itoa(i)))
+	}
+	remembered := 0
+	for i := 0; i < 1000; i++ {
+		if f.Observe([]byte("key-" + strconv.Itoa(i))) {
+			remembered++
+		}
+	}
+	if remembered == 0 || remembered > 16 {
+		t.Fatalf("%d keys remembered in 16 slots", remembered)
+	}
+	for i := 0; i < 10_000; i++ {
+		if f.Observe([]byte("other-" + strconv.Itoa(i))) {
+			t.Fatalf("false positive for other-%d", i)
+		}
+	}
+}
+
+// TestInverse_NoFalsePositivesConcurrent hammers a small filter from many
+// goroutines, so slots are constantly contended, and checks that a key is
+// never reported before it was added. Run it with -race.
+func TestInverse_NoFalsePositivesConcurrent(t *testing.T) {
+	f := NewInverse(64)
+	var wg sync.WaitGroup
+	errs := make(chan string, 8)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			prefix := strconv.Itoa(w) + "-"
+			for i := 0; i < 5000; i++ {
+				key := []byte(prefix + strconv.Itoa(i))
+				// Each key is unique to this goroutine and iteration, so
+				// a true result here can only be a false positive.
+				if f.Observe(key) || f.ObserveAndAdd(key) {
+					errs <- string(key)
+					return
+				}
+				if f.Observe([]byte(prefix + "never-" + strconv.Itoa(i))) {
+					errs <- prefix + "never-" + strconv.Itoa(i)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for key := range errs {
+		t.Errorf("false positive for %s", key)
+	}
+}
+
+func TestInverse_ConcurrentDuplicatesSeenOnce(t *testing.T) {
+	f := NewInverse(1 << 16)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	firsts := 0
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !f.ObserveAndAdd([]byte("same-alert")) {
+				mu.Lock()
+				firsts++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if firsts != 1 {
+		t.Fatalf("%d goroutines saw the alert as new, want exactly 1", firsts)
+	}
+}
@@ -0,0 +1,143 @@
+package bloom
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"slices"
+)
+
+// ErrIncompatible is returned when an operation combines filters whose
+// parameters differ. The wrapped message names the first mismatch.
+var ErrIncompatible = errors.New("bloom: incompatible filters")
+
+// ForEachSetBit calls fn with the position of every set bit in ascending
+// order, stopping early if fn returns false. It settles a lazy filter
+// first (see WithLazyReset).
+func (bf *BloomFilter) ForEachSetBit(fn func(pos uint64) bool) {
+	if !bf.initialized() {
+		return
+	}
+	bf.settle()
+	n, step := bf.wordCount(), bf.spanWords()
+	for start := 0; start < n; start += step {
+		for j, w := range bf.span(start, min(step, n-start)) {
+			i := start + j
+			if i == n-1 {
+				w &= lastWordMask(bf.m)
+			}
+			base := uint64(i) * 64
+			for w != 0 {
+				if !fn(base + uint64(bits.TrailingZeros64(w))) {
+					return
+				}
+				w &= w - 1 // clear lowest set bit
+			}
+		}
+	}
+}
+
+// SetBitPositions returns the positions of all set bits in ascending order.
+func (bf *BloomFilter) SetBitPositions() []uint64 {
+	positions := make([]uint64, 0, bf.setBitsHint())
+	bf.ForEachSetBit(func(pos uint64) bool {
+		positions = append(positions, pos)
+		return true
+	})
+	return positions
+}
+
+// Diff returns the positions set in bf but not in other, and those set in
+// other but not in bf, both in ascending order. The filters must have the
+// same m and k.
+func (bf *BloomFilter) Diff(other *BloomFilter) (onlyInA, onlyInB []uint64, err error) {
+	if err := bf.checkCompatible(other); err != nil {
+		return nil, nil, err
+	}
+	last := bf.wordCount() - 1
+	eachSpanPair(&bf.storage, &other.storage, func(start int, x, y []uint64) {
+		for j := range x {
+			i, a, b := start+j, x[j], y[j]
+			if i == last {
+				a &= lastWordMask(bf.m)
+				b &= lastWordMask(bf.m)
+			}
+			onlyInA = appendPositions(onlyInA, uint64(i)*64, a&^b)
+			onlyInB = appendPositions(onlyInB, uint64(i)*64, b&^a)
+		}
+	})
+	return onlyInA, onlyInB, nil
+}
+
+// appendPositions appends base+offset for every set bit in w.
+func appendPositions(dst []uint64, base, w uint64) []uint64 {
+	for w != 0 {
+		dst = append(dst, base+uint64(bits.TrailingZeros64(w)))
+		w &= w - 1
+	}
+	return dst
+}
+
+// CompatibleWith returns nil if bf and other could be merged or compared:
+// both initialized, with the same m, k and probe scheme. Otherwise it
+// returns ErrUninitialized or an ErrIncompatible naming the first
+// mismatch.
+func (bf *BloomFilter) CompatibleWith(other *BloomFilter) error {
+	return bf.checkCompatible(other)
+}
+
+// Equal reports whether bf and other are compatible and have the same bits
+// set. Storage capacity and padding bits beyond m are ignored. Two nil or
+// zero-value filters are equal.
+func (bf *BloomFilter) Equal(other *BloomFilter) bool {
+	if !bf.initialized() || !other.initialized() {
+		return bf.initialized() == other.initialized()
+	}
+	if bf.checkCompatible(other) != nil {
+		return false
+	}
+	last := bf.wordCount() - 1
+	equal := true
+	eachSpanPair(&bf.storage, &other.storage, func(start int, x, y []uint64) {
+		if !equal {
+			return
+		}
+		if start+len(x) > last {
+			// The final span: compare the last word separately, masked.
+			x, y = x[:last-start], y[:last-start]
+		}
+		equal = slices.Equal(x, y)
+	})
+	mask := lastWordMask(bf.m)
+	return equal && *bf.word(uint64(last))&mask == *other.word(uint64(last))&mask
+}
+
+// checkCompatible reports whether bf and other share the geometry needed
+// to compare or combine their bits.
+func (bf *BloomFilter) checkCompatible(other *BloomFilter) error {
+	switch {
+	case !bf.initialized() || !other.initialized():
+		return ErrUninitialized
+	case bf.m != other.m:
+		return fmt.Errorf("%w: m %d != %d", ErrIncompatible, bf.m, other.m)
+	case bf.k != other.k:
+		return fmt.Errorf("%w: k %d != %d", ErrIncompatible, bf.k, other.k)
+	case bf.scheme != other.scheme:
+		return fmt.Errorf("%w: probe scheme %s != %s", ErrIncompatible, bf.scheme, other.scheme)
+	case bf.seed != other.seed:
+		return fmt.Errorf("%w: seed %#x != %#x", ErrIncompatible, bf.seed, other.seed)
+	case !sameHasher(bf.hasher, other.hasher):
+		return fmt.Errorf("%w: hasher %s != %s", ErrIncompatible, bf.hasher, other.hasher)
+	case bf.Fingerprint() != other.Fingerprint():
+		return fmt.Errorf("%w: fingerprint %#x != %#x", ErrIncompatible, bf.Fingerprint(), other.Fingerprint())
+	}
+	return nil
+}
+
+// setBitsHint returns the set-bit count for sizing result slices.
+func (bf *BloomFilter) setBitsHint() uint64 {
+	if bf == nil {
+		return 0
+	}
+	return bf.setBits
+}
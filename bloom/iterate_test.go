@@ -0,0 +1,112 @@
+package bloom
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestForEachSetBit_LastPartialWord(t *testing.T) {
+	bf := New(130, 1) // three words, the last holding only two valid bits
+	for _, pos := range []uint64{0, 63, 64, 128, 129} {
+		bf.setBit(pos)
+	}
+
+	want := []uint64{0, 63, 64, 128, 129}
+	if got := bf.SetBitPositions(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("SetBitPositions = %v, want %v", got, want)
+	}
+
+	var seen []uint64
+	bf.ForEachSetBit(func(pos uint64) bool {
+		seen = append(seen, pos)
+		return len(seen) < 2
+	})
+	if !reflect.DeepEqual(seen, want[:2]) {
+		t.Fatalf("early stop visited %v, want %v", seen, want[:2])
+	}
+}
+
+func TestForEachSetBit_IgnoresPadding(t *testing.T) {
+	bf := New(100, 1)
+	bf.setBit(99)
+	bf.bits[1] |= 1 << 40 // position 104, beyond m
+
+	if got := bf.SetBitPositions(); !reflect.DeepEqual(got, []uint64{99}) {
+		t.Fatalf("SetBitPositions = %v, want [99]", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := New(200, 1)
+	b := New(200, 1)
+	for _, pos := range []uint64{1, 70, 199} {
+		a.setBit(pos)
+	}
+	for _, pos := range []uint64{1, 128, 199} {
+		b.setBit(pos)
+	}
+
+	onlyA, onlyB, err := a.Diff(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(onlyA, []uint64{70}) || !reflect.DeepEqual(onlyB, []uint64{128}) {
+		t.Fatalf("Diff = %v, %v; want [70], [128]", onlyA, onlyB)
+	}
+
+	if _, _, err := a.Diff(New(201, 1)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible for different m, got %v", err)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := New(100, 3)
+	b := &BloomFilter{m: 100, k: 3, storage: contiguous(make([]uint64, 2, 64))}
+	for _, key := range []string{"x", "y", "z"} {
+		a.Add([]byte(key))
+		b.Add([]byte(key))
+	}
+	if !a.Equal(b) || !b.Equal(a) {
+		t.Fatal("filters with the same bits but different capacities should be equal")
+	}
+
+	b.bits[1] |= 1 << 40 // bit 104, beyond m
+	if !a.Equal(b) {
+		t.Fatal("padding bits beyond m must not affect equality")
+	}
+
+	b.Add([]byte("extra"))
+	if a.Equal(b) {
+		t.Fatal("filters with different bits should not be equal")
+	}
+	if a.Equal(New(128, 3)) || a.Equal(nil) {
+		t.Fatal("incompatible or nil filters should not be equal")
+	}
+	if !(*BloomFilter)(nil).Equal(&BloomFilter{}) {
+		t.Fatal("nil and zero-value filters should be equal")
+	}
+
+	s := NewSafe(100, 3)
+	for _, key := range []string{"x", "y", "z"} {
+		s.Add([]byte(key))
+	}
+	if !s.Equal(a) {
+		t.Fatal("SafeBloom.Equal disagrees with BloomFilter.Equal")
+	}
+}
+
+func TestCompatibleWith(t *testing.T) {
+	a := New(100, 3)
+	if err := a.CompatibleWith(New(100, 3)); err != nil {
+		t.Fatal(err)
+	}
+	err := NewSafe(100, 3).CompatibleWith(New(100, 4))
+	if !errors.Is(err, ErrIncompatible) || !strings.Contains(err.Error(), "k 3 != 4") {
+		t.Fatalf("expected ErrIncompatible naming k, got %v", err)
+	}
+	if err := a.CompatibleWith(nil); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("expected ErrUninitialized, got %v", err)
+	}
+}
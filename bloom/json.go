@@ -0,0 +1,90 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+var (
+	_ json.Marshaler   = (*BloomFilter)(nil)
+	_ json.Unmarshaler = (*BloomFilter)(nil)
+)
+
+// jsonFilter is the JSON form of a BloomFilter. Bits holds the bit words as
+// little-endian bytes, exactly as in the binary format; encoding/json
+// renders it as standard base64.
+type jsonFilter struct {
+	M      uint64 `json:"m"`
+	K      uint64 `json:"k"`
+	Scheme string `json:"scheme,omitempty"` // omitted for the default scheme
+	Hasher string `json:"hasher,omitempty"` // registered name; omitted for the default hasher
+	Seed   uint64 `json:"seed,omitempty"`
+
+	Inserts  uint64 `json:"inserts,omitempty"`
+	Capacity uint64 `json:"capacity,omitempty"`
+
+	Bits []byte `json:"bits"`
+}
+
+// MarshalJSON implements json.Marshaler, producing
+// {"m":..., "k":..., "inserts":..., "capacity":..., "bits":"<base64>"},
+// with zero counts, the default scheme, the default hasher and a zero seed
+// omitted.
+func (bf *BloomFilter) MarshalJSON() ([]byte, error) {
+	if !bf.initialized() {
+		return nil, ErrUninitialized
+	}
+	if err := bf.checkEncodable(); err != nil {
+		return nil, err
+	}
+	jf := jsonFilter{M: bf.m, K: bf.k, Inserts: bf.inserts, Capacity: bf.capacity, Bits: make([]byte, 0, bf.wordCount()*8)}
+	if bf.scheme != schemeFNV {
+		jf.Scheme = bf.scheme.String()
+	}
+	if bf.hasher != nil {
+		jf.Hasher = bf.hasher.name
+	}
+	jf.Seed = bf.seed
+	jf.Bits = bf.appendWords(jf.Bits, binary.LittleEndian)
+	return json.Marshal(jf)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, replacing the receiver's
+// contents. The decoded bits must be exactly (m+63)/64 words long.
+func (bf *BloomFilter) UnmarshalJSON(data []byte) error {
+	var jf jsonFilter
+	if err := json.Unmarshal(data, &jf); err != nil {
+		return err
+	}
+
+	s := schemeFNV
+	if jf.Scheme != "" {
+		var ok bool
+		if s, ok = parseScheme(jf.Scheme); !ok {
+			return fmt.Errorf("%w: unknown probe scheme %q", ErrUnsupportedFormat, jf.Scheme)
+		}
+	}
+	hc, err := configForName(jf.Hasher)
+	if err != nil {
+		return err
+	}
+	if jf.M == 0 || jf.K == 0 {
+		return fmt.Errorf("%w: m=%d k=%d", ErrCorrupt, jf.M, jf.K)
+	}
+	words := wordsFor(jf.M)
+	if uint64(len(jf.Bits)) != words*8 {
+		return fmt.Errorf("%w: bits is %d bytes, want %d for m=%d", ErrCorrupt, len(jf.Bits), words*8, jf.M)
+	}
+
+	w := make([]uint64, words)
+	for i := range w {
+		w[i] = binary.LittleEndian.Uint64(jf.Bits[i*8:])
+	}
+	decoded, err := newFromHeader(header{m: jf.M, k: jf.K, words: words, scheme: s, hasher: hc.hasherID(), seed: jf.Seed, inserts: jf.Inserts, capacity: jf.Capacity}, contiguous(w))
+	if err != nil {
+		return err
+	}
+	*bf = *decoded
+	return nil
+}
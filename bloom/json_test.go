@@ -0,0 +1,76 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestJSON_RoundTrip(t *testing.T) {
+	bf := New(1000, 4)
+	for i := 0; i < 100; i++ {
+		bf.Add([]byte("tenant-" + strconv.Itoa(i)))
+	}
+
+	data, err := json.Marshal(bf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), `{"m":1000,"k":4,"inserts":100,"bits":"`) {
+		t.Fatalf("unexpected JSON shape: %.60s", data)
+	}
+
+	var got BloomFilter
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		key := []byte("tenant-" + strconv.Itoa(i))
+		if got.MightContain(key) != bf.MightContain(key) {
+			t.Fatalf("key %d: JSON-decoded filter disagrees with original", i)
+		}
+	}
+
+	// JSON and binary describe the same filter.
+	fromJSON, _ := got.MarshalBinary()
+	original, _ := bf.MarshalBinary()
+	if !bytes.Equal(fromJSON, original) {
+		t.Fatal("JSON round trip changed the binary encoding")
+	}
+}
+
+func TestJSON_KeepsScheme(t *testing.T) {
+	bf := NewGuavaWithEstimates(100, 0.01)
+	bf.Add([]byte("guava"))
+
+	data, err := json.Marshal(bf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got BloomFilter
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.scheme != schemeGuava64 || !got.MightContain([]byte("guava")) {
+		t.Fatal("JSON round trip lost the probe scheme")
+	}
+}
+
+func TestJSON_Rejects(t *testing.T) {
+	cases := map[string]string{
+		"short bits":     `{"m":128,"k":3,"bits":"AAAAAAAAAAA="}`,
+		"zero m":         `{"m":0,"k":3,"bits":""}`,
+		"padding bits":   `{"m":4,"k":1,"bits":"EAAAAAAAAAA="}`,
+		"unknown scheme": `{"m":64,"k":1,"scheme":"md5","bits":"AAAAAAAAAAA="}`,
+	}
+	for name, data := range cases {
+		var bf BloomFilter
+		err := json.Unmarshal([]byte(data), &bf)
+		if !errors.Is(err, ErrCorrupt) && !errors.Is(err, ErrUnsupportedFormat) {
+			t.Fatalf("%s: expected a decoding error, got %v", name, err)
+		}
+	}
+}
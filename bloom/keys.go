@@ -0,0 +1,140 @@
+package bloom
+
+import "encoding/binary"
+
+// Canonical key encodings. Filters built by different services agree on
+// integer and UUID keys only if every service encodes them the same way,
+// so these helpers fix one layout each, and the layouts never change:
+//
+//	uint64  8 bytes, little-endian
+//	uint32  4 bytes, little-endian
+//	int64   8 bytes, little-endian two's complement (same as uint64(v))
+//	UUID    the 16 bytes as given, in RFC 4122 (network) order
+//
+// AddUint64(7) is exactly Add([]byte{7, 0, 0, 0, 0, 0, 0, 0}), so typed
+// and byte-slice calls can be mixed freely. The helpers encode into stack
+// buffers and do not allocate.
+
+// AddUint64 inserts v, encoded as 8 little-endian bytes.
+func (bf *BloomFilter) AddUint64(v uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	bf.Add(buf[:])
+}
+
+// MightContainUint64 checks if v, encoded as AddUint64 does, might be in
+// the filter.
+func (bf *BloomFilter) MightContainUint64(v uint64) bool {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return bf.MightContain(buf[:])
+}
+
+// AddUint32 inserts v, encoded as 4 little-endian bytes. Note that
+// AddUint32(7) and AddUint64(7) are different keys.
+func (bf *BloomFilter) AddUint32(v uint32) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	bf.Add(buf[:])
+}
+
+// MightContainUint32 checks if v, encoded as AddUint32 does, might be in
+// the filter.
+func (bf *BloomFilter) MightContainUint32(v uint32) bool {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return bf.MightContain(buf[:])
+}
+
+// AddInt64 inserts v, encoded as 8 little-endian two's complement bytes;
+// AddInt64(v) is AddUint64(uint64(v)).
+func (bf *BloomFilter) AddInt64(v int64) {
+	bf.AddUint64(uint64(v))
+}
+
+// MightContainInt64 checks if v, encoded as AddInt64 does, might be in the
+// filter.
+func (bf *BloomFilter) MightContainInt64(v int64) bool {
+	return bf.MightContainUint64(uint64(v))
+}
+
+// AddUUID inserts the 16 bytes of id as given. A UUID type from another
+// package, such as github.com/google/uuid's, converts directly.
+func (bf *BloomFilter) AddUUID(id [16]byte) {
+	bf.Add(id[:])
+}
+
+// MightContainUUID checks if id might be in the filter.
+func (bf *BloomFilter) MightContainUUID(id [16]byte) bool {
+	return bf.MightContain(id[:])
+}
+
+// AddUint64 inserts v safely. See BloomFilter.AddUint64.
+func (s *SafeBloom) AddUint64(v uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bf.AddUint64(v)
+}
+
+// MightContainUint64 checks membership of v safely.
+func (s *SafeBloom) MightContainUint64(v uint64) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.MightContainUint64(v)
+}
+
+// AddUint32 inserts v safely. See BloomFilter.AddUint32.
+func (s *SafeBloom) AddUint32(v uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bf.AddUint32(v)
+}
+
+// MightContainUint32 checks membership of v safely.
+func (s *SafeBloom) MightContainUint32(v uint32) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.MightContainUint32(v)
+}
+
+// AddInt64 inserts v safely. See BloomFilter.AddInt64.
+func (s *SafeBloom) AddInt64(v int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bf.AddInt64(v)
+}
+
+// MightContainInt64 checks membership of v safely.
+func (s *SafeBloom) MightContainInt64(v int64) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.MightContainInt64(v)
+}
+
+// AddUUID inserts id safely. See BloomFilter.AddUUID.
+func (s *SafeBloom) AddUUID(id [16]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bf.AddUUID(id)
+}
+
+// MightContainUUID checks membership of id safely.
+func (s *SafeBloom) MightContainUUID(id [16]byte) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.MightContainUUID(id)
+}
+
+// AddTuple inserts the tuple of parts safely. See BloomFilter.AddTuple.
+func (s *SafeBloom) AddTuple(parts ...[]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bf.AddTuple(parts...)
+}
+
+// MightContainTuple checks membership of the tuple of parts safely.
+func (s *SafeBloom) MightContainTuple(parts ...[]byte) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.MightContainTuple(parts...)
+}
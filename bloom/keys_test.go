@@ -0,0 +1,74 @@
+package bloom
+
+import "testing"
+
+// TestTypedKeys_PinnedEncodings fixes the byte layout of every typed
+// helper: each must set exactly the bits Add sets for the literal bytes
+// below. Changing a layout breaks interoperability with filters built by
+// older versions, so these literals must never be edited.
+func TestTypedKeys_PinnedEncodings(t *testing.T) {
+	uuid := [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	cases := []struct {
+		name  string
+		add   func(*BloomFilter)
+		has   func(*BloomFilter) bool
+		bytes []byte
+	}{
+		{"uint64", func(bf *BloomFilter) { bf.AddUint64(0x0102030405060708) },
+			func(bf *BloomFilter) bool { return bf.MightContainUint64(0x0102030405060708) },
+			[]byte{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01}},
+		{"uint64 small", func(bf *BloomFilter) { bf.AddUint64(7) },
+			func(bf *BloomFilter) bool { return bf.MightContainUint64(7) },
+			[]byte{7, 0, 0, 0, 0, 0, 0, 0}},
+		{"uint32", func(bf *BloomFilter) { bf.AddUint32(0x01020304) },
+			func(bf *BloomFilter) bool { return bf.MightContainUint32(0x01020304) },
+			[]byte{0x04, 0x03, 0x02, 0x01}},
+		{"int64", func(bf *BloomFilter) { bf.AddInt64(-2) },
+			func(bf *BloomFilter) bool { return bf.MightContainInt64(-2) },
+			[]byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"uuid", func(bf *BloomFilter) { bf.AddUUID(uuid) },
+			func(bf *BloomFilter) bool { return bf.MightContainUUID(uuid) },
+			[]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}},
+	}
+	for _, c := range cases {
+		typed, raw := New(4099, 5), New(4099, 5)
+		c.add(typed)
+		raw.Add(c.bytes)
+		if !typed.Equal(raw) {
+			t.Fatalf("%s: encoding is no longer % x", c.name, c.bytes)
+		}
+		if !c.has(raw) {
+			t.Fatalf("%s: typed lookup misses the byte-slice key", c.name)
+		}
+	}
+
+}
+
+func TestTypedKeys_SafeBloomAndAllocs(t *testing.T) {
+	s := NewSafeWithEstimates(1000, 0.01)
+	id := [16]byte{1, 2, 3}
+	s.AddUint64(1)
+	s.AddUint32(2)
+	s.AddInt64(-3)
+	s.AddUUID(id)
+	if !s.MightContainUint64(1) || !s.MightContainUint32(2) || !s.MightContainInt64(-3) || !s.MightContainUUID(id) {
+		t.Fatal("SafeBloom typed keys missing")
+	}
+
+	bf := NewWithEstimates(1000, 0.01)
+	allocs := testing.AllocsPerRun(100, func() {
+		bf.AddUint64(1)
+		bf.MightContainUint64(1)
+		bf.AddUint32(2)
+		bf.MightContainUint32(2)
+		bf.AddInt64(-3)
+		bf.MightContainInt64(-3)
+		bf.AddUUID(id)
+		bf.MightContainUUID(id)
+		s.AddUint64(1)
+		s.MightContainUUID(id)
+	})
+	if allocs != 0 {
+		t.Fatalf("typed helpers allocate %.1f times per call set, want 0", allocs)
+	}
+}
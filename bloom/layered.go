@@ -0,0 +1,168 @@
+package bloom
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Layered is a layered Bloom filter that estimates how many times each key
+// was added, up to a cap, without per-key counters. It stacks L filters
+// with identical parameters: Add inserts a key into the first layer that
+// does not already report it, and Count returns the number of consecutive
+// layers, from the first, that report it.
+//
+// Counts are never too low: after c Adds of a key, Count returns at least
+// min(c, L). They can be too high when false positives let a key skip a
+// layer it was never inserted into. All layers probe the same positions
+// for a key, so these false positives are correlated rather than
+// independent, and the chance of overcounting does not shrink
+// geometrically with each extra layer. What keeps it in check is that
+// layer i only holds keys added more than i times: a key truly added t
+// times is reported at c > t only if it is a false positive in layers t
+// through c-1, which is at most the false positive rate of layer c-1,
+// the sparsest of them. With skewed workloads, where most keys are seen
+// once, upper layers are nearly empty and overcounts by two or more are
+// rare; see TestLayered_NeverUndercounts.
+//
+// Note: This type is not safe for concurrent use without external locking.
+// See SafeLayered.
+type Layered struct {
+	layers []*BloomFilter
+}
+
+// NewLayered creates a layered filter with layers layers, each sized for
+// n keys at fpRate as NewWithEstimates. Size n for the keys expected in
+// the first layer, the number of distinct keys.
+// It panics if layers < 1, and as NewWithEstimates for bad n or fpRate.
+func NewLayered(n uint64, fpRate float64, layers int) *Layered {
+	if layers < 1 {
+		panic("bloom: layers must be >= 1")
+	}
+	l := &Layered{layers: make([]*BloomFilter, layers)}
+	for i := range l.layers {
+		l.layers[i] = NewWithEstimates(n, fpRate)
+	}
+	return l
+}
+
+// Add inserts data into the first layer that does not report it, and
+// returns data's new count, the same as Count would. Once every layer
+// reports data, Add changes nothing and returns the number of layers.
+// It panics with ErrUninitialized on a zero-value or nil filter.
+func (l *Layered) Add(data []byte) uint64 {
+	if l == nil || len(l.layers) == 0 {
+		panic(ErrUninitialized)
+	}
+	h := l.layers[0].hashes(data)
+	for i, bf := range l.layers {
+		if !bf.containsHashes(h) {
+			bf.addHashes(h)
+			return uint64(i + 1)
+		}
+	}
+	return uint64(len(l.layers))
+}
+
+// Count returns how many times data was added, capped at the number of
+// layers. It may overcount but never undercounts; see Layered.
+func (l *Layered) Count(data []byte) uint64 {
+	if l == nil || len(l.layers) == 0 {
+		return 0
+	}
+	h := l.layers[0].hashes(data)
+	for i, bf := range l.layers {
+		if !bf.containsHashes(h) {
+			return uint64(i)
+		}
+	}
+	return uint64(len(l.layers))
+}
+
+// MightContain reports whether data was added at least once.
+func (l *Layered) MightContain(data []byte) bool {
+	return l.Count(data) > 0
+}
+
+// Layers returns the number of layers, the highest count reported.
+func (l *Layered) Layers() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.layers)
+}
+
+// Reset clears every layer.
+func (l *Layered) Reset() {
+	if l == nil {
+		return
+	}
+	for _, bf := range l.layers {
+		bf.Reset()
+	}
+}
+
+// SizeInBytes reports the memory held by all layers.
+func (l *Layered) SizeInBytes() uint64 {
+	if l == nil {
+		return 0
+	}
+	var size uint64
+	for _, bf := range l.layers {
+		size += bf.SizeInBytes()
+	}
+	return size
+}
+
+// Info returns a small description of the filter's configuration.
+func (l *Layered) Info() string {
+	if l == nil || len(l.layers) == 0 {
+		return "Layered{nil}"
+	}
+	return fmt.Sprintf("Layered{layers=%d, %s}", len(l.layers), l.layers[0].Info())
+}
+
+// SafeLayered wraps Layered with a mutex to allow safe concurrent use.
+type SafeLayered struct {
+	mu sync.RWMutex
+	l  *Layered
+}
+
+// NewSafeLayered creates a concurrency-safe layered filter. See NewLayered.
+func NewSafeLayered(n uint64, fpRate float64, layers int) *SafeLayered {
+	return &SafeLayered{l: NewLayered(n, fpRate, layers)}
+}
+
+// Add inserts data safely and returns its new count.
+func (s *SafeLayered) Add(data []byte) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.l.Add(data)
+}
+
+// Count returns data's count safely.
+func (s *SafeLayered) Count(data []byte) uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.l.Count(data)
+}
+
+// MightContain checks membership safely.
+func (s *SafeLayered) MightContain(data []byte) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.l.MightContain(data)
+}
+
+// Reset clears the filter safely.
+func (s *SafeLayered) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.l.Reset()
+}
+
+// Info returns metadata safely.
+func (s *SafeLayered) Info() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.l.Info()
+}
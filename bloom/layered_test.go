@@ -0,0 +1,80 @@
+package bloom
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestLayered_Counts(t *testing.T) {
+	l := NewLayered(1000, 0.001, 3)
+	for want := uint64(1); want <= 5; want++ {
+		if got := l.Add([]byte("alert")); got != min(want, 3) {
+			t.Fatalf("Add #%d returned %d, want %d", want, got, min(want, 3))
+		}
+		if got := l.Count([]byte("alert")); got != min(want, 3) {
+			t.Fatalf("Count after %d adds = %d", want, got)
+		}
+	}
+	if l.Count([]byte("other")) != 0 || l.MightContain([]byte("other")) {
+		t.Fatal("unseen key has a count")
+	}
+	l.Reset()
+	if l.Count([]byte("alert")) != 0 {
+		t.Fatal("Reset kept a count")
+	}
+}
+
+// TestLayered_NeverUndercounts adds keys with skewed multiplicities (most
+// once, a few many times) and checks that no key's count is below its
+// true count, and that overcounts stay rare.
+func TestLayered_NeverUndercounts(t *testing.T) {
+	const layers = 4
+	r := rand.New(rand.NewPCG(31, 32))
+	keys := randomKeys("item-", 20_000, 33)
+	truth := make([]uint64, len(keys))
+
+	l := NewLayered(uint64(len(keys)), 0.01, layers)
+	for range 60_000 {
+		// Geometric-ish skew: key i is picked with weight ~ 1/(i+1).
+		i := int(float64(len(keys)) * r.Float64() * r.Float64() * r.Float64())
+		l.Add(keys[i])
+		truth[i]++
+	}
+
+	overcounts := 0
+	for i, key := range keys {
+		want := min(truth[i], layers)
+		got := l.Count(key)
+		if got < want {
+			t.Fatalf("key %d added %d times reports %d", i, truth[i], got)
+		}
+		if got > want {
+			overcounts++
+		}
+	}
+	if rate := float64(overcounts) / float64(len(keys)); rate > 0.01 {
+		t.Fatalf("%.2f%% of keys overcounted, want under the 1%% layer rate", 100*rate)
+	}
+}
+
+func TestSafeLayered_Concurrent(t *testing.T) {
+	s := NewSafeLayered(1000, 0.01, 3)
+	var wg sync.WaitGroup
+	for w := 0; w < 6; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				s.Add([]byte("key-" + strconv.Itoa(i)))
+			}
+		}()
+	}
+	wg.Wait()
+	for i := 0; i < 100; i++ {
+		if got := s.Count([]byte("key-" + strconv.Itoa(i))); got != 3 {
+			t.Fatalf("key-%d added 6 times counts %d, want the cap of 3", i, got)
+		}
+	}
+}
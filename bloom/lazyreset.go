@@ -0,0 +1,140 @@
+package bloom
+
+import "fmt"
+
+// Lazy reset. Clearing a multi-gigabyte filter costs milliseconds and
+// evicts everything else from cache, which adds up for a filter reset
+// every few seconds. A lazy filter pairs each block of lazyBlockWords
+// words, one cache line, with a generation stamp, and Reset only moves the
+// filter to a new generation. A block whose stamp is older holds the bits
+// of a previous generation and reads as zero: probes skip it, and the
+// first Add to touch it clears it and stamps it current.
+//
+// Code that walks the words directly goes through eachSpan and
+// eachSpanPair, which settle the filter first, clearing every stale block
+// in one pass, so encodings and bulk operations only ever see the logical
+// state. Only the probe paths in addHashes and containsHashes, and a few
+// others that take single bits, deal with stale blocks themselves; they
+// check the stale flag first, so a filter with nothing stale, lazy or
+// not, pays one predictable branch per bit.
+
+const lazyBlockWords = 8
+
+// WithLazyReset makes Reset take constant time instead of clearing the
+// words, at a cost of 2 bytes per 64 bytes of filter. The filter behaves
+// exactly as it would otherwise and encodes identically; the first bulk
+// operation after a Reset, such as encoding, merging, comparing or
+// ForEachSetBit, clears the words left over from before it in one pass,
+// and every 65536th Reset clears them outright.
+//
+// Because that first pass writes to the filter, a lazy filter's bulk
+// operations count as writes: they must not run concurrently with any
+// other use of the filter. SafeBloom, Sharded and Atomic, which run
+// operations concurrently, reject the option with ErrInvalidOption.
+// The mode is not recorded in encodings; decoding gives an ordinary
+// filter.
+func WithLazyReset() Option {
+	return func(o *options) error {
+		o.lazyReset = true
+		return nil
+	}
+}
+
+// rejectLazy fails for a lazy filter wrapped by a concurrent type.
+func rejectLazy(bf *BloomFilter, wrapper string) error {
+	if bf.lazy() {
+		return fmt.Errorf("%w: %s does not support WithLazyReset", ErrInvalidOption, wrapper)
+	}
+	return nil
+}
+
+// generations is the lazy reset state of a filter's words.
+type generations struct {
+	stamps []uint16 // generation of each block of lazyBlockWords words
+	epoch  uint16   // current generation
+	stale  bool     // some block is from an older generation
+}
+
+// lazy reports whether Reset is lazy.
+func (st *storage) lazy() bool {
+	return st.gen != nil
+}
+
+// stale reports whether some words are left over from before a Reset.
+func (st *storage) stale() bool {
+	return st.gen != nil && st.gen.stale
+}
+
+// setLazy turns lazy reset on or off. The words must hold the logical
+// state, as they do after settle or a copy.
+func (st *storage) setLazy(on bool) {
+	switch {
+	case on && st.gen == nil:
+		st.gen = &generations{stamps: make([]uint16, (st.wordCount()+lazyBlockWords-1)/lazyBlockWords)}
+	case !on && st.gen != nil:
+		st.settle()
+		st.gen = nil
+	}
+}
+
+// lazyReset moves a lazy filter to a new generation, making every block
+// stale. When the generation wraps around, stamps from 65536 resets ago
+// would look current, so the words are cleared for real instead.
+func (st *storage) lazyReset() {
+	g := st.gen
+	g.epoch++
+	if g.epoch != 0 {
+		g.stale = true
+		return
+	}
+	g.stale = false
+	for i := range st.wordCount() {
+		*st.word(uint64(i)) = 0
+	}
+	clear(g.stamps)
+}
+
+// settle clears every stale block and stamps it current, so the words
+// hold the logical state.
+func (st *storage) settle() {
+	if st.stale() {
+		st.clearStale()
+	}
+}
+
+func (st *storage) clearStale() {
+	st.gen.stale = false
+	for b, stamp := range st.gen.stamps {
+		if stamp != st.gen.epoch {
+			st.clearBlock(b)
+		}
+	}
+}
+
+// clearBlock zeroes block b and stamps it current.
+func (st *storage) clearBlock(b int) {
+	for i, end := b*lazyBlockWords, min((b+1)*lazyBlockWords, st.wordCount()); i < end; i++ {
+		*st.word(uint64(i)) = 0
+	}
+	st.gen.stamps[b] = st.gen.epoch
+}
+
+// freshen clears the block holding bit pos if it is stale, before the bit
+// is set. Callers check stale first.
+func (st *storage) freshen(pos uint64) {
+	if b := pos / (64 * lazyBlockWords); st.gen.stamps[b] != st.gen.epoch {
+		st.clearBlock(int(b))
+	}
+}
+
+// live reports whether the block holding bit pos belongs to the current
+// generation. Callers check stale first.
+func (st *storage) live(pos uint64) bool {
+	return st.gen.stamps[pos/(64*lazyBlockWords)] == st.gen.epoch
+}
+
+// hasBit is getBit for callers outside the probe loop: it reads a bit in a
+// stale block as zero.
+func (bf *BloomFilter) hasBit(pos uint64) bool {
+	return bf.getBit(pos) && (!bf.stale() || bf.live(pos))
+}
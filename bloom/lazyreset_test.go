@@ -0,0 +1,226 @@
+package bloom
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// lazyPair returns an ordinary filter and a lazy one with the same
+// parameters and extra options.
+func lazyPair(t *testing.T, m, k uint64, opts ...Option) (eager, lazy *BloomFilter) {
+	t.Helper()
+	eager, err := NewWithOptions(0, 0, append(opts, WithExplicitSize(m, k))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lazy, err = NewWithOptions(0, 0, append(opts, WithExplicitSize(m, k), WithLazyReset())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return eager, lazy
+}
+
+// sameLogicalState fails unless lazy, which may have stale blocks, reads
+// exactly like eager. The probe checks run before anything settles lazy.
+func sameLogicalState(t *testing.T, eager, lazy *BloomFilter, probes [][]byte) {
+	t.Helper()
+	for i, key := range probes {
+		if lazy.MightContain(key) != eager.MightContain(key) {
+			t.Fatalf("probe %d: lazy and eager filters disagree", i)
+		}
+	}
+	if got, want := lazy.Explain(probes[0]), eager.Explain(probes[0]); !slices.Equal(got.Probes, want.Probes) {
+		t.Fatal("Explain differs")
+	}
+	st, eagerSt := lazy.Stats(), eager.Stats()
+	if st.SizeBytes = eagerSt.SizeBytes; st != eagerSt {
+		t.Fatalf("Stats() = %+v, want %+v", st, eagerSt)
+	}
+	got, err := lazy.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := eager.MarshalBinary(); !bytes.Equal(got, want) {
+		t.Fatal("encodings differ")
+	}
+	if !lazy.Equal(eager) || countBits(lazy) != lazy.setBits {
+		t.Fatal("words differ once settled")
+	}
+}
+
+func TestLazyReset_MatchesReset(t *testing.T) {
+	const m = 101*64 + 17 // a short last block, with padding bits
+	for name, opts := range map[string][]Option{
+		"contiguous": nil,
+		"chunked":    {withChunkShift(3)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			eager, lazy := lazyPair(t, m, 4, opts...)
+			probes := randomKeys("probe", 1000, 51)
+			for round := range 4 {
+				keys := randomKeys("round"+string(rune('a'+round)), 150, uint64(52+round))
+				eager.AddAll(keys[:100])
+				lazy.AddAll(keys[:100])
+				// Add after a read has settled, as well as right after Reset.
+				sameLogicalState(t, eager, lazy, append(keys, probes...))
+				eager.AddAll(keys[100:])
+				lazy.AddAll(keys[100:])
+				eager.Reset()
+				lazy.Reset()
+				if !lazy.stale() {
+					t.Fatal("Reset cleared the words")
+				}
+				sameLogicalState(t, eager, lazy, append(keys, probes...))
+
+				// Half-refilled, with stale blocks between live ones.
+				eager.AddAll(keys[:20])
+				lazy.AddAll(keys[:20])
+				sameLogicalState(t, eager, lazy, append(keys, probes...))
+				eager.Reset()
+				lazy.Reset()
+			}
+		})
+	}
+}
+
+func TestLazyReset_Operations(t *testing.T) {
+	keys := randomKeys("ops", 300, 56)
+	eager, lazy := lazyPair(t, 1<<14, 5)
+	lazy.AddAll(randomKeys("stale", 500, 57))
+	lazy.Reset()
+	eager.AddAll(keys)
+	lazy.AddAll(keys)
+
+	if !slices.Equal(lazy.BitWords(), eager.BitWords()) {
+		t.Fatal("BitWords differ")
+	}
+	lazy.Reset()
+	lazy.AddAll(keys)
+	if !slices.Equal(lazy.BitDistribution(9), eager.BitDistribution(9)) {
+		t.Fatal("BitDistribution differs")
+	}
+	lazy.Reset()
+	lazy.AddAll(keys)
+	d, _ := lazy.DeltaSince(0)
+	applied := New(1<<14, 5)
+	if err := applied.ApplyDelta(d); err != nil || !applied.Equal(eager) {
+		t.Fatalf("DeltaSince of a stale filter: %v", err)
+	}
+	lazy.Reset()
+	if err := lazy.ApplyDelta(d); err != nil || !lazy.Equal(eager) {
+		t.Fatalf("ApplyDelta into a stale filter: %v", err)
+	}
+	lazy.Reset()
+	if n := len(lazy.SetBitPositions()); n != 0 {
+		t.Fatalf("SetBitPositions() after Reset has %d bits", n)
+	}
+	lazy.AddAll(keys)
+	if !slices.Equal(lazy.SetBitPositions(), eager.SetBitPositions()) {
+		t.Fatal("SetBitPositions differs")
+	}
+	lazy.Reset()
+	lazy.AddAllSorted(keys, nil)
+	if !lazy.Equal(eager) {
+		t.Fatal("AddAllSorted into a stale filter differs")
+	}
+
+	// The mode survives copies and rebuilds, and follows the source of
+	// CopyFrom.
+	lazy.Reset()
+	if c := lazy.Clone(); !c.lazy() || c.Count() != 0 || c.MightContain(keys[0]) {
+		t.Fatal("Clone of a stale lazy filter")
+	}
+	if err := MergeAll(lazy, eager); err != nil || !lazy.Equal(eager) {
+		t.Fatalf("MergeAll: %v", err)
+	}
+	if err := lazy.RebuildSize(1<<15, 5); err != nil || !lazy.lazy() {
+		t.Fatalf("Rebuild lost the mode: %v", err)
+	}
+	if err := eager.CopyFrom(lazy); err != nil || !eager.lazy() {
+		t.Fatalf("CopyFrom a lazy filter: %v", err)
+	}
+	if err := lazy.CopyFrom(New(64, 2)); err != nil || lazy.lazy() {
+		t.Fatalf("CopyFrom an ordinary filter: %v", err)
+	}
+}
+
+func TestLazyReset_Wraparound(t *testing.T) {
+	bf, err := NewWithOptions(1000, 0.01, WithLazyReset())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fast-forward to the last generations before the stamps wrap.
+	bf.gen.epoch = 1<<16 - 2
+	for i := range bf.gen.stamps {
+		bf.gen.stamps[i] = bf.gen.epoch
+	}
+	bf.AddString("old")
+	bf.Reset()
+	bf.AddString("new")
+	// Generation 0 again would make blocks stamped 0 long ago look
+	// current, so this Reset clears the words outright.
+	bf.Reset()
+	if bf.stale() || bf.gen.epoch != 0 || countBits(bf) != 0 {
+		t.Fatal("wrapping the generation did not clear the words")
+	}
+	bf.AddString("newer")
+	if bf.MightContainString("old") || bf.MightContainString("new") || !bf.MightContainString("newer") {
+		t.Fatal("wrong keys after wrapping")
+	}
+}
+
+func TestLazyReset_Options(t *testing.T) {
+	bf, err := NewWithOptions(100_000, 0.01, WithLazyReset())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain := NewWithEstimates(100_000, 0.01); bf.SizeInBytes() <= plain.SizeInBytes() || bf.SizeInBytes() > plain.SizeInBytes()*33/32+64 {
+		t.Fatalf("SizeInBytes() = %d, ordinary filter %d", bf.SizeInBytes(), plain.SizeInBytes())
+	}
+	data, _ := bf.MarshalBinary()
+	var decoded BloomFilter
+	if err := decoded.UnmarshalBinary(data); err != nil || decoded.lazy() {
+		t.Fatalf("decoded filter is lazy: %v", err)
+	}
+
+	if _, err := NewSafeWithOptions(1000, 0.01, WithLazyReset()); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("SafeBloom: %v", err)
+	}
+	if _, err := NewAtomicWithOptions(1000, 0.01, WithLazyReset()); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("Atomic: %v", err)
+	}
+	if _, err := NewShardedWithOptions(1000, 0.01, 4, WithLazyReset()); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("Sharded: %v", err)
+	}
+}
+
+func BenchmarkLazyReset(b *testing.B) {
+	keys := benchmarkKeys(100_000)
+	for _, c := range []struct {
+		name string
+		opts []Option
+	}{{"eager", nil}, {"lazy", []Option{WithLazyReset()}}} {
+		// 120 MB of words.
+		bf, err := NewWithOptions(100_000_000, 0.01, c.opts...)
+		if err != nil {
+			b.Fatal(err)
+		}
+		bf.AddAll(keys)
+		// A lazy Reset takes a few ns; the real clear every 65536th one
+		// accounts for most of its average.
+		b.Run("Reset/"+c.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.Reset()
+			}
+		})
+		// Right after a Reset, refilled, probes must check the stamps.
+		bf.AddAll(keys)
+		b.Run("MightContain/"+c.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bf.MightContain(keys[i%len(keys)])
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package bloom
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"iter"
+)
+
+// linesBufferSize is the read buffer of AddLines. Longer lines are
+// gathered in a separate buffer that grows as needed.
+const linesBufferSize = 64 << 10
+
+// AddLines adds every line read from r and returns how many it added.
+// Lines end in "\n" or "\r\n", the terminator is not part of the key, and
+// empty lines are skipped, so AddLines of "a\r\n\nb" adds "a" and "b".
+// There is no line length limit; memory grows to the longest line.
+//
+// If r fails, AddLines returns the error with the number of lines added
+// before it; a partial line read just before the error is not added, so
+// callers can resume after that many lines. It returns ErrUninitialized on
+// a zero-value or nil filter.
+func (bf *BloomFilter) AddLines(r io.Reader) (int64, error) {
+	if !bf.initialized() {
+		return 0, ErrUninitialized
+	}
+	return scanLines(r, func(line []byte) bool {
+		bf.Add(line)
+		return true
+	})
+}
+
+// Lines returns an iterator over the keys AddLines would add from r, for
+// loaders such as ParallelAddSeq. A read error is yielded once, after the
+// last complete line. Each key is only valid until the next iteration.
+func Lines(r io.Reader) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		stopped := false
+		_, err := scanLines(r, func(line []byte) bool {
+			stopped = !yield(line, nil)
+			return !stopped
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+// scanLines calls fn with each non-empty line of r, as described for
+// AddLines, until fn returns false. The slice passed to fn is only valid
+// during the call.
+//
+// bufio.Scanner would be simpler, but it hands over the partial line
+// before a read error as if it were complete.
+func scanLines(r io.Reader, fn func(line []byte) bool) (int64, error) {
+	br := bufio.NewReaderSize(r, linesBufferSize)
+	var (
+		n    int64
+		long []byte // a line longer than br's buffer, so far
+	)
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			long = append(long, chunk...)
+			continue
+		}
+		line := chunk
+		if len(long) > 0 {
+			long = append(long, chunk...)
+			line, long = long, long[:0]
+		}
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+		line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+		if len(line) > 0 {
+			if !fn(line) {
+				return n, nil
+			}
+			n++
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+	}
+}
+
+// AddLines adds every line read from r safely, taking the lock once per
+// batch of lines rather than per line. See BloomFilter.AddLines.
+func (s *SafeBloom) AddLines(r io.Reader) (int64, error) {
+	var (
+		arena []byte // the batch's lines, back to back
+		ends  []int  // end offset of each line in arena
+		keys  [][]byte
+	)
+	flush := func() {
+		keys = keys[:0]
+		start := 0
+		for _, end := range ends {
+			keys = append(keys, arena[start:end])
+			start = end
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.bf.AddAll(keys)
+		arena, ends = arena[:0], ends[:0]
+	}
+
+	s.mu.RLock()
+	ok := s.bf.initialized()
+	s.mu.RUnlock()
+	if !ok {
+		return 0, ErrUninitialized
+	}
+	n, err := scanLines(r, func(line []byte) bool {
+		arena = append(arena, line...)
+		ends = append(ends, len(arena))
+		if len(ends) == parallelBatch {
+			flush()
+		}
+		return true
+	})
+	if len(ends) > 0 {
+		flush()
+	}
+	return n, err
+}
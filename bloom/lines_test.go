@@ -0,0 +1,123 @@
+package bloom
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAddLines(t *testing.T) {
+	var input bytes.Buffer
+	var want []string
+	for i := 0; i < 200_000; i++ {
+		key := "host-" + strconv.Itoa(i) + ".example.com"
+		want = append(want, key)
+		input.WriteString(key)
+		switch i % 4 {
+		case 0:
+			input.WriteString("\r\n")
+		case 1:
+			input.WriteString("\n\n") // empty line, skipped
+		default:
+			input.WriteString("\n")
+		}
+	}
+	// Pathological lengths: one byte, far past bufio.Scanner's 64 KiB
+	// default, and a final line with no terminator.
+	long := strings.Repeat("x", 3<<20)
+	input.WriteString("a\n\r\n" + long + "\r\n")
+	want = append(want, "a", long, "last")
+	input.WriteString("last")
+	if input.Len() < 5<<20 {
+		t.Fatalf("input is only %d bytes", input.Len())
+	}
+	data := input.Bytes()
+
+	bf := NewWithEstimates(uint64(len(want)), 0.001)
+	s := NewSafeWithEstimates(uint64(len(want)), 0.001)
+	for name, add := range map[string]func(io.Reader) (int64, error){"BloomFilter": bf.AddLines, "SafeBloom": s.AddLines} {
+		n, err := add(bytes.NewReader(data))
+		if err != nil || n != int64(len(want)) {
+			t.Fatalf("%s: added %d lines (%v), want %d", name, n, err, len(want))
+		}
+	}
+	for _, key := range want {
+		if !bf.MightContain([]byte(key)) || !s.MightContain([]byte(key)) {
+			t.Fatalf("line %.20q missing", key)
+		}
+	}
+	if bf.MightContain([]byte("a\r")) || bf.MightContain(nil) {
+		t.Fatal("terminator or empty line added as a key")
+	}
+	if bf.Count() != uint64(len(want)) || s.Count() != uint64(len(want)) {
+		t.Fatalf("counts %d and %d, want %d", bf.Count(), s.Count(), len(want))
+	}
+}
+
+// failAfter returns data and then err, as a reader whose connection drops
+// would.
+type failAfter struct {
+	data []byte
+	err  error
+}
+
+func (f *failAfter) Read(p []byte) (int, error) {
+	if len(f.data) == 0 {
+		return 0, f.err
+	}
+	n := copy(p, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+func TestAddLines_ReaderError(t *testing.T) {
+	errDropped := errors.New("connection dropped")
+	for _, s := range []*SafeBloom{nil, NewSafeWithEstimates(1000, 0.01)} {
+		bf := NewWithEstimates(1000, 0.01)
+		add := bf.AddLines
+		if s != nil {
+			bf, add = s.bf, s.AddLines
+		}
+		n, err := add(&failAfter{data: []byte("one\ntwo\r\n\nthr"), err: errDropped})
+		if !errors.Is(err, errDropped) || n != 2 {
+			t.Fatalf("AddLines = %d, %v; want 2 lines and the reader's error", n, err)
+		}
+		if !bf.MightContain([]byte("two")) || bf.MightContain([]byte("thr")) {
+			t.Fatal("partial line before the error was added, or a full one lost")
+		}
+	}
+
+	if _, err := (&BloomFilter{}).AddLines(strings.NewReader("a\n")); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("zero filter: got %v, want ErrUninitialized", err)
+	}
+}
+
+func TestLines(t *testing.T) {
+	errDropped := errors.New("connection dropped")
+	var got []string
+	var gotErr error
+	for line, err := range Lines(&failAfter{data: []byte("one\ntwo\r\n\nthr"), err: errDropped}) {
+		if err != nil {
+			gotErr = err
+			continue
+		}
+		got = append(got, string(line))
+	}
+	if strings.Join(got, ",") != "one,two" || !errors.Is(gotErr, errDropped) {
+		t.Fatalf("Lines yielded %q then %v", got, gotErr)
+	}
+
+	// Stopping early neither reads on nor yields the error.
+	n := 0
+	for _, err := range Lines(&failAfter{data: []byte("a\nb\nc\n"), err: errDropped}) {
+		if err != nil {
+			t.Fatal("error yielded after the loop stopped")
+		}
+		if n++; n == 2 {
+			break
+		}
+	}
+}
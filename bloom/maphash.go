@@ -0,0 +1,55 @@
+package bloom
+
+import (
+	"fmt"
+	"hash/maphash"
+)
+
+// MaphashHasher is a Hasher built on hash/maphash, which is much faster
+// than FNV on short keys and randomly seeded per value. h1 is the maphash
+// of the key and h2 is derived from it by the murmur3 finalizer, so a key
+// is hashed once.
+//
+// A maphash seed cannot be exported, so filters using a MaphashHasher live
+// only in the process that built them: encoding them fails with
+// ErrUnsupportedFormat, and RegisterHasher refuses them. Filters share
+// hashing, and can be merged, only if they were built with the same
+// MaphashHasher value.
+type MaphashHasher struct {
+	seed maphash.Seed
+}
+
+// NewMaphashHasher returns a MaphashHasher with a new random seed.
+func NewMaphashHasher() MaphashHasher {
+	return MaphashHasher{seed: maphash.MakeSeed()}
+}
+
+// Hash128 implements Hasher. The zero MaphashHasher has no seed and panics;
+// use NewMaphashHasher.
+func (h MaphashHasher) Hash128(data []byte) (uint64, uint64) {
+	h1 := maphash.Bytes(h.seed, data)
+	return h1, mix64(h1 ^ 0x9e3779b97f4a7c15)
+}
+
+func (h MaphashHasher) validate() error {
+	if h == (MaphashHasher{}) {
+		return fmt.Errorf("%w: zero MaphashHasher; use NewMaphashHasher", ErrInvalidOption)
+	}
+	return nil
+}
+
+// processLocal marks MaphashHasher as impossible to reproduce in another
+// process.
+func (MaphashHasher) processLocal() {}
+
+// processLocalHasher is implemented by hashers whose output depends on
+// state that cannot be persisted.
+type processLocalHasher interface {
+	processLocal()
+}
+
+// errProcessLocal is the error for encoding or registering a
+// process-local hasher.
+func errProcessLocal(h Hasher) error {
+	return fmt.Errorf("%w: %T is seeded per process and cannot be serialized; use XXHasher or a seeded filter for persisted filters", ErrUnsupportedFormat, h)
+}
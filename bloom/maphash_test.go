@@ -0,0 +1,88 @@
+package bloom
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMaphashHasher(t *testing.T) {
+	h := NewMaphashHasher()
+	bf, err := NewWithOptions(5000, 0.01, WithHasher(h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := randomKeys("maphash", 5000, 13)
+	bf.AddAll(keys)
+	for _, k := range keys {
+		if !bf.MightContain(k) {
+			t.Fatalf("false negative for %q", k)
+		}
+	}
+	fp := 0
+	for _, k := range randomKeys("absent", 20000, 14) {
+		if bf.MightContain(k) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / 20000; rate > 0.02 {
+		t.Fatalf("false positive rate %.4f, want about 0.01", rate)
+	}
+
+	// Same hasher value: compatible. Another seed: not.
+	same, _ := NewWithOptions(5000, 0.01, WithHasher(h))
+	same.Add([]byte("x"))
+	if err := bf.Merge(same); err != nil {
+		t.Fatalf("merging filters sharing a MaphashHasher: %v", err)
+	}
+	other, _ := NewWithOptions(5000, 0.01, WithHasher(NewMaphashHasher()))
+	if err := bf.Merge(other); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("merging different maphash seeds: %v", err)
+	}
+}
+
+func TestMaphashHasher_NotSerializable(t *testing.T) {
+	bf, _ := NewWithOptions(100, 0.01, WithHasher(NewMaphashHasher()))
+	bf.Add([]byte("k"))
+	_, err := bf.MarshalBinary()
+	if !errors.Is(err, ErrUnsupportedFormat) || !strings.Contains(err.Error(), "per process") {
+		t.Fatalf("MarshalBinary error = %v", err)
+	}
+	if _, err := bf.MarshalJSON(); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("MarshalJSON error = %v", err)
+	}
+	if _, err := bf.WriteTo(&strings.Builder{}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("WriteTo error = %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("RegisterHasher accepted a MaphashHasher")
+		}
+	}()
+	RegisterHasher("test-maphash", NewMaphashHasher())
+}
+
+func TestMaphashHasher_ZeroValue(t *testing.T) {
+	if _, err := NewWithOptions(100, 0.01, WithHasher(MaphashHasher{})); !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("zero MaphashHasher: %v", err)
+	}
+}
+
+func BenchmarkMaphashHasher_ShortString(b *testing.B) {
+	for _, h := range []Hasher{FNVHasher{}, NewMaphashHasher()} {
+		bf, _ := NewWithOptions(1<<20, 0.01, WithHasher(h))
+		name := "fnv"
+		if _, ok := h.(MaphashHasher); ok {
+			name = "maphash"
+		}
+		b.Run(name, func(b *testing.B) {
+			key := "user-1234567890"
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bf.AddString(key)
+				bf.MightContainString(key)
+			}
+		})
+	}
+}
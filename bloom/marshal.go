@@ -0,0 +1,76 @@
+package bloom
+
+import "encoding"
+
+// AddMarshaler inserts the bytes v.MarshalBinary returns, so AddMarshaler(v)
+// is Add of those bytes. If v also implements encoding.BinaryAppender, as
+// netip.Addr and time.Time do, it appends into a reused buffer instead and
+// the call does not allocate beyond converting v to an interface, which
+// passing a pointer avoids. Marshaling errors are returned, and nothing
+// is added.
+//
+// The key is whatever v marshals to, so v's encoding must be
+// deterministic: a type that marshals equal values differently (a
+// timestamp, random padding, unordered map fields) will not be found
+// again.
+func (bf *BloomFilter) AddMarshaler(v encoding.BinaryMarshaler) error {
+	key, pooled, err := marshalKey(v)
+	if err != nil {
+		return err
+	}
+	bf.Add(key)
+	releaseScratch(pooled)
+	return nil
+}
+
+// MightContainMarshaler checks if v, encoded as AddMarshaler does, might
+// be in the filter. It returns false with the error if marshaling fails.
+func (bf *BloomFilter) MightContainMarshaler(v encoding.BinaryMarshaler) (bool, error) {
+	key, pooled, err := marshalKey(v)
+	if err != nil {
+		return false, err
+	}
+	found := bf.MightContain(key)
+	releaseScratch(pooled)
+	return found, nil
+}
+
+// marshalKey encodes v, preferring AppendBinary into a pooled buffer,
+// which is returned for releaseScratch once the key is no longer needed.
+func marshalKey(v encoding.BinaryMarshaler) ([]byte, *[]byte, error) {
+	if a, ok := v.(encoding.BinaryAppender); ok {
+		pooled := scratchPool.Get().(*[]byte)
+		key, err := a.AppendBinary((*pooled)[:0])
+		if err != nil {
+			scratchPool.Put(pooled)
+			return nil, nil, err
+		}
+		*pooled = key
+		return key, pooled, nil
+	}
+	key, err := v.MarshalBinary()
+	return key, nil, err
+}
+
+// AddMarshaler inserts v safely, marshaling it before taking the lock. See
+// BloomFilter.AddMarshaler.
+func (s *SafeBloom) AddMarshaler(v encoding.BinaryMarshaler) error {
+	key, pooled, err := marshalKey(v)
+	if err != nil {
+		return err
+	}
+	s.Add(key)
+	releaseScratch(pooled)
+	return nil
+}
+
+// MightContainMarshaler checks membership of v safely.
+func (s *SafeBloom) MightContainMarshaler(v encoding.BinaryMarshaler) (bool, error) {
+	key, pooled, err := marshalKey(v)
+	if err != nil {
+		return false, err
+	}
+	found := s.MightContain(key)
+	releaseScratch(pooled)
+	return found, nil
+}
@@ -0,0 +1,83 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+// failingKey's MarshalBinary always fails.
+type failingKey struct{}
+
+var errMarshal = errors.New("cannot marshal")
+
+func (failingKey) MarshalBinary() ([]byte, error) { return nil, errMarshal }
+
+// stampedKey marshals differently on every call, as a key embedding a
+// timestamp or nonce would.
+type stampedKey struct {
+	id    uint64
+	calls *uint64
+}
+
+func (k stampedKey) MarshalBinary() ([]byte, error) {
+	*k.calls++
+	return binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(nil, k.id), *k.calls), nil
+}
+
+func TestAddMarshaler(t *testing.T) {
+	bf := NewWithEstimates(1000, 1e-6)
+	addr := netip.MustParseAddr("2001:db8::1")
+	if err := bf.AddMarshaler(addr); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := addr.MarshalBinary()
+	if !bf.MightContain(raw) {
+		t.Fatal("AddMarshaler did not add MarshalBinary's bytes")
+	}
+	if ok, err := bf.MightContainMarshaler(addr); !ok || err != nil {
+		t.Fatalf("MightContainMarshaler = %v, %v", ok, err)
+	}
+	if ok, _ := bf.MightContainMarshaler(netip.MustParseAddr("2001:db8::2")); ok {
+		t.Fatal("unrelated address reported present")
+	}
+
+	before := bf.Count()
+	if err := bf.AddMarshaler(failingKey{}); !errors.Is(err, errMarshal) {
+		t.Fatalf("AddMarshaler error = %v, want the marshal error", err)
+	}
+	if ok, err := bf.MightContainMarshaler(failingKey{}); ok || !errors.Is(err, errMarshal) {
+		t.Fatalf("MightContainMarshaler = %v, %v, want false and the marshal error", ok, err)
+	}
+	if bf.Count() != before {
+		t.Fatal("a failed marshal still added a key")
+	}
+
+	// Passing a pointer avoids boxing the value into the interface.
+	allocs := testing.AllocsPerRun(100, func() {
+		bf.AddMarshaler(&addr)
+		bf.MightContainMarshaler(&addr)
+	})
+	if allocs > 0 && !raceEnabled {
+		t.Fatalf("BinaryAppender keys allocate %.1f times per call", allocs)
+	}
+}
+
+// TestAddMarshaler_NonDeterministic documents that keys are whatever the
+// value marshals to: a type that encodes the same value differently each
+// time is never found again. Such types are the caller's problem.
+func TestAddMarshaler_NonDeterministic(t *testing.T) {
+	var calls uint64
+	key := stampedKey{id: 7, calls: &calls}
+	s := NewSafeWithEstimates(1000, 1e-6)
+	if err := s.AddMarshaler(key); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.MightContainMarshaler(key); ok || err != nil {
+		t.Fatalf("MightContainMarshaler = %v, %v; a non-deterministic key should be lost", ok, err)
+	}
+	if err := s.AddMarshaler(failingKey{}); !errors.Is(err, errMarshal) {
+		t.Fatalf("SafeBloom.AddMarshaler error = %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package bloom
+
+import "fmt"
+
+// Merge ORs other's bits into bf, so bf afterwards reports every key added
+// to either filter. Both must have the same m, k and probe scheme; on a
+// mismatch the error names the differing parameter and bf is unchanged.
+// The insert count becomes the sum of both, an upper bound on the number
+// of distinct keys: a key added to both filters is counted twice, so
+// Count and OverCapacity can overstate how full the result is.
+//
+// As an exception, other may be larger than bf by a power-of-two factor,
+// as when bf is a Fold of a filter like other; other is then folded into
+// bf on the fly.
+func (bf *BloomFilter) Merge(other *BloomFilter) error {
+	if err := bf.checkCompatible(other); err != nil {
+		if !bf.foldsInto(other) {
+			return err
+		}
+		bf.orFolded(other)
+		bf.inserts += other.inserts
+		bf.checkOverfill()
+		return nil
+	}
+	if bf == other {
+		return nil
+	}
+	eachSpanPair(&bf.storage, &other.storage, func(start int, _, words []uint64) {
+		for i, w := range words {
+			bf.orWord(start+i, w)
+		}
+	})
+	bf.inserts += other.inserts
+	bf.checkOverfill()
+	return nil
+}
+
+// Intersect ANDs other's bits into bf, under the same compatibility rules
+// as Merge. Every key added to both filters is still reported present
+// afterwards, but the result over-approximates the true intersection: a
+// bit can survive because different keys set it in each input, so the
+// false positive rate is higher than that of either input, and higher
+// than a filter built from the common keys alone. The insert count
+// becomes the smaller of the two.
+func (bf *BloomFilter) Intersect(other *BloomFilter) error {
+	if err := bf.checkCompatible(other); err != nil {
+		return err
+	}
+	eachSpanPair(&bf.storage, &other.storage, func(_ int, x, y []uint64) {
+		for i, w := range y {
+			x[i] &= w
+		}
+	})
+	bf.setBits = bf.onesCount()
+	bf.stopTracking()
+	bf.inserts = min(bf.inserts, other.inserts)
+	return nil
+}
+
+// IntersectNew is Intersect into a new filter, leaving both inputs
+// unchanged.
+func (bf *BloomFilter) IntersectNew(other *BloomFilter) (*BloomFilter, error) {
+	if err := bf.checkCompatible(other); err != nil {
+		return nil, err
+	}
+	result := bf.Clone()
+	if err := result.Intersect(other); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// mergeChunkWords is how many words MergeAll ORs from every source before
+// moving on, keeping the destination chunk in cache across sources.
+const mergeChunkWords = 512
+
+// MergeAll ORs every source into dst. All sources are checked against dst
+// before any bits change, so an incompatible source leaves dst untouched;
+// the error names the source's index and the mismatch. Merging n sources
+// this way makes a single pass over dst instead of n.
+func MergeAll(dst *BloomFilter, srcs ...*BloomFilter) error {
+	for i, src := range srcs {
+		if err := dst.checkCompatible(src); err != nil {
+			return fmt.Errorf("source %d: %w", i, err)
+		}
+	}
+	dst.settle()
+	for _, src := range srcs {
+		src.settle()
+	}
+	for start := 0; start < dst.wordCount(); start += mergeChunkWords {
+		end := min(start+mergeChunkWords, dst.wordCount())
+		for _, src := range srcs {
+			for i := start; i < end; i++ {
+				dst.orWord(i, *src.word(uint64(i)))
+			}
+		}
+	}
+	for _, src := range srcs {
+		if src != dst {
+			dst.inserts += src.inserts
+		}
+	}
+	return nil
+}
+
+// Union returns a new filter holding the bits of both a and b, leaving the
+// inputs unchanged. See Merge. When one input is a Fold of a filter like
+// the other, the result has the smaller size.
+func Union(a, b *BloomFilter) (*BloomFilter, error) {
+	if b.foldsInto(a) {
+		a, b = b, a
+	}
+	if err := a.checkCompatible(b); err != nil && !a.foldsInto(b) {
+		return nil, err
+	}
+	result := a.Clone()
+	if err := result.Merge(b); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
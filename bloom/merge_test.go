@@ -0,0 +1,196 @@
+package bloom
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMerge_DisjointShards(t *testing.T) {
+	shards := make([]*BloomFilter, 4)
+	for s := range shards {
+		shards[s] = NewWithEstimates(4000, 0.01)
+		for i := s; i < 4000; i += len(shards) {
+			shards[s].Add([]byte("key-" + strconv.Itoa(i)))
+		}
+	}
+
+	global := NewSafeWithEstimates(4000, 0.01)
+	for _, shard := range shards {
+		if err := global.Merge(shard); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 4000; i++ {
+		if !global.MightContain([]byte("key-" + strconv.Itoa(i))) {
+			t.Fatalf("key-%d missing after merge", i)
+		}
+	}
+
+	direct := NewWithEstimates(4000, 0.01)
+	for i := 0; i < 4000; i++ {
+		direct.Add([]byte("key-" + strconv.Itoa(i)))
+	}
+	merged := global.Snapshot()
+	if onlyA, onlyB, _ := merged.Diff(direct); len(onlyA) != 0 || len(onlyB) != 0 {
+		t.Fatal("merged shards differ from a filter built directly")
+	}
+	if merged.setBits != direct.setBits || merged.inserts != 4000 {
+		t.Fatalf("merged counters: %d bits, %d inserts", merged.setBits, merged.inserts)
+	}
+}
+
+func TestMerge_NamesMismatch(t *testing.T) {
+	bf := New(1024, 3)
+	bf.Add([]byte("keep"))
+	guava := New(1024, 3)
+	guava.scheme = schemeGuava64
+	cases := map[string]*BloomFilter{
+		"m ":            New(1536, 3), // not a power-of-two multiple, so not foldable
+		"k ":            New(1024, 4),
+		"probe scheme ": guava,
+	}
+
+	for param, other := range cases {
+		err := bf.Merge(other)
+		if !errors.Is(err, ErrIncompatible) || !strings.Contains(err.Error(), param) {
+			t.Fatalf("expected ErrIncompatible naming %q, got %v", param, err)
+		}
+	}
+	if err := bf.Merge(nil); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("expected ErrUninitialized merging nil, got %v", err)
+	}
+	if bf.setBits != 3 || !bf.MightContain([]byte("keep")) {
+		t.Fatal("failed merges must leave the filter unchanged")
+	}
+}
+
+func TestIntersect_KeepsCommonKeys(t *testing.T) {
+	a := NewWithEstimates(2000, 0.01)
+	b := NewWithEstimates(2000, 0.01)
+	for i := 0; i < 1500; i++ {
+		a.Add([]byte("id-" + strconv.Itoa(i)))
+	}
+	for i := 500; i < 2000; i++ {
+		b.Add([]byte("id-" + strconv.Itoa(i)))
+	}
+	aBits, bBits := a.setBits, b.setBits
+
+	both, err := a.IntersectNew(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.setBits != aBits || b.setBits != bBits {
+		t.Fatal("IntersectNew modified its inputs")
+	}
+	for i := 500; i < 1500; i++ {
+		if !both.MightContain([]byte("id-" + strconv.Itoa(i))) {
+			t.Fatalf("id-%d is in both inputs but missing from the intersection", i)
+		}
+	}
+	if both.setBits > min(aBits, bBits) {
+		t.Fatalf("intersection has %d bits set, more than either input", both.setBits)
+	}
+
+	s := NewSafeWithEstimates(2000, 0.01)
+	if err := s.Merge(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Intersect(b); err != nil {
+		t.Fatal(err)
+	}
+	if onlyA, onlyB, _ := s.Snapshot().Diff(both); len(onlyA) != 0 || len(onlyB) != 0 {
+		t.Fatal("SafeBloom.Intersect disagrees with IntersectNew")
+	}
+
+	if _, err := a.IntersectNew(New(64, 3)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible, got %v", err)
+	}
+}
+
+func TestMergeAll_ManyPartials(t *testing.T) {
+	const partials, perPartial = 48, 100
+	srcs := make([]*BloomFilter, partials)
+	for p := range srcs {
+		srcs[p] = NewWithEstimates(partials*perPartial, 0.01)
+		for i := p * perPartial; i < (p+1)*perPartial; i++ {
+			srcs[p].Add([]byte("key-" + strconv.Itoa(i)))
+		}
+	}
+
+	dst := NewWithEstimates(partials*perPartial, 0.01)
+	if err := MergeAll(dst, srcs...); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < partials*perPartial; i++ {
+		if !dst.MightContain([]byte("key-" + strconv.Itoa(i))) {
+			t.Fatalf("key-%d missing after MergeAll", i)
+		}
+	}
+	if dst.inserts != partials*perPartial {
+		t.Fatalf("MergeAll counted %d inserts", dst.inserts)
+	}
+
+	pairwise, err := Union(srcs[0], srcs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pairwise.Merge(srcs[2]); err != nil {
+		t.Fatal(err)
+	}
+	three := NewWithEstimates(partials*perPartial, 0.01)
+	if err := MergeAll(three, srcs[:3]...); err != nil {
+		t.Fatal(err)
+	}
+	if !three.Equal(pairwise) {
+		t.Fatal("MergeAll disagrees with pairwise Union and Merge")
+	}
+	if srcs[0].inserts != perPartial || srcs[0].MightContain([]byte("key-150")) {
+		t.Fatal("Union modified its input")
+	}
+}
+
+func TestMergeAll_ValidatesFirst(t *testing.T) {
+	dst := New(1024, 3)
+	good := New(1024, 3)
+	good.Add([]byte("good"))
+	err := MergeAll(dst, good, good, New(1024, 5))
+	if !errors.Is(err, ErrIncompatible) || !strings.Contains(err.Error(), "source 2") {
+		t.Fatalf("expected ErrIncompatible naming source 2, got %v", err)
+	}
+	if dst.setBits != 0 {
+		t.Fatal("a rejected MergeAll must not modify dst")
+	}
+}
+
+func benchmarkPartials(n int) []*BloomFilter {
+	srcs := make([]*BloomFilter, n)
+	for p := range srcs {
+		srcs[p] = New(1<<24, 7)
+		for _, key := range benchmarkKeys(1000) {
+			srcs[p].Add(append([]byte{byte(p)}, key...))
+		}
+	}
+	return srcs
+}
+
+func BenchmarkMergeAll(b *testing.B) {
+	srcs := benchmarkPartials(64)
+	dst := New(1<<24, 7)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		MergeAll(dst, srcs...)
+	}
+}
+
+func BenchmarkMerge_Repeated(b *testing.B) {
+	srcs := benchmarkPartials(64)
+	dst := New(1<<24, 7)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, src := range srcs {
+			dst.Merge(src)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Package metrics publishes Bloom filter health to monitoring systems.
+//
+// Numbers are pulled from the filter's Stats() at read time, so publishing a
+// filter adds no work to its Add or MightContain paths.
+//
+// The Prometheus collector lives in the promcollector module so that this
+// package, like bloom itself, has no third-party dependencies.
+package metrics
+
+import (
+	"expvar"
+
+	"github.com/Abhisheklearn12/bloom-filter/bloom"
+)
+
+// Source is anything that can report filter statistics, such as
+// *bloom.BloomFilter or *bloom.SafeBloom.
+type Source interface {
+	Stats() bloom.Stats
+}
+
+// Snapshot is the subset of bloom.Stats exported as filter health metrics.
+type Snapshot struct {
+	FillRatio       float64 `json:"fill_ratio"`
+	EstimatedFPRate float64 `json:"estimated_fp_rate"`
+	Bits            uint64  `json:"bits"`
+	BitsSet         uint64  `json:"bits_set"`
+	Inserts         uint64  `json:"inserts"`
+	Saturated       bool    `json:"saturated"`
+}
+
+// Take reads src's statistics and returns the exported subset.
+func Take(src Source) Snapshot {
+	st := src.Stats()
+	return Snapshot{
+		FillRatio:       st.FillRatio,
+		EstimatedFPRate: st.EstimatedFPRate,
+		Bits:            st.M,
+		BitsSet:         st.BitsSet,
+		Inserts:         st.Inserts,
+		Saturated:       st.Saturated,
+	}
+}
+
+// Publish registers src with expvar under name, so /debug/vars reports its
+// current Snapshot on every read. Like expvar.Publish it panics if name is
+// already registered; publish each filter under its own name.
+//
+// A non-thread-safe *bloom.BloomFilter must not be published while other
+// goroutines write to it; publish a *bloom.SafeBloom instead.
+func Publish(name string, src Source) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return Take(src)
+	}))
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/Abhisheklearn12/bloom-filter/bloom"
+)
+
+func TestPublish(t *testing.T) {
+	a := bloom.NewSafe(1024, 3)
+	b := bloom.NewSafe(2048, 3)
+	Publish("test_filter_a", a)
+	Publish("test_filter_b", b)
+
+	a.Add([]byte("foo"))
+
+	// Values are pulled at read time, after the Add.
+	var snap Snapshot
+	if err := json.Unmarshal([]byte(expvar.Get("test_filter_a").String()), &snap); err != nil {
+		t.Fatal(err)
+	}
+	if snap.Bits != 1024 || snap.Inserts != 1 || snap.BitsSet == 0 || snap.FillRatio <= 0 {
+		t.Fatalf("unexpected snapshot for a: %+v", snap)
+	}
+
+	if err := json.Unmarshal([]byte(expvar.Get("test_filter_b").String()), &snap); err != nil {
+		t.Fatal(err)
+	}
+	if snap.Bits != 2048 || snap.Inserts != 0 {
+		t.Fatalf("unexpected snapshot for b: %+v", snap)
+	}
+}
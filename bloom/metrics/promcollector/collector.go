@@ -0,0 +1,93 @@
+// Package promcollector exposes Bloom filter health as Prometheus metrics.
+//
+// It is a separate module so that only services which opt in depend on the
+// Prometheus client library.
+package promcollector
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/Abhisheklearn12/bloom-filter/bloom/metrics"
+)
+
+// Collector implements prometheus.Collector for any number of named
+// filters. Stats are read from each filter at scrape time.
+type Collector struct {
+	mu      sync.RWMutex
+	sources map[string]metrics.Source
+
+	fillRatio *prometheus.Desc
+	fpRate    *prometheus.Desc
+	bits      *prometheus.Desc
+	bitsSet   *prometheus.Desc
+	inserts   *prometheus.Desc
+	saturated *prometheus.Desc
+}
+
+// New creates a Collector whose metric names are prefixed with namespace
+// (e.g. "myapp" yields myapp_bloom_fill_ratio). Every metric carries a
+// "filter" label holding the registered name.
+func New(namespace string) *Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "bloom", name), help, []string{"filter"}, nil)
+	}
+	return &Collector{
+		sources:   make(map[string]metrics.Source),
+		fillRatio: desc("fill_ratio", "Fraction of filter bits that are set."),
+		fpRate:    desc("estimated_false_positive_rate", "False positive rate estimated from the current fill."),
+		bits:      desc("bits", "Number of bits (m) in the filter."),
+		bitsSet:   desc("bits_set", "Number of bits currently set."),
+		inserts:   desc("insertions_total", "Number of Add calls since construction or reset."),
+		saturated: desc("saturated", "1 if the filter is over capacity or past its fill threshold."),
+	}
+}
+
+// Register adds a filter under name. It returns an error if name is taken.
+func (c *Collector) Register(name string, src metrics.Source) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.sources[name]; ok {
+		return fmt.Errorf("promcollector: filter %q already registered", name)
+	}
+	c.sources[name] = src
+	return nil
+}
+
+// Unregister removes the filter registered under name, if any.
+func (c *Collector) Unregister(name string) {
+	c.mu.Lock()
+	delete(c.sources, name)
+	c.mu.Unlock()
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.fillRatio
+	ch <- c.fpRate
+	ch <- c.bits
+	ch <- c.bitsSet
+	ch <- c.inserts
+	ch <- c.saturated
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for name, src := range c.sources {
+		snap := metrics.Take(src)
+		saturated := 0.0
+		if snap.Saturated {
+			saturated = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.fillRatio, prometheus.GaugeValue, snap.FillRatio, name)
+		ch <- prometheus.MustNewConstMetric(c.fpRate, prometheus.GaugeValue, snap.EstimatedFPRate, name)
+		ch <- prometheus.MustNewConstMetric(c.bits, prometheus.GaugeValue, float64(snap.Bits), name)
+		ch <- prometheus.MustNewConstMetric(c.bitsSet, prometheus.GaugeValue, float64(snap.BitsSet), name)
+		ch <- prometheus.MustNewConstMetric(c.inserts, prometheus.CounterValue, float64(snap.Inserts), name)
+		ch <- prometheus.MustNewConstMetric(c.saturated, prometheus.GaugeValue, saturated, name)
+	}
+}
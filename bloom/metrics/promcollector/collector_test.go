@@ -0,0 +1,67 @@
+package promcollector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/Abhisheklearn12/bloom-filter/bloom"
+)
+
+func TestCollector(t *testing.T) {
+	users := bloom.NewSafe(64, 1)
+	orders := bloom.NewSafe(128, 1)
+
+	c := New("test")
+	if err := c.Register("users", users); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Register("orders", orders); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Register("users", users); err == nil {
+		t.Fatal("expected error registering a duplicate name")
+	}
+
+	users.Add([]byte("alice"))
+
+	want := `
+# HELP test_bloom_bits Number of bits (m) in the filter.
+# TYPE test_bloom_bits gauge
+test_bloom_bits{filter="orders"} 128
+test_bloom_bits{filter="users"} 64
+# HELP test_bloom_bits_set Number of bits currently set.
+# TYPE test_bloom_bits_set gauge
+test_bloom_bits_set{filter="orders"} 0
+test_bloom_bits_set{filter="users"} 1
+# HELP test_bloom_insertions_total Number of Add calls since construction or reset.
+# TYPE test_bloom_insertions_total counter
+test_bloom_insertions_total{filter="orders"} 0
+test_bloom_insertions_total{filter="users"} 1
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(want),
+		"test_bloom_bits", "test_bloom_bits_set", "test_bloom_insertions_total")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP test_bloom_fill_ratio Fraction of filter bits that are set.
+# TYPE test_bloom_fill_ratio gauge
+test_bloom_fill_ratio{filter="orders"} 0
+test_bloom_fill_ratio{filter="users"} 0.015625
+`), "test_bloom_fill_ratio"); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Unregister("orders")
+	if n := testutil.CollectAndCount(c, "test_bloom_bits"); n != 1 {
+		t.Fatalf("expected 1 series after Unregister, got %d", n)
+	}
+
+	if err := prometheus.NewPedanticRegistry().Register(c); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,160 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"unsafe"
+)
+
+// Mapped file layout:
+//
+//	magic    [4]byte "BLMM"
+//	header           the binary encoding header (see MarshalBinary)
+//	padding          zeros up to mmapDataOffset
+//	bits     words * uint64, host byte order (little-endian only)
+//
+// The words start on an 8-byte boundary so they can be used in place.
+// New files get a version 5 header, the newest that fits before the
+// words; mapped filters are always unseeded, so nothing is lost.
+const (
+	mmapDataOffset    = 64
+	mmapHeaderVersion = 5
+)
+
+var mmapMagic = [4]byte{'B', 'L', 'M', 'M'}
+
+// ErrMmapUnsupported is returned by NewMmap on platforms without mmap
+// support, or on big-endian hosts where the mapped words would not match
+// the little-endian file format.
+var ErrMmapUnsupported = errors.New("bloom: mmap not supported on this platform")
+
+// mapping is the file and memory region behind a mapped filter.
+type mapping struct {
+	file *os.File
+	data []byte
+}
+
+// NewMmap opens or creates a filter whose bitset lives in the file at
+// path, mapped into memory instead of allocated on the heap, so filters
+// larger than RAM are paged in and out by the kernel. A new file is
+// created at its full size up front; an existing one must have been
+// created with the same m and k, or ErrIncompatible is returned.
+// Reopening counts the set bits, which reads the whole file once.
+//
+// Add and MightContain work unchanged. Call Flush to write changes to
+// disk and Close to release the mapping; the filter is uninitialized
+// after Close.
+func NewMmap(path string, m, k uint64) (*BloomFilter, error) {
+	if m == 0 {
+		return nil, fmt.Errorf("%w: m must be > 0", ErrCorrupt)
+	}
+	if k == 0 {
+		return nil, fmt.Errorf("%w: k must be > 0", ErrCorrupt)
+	}
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		return nil, ErrMmapUnsupported
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	bf, err := mapFilter(f, m, k)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return bf, nil
+}
+
+func mapFilter(f *os.File, m, k uint64) (*BloomFilter, error) {
+	want := header{version: mmapHeaderVersion, m: m, k: k, words: wordsFor(m)}
+	size := int64(mmapDataOffset + want.words*8)
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	fresh := info.Size() == 0
+	if fresh {
+		if err := f.Truncate(size); err != nil {
+			return nil, err
+		}
+	} else if info.Size() != size {
+		return nil, fmt.Errorf("%w: file is %d bytes, want %d for m=%d", ErrIncompatible, info.Size(), size, m)
+	}
+
+	data, err := mmapFile(f, int(size))
+	if err != nil {
+		return nil, err
+	}
+	if fresh {
+		copy(data, mmapMagic[:])
+		copy(data[len(mmapMagic):], want.append(nil))
+	} else if err := checkMmapHeader(data, want); err != nil {
+		munmapFile(data)
+		return nil, err
+	}
+
+	words := unsafe.Slice((*uint64)(unsafe.Pointer(&data[mmapDataOffset])), want.words)
+	return &BloomFilter{
+		m:       m,
+		k:       k,
+		storage: contiguous(words),
+		mapped:  &mapping{file: f, data: data},
+		setBits: popcount(words),
+	}, nil
+}
+
+func checkMmapHeader(data []byte, want header) error {
+	if string(data[:len(mmapMagic)]) != string(mmapMagic[:]) {
+		return fmt.Errorf("%w: bad magic number", ErrCorrupt)
+	}
+	h, err := parseHeader(data[len(mmapMagic):mmapDataOffset])
+	if err != nil {
+		return err
+	}
+	if h.m != want.m || h.k != want.k {
+		return fmt.Errorf("%w: file has m=%d k=%d, want m=%d k=%d", ErrIncompatible, h.m, h.k, want.m, want.k)
+	}
+	if h.scheme != schemeFNV {
+		return fmt.Errorf("%w: file uses probe scheme %s", ErrIncompatible, h.scheme)
+	}
+	if h.hasher != 0 {
+		return fmt.Errorf("%w: file uses hasher %#x", ErrIncompatible, h.hasher)
+	}
+	if h.seed != 0 {
+		return fmt.Errorf("%w: file uses a hash seed", ErrIncompatible)
+	}
+	return nil
+}
+
+// Flush writes changes to a memory-mapped filter back to its file. It is a
+// no-op for heap-backed filters.
+func (bf *BloomFilter) Flush() error {
+	if bf == nil || bf.mapped == nil {
+		return nil
+	}
+	return msyncFile(bf.mapped.data)
+}
+
+// Close flushes and unmaps a memory-mapped filter and closes its file,
+// leaving bf uninitialized. It is a no-op for heap-backed filters.
+func (bf *BloomFilter) Close() error {
+	if bf == nil || bf.mapped == nil {
+		return nil
+	}
+	mp := bf.mapped
+	*bf = BloomFilter{}
+
+	err := msyncFile(mp.data)
+	if uerr := munmapFile(mp.data); err == nil {
+		err = uerr
+	}
+	if cerr := mp.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd)
+
+package bloom
+
+import "os"
+
+func mmapFile(*os.File, int) ([]byte, error) { return nil, ErrMmapUnsupported }
+
+func munmapFile([]byte) error { return ErrMmapUnsupported }
+
+func msyncFile([]byte) error { return ErrMmapUnsupported }
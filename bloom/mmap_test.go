@@ -0,0 +1,73 @@
+//go:build linux || darwin || freebsd
+
+package bloom
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestMmap_ReopenKeepsBits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.mmap")
+	bf, err := NewMmap(path, 100_000, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		bf.Add([]byte(strconv.Itoa(i)))
+	}
+	if err := bf.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	setBits := bf.setBits
+	if err := bf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if bf.MightContain([]byte("0")) {
+		t.Fatal("closed filter should be empty")
+	}
+
+	reopened, err := NewMmap(path, 100_000, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	for i := 0; i < 1000; i++ {
+		if !reopened.MightContain([]byte(strconv.Itoa(i))) {
+			t.Fatalf("false negative for %d after reopen", i)
+		}
+	}
+	if reopened.setBits != setBits {
+		t.Fatalf("set-bit count %d after reopen, want %d", reopened.setBits, setBits)
+	}
+
+	heap := New(100_000, 4)
+	for i := 0; i < 1000; i++ {
+		heap.Add([]byte(strconv.Itoa(i)))
+	}
+	onlyMapped, onlyHeap, err := reopened.Diff(heap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(onlyMapped) != 0 || len(onlyHeap) != 0 {
+		t.Fatalf("mapped and heap filters differ: %d vs %d bits", len(onlyMapped), len(onlyHeap))
+	}
+}
+
+func TestMmap_RejectsMismatchedParameters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.mmap")
+	bf, err := NewMmap(path, 1024, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf.Close()
+
+	if _, err := NewMmap(path, 2048, 3); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible for a different m, got %v", err)
+	}
+	if _, err := NewMmap(path, 1024, 5); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible for a different k, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Murmur3Hasher is a Hasher using MurmurHash3_x64_128 with the given seed,
+// taking the digest's two 64-bit halves as h1 and h2. Filters built with it
+// set the same bits as other implementations that double hash the
+// reference murmur3 digest, such as Guava's Hashing.murmur3_128(seed) or
+// Python's mmh3.hash64 read as unsigned, so together with NewFromParts they
+// can query filters produced in those languages.
+//
+// Murmur3Hasher{} (seed 0) is registered as "murmur3-128". To serialize a
+// filter using another seed, register that value under its own name first,
+// e.g. RegisterHasher("murmur3-128-seed42", Murmur3Hasher{Seed: 42}).
+type Murmur3Hasher struct {
+	Seed uint32
+}
+
+// Hash128 implements Hasher.
+func (h Murmur3Hasher) Hash128(data []byte) (uint64, uint64) {
+	return murmur3x64_128(data, h.Seed)
+}
+
+// exactH2 keeps an h2 of 0, as for the empty key under seed 0: the other
+// implementations use it as is.
+func (Murmur3Hasher) exactH2() {}
+
+// murmur3x64_128 is MurmurHash3_x64_128 (Austin Appleby), returning the two
+// 64-bit halves of the digest. It matches the reference implementation and
+// Guava's Hashing.murmur3_128(seed).
+func murmur3x64_128(data []byte, seed uint32) (uint64, uint64) {
+	return murmur3x64(data, seed, false)
+}
+
+// cassandraMurmur3 is Cassandra's MurmurHash.hash3_x64_128, which differs
+// from the reference only in sign-extending tail bytes >= 0x80 (Java reads
+// them as signed bytes).
+func cassandraMurmur3(data []byte) (uint64, uint64) {
+	return murmur3x64(data, 0, true)
+}
+
+func murmur3x64(data []byte, seed uint32, signedTail bool) (uint64, uint64) {
+	const (
+		c1 = 0x87c37b91114253d5
+		c2 = 0x4cf5ad432745937f
+	)
+	h1, h2 := uint64(seed), uint64(seed)
+	length := uint64(len(data))
+
+	for len(data) >= 16 {
+		k1 := binary.LittleEndian.Uint64(data)
+		k2 := binary.LittleEndian.Uint64(data[8:])
+		data = data[16:]
+
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	tail := func(b byte) uint64 {
+		if signedTail {
+			return uint64(int8(b))
+		}
+		return uint64(b)
+	}
+	var k1, k2 uint64
+	switch len(data) {
+	case 15:
+		k2 ^= tail(data[14]) << 48
+		fallthrough
+	case 14:
+		k2 ^= tail(data[13]) << 40
+		fallthrough
+	case 13:
+		k2 ^= tail(data[12]) << 32
+		fallthrough
+	case 12:
+		k2 ^= tail(data[11]) << 24
+		fallthrough
+	case 11:
+		k2 ^= tail(data[10]) << 16
+		fallthrough
+	case 10:
+		k2 ^= tail(data[9]) << 8
+		fallthrough
+	case 9:
+		k2 ^= tail(data[8])
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+		fallthrough
+	case 8:
+		k1 ^= tail(data[7]) << 56
+		fallthrough
+	case 7:
+		k1 ^= tail(data[6]) << 48
+		fallthrough
+	case 6:
+		k1 ^= tail(data[5]) << 40
+		fallthrough
+	case 5:
+		k1 ^= tail(data[4]) << 32
+		fallthrough
+	case 4:
+		k1 ^= tail(data[3]) << 24
+		fallthrough
+	case 3:
+		k1 ^= tail(data[2]) << 16
+		fallthrough
+	case 2:
+		k1 ^= tail(data[1]) << 8
+		fallthrough
+	case 1:
+		k1 ^= tail(data[0])
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+	}
+
+	h1 ^= length
+	h2 ^= length
+	h1 += h2
+	h2 += h1
+	h1 = mix64(h1)
+	h2 = mix64(h2)
+	h1 += h2
+	h2 += h1
+	return h1, h2
+}
+
+// mix64 is the murmur3 64-bit finalizer (fmix64).
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,149 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// Vectors from Guava's Murmur3Hash128Test, which match the reference
+// MurmurHash3_x64_128.
+func TestMurmur3x64_128(t *testing.T) {
+	cases := []struct {
+		seed   uint32
+		h1, h2 uint64
+		input  string
+	}{
+		{0, 0x629942693e10f867, 0x92db0b82baeb5347, "hell"},
+		{1, 0xa78ddff5adae8d10, 0x128900ef20900135, "hello"},
+		{2, 0x8a486b23f422e826, 0xf962a2c58947765f, "hello "},
+		{3, 0x2ea59f466f6bed8c, 0xc610990acc428a17, "hello w"},
+		{4, 0x79f6305a386c572c, 0x46305aed3483b94e, "hello wo"},
+		{5, 0xc2219d213ec1f1b5, 0xa1d8e2e0a52785bd, "hello wor"},
+		{0, 0xe34bbc7bbc071b6c, 0x7a433ca9c49a9347, "The quick brown fox jumps over the lazy dog"},
+		{0, 0x658ca970ff85269a, 0x43fee3eaa68e5c3e, "The quick brown fox jumps over the lazy cog"},
+		{0, 0, 0, ""},
+	}
+	for _, c := range cases {
+		h1, h2 := murmur3x64_128([]byte(c.input), c.seed)
+		if h1 != c.h1 || h2 != c.h2 {
+			t.Errorf("murmur3(%q, %d) = %#x %#x, want %#x %#x", c.input, c.seed, h1, h2, c.h1, c.h2)
+		}
+	}
+}
+
+func TestMurmur3Hasher(t *testing.T) {
+	// Seed 42 values from testdata/murmur3/murmur3_vectors.py, an
+	// implementation independent of this package.
+	cases := []struct {
+		input  string
+		h1, h2 uint64
+	}{
+		{"", 0xf02aa77dfa1b8523, 0xd1016610da11cbb9},
+		{"hello", 0xc4b8b3c960af6f08, 0x2334b875b0efbc7a},
+		{"The quick brown fox jumps over the lazy dog", 0x740dcf93fe0bd5d7, 0xc4546cf4ec705c8f},
+	}
+	for _, c := range cases {
+		h1, h2 := Murmur3Hasher{Seed: 42}.Hash128([]byte(c.input))
+		if h1 != c.h1 || h2 != c.h2 {
+			t.Errorf("Murmur3Hasher{42}(%q) = %#x %#x, want %#x %#x", c.input, h1, h2, c.h1, c.h2)
+		}
+	}
+}
+
+// testdata/murmur3_seed42_m959_k7.bin holds the little-endian bit words
+// of a filter with m=959, k=7 built by testdata/murmur3/Murmur3Vectors.java
+// (bits (h1 + i*h2) mod m of Guava's murmur3_128(42)) after adding "key-0"
+// through "key-99". Of "key-100" through "key-10099", exactly 108 are false
+// positives. The checked-in file was written by the script's Python port,
+// murmur3_vectors.py, which produces identical output.
+const murmur3Golden = "testdata/murmur3_seed42_m959_k7.bin"
+
+func TestMurmur3Hasher_Golden(t *testing.T) {
+	golden, err := os.ReadFile(murmur3Golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := make([]uint64, len(golden)/8)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint64(golden[i*8:])
+	}
+	bf, err := NewFromParts(959, 7, words, WithHasher(Murmur3Hasher{Seed: 42}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if !bf.MightContain([]byte("key-" + strconv.Itoa(i))) {
+			t.Fatalf("false negative for key-%d", i)
+		}
+	}
+	falsePositives := 0
+	for i := 100; i < 10100; i++ {
+		if bf.MightContain([]byte("key-" + strconv.Itoa(i))) {
+			falsePositives++
+		}
+	}
+	if falsePositives != 108 {
+		t.Fatalf("%d false positives, want 108 as reported by the producer", falsePositives)
+	}
+
+	built, _ := NewWithOptions(0, 0, WithExplicitSize(959, 7), WithHasher(Murmur3Hasher{Seed: 42}))
+	for i := 0; i < 100; i++ {
+		built.Add([]byte("key-" + strconv.Itoa(i)))
+	}
+	if !built.Equal(bf) {
+		t.Fatal("Go-built filter differs from the golden bits")
+	}
+
+	// Seed 42 is not registered, so the filter cannot be persisted until
+	// it is; seed 0 is registered out of the box.
+	if _, err := bf.MarshalBinary(); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("MarshalBinary with unregistered seed: %v", err)
+	}
+	zero, _ := NewWithOptions(100, 0.01, WithHasher(Murmur3Hasher{}))
+	if zero.Hasher() != "murmur3-128" {
+		t.Fatalf("Hasher() = %q, want murmur3-128", zero.Hasher())
+	}
+	data, err := zero.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got BloomFilter
+	if err := got.UnmarshalBinary(data); err != nil || got.Hasher() != "murmur3-128" {
+		t.Fatalf("round trip: %v, hasher %q", err, got.Hasher())
+	}
+	other, _ := NewWithOptions(0, 0, WithExplicitSize(959, 7), WithHasher(Murmur3Hasher{Seed: 7}))
+	if err := bf.Merge(other); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("merging different murmur3 seeds: %v", err)
+	}
+}
+
+// TestMurmur3Hasher_EmptyKey checks the vector printed by
+// murmur3_vectors.py for the empty key under seed 0: h1 = h2 = 0, so Guava
+// and mmh3 put every probe on bit 0. The h2 fix-up other hashers get would
+// move them.
+func TestMurmur3Hasher_EmptyKey(t *testing.T) {
+	bf, _ := NewWithOptions(0, 0, WithExplicitSize(959, 7), WithHasher(Murmur3Hasher{}))
+	bf.Add(nil)
+	if got := bf.SetBitPositions(); len(got) != 1 || got[0] != 0 {
+		t.Fatalf("empty key set bits %v, want [0]", got)
+	}
+	if d := bf.Hash(nil); d != (Digest{}) || !bf.ContainsHash(d) {
+		t.Fatalf("Hash(empty) = %+v", d)
+	}
+	other := bf.Clone()
+	other.Reset()
+	other.AddHash(Digest{})
+	if !other.Equal(bf) {
+		t.Fatal("AddHash of the empty key's digest differs from Add")
+	}
+
+	// Other hashers keep the fix-up.
+	id, _ := NewWithOptions(0, 0, WithExplicitSize(959, 7), WithHasher(IdentityHasher{}))
+	id.AddHash(Digest{})
+	if id.setBits < 2 {
+		t.Fatalf("zero digest set %d bits under IdentityHasher", id.setBits)
+	}
+}
@@ -0,0 +1,257 @@
+package bloom
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrConflictingOptions is returned by NewWithOptions when options
+	// contradict each other or the sizing arguments.
+	ErrConflictingOptions = errors.New("bloom: conflicting options")
+
+	// ErrInvalidOption is returned by NewWithOptions for an option whose
+	// value is out of range.
+	ErrInvalidOption = errors.New("bloom: invalid option")
+)
+
+// Option configures a filter built by NewWithOptions or NewSafeWithOptions.
+type Option func(*options) error
+
+// options collects the settings of a NewWithOptions call before any of
+// them is validated against the others.
+type options struct {
+	m, k       uint64 // explicit size; 0 = derive from n and fpRate
+	scheme     scheme
+	schemeSet  bool
+	threshold  float64 // 0 = DefaultSaturationThreshold
+	hasher     Hasher  // nil = FNVHasher
+	seed       uint64  // 0 = unseeded, unless randomSeed
+	randomSeed bool
+	chunkShift uint // 0 = contiguous unless very large (see chunked.go)
+	lazyReset  bool
+
+	onOverfill func(count, capacity uint64)
+}
+
+// WithExplicitSize sets the no. of bits (m) and hash functions (k) instead
+// of deriving them from n and fpRate, which must then be 0; a non-zero n is
+// still recorded as the designed capacity. By default the size comes from
+// EstimateParameters(n, fpRate).
+func WithExplicitSize(m, k uint64) Option {
+	return func(o *options) error {
+		if o.m != 0 || o.k != 0 {
+			return fmt.Errorf("%w: WithExplicitSize given twice", ErrConflictingOptions)
+		}
+		switch {
+		case m == 0:
+			return ErrZeroBits
+		case k == 0:
+			return ErrZeroHashes
+		case m > maxBits:
+			return fmt.Errorf("%w: m=%d bits", ErrFilterTooLarge, m)
+		}
+		o.m, o.k = m, k
+		return nil
+	}
+}
+
+// WithSaturationThreshold sets the fill ratio at which IsSaturated reports
+// true, as SetSaturationThreshold does; ratio must be in (0, 1]. The
+// default is DefaultSaturationThreshold.
+func WithSaturationThreshold(ratio float64) Option {
+	return func(o *options) error {
+		if !(ratio > 0 && ratio <= 1) {
+			return fmt.Errorf("%w: saturation threshold %v is not in (0, 1]", ErrInvalidOption, ratio)
+		}
+		if o.threshold != 0 {
+			return fmt.Errorf("%w: WithSaturationThreshold given twice", ErrConflictingOptions)
+		}
+		o.threshold = ratio
+		return nil
+	}
+}
+
+// WithOverfillCallback registers fn to be called once, from the Add (or
+// Merge, ApplyDelta, ParallelAddAll) that first takes the insert count past
+// the designed capacity n, with the count and capacity at that moment. It
+// fires again only after Reset or Rebuild. fn runs synchronously on the
+// inserting goroutine; under a SafeBloom it runs with the write lock held
+// and must not call back into the SafeBloom. The callback is not
+// serialized.
+//
+// It requires a non-zero n, since a filter without a capacity can never
+// overfill.
+func WithOverfillCallback(fn func(count, capacity uint64)) Option {
+	return func(o *options) error {
+		if fn == nil {
+			return fmt.Errorf("%w: nil overfill callback", ErrInvalidOption)
+		}
+		if o.onOverfill != nil {
+			return fmt.Errorf("%w: WithOverfillCallback given twice", ErrConflictingOptions)
+		}
+		o.onOverfill = fn
+		return nil
+	}
+}
+
+// WithHasher sets the Hasher that derives probe positions from keys; the
+// default is FNVHasher. Register h with RegisterHasher first if the filter
+// is to be serialized: encodings record the hasher's registered name, and
+// encoding a filter whose hasher is not registered fails with
+// ErrUnsupportedFormat. Filters with different hashers cannot be merged or
+// compared. A hasher that cannot work, such as a zero MaphashHasher or a
+// non-deterministic HasherFromHash64, fails with ErrInvalidOption. It
+// conflicts with the foreign-format constructors, whose hashing is fixed.
+func WithHasher(h Hasher) Option {
+	return func(o *options) error {
+		if h == nil {
+			return fmt.Errorf("%w: nil hasher", ErrInvalidOption)
+		}
+		if v, ok := h.(validatingHasher); ok {
+			if err := v.validate(); err != nil {
+				return err
+			}
+		}
+		if o.hasher != nil {
+			return fmt.Errorf("%w: WithHasher given twice", ErrConflictingOptions)
+		}
+		o.hasher = h
+		return nil
+	}
+}
+
+// WithEnhancedDoubleHashing derives probe positions by enhanced double
+// hashing, (h1 + i*h2 + (i^3-i)/6) mod m, instead of the default
+// (h1 + i*h2) mod m. Plain double hashing makes the k probes of two keys
+// coincide more often than independent hashes would whenever their h1 and
+// h2 agree modulo a factor of m, which pushes the false positive rate above
+// theory, most visibly at k >= 8; the cubic term breaks that correlation
+// (Dillinger and Manolios, "Bloom Filters in Probabilistic Verification").
+// The scheme is recorded in encodings as "fnv-enhanced" and works with
+// WithHasher and WithSeed. Filters using it cannot be combined with
+// default filters, and releases before it cannot decode them.
+func WithEnhancedDoubleHashing() Option {
+	return withScheme(schemeEnhanced)
+}
+
+// WithSplit64Hashing derives probe positions the way C implementations
+// that compute a single 64-bit hash per key do: g1 is the low 32 bits of
+// h1, g2 its high 32 bits forced odd, and position i is (g1 + i*g2) mod m.
+// Only h1 of the filter's Hasher is used, so with the default hashing the
+// filter sets exactly the bits of such a service hashing with 64-bit
+// FNV-1a, and WithHasher(XXHasher{}) matches one using XXH64 with seed 0.
+// Two independent 32-bit halves give fewer distinct probe sequences than
+// the default, so prefer it only for interoperability. The scheme is
+// recorded in encodings as "fnv-split64" and works with WithHasher and
+// WithSeed; filters using it cannot be combined with filters of any other
+// scheme, and releases before it cannot decode them.
+func WithSplit64Hashing() Option {
+	return withScheme(schemeSplit64)
+}
+
+// WithFastRange reduces the default double hashing's h1 + i*h2 to a bit
+// position by multiply-shift, (h * m) >> 64, instead of h mod m. A 64-bit
+// division costs tens of cycles and runs k times per Add and MightContain,
+// so this makes both measurably faster at the same false positive rate;
+// positions stay uniform for any m. It moves every bit, so the scheme is
+// recorded in encodings as "fnv-fastrange" and filters using it cannot be
+// combined with filters of any other scheme. Filters encoded without it
+// keep the modulo reduction when decoded, and releases before it cannot
+// decode filters built with it. It works with WithHasher and WithSeed.
+func WithFastRange() Option {
+	return withScheme(schemeFastRange)
+}
+
+// withScheme selects the probe scheme; the default is schemeFNV. It backs
+// the constructors for foreign formats.
+func withScheme(s scheme) Option {
+	return func(o *options) error {
+		if o.schemeSet && o.scheme != s {
+			return fmt.Errorf("%w: probe schemes %s and %s", ErrConflictingOptions, o.scheme, s)
+		}
+		o.scheme, o.schemeSet = s, true
+		return nil
+	}
+}
+
+// NewWithOptions creates a filter for n expected insertions at false
+// positive rate fpRate, adjusted by opts. All options are applied and
+// checked together before anything is allocated: a conflict fails with
+// ErrConflictingOptions, a bad option value with ErrInvalidOption or the
+// TryNew error it corresponds to, and bad n or fpRate with the errors of
+// TryNewWithEstimates.
+//
+// Every other BloomFilter constructor is a shorthand for a call to it.
+func NewWithOptions(n uint64, fpRate float64, opts ...Option) (*BloomFilter, error) {
+	var o options
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.onOverfill != nil && n == 0 {
+		return nil, fmt.Errorf("%w: WithOverfillCallback needs a capacity n > 0", ErrConflictingOptions)
+	}
+
+	var hc *hasherConfig
+	if o.hasher != nil {
+		if !o.scheme.native() {
+			return nil, fmt.Errorf("%w: WithHasher and probe scheme %s", ErrConflictingOptions, o.scheme)
+		}
+		hc = configForHasher(o.hasher)
+	}
+
+	seed := o.seed
+	if seed != 0 || o.randomSeed {
+		if !o.scheme.native() || o.hasher != nil {
+			return nil, fmt.Errorf("%w: seeds apply only to the default hashing", ErrConflictingOptions)
+		}
+		if o.randomSeed {
+			var err error
+			if seed, err = randomSeed(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	m, k := o.m, o.k
+	if m != 0 {
+		if fpRate != 0 {
+			return nil, fmt.Errorf("%w: WithExplicitSize and fpRate %v; pass fpRate 0 with an explicit size", ErrConflictingOptions, fpRate)
+		}
+	} else {
+		var err error
+		if m, k, err = validEstimates(n, fpRate); err != nil {
+			return nil, err
+		}
+	}
+
+	bf := &BloomFilter{
+		m:          m,
+		k:          k,
+		storage:    newStorage(int(wordsFor(m)), o.chunkShift),
+		scheme:     o.scheme,
+		hasher:     hc,
+		seed:       seed,
+		capacity:   n,
+		threshold:  o.threshold,
+		onOverfill: o.onOverfill,
+	}
+	bf.setLazy(o.lazyReset)
+	return bf, nil
+}
+
+// NewSafeWithOptions is NewWithOptions returning the filter wrapped in a
+// SafeBloom. WithLazyReset fails with ErrInvalidOption.
+func NewSafeWithOptions(n uint64, fpRate float64, opts ...Option) (*SafeBloom, error) {
+	bf, err := NewWithOptions(n, fpRate, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := rejectLazy(bf, "SafeBloom"); err != nil {
+		return nil, err
+	}
+	return &SafeBloom{bf: bf}, nil
+}
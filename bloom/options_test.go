@@ -0,0 +1,60 @@
+package bloom
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	bf, err := NewWithOptions(1000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := NewWithEstimates(1000, 0.01); bf.Params() != want.Params() || bf.Stats() != want.Stats() {
+		t.Fatalf("defaults give %s, want %s", bf.Info(), want.Info())
+	}
+
+	bf, err = NewWithOptions(500, 0, WithExplicitSize(4096, 3), WithSaturationThreshold(0.25))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bf.m != 4096 || bf.k != 3 || bf.capacity != 500 || bf.saturationThreshold() != 0.25 {
+		t.Fatalf("options not applied: %s, capacity %d, threshold %v", bf.Info(), bf.capacity, bf.threshold)
+	}
+	if want := New(4096, 3); bf.Params() != want.Params() {
+		t.Fatalf("explicit size gives %+v, New gives %+v", bf.Params(), want.Params())
+	}
+
+	s, err := NewSafeWithOptions(0, 0, WithExplicitSize(64, 2))
+	if err != nil || s.Params().M != 64 {
+		t.Fatalf("NewSafeWithOptions: %v", err)
+	}
+}
+
+func TestNewWithOptions_Conflicts(t *testing.T) {
+	cases := []struct {
+		name   string
+		n      uint64
+		fpRate float64
+		opts   []Option
+		want   error
+	}{
+		{"explicit size and fpRate", 1000, 0.01, []Option{WithExplicitSize(1024, 3)}, ErrConflictingOptions},
+		{"explicit size twice", 0, 0, []Option{WithExplicitSize(1024, 3), WithExplicitSize(2048, 3)}, ErrConflictingOptions},
+		{"threshold twice", 1000, 0.01, []Option{WithSaturationThreshold(0.5), WithSaturationThreshold(0.6)}, ErrConflictingOptions},
+		{"two schemes", 1000, 0.01, []Option{withScheme(schemeGuava64), withScheme(schemeCassandra)}, ErrConflictingOptions},
+		{"threshold out of range", 1000, 0.01, []Option{WithSaturationThreshold(1.5)}, ErrInvalidOption},
+		{"zero m", 0, 0, []Option{WithExplicitSize(0, 3)}, ErrZeroBits},
+		{"zero k", 0, 0, []Option{WithExplicitSize(64, 0)}, ErrZeroHashes},
+		{"no size", 0, 0, nil, ErrZeroInsertions},
+		{"bad fpRate", 1000, 2, nil, ErrInvalidFPRate},
+	}
+	for _, c := range cases {
+		if _, err := NewWithOptions(c.n, c.fpRate, c.opts...); !errors.Is(err, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, err)
+		}
+		if _, err := NewSafeWithOptions(c.n, c.fpRate, c.opts...); !errors.Is(err, c.want) {
+			t.Errorf("%s (safe): expected %v, got %v", c.name, c.want, err)
+		}
+	}
+}
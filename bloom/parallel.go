@@ -0,0 +1,246 @@
+package bloom
+
+import (
+	"context"
+	"iter"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// parallelBatch is the number of keys a worker collects before applying
+// them, so SafeBloom takes its lock once per batch rather than per key.
+const parallelBatch = 256
+
+// ParallelAddAll drains keys on `workers` goroutines, hashing concurrently
+// and setting bits with atomic OR operations. workers <= 0 means
+// runtime.GOMAXPROCS(0). It returns the number of keys added, and ctx.Err()
+// if ctx was cancelled before keys was closed.
+//
+// The filter must not be read or written by anything else until
+// ParallelAddAll returns; use SafeBloom.ParallelAddAll to load while serving
+// queries.
+func (bf *BloomFilter) ParallelAddAll(ctx context.Context, keys <-chan []byte, workers int) (uint64, error) {
+	if !bf.initialized() {
+		return 0, ErrUninitialized
+	}
+	bf.settle()
+	n, err := parallelLoad(ctx, keys, workers, func(batch [][]byte) {
+		for _, key := range batch {
+			bf.addAtomic(key)
+		}
+	})
+	bf.checkOverfill()
+	return n, err
+}
+
+// addAtomic is Add for use by concurrent loaders: bits, the set-bit count,
+// the insert count and dirty tracking are all updated atomically.
+func (bf *BloomFilter) addAtomic(data []byte) {
+	h := bf.hashes(data)
+	for i := uint64(0); i < bf.k; i++ {
+		pos := bf.location(h, i)
+		mask := uint64(1) << (pos % 64)
+		if atomic.OrUint64(bf.word(pos/64), mask)&mask == 0 {
+			atomic.AddUint64(&bf.setBits, 1)
+			bf.markDirtyAtomic(pos / 64)
+		}
+	}
+	atomic.AddUint64(&bf.inserts, 1)
+}
+
+// parallelLoad runs workers that read keys in batches and hand each batch to
+// apply. It returns the number of keys applied.
+func parallelLoad(ctx context.Context, keys <-chan []byte, workers int, apply func(batch [][]byte)) (uint64, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		added atomic.Uint64
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			batch := make([][]byte, 0, parallelBatch)
+			flush := func() {
+				if len(batch) > 0 {
+					apply(batch)
+					added.Add(uint64(len(batch)))
+					batch = batch[:0]
+				}
+			}
+			defer flush()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case key, ok := <-keys:
+					if !ok {
+						return
+					}
+					batch = append(batch, key)
+					if len(batch) == parallelBatch {
+						flush()
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return added.Load(), ctx.Err()
+}
+
+// ParallelAddSeq is ParallelAddAll for keys produced by an iterator, such
+// as Lines over a file. The iterator runs on the calling goroutine and may
+// reuse its key buffers: keys are copied into batches that the workers
+// hash and apply concurrently. The first error the iterator yields stops
+// the load and is returned; cancelling ctx stops it after the current
+// batch and returns ctx.Err(). Either way every key yielded so far is
+// added before ParallelAddSeq returns, and the count says how many.
+//
+// As with ParallelAddAll, the filter must not be used by anything else
+// until ParallelAddSeq returns.
+func (bf *BloomFilter) ParallelAddSeq(ctx context.Context, keys iter.Seq2[[]byte, error], workers int) (uint64, error) {
+	if !bf.initialized() {
+		return 0, ErrUninitialized
+	}
+	bf.settle()
+	n, err := parallelLoadSeq(ctx, keys, workers, func(batch [][]byte) {
+		for _, key := range batch {
+			bf.addAtomic(key)
+		}
+	})
+	bf.checkOverfill()
+	return n, err
+}
+
+// ParallelAddSeq is ParallelAddAll for keys produced by an iterator. See
+// BloomFilter.ParallelAddSeq and SafeBloom.ParallelAddAll.
+func (s *SafeBloom) ParallelAddSeq(ctx context.Context, keys iter.Seq2[[]byte, error], workers int) (uint64, error) {
+	apply, err := s.parallelApply()
+	if err != nil {
+		return 0, err
+	}
+	return parallelLoadSeq(ctx, keys, workers, apply)
+}
+
+// ParallelAddAll drains keys on `workers` goroutines that hash and set bits
+// with atomic operations, while the filter keeps serving queries. See
+// BloomFilter.ParallelAddAll for the return values. A Reset during the load
+// drops the keys added before it, as for Add.
+func (a *Atomic) ParallelAddAll(ctx context.Context, keys <-chan []byte, workers int) (uint64, error) {
+	if !a.bf.Load().initialized() {
+		return 0, ErrUninitialized
+	}
+	return parallelLoad(ctx, keys, workers, a.addBatch)
+}
+
+// ParallelAddSeq is ParallelAddAll for keys produced by an iterator. See
+// BloomFilter.ParallelAddSeq.
+func (a *Atomic) ParallelAddSeq(ctx context.Context, keys iter.Seq2[[]byte, error], workers int) (uint64, error) {
+	if !a.bf.Load().initialized() {
+		return 0, ErrUninitialized
+	}
+	return parallelLoadSeq(ctx, keys, workers, a.addBatch)
+}
+
+func (a *Atomic) addBatch(batch [][]byte) {
+	bf := a.bf.Load()
+	for _, key := range batch {
+		bf.addAtomic(key)
+	}
+}
+
+// seqBatch is a batch of keys copied out of an iterator: the keys back to
+// back in arena, and a view of each.
+type seqBatch struct {
+	arena []byte
+	keys  [][]byte
+}
+
+// parallelLoadSeq is parallelLoad for an iterator. The caller's goroutine
+// ranges over keys, copying them into batches of parallelBatch keys for the
+// workers; used batches come back over a free list, so a long load
+// allocates only as many batches as are in flight.
+func parallelLoadSeq(ctx context.Context, keys iter.Seq2[[]byte, error], workers int, apply func(batch [][]byte)) (uint64, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		added atomic.Uint64
+		full  = make(chan *seqBatch, workers)
+		free  = make(chan *seqBatch, 2*workers+1)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range full {
+				apply(b.keys)
+				added.Add(uint64(len(b.keys)))
+				select {
+				case free <- b:
+				default:
+				}
+			}
+		}()
+	}
+
+	next := func() *seqBatch {
+		select {
+		case b := <-free:
+			b.arena, b.keys = b.arena[:0], b.keys[:0]
+			return b
+		default:
+			return &seqBatch{keys: make([][]byte, 0, parallelBatch)}
+		}
+	}
+	// send hands b to the workers. The views are taken only now, since
+	// appending to the arena may have moved it.
+	send := func(b *seqBatch, ends []int) {
+		start := 0
+		for _, end := range ends {
+			b.keys = append(b.keys, b.arena[start:end:end])
+			start = end
+		}
+		full <- b
+	}
+
+	var err error
+	cur, ends := next(), make([]int, 0, parallelBatch)
+	if ctx.Err() == nil {
+		for key, kerr := range keys {
+			if kerr != nil {
+				err = kerr
+				break
+			}
+			cur.arena = append(cur.arena, key...)
+			ends = append(ends, len(cur.arena))
+			if len(ends) < parallelBatch {
+				continue
+			}
+			send(cur, ends)
+			cur, ends = next(), ends[:0]
+			if ctx.Err() != nil {
+				break
+			}
+		}
+	}
+	if len(ends) > 0 {
+		send(cur, ends)
+	}
+	close(full)
+	wg.Wait()
+
+	if err == nil {
+		err = ctx.Err()
+	}
+	return added.Load(), err
+}
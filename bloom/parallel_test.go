@@ -0,0 +1,314 @@
+package bloom
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"iter"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func feedKeys(keys [][]byte) <-chan []byte {
+	ch := make(chan []byte, 64)
+	go func() {
+		defer close(ch)
+		for _, key := range keys {
+			ch <- key
+		}
+	}()
+	return ch
+}
+
+func TestParallelAddAll_NoFalseNegatives(t *testing.T) {
+	keys := benchmarkKeys(20000)
+	bf := NewWithEstimates(uint64(len(keys)), 0.01)
+
+	added, err := bf.ParallelAddAll(context.Background(), feedKeys(keys), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != uint64(len(keys)) {
+		t.Fatalf("added %d keys, want %d", added, len(keys))
+	}
+
+	// The parallel load must produce exactly the sequential state.
+	seq := NewWithEstimates(uint64(len(keys)), 0.01)
+	for _, key := range keys {
+		seq.Add(key)
+	}
+	for i := range seq.bits {
+		if seq.bits[i] != bf.bits[i] {
+			t.Fatalf("word %d differs from sequential load", i)
+		}
+	}
+	if seq.setBits != bf.setBits || seq.inserts != bf.inserts {
+		t.Fatalf("counters differ: setBits %d/%d inserts %d/%d", bf.setBits, seq.setBits, bf.inserts, seq.inserts)
+	}
+}
+
+func TestParallelAddAll_Cancel(t *testing.T) {
+	bf := New(1<<16, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	keys := make(chan []byte)
+	go func() {
+		for i := 0; i < 100; i++ {
+			keys <- []byte(strconv.Itoa(i))
+		}
+		cancel()
+	}()
+
+	added, err := bf.ParallelAddAll(ctx, keys, 2)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if added != 100 {
+		t.Fatalf("added %d keys, want 100", added)
+	}
+	for i := 0; i < 100; i++ {
+		if !bf.MightContain([]byte(strconv.Itoa(i))) {
+			t.Fatalf("expected key %d to be present", i)
+		}
+	}
+}
+
+func TestSafeBloom_ParallelAddAllWithReaders(t *testing.T) {
+	keys := benchmarkKeys(10000)
+	s := NewSafeWithEstimates(uint64(len(keys)), 0.01)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				s.MightContain(keys[0])
+			}
+		}
+	}()
+
+	added, err := s.ParallelAddAll(context.Background(), feedKeys(keys), 4)
+	close(done)
+	wg.Wait()
+	if err != nil || added != uint64(len(keys)) {
+		t.Fatalf("got (%d, %v), want (%d, nil)", added, err, len(keys))
+	}
+	for i, key := range keys {
+		if !s.MightContain(key) {
+			t.Fatalf("expected key %d to be present", i)
+		}
+	}
+}
+
+// seqKeys yields keys through one reused buffer, as a file reader would,
+// then err if it is non-nil.
+func seqKeys(keys [][]byte, err error) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		var buf []byte
+		for _, key := range keys {
+			buf = append(buf[:0], key...)
+			if !yield(buf, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+func TestParallelAddSeq_MatchesSequential(t *testing.T) {
+	keys := benchmarkKeys(20000)
+	seq := NewWithEstimates(uint64(len(keys)), 0.01)
+	seq.AddAll(keys)
+
+	bf := NewWithEstimates(uint64(len(keys)), 0.01)
+	s := NewSafeWithEstimates(uint64(len(keys)), 0.01)
+	a := NewAtomicWithEstimates(uint64(len(keys)), 0.01)
+	for name, load := range map[string]func(iter.Seq2[[]byte, error]) (uint64, error){
+		"BloomFilter": func(k iter.Seq2[[]byte, error]) (uint64, error) { return bf.ParallelAddSeq(context.Background(), k, 4) },
+		"SafeBloom":   func(k iter.Seq2[[]byte, error]) (uint64, error) { return s.ParallelAddSeq(context.Background(), k, 4) },
+		"Atomic":      func(k iter.Seq2[[]byte, error]) (uint64, error) { return a.ParallelAddSeq(context.Background(), k, 4) },
+	} {
+		added, err := load(seqKeys(keys, nil))
+		if err != nil || added != uint64(len(keys)) {
+			t.Fatalf("%s: got (%d, %v), want (%d, nil)", name, added, err, len(keys))
+		}
+	}
+	for name, got := range map[string]*BloomFilter{"BloomFilter": bf, "SafeBloom": s.Snapshot(), "Atomic": a.Snapshot()} {
+		if !got.Equal(seq) || got.setBits != seq.setBits || got.inserts != seq.inserts {
+			t.Fatalf("%s: parallel load differs from sequential", name)
+		}
+	}
+
+	// Lines composes with the loader.
+	text := string(bytes.Join(keys, []byte("\n")))
+	fromLines := NewWithEstimates(uint64(len(keys)), 0.01)
+	if _, err := fromLines.ParallelAddSeq(context.Background(), Lines(strings.NewReader(text)), 0); err != nil || !fromLines.Equal(seq) {
+		t.Fatalf("loading Lines: %v", err)
+	}
+}
+
+// TestParallelAdd_Delta checks that keys loaded in parallel reach a replica
+// kept in sync by deltas.
+func TestParallelAdd_Delta(t *testing.T) {
+	keys := benchmarkKeys(20000)
+	for name, load := range map[string]func(*BloomFilter, [][]byte) (uint64, error){
+		"ParallelAddAll": func(bf *BloomFilter, k [][]byte) (uint64, error) {
+			return bf.ParallelAddAll(context.Background(), feedKeys(k), 4)
+		},
+		"ParallelAddSeq": func(bf *BloomFilter, k [][]byte) (uint64, error) {
+			return bf.ParallelAddSeq(context.Background(), seqKeys(k, nil), 4)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			source := NewWithEstimates(uint64(len(keys)), 0.01)
+			replica := NewWithEstimates(uint64(len(keys)), 0.01)
+			source.AddAll(keys[:100])
+			d, gen := source.DeltaSince(0)
+			if err := replica.ApplyDelta(d); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := load(source, keys[100:]); err != nil {
+				t.Fatal(err)
+			}
+			d, _ = source.DeltaSince(gen)
+			if d.Full {
+				t.Fatal("expected an incremental delta")
+			}
+			if err := replica.ApplyDelta(d); err != nil {
+				t.Fatal(err)
+			}
+			if !replica.Equal(source) || replica.setBits != source.setBits {
+				t.Fatal("replica diverged from the source")
+			}
+			for _, k := range keys {
+				if !replica.MightContain(k) {
+					t.Fatalf("replica missing %q", k)
+				}
+			}
+		})
+	}
+}
+
+func TestParallelAddSeq_Error(t *testing.T) {
+	errRead := errors.New("read failed")
+	keys := benchmarkKeys(1000)
+	bf := NewWithEstimates(uint64(len(keys)), 0.01)
+	added, err := bf.ParallelAddSeq(context.Background(), seqKeys(keys, errRead), 3)
+	if !errors.Is(err, errRead) || added != uint64(len(keys)) {
+		t.Fatalf("got (%d, %v), want (%d, %v)", added, err, len(keys), errRead)
+	}
+	for i, key := range keys {
+		if !bf.MightContain(key) {
+			t.Fatalf("key %d before the error is missing", i)
+		}
+	}
+
+	if _, err := new(BloomFilter).ParallelAddSeq(context.Background(), seqKeys(keys, nil), 2); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("zero filter: %v", err)
+	}
+	if _, err := new(Atomic).ParallelAddAll(context.Background(), feedKeys(keys), 2); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("zero Atomic: %v", err)
+	}
+}
+
+func TestParallelAddSeq_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	yielded := 0
+	endless := func(yield func([]byte, error) bool) {
+		for i := 0; ; i++ {
+			if i == 1000 {
+				cancel()
+			}
+			yielded++
+			if !yield([]byte(strconv.Itoa(i)), nil) {
+				return
+			}
+		}
+	}
+	bf := New(1<<16, 4)
+	added, err := bf.ParallelAddSeq(ctx, endless, 2)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if added != uint64(yielded) || added < 1000 {
+		t.Fatalf("added %d of %d yielded keys", added, yielded)
+	}
+	for i := 0; i < yielded; i++ {
+		if !bf.MightContain([]byte(strconv.Itoa(i))) {
+			t.Fatalf("yielded key %d is missing", i)
+		}
+	}
+}
+
+func TestAtomic_ParallelAddAllWithReaders(t *testing.T) {
+	keys := benchmarkKeys(10000)
+	a := NewAtomicWithEstimates(uint64(len(keys)), 0.01)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				a.MightContain(keys[0])
+			}
+		}
+	}()
+
+	added, err := a.ParallelAddAll(context.Background(), feedKeys(keys), 8)
+	close(done)
+	wg.Wait()
+	if err != nil || added != uint64(len(keys)) || a.Count() != added {
+		t.Fatalf("got (%d, %v), want (%d, nil)", added, err, len(keys))
+	}
+	for i, key := range keys {
+		if !a.MightContain(key) {
+			t.Fatalf("expected key %d to be present", i)
+		}
+	}
+}
+
+func BenchmarkParallelAddSeq(b *testing.B) {
+	keys := benchmarkKeys(1 << 16)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(strconv.Itoa(workers), func(b *testing.B) {
+			a := NewAtomicWithEstimates(uint64(len(keys)), 0.01)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := a.ParallelAddSeq(context.Background(), seqKeys(keys, nil), workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/key")
+		})
+	}
+}
+
+func BenchmarkParallelAddAll(b *testing.B) {
+	keys := benchmarkKeys(1 << 16)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(strconv.Itoa(workers), func(b *testing.B) {
+			bf := NewWithEstimates(uint64(len(keys)), 0.01)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := bf.ParallelAddAll(context.Background(), feedKeys(keys), workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
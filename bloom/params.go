@@ -0,0 +1,54 @@
+package bloom
+
+import "fmt"
+
+// Params are the parameters that decide where a key's bits land. Two
+// filters with equal Params can be merged, intersected and compared, and
+// NewMatching builds an empty filter from them. Params marshal to JSON
+// for logging and for comparing configurations across services.
+type Params struct {
+	M      uint64 `json:"m"`                // no. of bits
+	K      uint64 `json:"k"`                // no. of hash functions
+	Scheme string `json:"scheme"`           // probe scheme, e.g. "fnv" or "guava-murmur128-mitz64"
+	Seed   uint64 `json:"seed"`             // hash seed; 0 if unseeded
+	Hasher string `json:"hasher,omitempty"` // registered Hasher name; empty for the default
+}
+
+// Params returns the filter's parameters. A zero-value or nil filter has
+// zero Params.
+func (bf *BloomFilter) Params() Params {
+	if !bf.initialized() {
+		return Params{}
+	}
+	p := Params{M: bf.m, K: bf.k, Scheme: bf.scheme.String(), Seed: bf.seed}
+	if bf.hasher != nil {
+		p.Hasher = bf.hasher.String()
+	}
+	return p
+}
+
+// NewMatching creates an empty filter with parameters p, guaranteed to be
+// compatible with any filter whose Params equal p. Params with a zero m or
+// k fail with ErrCorrupt; an unknown scheme or an unregistered hasher,
+// which this version cannot reproduce, fail with ErrUnsupportedFormat.
+func NewMatching(p Params) (*BloomFilter, error) {
+	if p.M == 0 || p.K == 0 {
+		return nil, fmt.Errorf("%w: m=%d k=%d", ErrCorrupt, p.M, p.K)
+	}
+	s, ok := parseScheme(p.Scheme)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown probe scheme %q", ErrUnsupportedFormat, p.Scheme)
+	}
+	hc, err := configForName(p.Hasher)
+	if err != nil {
+		return nil, err
+	}
+	opts := []Option{WithExplicitSize(p.M, p.K), withScheme(s)}
+	if hc != nil {
+		opts = append(opts, WithHasher(hc.h))
+	}
+	if p.Seed != 0 {
+		opts = append(opts, WithSeed(p.Seed))
+	}
+	return NewWithOptions(0, 0, opts...)
+}
@@ -0,0 +1,68 @@
+package bloom
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParams_NewMatchingMerges(t *testing.T) {
+	for _, orig := range []*BloomFilter{
+		NewWithEstimates(1000, 0.01),
+		NewGuavaWithEstimates(1000, 0.01),
+		NewBitsAndBloomsWithEstimates(1000, 0.01),
+	} {
+		orig.Add([]byte("existing"))
+		p := orig.Params()
+
+		data, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Params
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded != p {
+			t.Fatalf("%s: JSON round trip gave %+v, %v", data, decoded, err)
+		}
+
+		fresh, err := NewMatching(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fresh.Add([]byte("new"))
+		if err := orig.Merge(fresh); err != nil {
+			t.Fatalf("%s: %v", p.Scheme, err)
+		}
+		if !orig.MightContain([]byte("new")) || !orig.MightContain([]byte("existing")) {
+			t.Fatalf("%s: keys missing after Merge", p.Scheme)
+		}
+		if fresh.Fingerprint() != orig.Fingerprint() {
+			t.Fatalf("%s: fingerprints differ", p.Scheme)
+		}
+	}
+
+	s := NewSafe(1024, 3)
+	if got := s.Params(); got != (Params{M: 1024, K: 3, Scheme: "fnv"}) {
+		t.Fatalf("SafeBloom Params = %+v", got)
+	}
+	if got := new(BloomFilter).Params(); got != (Params{}) {
+		t.Fatalf("zero-value Params = %+v", got)
+	}
+}
+
+func TestParams_NewMatchingErrors(t *testing.T) {
+	for _, c := range []struct {
+		p    Params
+		want error
+	}{
+		{Params{K: 3, Scheme: "fnv"}, ErrCorrupt},
+		{Params{M: 64, Scheme: "fnv"}, ErrCorrupt},
+		{Params{M: 64, K: 3, Scheme: "md5"}, ErrUnsupportedFormat},
+		{Params{M: 64, K: 3}, ErrUnsupportedFormat},
+		{Params{M: 64, K: 3, Scheme: "fnv", Hasher: "md5"}, ErrUnsupportedFormat},
+		{Params{M: 64, K: 3, Scheme: "guava-murmur128-mitz64", Seed: 1}, ErrConflictingOptions},
+	} {
+		if _, err := NewMatching(c.p); !errors.Is(err, c.want) {
+			t.Errorf("%+v: expected %v, got %v", c.p, c.want, err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package bloom
+
+import (
+	"fmt"
+	"math/bits"
+	"unsafe"
+)
+
+// Partitioned is a partitioned Bloom filter: the m bits are split into k
+// equal slices and the i-th probe only ever sets a bit in slice i. Each key
+// therefore sets exactly k bits, one per slice, and no two of its probes
+// can collide, which makes the worst case more predictable than a
+// standard filter's at a near-identical false positive rate.
+//
+// Probe positions come from the same FNV double hashing as BloomFilter,
+// reduced modulo the slice size.
+//
+// Note: This type is not safe for concurrent use without external locking.
+type Partitioned struct {
+	k         uint64
+	sliceBits uint64   // bits per slice
+	bits      []uint64 // k*sliceBits bits, slice i at [i*sliceBits, (i+1)*sliceBits)
+	sliceSet  []uint64 // no. of bits set in each slice
+	inserts   uint64
+}
+
+// NewPartitioned creates a partitioned filter with at least m bits split
+// into k slices; m is rounded up to a multiple of k.
+// m and k ==> must be >0.
+func NewPartitioned(m, k uint64) *Partitioned {
+	if m == 0 {
+		panic("bloom: m (no. of bits) must be > 0")
+	}
+	if k == 0 {
+		panic("bloom: k (no. of hash fucntions) must be > 0")
+	}
+	sliceBits := (m + k - 1) / k
+	return &Partitioned{
+		k:         k,
+		sliceBits: sliceBits,
+		bits:      make([]uint64, wordsFor(sliceBits*k)),
+		sliceSet:  make([]uint64, k),
+	}
+}
+
+// NewPartitionedWithEstimates creates a partitioned filter sized for n
+// items at fpRate, using the same m and k as NewWithEstimates.
+func NewPartitionedWithEstimates(n uint64, fpRate float64) *Partitioned {
+	m, k := EstimateParameters(n, fpRate)
+	return NewPartitioned(m, k)
+}
+
+// Add inserts data, setting one bit in each slice.
+// It panics with ErrUninitialized on a zero-value or nil filter.
+func (p *Partitioned) Add(data []byte) {
+	if p == nil || p.k == 0 {
+		panic(ErrUninitialized)
+	}
+	h1, h2 := fnvHashes(data)
+	for i := uint64(0); i < p.k; i++ {
+		pos := p.location(h1, h2, i)
+		mask := uint64(1) << (pos % 64)
+		if p.bits[pos/64]&mask == 0 {
+			p.bits[pos/64] |= mask
+			p.sliceSet[i]++
+		}
+	}
+	p.inserts++
+}
+
+// MightContain checks if data might be in the filter.
+// Returns false -> definitely not present.
+// Returns true  -> might be present (subject to false positives).
+// A zero-value or nil filter contains nothing.
+func (p *Partitioned) MightContain(data []byte) bool {
+	if p == nil || p.k == 0 {
+		return false
+	}
+	h1, h2 := fnvHashes(data)
+	for i := uint64(0); i < p.k; i++ {
+		pos := p.location(h1, h2, i)
+		if p.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// location returns the i-th probe position: (h1 + i*h2) mod sliceBits,
+// within slice i.
+func (p *Partitioned) location(h1, h2, i uint64) uint64 {
+	return i*p.sliceBits + (h1+i*h2)%p.sliceBits
+}
+
+// Reset clears all bits in the filter.
+func (p *Partitioned) Reset() {
+	if p == nil {
+		return
+	}
+	clear(p.bits)
+	clear(p.sliceSet)
+	p.inserts = 0
+}
+
+// Merge ORs other's bits into p. Both must be partitioned filters with the
+// same slice size and k; otherwise it fails with ErrIncompatible and p is
+// unchanged.
+func (p *Partitioned) Merge(other *Partitioned) error {
+	switch {
+	case p == nil || p.k == 0 || other == nil || other.k == 0:
+		return ErrUninitialized
+	case p.k != other.k:
+		return fmt.Errorf("%w: k %d != %d", ErrIncompatible, p.k, other.k)
+	case p.sliceBits != other.sliceBits:
+		return fmt.Errorf("%w: slice size %d != %d", ErrIncompatible, p.sliceBits, other.sliceBits)
+	}
+	if p == other {
+		return nil
+	}
+	for i, w := range other.bits {
+		p.bits[i] |= w
+	}
+	for i := range p.sliceSet {
+		p.sliceSet[i] = popcountRange(p.bits, uint64(i)*p.sliceBits, uint64(i+1)*p.sliceBits)
+	}
+	p.inserts += other.inserts
+	return nil
+}
+
+// SliceFillRatios returns the fraction of bits set in each slice. With a
+// well-mixed hash every slice fills at the same rate; a slice running
+// noticeably ahead or behind the others points at skew in that probe.
+func (p *Partitioned) SliceFillRatios() []float64 {
+	if p == nil {
+		return nil
+	}
+	ratios := make([]float64, p.k)
+	for i, set := range p.sliceSet {
+		ratios[i] = float64(set) / float64(p.sliceBits)
+	}
+	return ratios
+}
+
+// M returns the total number of bits, k times the slice size.
+func (p *Partitioned) M() uint64 {
+	if p == nil {
+		return 0
+	}
+	return p.k * p.sliceBits
+}
+
+// K returns the number of slices and hash functions.
+func (p *Partitioned) K() uint64 {
+	if p == nil {
+		return 0
+	}
+	return p.k
+}
+
+// SizeInBytes reports the memory held by the filter.
+func (p *Partitioned) SizeInBytes() uint64 {
+	if p == nil {
+		return 0
+	}
+	return uint64(len(p.bits)+len(p.sliceSet))*8 + uint64(unsafe.Sizeof(*p))
+}
+
+// Info returns a small description of the filter's configuration.
+func (p *Partitioned) Info() string {
+	if p == nil {
+		return "Partitioned{nil}"
+	}
+	return fmt.Sprintf("Partitioned{m=%d bits, k=%d slices of %d}", p.M(), p.k, p.sliceBits)
+}
+
+// popcountRange returns the number of set bits in positions [start, end).
+func popcountRange(words []uint64, start, end uint64) uint64 {
+	var n uint64
+	for start < end {
+		w := words[start/64] >> (start % 64)
+		span := min(64-start%64, end-start)
+		if span < 64 {
+			w &= 1<<span - 1
+		}
+		n += uint64(bits.OnesCount64(w))
+		start += span
+	}
+	return n
+}
@@ -0,0 +1,103 @@
+package bloom
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestPartitioned_NoFalseNegatives(t *testing.T) {
+	p := NewPartitionedWithEstimates(10_000, 0.01)
+	for i := 0; i < 10_000; i++ {
+		p.Add([]byte("key-" + strconv.Itoa(i)))
+	}
+	for i := 0; i < 10_000; i++ {
+		if !p.MightContain([]byte("key-" + strconv.Itoa(i))) {
+			t.Fatalf("false negative for key-%d", i)
+		}
+	}
+
+	falsePositives := 0
+	for _, key := range randomKeys("absent-", 100_000, 3) {
+		if p.MightContain(key) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / 100_000; rate > 0.02 {
+		t.Fatalf("false positive rate %.4f, want about 0.01", rate)
+	}
+}
+
+func TestPartitioned_OneBitPerSlice(t *testing.T) {
+	p := NewPartitioned(1000, 7)
+	if p.M() != 1001 || p.sliceBits != 143 {
+		t.Fatalf("got %s, want m rounded up to 1001", p.Info())
+	}
+	p.Add([]byte("only"))
+	for i, ratio := range p.SliceFillRatios() {
+		if ratio != 1.0/143 {
+			t.Fatalf("slice %d fill %v after one key, want exactly one bit", i, ratio)
+		}
+	}
+}
+
+func TestPartitioned_SliceFillRatiosBalanced(t *testing.T) {
+	p := NewPartitionedWithEstimates(5000, 0.01)
+	for _, key := range randomKeys("user-", 5000, 4) {
+		p.Add(key)
+	}
+	ratios := p.SliceFillRatios()
+	for i, r := range ratios {
+		if math.Abs(r-ratios[0]) > 0.05 {
+			t.Fatalf("slice %d fill %.3f vs slice 0 %.3f: unexpected skew", i, r, ratios[0])
+		}
+	}
+}
+
+func TestPartitioned_Merge(t *testing.T) {
+	a, b := NewPartitioned(4096, 5), NewPartitioned(4096, 5)
+	for i := 0; i < 200; i++ {
+		a.Add([]byte("a-" + strconv.Itoa(i)))
+		b.Add([]byte("b-" + strconv.Itoa(i)))
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		if !a.MightContain([]byte("a-"+strconv.Itoa(i))) || !a.MightContain([]byte("b-"+strconv.Itoa(i))) {
+			t.Fatalf("merged filter lost key %d", i)
+		}
+	}
+	for i := uint64(0); i < a.k; i++ {
+		if want := popcountRange(a.bits, i*a.sliceBits, (i+1)*a.sliceBits); a.sliceSet[i] != want {
+			t.Fatalf("slice %d count %d, want %d", i, a.sliceSet[i], want)
+		}
+	}
+
+	if err := a.Merge(NewPartitioned(4096, 4)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible for a different k, got %v", err)
+	}
+	if err := a.Merge(NewPartitioned(5000, 5)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible for a different slice size, got %v", err)
+	}
+	if err := a.Merge(nil); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("expected ErrUninitialized, got %v", err)
+	}
+
+	a.Reset()
+	if a.MightContain([]byte("a-1")) || a.SliceFillRatios()[0] != 0 {
+		t.Fatal("Reset did not clear the filter")
+	}
+}
+
+func TestPopcountRange(t *testing.T) {
+	words := []uint64{^uint64(0), ^uint64(0), 0b1011}
+	for _, c := range []struct{ start, end, want uint64 }{
+		{0, 0, 0}, {0, 64, 64}, {3, 67, 64}, {60, 130, 70}, {128, 132, 3}, {129, 131, 1},
+	} {
+		if got := popcountRange(words, c.start, c.end); got != c.want {
+			t.Errorf("popcountRange(%d, %d) = %d, want %d", c.start, c.end, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package bloom
+
+import (
+	"fmt"
+	"slices"
+)
+
+// M returns the number of bits in the filter, or 0 for a zero-value or nil
+// filter.
+func (bf *BloomFilter) M() uint64 {
+	if bf == nil {
+		return 0
+	}
+	return bf.m
+}
+
+// K returns the number of hash functions, or 0 for a zero-value or nil
+// filter.
+func (bf *BloomFilter) K() uint64 {
+	if bf == nil {
+		return 0
+	}
+	return bf.k
+}
+
+// BitWords returns a copy of the bitset as (M()+63)/64 words; bit i is
+// bit i%64 of word i/64. Together with M and K it is everything
+// NewFromParts needs to rebuild the filter.
+func (bf *BloomFilter) BitWords() []uint64 {
+	if bf == nil {
+		return nil
+	}
+	bf.settle()
+	if bf.chunked() {
+		return slices.Concat(bf.chunks...)
+	}
+	return append([]uint64(nil), bf.bits...)
+}
+
+// NewFromParts rebuilds a filter from the values returned by M, K and
+// BitWords, copying words. It fails with ErrCorrupt if m or k is zero,
+// len(words) != (m+63)/64, or any padding bit beyond m is set.
+//
+// opts may select the Hasher the words were built with (WithHasher), which
+// also makes it the way to load a bitset produced by another language.
+// Sizing options conflict with m and k.
+//
+// Filters built with a non-default probe scheme (NewGuavaWithEstimates,
+// ImportGuava) cannot be rebuilt this way; use MarshalBinary instead.
+func NewFromParts(m, k uint64, words []uint64, opts ...Option) (*BloomFilter, error) {
+	if m == 0 || k == 0 {
+		return nil, fmt.Errorf("%w: m=%d k=%d", ErrCorrupt, m, k)
+	}
+	if uint64(len(words)) != wordsFor(m) {
+		return nil, fmt.Errorf("%w: %d words for m=%d, want %d", ErrCorrupt, len(words), m, wordsFor(m))
+	}
+	if words[len(words)-1]&^lastWordMask(m) != 0 {
+		return nil, fmt.Errorf("%w: padding bits set beyond m", ErrCorrupt)
+	}
+	bf, err := NewWithOptions(0, 0, append([]Option{WithExplicitSize(m, k)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	bf.eachSpan(func(start int, span []uint64) { copy(span, words[start:]) })
+	bf.setBits = bf.onesCount()
+	return bf, nil
+}
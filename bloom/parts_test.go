@@ -0,0 +1,55 @@
+package bloom
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestParts_RoundTrip(t *testing.T) {
+	bf := NewWithEstimates(500, 0.01)
+	for i := 0; i < 500; i++ {
+		bf.Add([]byte(strconv.Itoa(i)))
+	}
+
+	words := bf.BitWords()
+	got, err := NewFromParts(bf.M(), bf.K(), words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range words {
+		words[i] = 0 // neither filter aliases the caller's slice
+	}
+	if got.setBits != bf.setBits {
+		t.Fatalf("rebuilt filter has %d set bits, want %d", got.setBits, bf.setBits)
+	}
+	for i := 0; i < 1000; i++ {
+		key := []byte(strconv.Itoa(i))
+		if got.MightContain(key) != bf.MightContain(key) {
+			t.Fatalf("key %d: rebuilt filter disagrees with original", i)
+		}
+	}
+
+	var zero *BloomFilter
+	if zero.M() != 0 || zero.K() != 0 || zero.BitWords() != nil {
+		t.Fatal("nil filter should report empty parts")
+	}
+}
+
+func TestParts_Validation(t *testing.T) {
+	cases := map[string]struct {
+		m, k  uint64
+		words []uint64
+	}{
+		"zero m":       {0, 3, nil},
+		"zero k":       {64, 0, make([]uint64, 1)},
+		"short":        {65, 3, make([]uint64, 1)},
+		"long":         {64, 3, make([]uint64, 2)},
+		"padding bits": {10, 3, []uint64{1 << 10}},
+	}
+	for name, tc := range cases {
+		if _, err := NewFromParts(tc.m, tc.k, tc.words); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: expected ErrCorrupt, got %v", name, err)
+		}
+	}
+}
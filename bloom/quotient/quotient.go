@@ -0,0 +1,590 @@
+// Package quotient implements a quotient filter (Bender et al., "Don't
+// Thrash: How to Cache Your Hash on Flash", 2012).
+//
+// A quotient filter stores a p-bit fingerprint of each key, split into a
+// q-bit quotient, which picks a slot, and an r-bit remainder, which is
+// stored in it. Unlike a Bloom filter it supports Delete, and because the
+// whole fingerprint can be recovered from the table, it can be resized
+// (DoubleCapacity) and merged without the original keys.
+//
+// Errors are the bloom package's: decoding fails with bloom.ErrCorrupt
+// or bloom.ErrUnsupportedVersion, a full filter with bloom.ErrFilterFull
+// and mismatched fingerprints with bloom.ErrIncompatible.
+package quotient
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"unsafe"
+
+	"github.com/Abhisheklearn12/bloom-filter/bloom"
+)
+
+// Each slot holds a remainder above three metadata bits:
+//
+//   - occupied: some stored fingerprint has this slot as its quotient.
+//     The bit belongs to the slot, not to the remainder stored in it.
+//   - continuation: the remainder is not the first of its run.
+//   - shifted: the remainder is not in its canonical slot.
+//
+// Remainders with the same quotient form a sorted run, and runs of
+// adjacent quotients pack into clusters, shifted right (wrapping around
+// the end of the table) as needed. A slot with all three bits clear is
+// empty.
+const (
+	occupied     = 1 << 0
+	continuation = 1 << 1
+	shifted      = 1 << 2
+	metaBits     = 3
+	metaMask     = occupied | continuation | shifted
+)
+
+// Filter is a quotient filter with 2^q slots and r-bit remainders.
+//
+// It stores a multiset of fingerprints: adding a key twice stores it
+// twice, and Delete removes one copy. So deleting a key never removes a
+// different key that happens to share its fingerprint, but deleting a key
+// that was never added may remove one that was.
+//
+// False positives occur when a key's p = q+r-bit fingerprint matches a
+// stored one, with probability about n/2^p for n stored keys, or
+// load·2^-r. One slot is always kept empty, so a filter holds at most
+// 2^q-1 keys; lookups slow down as clusters grow, so keep the load under
+// about 75% by calling DoubleCapacity.
+//
+// Note: This type is not safe for concurrent use without external locking.
+type Filter struct {
+	q, r  uint     // log2 of the slot count; remainder bits
+	slots []uint64 // 2^q packed (r+3)-bit entries
+	count uint64   // stored fingerprints
+}
+
+// New creates an empty quotient filter with 2^log2Slots slots and
+// remainderBits-bit remainders.
+// It panics unless log2Slots is in [1, 48], remainderBits is in [1, 61] and
+// their sum is at most 64.
+func New(log2Slots, remainderBits uint) *Filter {
+	if log2Slots < 1 || log2Slots > 48 {
+		panic("quotient: log2Slots must be between 1 and 48")
+	}
+	if remainderBits < 1 || remainderBits > 61 || log2Slots+remainderBits > 64 {
+		panic("quotient: remainderBits must be between 1 and 61, with log2Slots+remainderBits <= 64")
+	}
+	f := &Filter{q: log2Slots, r: remainderBits}
+	f.slots = make([]uint64, (f.nslots()*uint64(f.width())+63)/64)
+	return f
+}
+
+// Add inserts data. It returns bloom.ErrFilterFull, leaving the filter
+// unchanged, once it holds Capacity keys.
+func (f *Filter) Add(data []byte) error {
+	return f.insert(f.fingerprint(data))
+}
+
+// MayContain reports whether data might be in the filter.
+// Returns false -> definitely not present.
+// Returns true  -> might be present (subject to false positives).
+func (f *Filter) MayContain(data []byte) bool {
+	return f.contains(f.fingerprint(data))
+}
+
+// Delete removes one copy of data's fingerprint, reporting whether one was
+// found. Only delete keys that were added; see Filter.
+func (f *Filter) Delete(data []byte) bool {
+	return f.remove(f.fingerprint(data))
+}
+
+// Len returns the number of stored fingerprints, counting duplicates.
+func (f *Filter) Len() uint64 {
+	return f.count
+}
+
+// Capacity returns the most keys the filter can hold, 2^q-1.
+func (f *Filter) Capacity() uint64 {
+	return f.nslots() - 1
+}
+
+// LoadFactor returns the fraction of slots in use.
+func (f *Filter) LoadFactor() float64 {
+	return float64(f.count) / float64(f.nslots())
+}
+
+// DoubleCapacity doubles the slot count by moving one bit of each
+// fingerprint from the remainder to the quotient, rebuilding the table
+// from the stored fingerprints. Membership answers do not change. Since
+// the fingerprint size p does not change either, the false positive rate
+// at n keys stays about n/2^p: doubling buys room, not accuracy. It fails
+// with bloom.ErrFilterFull when the remainder is down to one bit.
+func (f *Filter) DoubleCapacity() error {
+	if f.r < 2 || f.q >= 48 {
+		return fmt.Errorf("%w: cannot shrink %d-bit remainders", bloom.ErrFilterFull, f.r)
+	}
+	grown := New(f.q+1, f.r-1)
+	f.each(func(fp uint64) {
+		grown.insert(fp)
+	})
+	*f = *grown
+	return nil
+}
+
+// Merge adds every fingerprint stored in other to f. Both filters must use
+// the same fingerprint size q+r, though their slot counts may differ.
+// It fails with bloom.ErrIncompatible on a size mismatch and with
+// bloom.ErrFilterFull if the result would exceed f's capacity; f is left
+// unchanged on error.
+func (f *Filter) Merge(other *Filter) error {
+	if f.q+f.r != other.q+other.r {
+		return fmt.Errorf("%w: %d-bit fingerprints, other has %d", bloom.ErrIncompatible, f.q+f.r, other.q+other.r)
+	}
+	if f.count+other.count > f.Capacity() {
+		return fmt.Errorf("%w: %d + %d keys exceed capacity %d", bloom.ErrFilterFull, f.count, other.count, f.Capacity())
+	}
+	if f == other {
+		other = other.clone()
+	}
+	other.each(func(fp uint64) {
+		f.insert(fp)
+	})
+	return nil
+}
+
+// SizeInBytes reports the memory held by the filter.
+func (f *Filter) SizeInBytes() uint64 {
+	return uint64(len(f.slots))*8 + uint64(unsafe.Sizeof(*f))
+}
+
+// Info returns a small description of the filter's configuration.
+func (f *Filter) Info() string {
+	return fmt.Sprintf("quotient.Filter{q=%d, r=%d, n=%d}", f.q, f.r, f.count)
+}
+
+// Binary format (all integers little-endian), following the bloom
+// package's conventions:
+//
+//	version  uint8   encodingVersion
+//	q        uint8   log2 of the slot count
+//	r        uint8   remainder bits
+//	count    uint64  stored fingerprints
+//	slots    ceil(2^q*(r+3)/64) * uint64
+const (
+	encodingVersion = 1
+	headerLen       = 1 + 1 + 1 + 8
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, headerLen+len(f.slots)*8)
+	buf = append(buf, encodingVersion, byte(f.q), byte(f.r))
+	buf = binary.LittleEndian.AppendUint64(buf, f.count)
+	for _, w := range f.slots {
+		buf = binary.LittleEndian.AppendUint64(buf, w)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, fully replacing
+// the receiver's contents. The slot metadata is checked for consistency,
+// since lookups on a malformed table could loop forever. Malformed data
+// fails with bloom.ErrCorrupt, an unknown version with
+// bloom.ErrUnsupportedVersion; the receiver is left untouched on error.
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: short header", bloom.ErrCorrupt)
+	}
+	if data[0] != encodingVersion {
+		return fmt.Errorf("%w: quotient version %d", bloom.ErrUnsupportedVersion, data[0])
+	}
+	if len(data) < headerLen {
+		return fmt.Errorf("%w: short header", bloom.ErrCorrupt)
+	}
+	q, r := uint(data[1]), uint(data[2])
+	count := binary.LittleEndian.Uint64(data[3:])
+	if q < 1 || q > 48 || r < 1 || r > 61 || q+r > 64 {
+		return fmt.Errorf("%w: q=%d, r=%d", bloom.ErrCorrupt, q, r)
+	}
+	words := ((uint64(1)<<q)*uint64(r+metaBits) + 63) / 64
+	payload := data[headerLen:]
+	if uint64(len(payload)) != words*8 {
+		return fmt.Errorf("%w: payload is %d bytes, want %d", bloom.ErrCorrupt, len(payload), words*8)
+	}
+
+	decoded := &Filter{q: q, r: r, slots: make([]uint64, words), count: count}
+	for i := range decoded.slots {
+		decoded.slots[i] = binary.LittleEndian.Uint64(payload[i*8:])
+	}
+	if err := decoded.validate(); err != nil {
+		return err
+	}
+	*f = *decoded
+	return nil
+}
+
+// validate checks the invariants lookups rely on: padding and empty slots
+// are zero, at least one slot is empty, continuations are shifted, every
+// shifted entry follows a used slot, the occupied bits match the runs, and
+// count matches the stored entries.
+func (f *Filter) validate() error {
+	if used := f.nslots() * uint64(f.width()) % 64; used != 0 && f.slots[len(f.slots)-1]>>used != 0 {
+		return fmt.Errorf("%w: padding bits set beyond the last slot", bloom.ErrCorrupt)
+	}
+	var entries, runs, occupiedSlots uint64
+	for i := uint64(0); i < f.nslots(); i++ {
+		e := f.get(i)
+		if e&occupied != 0 {
+			occupiedSlots++
+		}
+		if isEmpty(e) {
+			if e != 0 {
+				return fmt.Errorf("%w: empty slot %d holds a remainder", bloom.ErrCorrupt, i)
+			}
+			continue
+		}
+		entries++
+		if e&continuation == 0 {
+			runs++
+		}
+		if e&continuation != 0 && e&shifted == 0 {
+			return fmt.Errorf("%w: unshifted continuation in slot %d", bloom.ErrCorrupt, i)
+		}
+		if e&shifted != 0 && isEmpty(f.get(f.decr(i))) {
+			return fmt.Errorf("%w: shifted entry in slot %d follows an empty slot", bloom.ErrCorrupt, i)
+		}
+	}
+	if entries != f.count || entries >= f.nslots() || runs != occupiedSlots {
+		return fmt.Errorf("%w: %d entries in %d runs, %d occupied slots, header says %d", bloom.ErrCorrupt, entries, runs, occupiedSlots, f.count)
+	}
+	var sorted error
+	f.eachRun(func(rem []uint64) {
+		for i := 1; i < len(rem); i++ {
+			if rem[i] < rem[i-1] && sorted == nil {
+				sorted = fmt.Errorf("%w: unsorted run", bloom.ErrCorrupt)
+			}
+		}
+	})
+	return sorted
+}
+
+func (f *Filter) nslots() uint64 { return 1 << f.q }
+func (f *Filter) width() uint    { return f.r + metaBits }
+
+func (f *Filter) incr(i uint64) uint64 { return (i + 1) & (f.nslots() - 1) }
+func (f *Filter) decr(i uint64) uint64 { return (i - 1) & (f.nslots() - 1) }
+
+// fingerprint returns data's (q+r)-bit fingerprint: the top bits of a
+// 64-bit FNV-1a hash passed through the murmur3 finalizer, since FNV's
+// own high bits are poorly mixed for short keys.
+func (f *Filter) fingerprint(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+	return mix64(h.Sum64()) >> (64 - (f.q + f.r))
+}
+
+// split returns fp's quotient and remainder.
+func (f *Filter) split(fp uint64) (uint64, uint64) {
+	return fp >> f.r, fp & (1<<f.r - 1)
+}
+
+// get returns the entry in slot i.
+func (f *Filter) get(i uint64) uint64 {
+	w := uint64(f.width())
+	off := i * w
+	word, shift := off/64, off%64
+	v := f.slots[word] >> shift
+	if shift+w > 64 {
+		v |= f.slots[word+1] << (64 - shift)
+	}
+	return v & (^uint64(0) >> (64 - w))
+}
+
+// set stores entry e in slot i.
+func (f *Filter) set(i, e uint64) {
+	w := uint64(f.width())
+	mask := ^uint64(0) >> (64 - w)
+	off := i * w
+	word, shift := off/64, off%64
+	f.slots[word] = f.slots[word]&^(mask<<shift) | e<<shift
+	if shift+w > 64 {
+		spill := 64 - shift
+		f.slots[word+1] = f.slots[word+1]&^(mask>>spill) | e>>spill
+	}
+}
+
+func isEmpty(e uint64) bool        { return e&metaMask == 0 }
+func isRunStart(e uint64) bool     { return e&continuation == 0 && !isEmpty(e) }
+func isClusterStart(e uint64) bool { return e&metaMask == occupied }
+
+// runStart returns the slot where the run for quotient fq starts, or would
+// start if fq has none yet. fq's occupied bit must already be set.
+func (f *Filter) runStart(fq uint64) uint64 {
+	// Walk back to the start of the cluster, then forward run by run,
+	// pairing each occupied slot with the run it owns.
+	b := fq
+	for f.get(b)&shifted != 0 {
+		b = f.decr(b)
+	}
+	s := b
+	for b != fq {
+		for {
+			s = f.incr(s)
+			if f.get(s)&continuation == 0 {
+				break
+			}
+		}
+		for {
+			b = f.incr(b)
+			if f.get(b)&occupied != 0 {
+				break
+			}
+		}
+	}
+	return s
+}
+
+// contains reports whether fingerprint fp is stored.
+func (f *Filter) contains(fp uint64) bool {
+	fq, fr := f.split(fp)
+	if f.get(fq)&occupied == 0 {
+		return false
+	}
+	s := f.runStart(fq)
+	for {
+		rem := f.get(s) >> metaBits
+		if rem == fr {
+			return true
+		}
+		if rem > fr {
+			return false
+		}
+		s = f.incr(s)
+		if f.get(s)&continuation == 0 {
+			return false
+		}
+	}
+}
+
+// insert stores fingerprint fp after any equal remainders in its run.
+func (f *Filter) insert(fp uint64) error {
+	if f.count >= f.Capacity() {
+		return bloom.ErrFilterFull
+	}
+	fq, fr := f.split(fp)
+	entry := fr << metaBits
+	head := f.get(fq)
+	f.count++
+
+	if isEmpty(head) {
+		f.set(fq, entry|occupied)
+		return nil
+	}
+	hadRun := head&occupied != 0
+	if !hadRun {
+		f.set(fq, head|occupied)
+	}
+	start := f.runStart(fq)
+	s := start
+	if hadRun {
+		for {
+			if f.get(s)>>metaBits > fr {
+				break
+			}
+			s = f.incr(s)
+			if f.get(s)&continuation == 0 {
+				break
+			}
+		}
+		if s == start {
+			// fr becomes the new head of the run; the old head continues it.
+			f.set(start, f.get(start)|continuation)
+		} else {
+			entry |= continuation
+		}
+	}
+	if s != fq {
+		entry |= shifted
+	}
+	f.shiftInsert(s, entry)
+	return nil
+}
+
+// shiftInsert puts entry at slot s, moving the entries from s up to the
+// next empty slot one slot right. Occupied bits stay with their slots.
+func (f *Filter) shiftInsert(s, entry uint64) {
+	curr := entry
+	for {
+		prev := f.get(s)
+		empty := isEmpty(prev)
+		if !empty {
+			prev |= shifted
+			if prev&occupied != 0 {
+				curr |= occupied
+				prev &^= occupied
+			}
+		}
+		f.set(s, curr)
+		if empty {
+			return
+		}
+		curr = prev
+		s = f.incr(s)
+	}
+}
+
+// remove deletes one copy of fingerprint fp, reporting whether it was found.
+func (f *Filter) remove(fp uint64) bool {
+	fq, fr := f.split(fp)
+	head := f.get(fq)
+	if head&occupied == 0 || f.count == 0 {
+		return false
+	}
+	start := f.runStart(fq)
+	s := start
+	for {
+		rem := f.get(s) >> metaBits
+		if rem == fr {
+			break
+		}
+		if rem > fr {
+			return false
+		}
+		s = f.incr(s)
+		if f.get(s)&continuation == 0 {
+			return false
+		}
+	}
+
+	removingHead := s == start
+	if removingHead && f.get(f.incr(s))&continuation == 0 {
+		// fr was the only remainder for fq.
+		f.set(fq, f.get(fq)&^occupied)
+	}
+	f.shiftDelete(s, fq)
+	if removingHead {
+		// The next remainder in the run, if any, slid into s and is now
+		// its head.
+		next := f.get(s)
+		updated := next
+		if updated&continuation != 0 {
+			updated &^= continuation
+		}
+		if s == fq && isRunStart(updated) {
+			updated &^= shifted
+		}
+		if updated != next {
+			f.set(s, updated)
+		}
+	}
+	f.count--
+	return true
+}
+
+// shiftDelete removes the entry at slot s, whose run belongs to quotient
+// quot, moving the rest of the cluster one slot left. Runs that slide back
+// into their canonical slot lose their shifted bit.
+func (f *Filter) shiftDelete(s, quot uint64) {
+	curr := f.get(s)
+	sp := f.incr(s)
+	orig := s
+	for {
+		next := f.get(sp)
+		currOccupied := curr&occupied != 0
+		if isEmpty(next) || isClusterStart(next) || sp == orig {
+			f.set(s, curr&occupied)
+			return
+		}
+		updated := next
+		if isRunStart(next) {
+			// next heads the run of the next occupied quotient.
+			for {
+				quot = f.incr(quot)
+				if f.get(quot)&occupied != 0 {
+					break
+				}
+			}
+			if quot == s {
+				updated &^= shifted
+			}
+		}
+		if currOccupied {
+			updated |= occupied
+		} else {
+			updated &^= occupied
+		}
+		f.set(s, updated)
+		s = sp
+		sp = f.incr(sp)
+		curr = next
+	}
+}
+
+// each calls fn with every stored fingerprint, duplicates included.
+func (f *Filter) each(fn func(fp uint64)) {
+	f.walk(func(quot, rem uint64) {
+		fn(quot<<f.r | rem)
+	})
+}
+
+// eachRun calls fn with the remainders of each run in slot order.
+func (f *Filter) eachRun(fn func(rem []uint64)) {
+	var run []uint64
+	var last uint64
+	started := false
+	f.walk(func(quot, rem uint64) {
+		if started && quot != last {
+			fn(run)
+			run = run[:0]
+		}
+		started, last = true, quot
+		run = append(run, rem)
+	})
+	if started {
+		fn(run)
+	}
+}
+
+// walk calls fn with the quotient and remainder of every stored entry,
+// cluster by cluster, starting after an empty slot.
+func (f *Filter) walk(fn func(quot, rem uint64)) {
+	if f.count == 0 {
+		return
+	}
+	start := uint64(0)
+	for !isEmpty(f.get(start)) {
+		start++
+	}
+	var quot uint64
+	for n, s := uint64(0), f.incr(start); n < f.nslots(); n, s = n+1, f.incr(s) {
+		e := f.get(s)
+		switch {
+		case isEmpty(e):
+			continue
+		case isClusterStart(e):
+			quot = s
+		case isRunStart(e):
+			for {
+				quot = f.incr(quot)
+				if f.get(quot)&occupied != 0 {
+					break
+				}
+			}
+		}
+		fn(quot, e>>metaBits)
+	}
+}
+
+// clone returns a deep copy of f.
+func (f *Filter) clone() *Filter {
+	c := *f
+	c.slots = append([]uint64(nil), f.slots...)
+	return &c
+}
+
+// mix64 is the murmur3 64-bit finalizer (fmix64).
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
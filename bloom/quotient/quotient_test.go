@@ -0,0 +1,273 @@
+package quotient
+
+import (
+	"errors"
+	"maps"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+
+	"github.com/Abhisheklearn12/bloom-filter/bloom"
+)
+
+// contents returns the stored fingerprints with their multiplicities.
+func contents(f *Filter) map[uint64]int {
+	got := make(map[uint64]int)
+	f.each(func(fp uint64) { got[fp]++ })
+	return got
+}
+
+// checkAgainst fails unless f is structurally valid and stores exactly the
+// fingerprints in want, answering contains accordingly for every
+// fingerprint of its size.
+func checkAgainst(t *testing.T, f *Filter, want map[uint64]int, step string) {
+	t.Helper()
+	if err := f.validate(); err != nil {
+		t.Fatalf("%s: %v", step, err)
+	}
+	if got := contents(f); !maps.Equal(got, want) {
+		t.Fatalf("%s: stored %v, want %v", step, got, want)
+	}
+	if p := f.q + f.r; p <= 12 {
+		for fp := uint64(0); fp < 1<<p; fp++ {
+			if f.contains(fp) != (want[fp] > 0) {
+				t.Fatalf("%s: contains(%d) = %v", step, fp, !(want[fp] > 0))
+			}
+		}
+	}
+}
+
+// TestExhaustiveSmall inserts every sequence of fingerprints up to a
+// small length into tiny tables, where runs and clusters wrap around the
+// end, then deletes them in insertion and in reverse order, checking the
+// table against a map after every step.
+func TestExhaustiveSmall(t *testing.T) {
+	cases := []struct{ q, r, length uint }{
+		{1, 2, 1},
+		{2, 2, 3},
+		{3, 1, 4},
+		{3, 2, 3},
+	}
+	for _, c := range cases {
+		alphabet := uint64(1) << (c.q + c.r)
+		seq := make([]uint64, c.length)
+		total := uint64(1)
+		for range c.length {
+			total *= alphabet
+		}
+		for n := uint64(0); n < total; n++ {
+			for i, v := 0, n; i < len(seq); i, v = i+1, v/alphabet {
+				seq[i] = v % alphabet
+			}
+			for _, reverse := range []bool{false, true} {
+				exerciseSequence(t, New(c.q, c.r), seq, reverse)
+			}
+		}
+	}
+}
+
+func exerciseSequence(t *testing.T, f *Filter, seq []uint64, reverse bool) {
+	t.Helper()
+	want := make(map[uint64]int)
+	for i, fp := range seq {
+		if err := f.insert(fp); err != nil {
+			t.Fatalf("q=%d r=%d %v: insert %d: %v", f.q, f.r, seq, i, err)
+		}
+		want[fp]++
+		checkAgainst(t, f, want, "q="+strconv.Itoa(int(f.q))+" r="+strconv.Itoa(int(f.r))+" insert")
+	}
+	for fp := uint64(0); fp < 1<<(f.q+f.r); fp++ {
+		if want[fp] == 0 && f.remove(fp) {
+			t.Fatalf("%v: removed absent fingerprint %d", seq, fp)
+		}
+	}
+	for i := range seq {
+		fp := seq[i]
+		if reverse {
+			fp = seq[len(seq)-1-i]
+		}
+		if !f.remove(fp) {
+			t.Fatalf("%v: fingerprint %d not found for removal", seq, fp)
+		}
+		if want[fp]--; want[fp] == 0 {
+			delete(want, fp)
+		}
+		checkAgainst(t, f, want, "remove")
+	}
+	if f.Len() != 0 {
+		t.Fatalf("%v: %d entries left", seq, f.Len())
+	}
+}
+
+// TestRandomDifferential runs random adds and deletes against a map of
+// fingerprints, doubling the filter part way through.
+func TestRandomDifferential(t *testing.T) {
+	r := rand.New(rand.NewPCG(51, 52))
+	f := New(9, 7)
+	keys := make([][]byte, 2000)
+	for i := range keys {
+		keys[i] = []byte("key-" + strconv.Itoa(i))
+	}
+	oracle := make(map[uint64]int)
+	total := uint64(0)
+
+	for op := 0; op < 40_000; op++ {
+		key := keys[r.IntN(len(keys))]
+		fp := f.fingerprint(key)
+		if r.IntN(3) > 0 && f.LoadFactor() < 0.9 {
+			if err := f.Add(key); err != nil {
+				t.Fatal(err)
+			}
+			oracle[fp]++
+			total++
+		} else {
+			if got := f.Delete(key); got != (oracle[fp] > 0) {
+				t.Fatalf("op %d: Delete = %v, oracle has %d", op, got, oracle[fp])
+			}
+			if oracle[fp] > 0 {
+				if oracle[fp]--; oracle[fp] == 0 {
+					delete(oracle, fp)
+				}
+				total--
+			}
+		}
+		if op == 20_000 {
+			if err := f.DoubleCapacity(); err != nil {
+				t.Fatal(err)
+			}
+			if f.q != 10 || f.r != 6 {
+				t.Fatalf("DoubleCapacity gave q=%d r=%d", f.q, f.r)
+			}
+		}
+		if op%997 == 0 {
+			checkAgainst(t, f, oracle, "op "+strconv.Itoa(op))
+		}
+	}
+
+	checkAgainst(t, f, oracle, "end")
+	if f.Len() != total {
+		t.Fatalf("Len = %d, want %d", f.Len(), total)
+	}
+	for _, key := range keys {
+		if f.MayContain(key) != (oracle[f.fingerprint(key)] > 0) {
+			t.Fatalf("%q: MayContain disagrees with the oracle", key)
+		}
+	}
+}
+
+func TestFalsePositiveRate(t *testing.T) {
+	f := New(16, 8)
+	const n = 1 << 15 // half load
+	for i := 0; i < n; i++ {
+		if err := f.Add([]byte("in-" + strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		if !f.MayContain([]byte("in-" + strconv.Itoa(i))) {
+			t.Fatalf("in-%d missing", i)
+		}
+	}
+	fps := 0
+	const trials = 200_000
+	for i := 0; i < trials; i++ {
+		if f.MayContain([]byte("out-" + strconv.Itoa(i))) {
+			fps++
+		}
+	}
+	// load * 2^-r = 0.5/256 ~ 0.195%.
+	if rate := float64(fps) / trials; rate < 0.0014 || rate > 0.0026 {
+		t.Fatalf("false positive rate %.3f%%, want ~0.195%%", 100*rate)
+	}
+}
+
+func TestFullAndMerge(t *testing.T) {
+	small := New(3, 5)
+	for i := 0; i < 7; i++ {
+		if err := small.Add([]byte("s-" + strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := small.Add([]byte("one-too-many")); !errors.Is(err, bloom.ErrFilterFull) {
+		t.Fatalf("expected ErrFilterFull, got %v", err)
+	}
+
+	big := New(6, 2) // same 8-bit fingerprints, more slots
+	big.Add([]byte("b-0"))
+	if err := big.Merge(small); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 7; i++ {
+		if !big.MayContain([]byte("s-" + strconv.Itoa(i))) {
+			t.Fatalf("s-%d missing after Merge", i)
+		}
+	}
+	if big.Len() != 8 {
+		t.Fatalf("Len = %d after Merge, want 8", big.Len())
+	}
+	if err := big.Merge(big); err != nil || big.Len() != 16 {
+		t.Fatalf("self-merge: %v, Len %d", err, big.Len())
+	}
+
+	before := contents(small)
+	if err := small.Merge(big); !errors.Is(err, bloom.ErrFilterFull) {
+		t.Fatalf("expected ErrFilterFull, got %v", err)
+	}
+	if err := small.Merge(New(3, 6)); !errors.Is(err, bloom.ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible, got %v", err)
+	}
+	if !maps.Equal(contents(small), before) {
+		t.Fatal("a failed Merge modified the filter")
+	}
+
+	tiny := New(1, 1)
+	if err := tiny.DoubleCapacity(); !errors.Is(err, bloom.ErrFilterFull) {
+		t.Fatalf("expected ErrFilterFull shrinking a 1-bit remainder, got %v", err)
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	f := New(8, 11) // 14-bit slots straddle word boundaries
+	for i := 0; i < 180; i++ {
+		f.Add([]byte("key-" + strconv.Itoa(i%150)))
+	}
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Filter
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got.Info() != f.Info() || !maps.Equal(contents(&got), contents(f)) {
+		t.Fatalf("decoded %s, want %s", got.Info(), f.Info())
+	}
+
+	// Find a used slot whose metadata we can break.
+	var used uint64
+	for f.get(used)&continuation == 0 {
+		used++
+	}
+	broken := f.clone()
+	broken.set(used, f.get(used)&^shifted)
+	badMeta, _ := broken.MarshalBinary()
+	badCount := append([]byte(nil), data...)
+	badCount[3]++
+	for name, bad := range map[string][]byte{
+		"truncated": data[:len(data)-1],
+		"header":    data[:headerLen-1],
+		"count":     badCount,
+		"metadata":  badMeta,
+		"params":    append([]byte{encodingVersion, 40, 40}, data[3:]...),
+	} {
+		if err := got.UnmarshalBinary(bad); !errors.Is(err, bloom.ErrCorrupt) {
+			t.Fatalf("%s: expected ErrCorrupt, got %v", name, err)
+		}
+	}
+	if err := got.UnmarshalBinary(append([]byte{9}, data[1:]...)); !errors.Is(err, bloom.ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+	if got.Info() != f.Info() {
+		t.Fatal("a failed decode modified the receiver")
+	}
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package bloom
+
+// raceEnabled reports whether tests run under the race detector, which
+// makes sync.Pool drop items at random and so defeats allocation checks.
+const raceEnabled = true
@@ -0,0 +1,141 @@
+package bloom
+
+import (
+	"fmt"
+	"io"
+)
+
+// readerChunkSize is the size of the pooled buffer AddReader and
+// MightContainReader stream content through.
+const readerChunkSize = 32 << 10
+
+// AddReader inserts the whole content of r as one key, exactly as Add would
+// insert the same bytes, without holding more than a fixed-size chunk in
+// memory. It suits keys such as file contents. If reading fails the error
+// is returned and the filter is left unmodified.
+//
+// Streaming needs the default hashing (seeded or not, with plain or
+// enhanced double hashing); a filter with a custom Hasher or a foreign
+// probe scheme fails with ErrIncompatible before reading. A zero-value or
+// nil filter fails with ErrUninitialized.
+func (bf *BloomFilter) AddReader(r io.Reader) error {
+	if !bf.initialized() {
+		return ErrUninitialized
+	}
+	h, err := bf.hashReader(r)
+	if err != nil {
+		return err
+	}
+	bf.addHashes(h)
+	return nil
+}
+
+// MightContainReader checks whether the whole content of r, as one key,
+// might be in the filter. See AddReader for the memory use and the filters
+// supported. A zero-value or nil filter contains nothing and r is not read.
+func (bf *BloomFilter) MightContainReader(r io.Reader) (bool, error) {
+	if !bf.initialized() {
+		return false, nil
+	}
+	h, err := bf.hashReader(r)
+	if err != nil {
+		return false, err
+	}
+	return bf.containsHashes(h), nil
+}
+
+// hashReader returns the base hashes of r's content under bf's hashing.
+func (bf *BloomFilter) hashReader(r io.Reader) (baseHashes, error) {
+	if !bf.scheme.native() {
+		return baseHashes{}, fmt.Errorf("%w: probe scheme %s cannot hash a stream", ErrIncompatible, bf.scheme)
+	}
+	if bf.hasher != nil {
+		return baseHashes{}, fmt.Errorf("%w: hasher %s cannot hash a stream", ErrIncompatible, bf.hasher)
+	}
+
+	pooled := scratchPool.Get().(*[]byte)
+	defer releaseScratch(pooled)
+	if cap(*pooled) < readerChunkSize {
+		*pooled = make([]byte, readerChunkSize)
+	}
+	buf := (*pooled)[:readerChunkSize]
+
+	st := newFNVStream(bf.seed)
+	for {
+		n, err := r.Read(buf)
+		st.write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return baseHashes{}, err
+		}
+	}
+	h1, h2 := st.sum()
+	return baseHashes{h1, h2}, nil
+}
+
+// AddReader streams r's content as one key and inserts it. The content is
+// hashed without holding the lock, which is only taken to set the bits.
+// See BloomFilter.AddReader.
+func (s *SafeBloom) AddReader(r io.Reader) error {
+	geom := s.hashingGeometry()
+	if !geom.initialized() {
+		return ErrUninitialized
+	}
+	h, err := geom.hashReader(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkHashing(&geom); err != nil {
+		return err
+	}
+	s.bf.addHashes(h)
+	return nil
+}
+
+// MightContainReader streams r's content as one key and checks it, taking
+// the read lock only to test the bits. See BloomFilter.MightContainReader.
+func (s *SafeBloom) MightContainReader(r io.Reader) (bool, error) {
+	geom := s.hashingGeometry()
+	if !geom.initialized() {
+		return false, nil
+	}
+	h, err := geom.hashReader(r)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.checkHashing(&geom); err != nil {
+		return false, err
+	}
+	return s.bf.containsHashes(h), nil
+}
+
+// hashingGeometry returns a storage-less copy of the current filter's
+// hashing parameters, safe to use without the lock.
+func (s *SafeBloom) hashingGeometry() BloomFilter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	bf := s.bf
+	if !bf.initialized() {
+		return BloomFilter{}
+	}
+	return BloomFilter{m: bf.m, k: bf.k, scheme: bf.scheme, hasher: bf.hasher, seed: bf.seed}
+}
+
+// checkHashing reports, with the lock held, whether the current filter
+// still hashes keys as geom does: a filter swapped in while a key was
+// being hashed outside the lock may not.
+func (s *SafeBloom) checkHashing(geom *BloomFilter) error {
+	bf := s.bf
+	if !bf.initialized() || bf.scheme.native() != geom.scheme.native() || bf.seed != geom.seed || !sameHasher(bf.hasher, geom.hasher) {
+		return fmt.Errorf("%w: filter replaced while hashing", ErrIncompatible)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package bloom
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+)
+
+// chunkReader returns r's content in reads of the given sizes, cycling.
+type chunkReader struct {
+	r     io.Reader
+	sizes []int
+	i     int
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	n := c.sizes[c.i%len(c.sizes)]
+	c.i++
+	return c.r.Read(p[:min(n, len(p))])
+}
+
+func TestAddReader_MatchesAdd(t *testing.T) {
+	content := make([]byte, 3*readerChunkSize+17)
+	rand.New(rand.NewSource(1)).Read(content)
+
+	seeded, _ := NewWithOptions(1000, 0.01, WithSeed(5))
+	enhanced, _ := NewWithOptions(1000, 0.01, WithEnhancedDoubleHashing())
+	for name, bf := range map[string]*BloomFilter{
+		"default":  NewWithEstimates(1000, 0.01),
+		"seeded":   seeded,
+		"enhanced": enhanced,
+	} {
+		for _, size := range []int{0, 1, 63, readerChunkSize, len(content)} {
+			key := content[:size]
+			want := bf.Clone()
+			want.Add(key)
+
+			readers := map[string]io.Reader{
+				"whole":   bytes.NewReader(key),
+				"onebyte": iotest.OneByteReader(bytes.NewReader(key)),
+				"half":    iotest.HalfReader(bytes.NewReader(key)),
+				"dataerr": iotest.DataErrReader(bytes.NewReader(key)),
+				"awkward": &chunkReader{r: bytes.NewReader(key), sizes: []int{7, 4093, 1, 0, 65536}},
+			}
+			for rname, r := range readers {
+				got := bf.Clone()
+				if err := got.AddReader(r); err != nil {
+					t.Fatalf("%s/%d/%s: %v", name, size, rname, err)
+				}
+				if !got.Equal(want) || got.Count() != want.Count() {
+					t.Fatalf("%s/%d/%s: AddReader set different bits from Add", name, size, rname)
+				}
+				found, err := got.MightContainReader(iotest.HalfReader(bytes.NewReader(key)))
+				if err != nil || !found {
+					t.Fatalf("%s/%d/%s: MightContainReader = %v, %v", name, size, rname, found, err)
+				}
+			}
+		}
+	}
+}
+
+func TestAddReader_Errors(t *testing.T) {
+	bf := NewWithEstimates(100, 0.01)
+	boom := errors.New("boom")
+	r := io.MultiReader(bytes.NewReader([]byte("partial content")), iotest.ErrReader(boom))
+	if err := bf.AddReader(r); !errors.Is(err, boom) {
+		t.Fatalf("AddReader error = %v, want %v", err, boom)
+	}
+	if bf.setBits != 0 || bf.Count() != 0 {
+		t.Fatal("failed AddReader modified the filter")
+	}
+	if _, err := bf.MightContainReader(iotest.ErrReader(boom)); !errors.Is(err, boom) {
+		t.Fatalf("MightContainReader error = %v", err)
+	}
+
+	var zero BloomFilter
+	if err := zero.AddReader(bytes.NewReader(nil)); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("zero-value AddReader: %v", err)
+	}
+	if found, err := zero.MightContainReader(iotest.ErrReader(boom)); found || err != nil {
+		t.Fatalf("zero-value MightContainReader = %v, %v", found, err)
+	}
+	hashed, _ := NewWithOptions(100, 0.01, WithHasher(XXHasher{}))
+	if err := hashed.AddReader(bytes.NewReader(nil)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("custom hasher AddReader: %v", err)
+	}
+	if err := NewGuavaWithEstimates(100, 0.01).AddReader(bytes.NewReader(nil)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("guava AddReader: %v", err)
+	}
+}
+
+func TestSafeBloom_AddReader(t *testing.T) {
+	s := NewSafeWithEstimates(100, 0.01)
+	content := bytes.Repeat([]byte("upload"), 10000)
+	if err := s.AddReader(bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	if !s.MightContain(content) {
+		t.Fatal("SafeBloom.AddReader did not add the content")
+	}
+	if found, err := s.MightContainReader(bytes.NewReader(content)); !found || err != nil {
+		t.Fatalf("SafeBloom.MightContainReader = %v, %v", found, err)
+	}
+	if found, _ := s.MightContainReader(bytes.NewReader(content[1:])); found {
+		t.Fatal("a different stream was found")
+	}
+}
@@ -0,0 +1,118 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"unsafe"
+)
+
+// ReaderAtFilter is a read-only filter whose words stay in an io.ReaderAt,
+// typically an *os.File, and are read on demand: each MightContain issues
+// up to k 8-byte reads. It suits filters too large to load on platforms
+// without mmap, or storage that cannot be mapped.
+//
+// It is safe for concurrent use if the underlying ReaderAt is, as
+// *os.File is.
+type ReaderAtFilter struct {
+	r      io.ReaderAt
+	offset int64       // where the words start
+	geom   BloomFilter // m, k, scheme, hasher and seed; no storage
+
+	readErrors atomic.Uint64
+}
+
+// OpenReaderAt opens a filter stored in r in any of the package's on-disk
+// forms: the binary encoding (MarshalBinary or WriteTo), a SaveFile file
+// or a NewMmap file. Only the header is read and the last word checked to
+// be present; a SaveFile checksum is not verified, since that would read
+// the whole file.
+func OpenReaderAt(r io.ReaderAt) (*ReaderAtFilter, error) {
+	buf := make([]byte, len(fileMagic)+1+headerLen(encodingVersion))
+	n, err := r.ReadAt(buf, 0)
+	if n == 0 {
+		return nil, fmt.Errorf("%w: short header: %v", ErrCorrupt, err)
+	}
+	buf = buf[:n]
+
+	start := 0
+	switch {
+	case bytes.HasPrefix(buf, fileMagic[:]):
+		if len(buf) <= len(fileMagic) {
+			return nil, fmt.Errorf("%w: short header", ErrCorrupt)
+		}
+		if v := buf[len(fileMagic)]; v != fileVersion {
+			return nil, fmt.Errorf("%w: file version %d", ErrUnsupportedVersion, v)
+		}
+		start = len(fileMagic) + 1
+	case bytes.HasPrefix(buf, mmapMagic[:]):
+		start = len(mmapMagic)
+	}
+	h, err := parseHeader(buf[start:])
+	if err != nil {
+		return nil, err
+	}
+	offset := int64(start + headerLen(h.version))
+	if start == len(mmapMagic) {
+		offset = mmapDataOffset
+	}
+
+	hc, err := configForID(h.hasher)
+	if err != nil {
+		return nil, err
+	}
+	f := &ReaderAtFilter{r: r, offset: offset, geom: BloomFilter{m: h.m, k: h.k, scheme: h.scheme, hasher: hc, seed: h.seed}}
+	var last [8]byte
+	if _, err := r.ReadAt(last[:], offset+int64(h.words-1)*8); err != nil {
+		return nil, fmt.Errorf("%w: stream ended before word %d: %v", ErrCorrupt, h.words-1, err)
+	}
+	return f, nil
+}
+
+// MightContain checks if data might be in the filter.
+// Returns false -> definitely not present.
+// Returns true  -> might be present (subject to false positives).
+// A failed read counts as present, so I/O errors never cause a false
+// negative; see ReadErrors.
+func (f *ReaderAtFilter) MightContain(data []byte) bool {
+	h := f.geom.hashes(data)
+	var word [8]byte
+	for i := uint64(0); i < f.geom.k; i++ {
+		pos := f.geom.location(h, i)
+		if _, err := f.r.ReadAt(word[:], f.offset+int64(pos/64)*8); err != nil {
+			f.readErrors.Add(1)
+			return true
+		}
+		if binary.LittleEndian.Uint64(word[:])&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadErrors returns the number of lookups answered present because a
+// read failed.
+func (f *ReaderAtFilter) ReadErrors() uint64 {
+	return f.readErrors.Load()
+}
+
+// Info returns a small description of the filter's configuration.
+func (f *ReaderAtFilter) Info() string {
+	return fmt.Sprintf("ReaderAtFilter{m=%d bits, k=%d}", f.geom.m, f.geom.k)
+}
+
+// SizeInBytes reports the memory held by the filter: only the struct, as
+// the bits stay in the ReaderAt.
+func (f *ReaderAtFilter) SizeInBytes() uint64 {
+	return uint64(unsafe.Sizeof(*f))
+}
+
+// Close closes the underlying ReaderAt if it is an io.Closer.
+func (f *ReaderAtFilter) Close() error {
+	if c, ok := f.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package bloom
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenReaderAt_AllFormats(t *testing.T) {
+	bf := New(10_007, 5)
+	keys := randomKeys("ra-", 1000, 81)
+	for _, key := range keys {
+		bf.Add(key)
+	}
+	dir := t.TempDir()
+
+	encoded, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := filepath.Join(dir, "saved.bloom")
+	if err := bf.SaveFile(saved); err != nil {
+		t.Fatal(err)
+	}
+	mapped, err := NewMmap(filepath.Join(dir, "mapped.bloom"), bf.m, bf.k)
+	if err != nil {
+		t.Skip(err)
+	}
+	if err := mapped.CopyFrom(bf); err != nil {
+		t.Fatal(err)
+	}
+	if err := mapped.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := map[string]func() (*ReaderAtFilter, error){
+		"encoding": func() (*ReaderAtFilter, error) { return OpenReaderAt(bytes.NewReader(encoded)) },
+		"savefile": func() (*ReaderAtFilter, error) { return openFileReaderAt(saved) },
+		"mmap":     func() (*ReaderAtFilter, error) { return openFileReaderAt(filepath.Join(dir, "mapped.bloom")) },
+	}
+	for name, open := range sources {
+		f, err := open()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for i, key := range keys {
+			if !f.MightContain(key) {
+				t.Fatalf("%s: key %d missing", name, i)
+			}
+		}
+		for i, key := range randomKeys("other-", 1000, 82) {
+			if f.MightContain(key) != bf.MightContain(key) {
+				t.Fatalf("%s: probe %d disagrees with the in-memory filter", name, i)
+			}
+		}
+		if f.ReadErrors() != 0 {
+			t.Fatalf("%s: %d read errors", name, f.ReadErrors())
+		}
+		f.Close()
+	}
+
+	if _, err := OpenReaderAt(bytes.NewReader(encoded[:len(encoded)-1])); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt for a truncated encoding, got %v", err)
+	}
+}
+
+func openFileReaderAt(path string) (*ReaderAtFilter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return OpenReaderAt(f)
+}
@@ -0,0 +1,59 @@
+package bloom
+
+import "fmt"
+
+// Rebuild re-initializes bf in place for n expected insertions at fpRate,
+// as NewWithEstimates would size it, leaving it empty. The word storage is
+// reused when the new size fits its capacity and reallocated otherwise.
+// The probe scheme and saturation threshold are kept.
+//
+// Invalid parameters fail with the error TryNewWithEstimates returns and
+// leave bf untouched. A memory-mapped filter cannot change size and fails
+// with ErrIncompatible.
+func (bf *BloomFilter) Rebuild(n uint64, fpRate float64) error {
+	m, k, err := validEstimates(n, fpRate)
+	if err != nil {
+		return err
+	}
+	if err := bf.rebuild(m, k); err != nil {
+		return err
+	}
+	bf.capacity = n
+	return nil
+}
+
+// RebuildSize is Rebuild with an explicit no. of bits (m) and hash
+// functions (k), validated as TryNew does. The rebuilt filter has no
+// designed capacity.
+func (bf *BloomFilter) RebuildSize(m, k uint64) error {
+	return bf.rebuild(m, k)
+}
+
+func (bf *BloomFilter) rebuild(m, k uint64) error {
+	if bf == nil {
+		return ErrUninitialized
+	}
+	switch {
+	case m == 0:
+		return ErrZeroBits
+	case k == 0:
+		return ErrZeroHashes
+	case m > maxBits:
+		return fmt.Errorf("%w: m=%d bits", ErrFilterTooLarge, m)
+	case bf.mapped != nil:
+		return fmt.Errorf("%w: a memory-mapped filter cannot be rebuilt", ErrIncompatible)
+	}
+
+	words, lazy := int(wordsFor(m)), bf.lazy()
+	if !bf.chunked() && cap(bf.bits) >= words {
+		bf.storage = contiguous(bf.bits[:words])
+		clear(bf.bits)
+	} else {
+		bf.storage = newStorage(words, bf.chunkShift)
+	}
+	bf.setLazy(lazy)
+	bf.m, bf.k = m, k
+	bf.setBits, bf.inserts, bf.capacity, bf.overfilled = 0, 0, 0, false
+	bf.stopTracking()
+	return nil
+}
@@ -0,0 +1,147 @@
+package bloom
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestRebuild(t *testing.T) {
+	bf := NewWithEstimates(10_000, 0.01)
+	bf.SetSaturationThreshold(0.6)
+	bf.Add([]byte("old"))
+	backing := &bf.bits[0]
+
+	// Shrinking reuses the words.
+	if err := bf.Rebuild(1000, 0.01); err != nil {
+		t.Fatal(err)
+	}
+	want := NewWithEstimates(1000, 0.01)
+	if bf.Params() != want.Params() || bf.Stats().Capacity != 1000 {
+		t.Fatalf("rebuilt to %s, want %s", bf.Info(), want.Info())
+	}
+	if &bf.bits[0] != backing {
+		t.Fatal("shrinking Rebuild reallocated the words")
+	}
+	if bf.MightContain([]byte("old")) || bf.setBits != 0 || bf.inserts != 0 || countBits(bf) != 0 {
+		t.Fatal("rebuilt filter is not empty")
+	}
+	if bf.saturationThreshold() != 0.6 {
+		t.Fatal("Rebuild dropped the saturation threshold")
+	}
+	bf.Add([]byte("new"))
+	if !bf.MightContain([]byte("new")) {
+		t.Fatal("rebuilt filter lost a key")
+	}
+
+	// Growing past the capacity reallocates.
+	if err := bf.RebuildSize(1<<20, 5); err != nil {
+		t.Fatal(err)
+	}
+	if bf.m != 1<<20 || bf.k != 5 || len(bf.bits) != 1<<14 || bf.Stats().Capacity != 0 || countBits(bf) != 0 {
+		t.Fatalf("RebuildSize gave %s", bf.Info())
+	}
+
+	// Invalid parameters leave the filter untouched.
+	bf.Add([]byte("kept"))
+	before := bf.Clone()
+	for _, c := range []struct {
+		err  error
+		want error
+	}{
+		{bf.Rebuild(0, 0.01), ErrZeroInsertions},
+		{bf.Rebuild(100, 1), ErrInvalidFPRate},
+		{bf.RebuildSize(0, 3), ErrZeroBits},
+		{bf.RebuildSize(64, 0), ErrZeroHashes},
+		{bf.RebuildSize(1<<62, 3), ErrFilterTooLarge},
+	} {
+		if !errors.Is(c.err, c.want) {
+			t.Errorf("expected %v, got %v", c.want, c.err)
+		}
+	}
+	if !bf.Equal(before) || bf.inserts != before.inserts {
+		t.Fatal("a failed Rebuild modified the filter")
+	}
+
+	var zero BloomFilter
+	if err := zero.Rebuild(100, 0.01); err != nil || !zero.initialized() {
+		t.Fatalf("zero-value Rebuild: %v", err)
+	}
+	if err := (*BloomFilter)(nil).Rebuild(100, 0.01); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("nil Rebuild: %v", err)
+	}
+
+	mapped, err := NewMmap(filepath.Join(t.TempDir(), "f.bloom"), 1024, 3)
+	if errors.Is(err, ErrMmapUnsupported) {
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapped.Close()
+	if err := mapped.Rebuild(10, 0.01); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("mapped Rebuild: expected ErrIncompatible, got %v", err)
+	}
+}
+
+// TestSafeBloom_RebuildRace rebuilds while readers and writers, including
+// ParallelAddAll, use the same SafeBloom.
+func TestSafeBloom_RebuildRace(t *testing.T) {
+	s := NewSafeWithEstimates(1000, 0.01)
+	keys := randomKeys("rebuild", 2000, 4)
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				key := keys[(g*500+i)%len(keys)]
+				// A Rebuild may land between these, so only the race
+				// detector and the final count check the results.
+				s.Add(key)
+				s.MightContain(key)
+				s.Stats()
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ch := make(chan []byte)
+		go func() {
+			defer close(ch)
+			for _, key := range keys {
+				ch <- key
+			}
+		}()
+		if _, err := s.ParallelAddAll(t.Context(), ch, 2); err != nil {
+			t.Error(err)
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		n := uint64(500 + 500*(i%4))
+		if err := s.Rebuild(n, 0.01); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	if got, want := s.Stats().BitsSet, countBits(s.Snapshot()); got != want {
+		t.Fatalf("counter %d, popcount %d", got, want)
+	}
+
+	var zero SafeBloom
+	if err := zero.Rebuild(0, 0.01); !errors.Is(err, ErrZeroInsertions) {
+		t.Fatalf("expected ErrZeroInsertions, got %v", err)
+	}
+	if err := zero.RebuildSize(64, 3); err != nil || zero.Params().M != 64 {
+		t.Fatalf("zero-value SafeBloom RebuildSize: %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"unsafe"
+)
+
+const (
+	// ribbonWidth is the number of consecutive solution rows a key's
+	// coefficients span.
+	ribbonWidth = 64
+
+	// maxRibbonAttempts bounds the seeds BuildRibbon tries. Every
+	// ribbonGrowEvery failed seeds the row count grows by ribbonGrowth.
+	maxRibbonAttempts = 32
+	ribbonGrowEvery   = 8
+	ribbonGrowth      = 0.02
+)
+
+// ribbonOverhead returns the fraction of extra rows over n keys at which
+// banding with 64-bit coefficients succeeds for most seeds. The slack a
+// fixed width needs grows with log n: measured thresholds are about 5% at
+// 10k keys, 8% at 100k, 11% at 1M and 13% at 4M.
+func ribbonOverhead(n int) float64 {
+	if n < 2 {
+		return 0.05
+	}
+	return max(0.05, 0.011*math.Log2(float64(n))-0.08)
+}
+
+// Ribbon is an immutable standard Ribbon filter (Dillinger & Walzer,
+// "Ribbon filter: practically smaller than Bloom and Xor", 2021) for a key
+// set fully known at build time.
+//
+// Each key contributes one linear equation over GF(2): the XOR of the
+// solution rows selected by its 64-bit coefficient, starting at a hashed
+// row, must equal its fpBits-bit fingerprint. A query recomputes that XOR
+// and compares. It takes about 1.07·fpBits bits per key for 10k keys,
+// rising slowly to 1.15·fpBits at a few million (see ribbonOverhead),
+// against 1.23·fpBits for StaticFilter and 1.44·fpBits for a BloomFilter
+// at the same 2^-fpBits false positive rate. The price is slower builds
+// and fpBits parity computations per query.
+//
+// Like the other filters it never reports a false negative for a key it
+// was built from.
+type Ribbon struct {
+	seed   uint64
+	m      uint64   // solution rows
+	fpBits uint64   // fingerprint width in bits (1..32)
+	data   []uint64 // solution, column-major within 64-row blocks
+}
+
+// BuildRibbon constructs a Ribbon for keys with fpBits-bit fingerprints,
+// for a false positive rate of 2^-fpBits. Duplicate keys are harmless. It
+// returns ErrStaticBuildFailed if no seed within a bounded number yields a
+// solvable system; the table grows a little after repeated failures, so
+// for distinct keys this is vanishingly rare.
+//
+// This panics if fpBits is not in [1, 32].
+func BuildRibbon(keys [][]byte, fpBits int) (*Ribbon, error) {
+	if fpBits < 1 || fpBits > 32 {
+		panic("bloom: fpBits must be between 1 and 32")
+	}
+	overhead := ribbonOverhead(len(keys))
+	return buildRibbon(keys, uint64(fpBits), func(attempt uint64) uint64 {
+		grown := overhead + ribbonGrowth*float64(attempt/ribbonGrowEvery)
+		return uint64(float64(len(keys))*(1+grown)) + ribbonWidth
+	})
+}
+
+// buildRibbon bands the keys' equations into rows(attempt) rows, retrying
+// with a new seed on inconsistency, then back-substitutes.
+func buildRibbon(keys [][]byte, fpBits uint64, rows func(attempt uint64) uint64) (*Ribbon, error) {
+	digests := make([][2]uint64, len(keys))
+	for i, key := range keys {
+		h1, h2 := murmur3x64_128(key, 0)
+		digests[i] = [2]uint64{h1, h2}
+	}
+
+	var coeffs []uint64
+	var results []uint32
+	var m uint64
+	for attempt := uint64(0); attempt < maxRibbonAttempts; attempt++ {
+		if m = rows(attempt); uint64(len(coeffs)) != m {
+			coeffs, results = make([]uint64, m), make([]uint32, m)
+		} else {
+			clear(coeffs)
+			clear(results)
+		}
+		rb := &Ribbon{seed: attempt * 0x9e3779b97f4a7c15, m: m, fpBits: fpBits}
+		ok := true
+		for _, d := range digests {
+			start, coeff, fp := rb.equation(d[0], d[1])
+			if !bandInsert(coeffs, results, start, coeff, fp) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			rb.backSubstitute(coeffs, results)
+			return rb, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no ribbon seed solved %d keys in %d rows", ErrStaticBuildFailed, len(keys), m)
+}
+
+// bandInsert adds the equation (start, coeff, fp) to the band by Gaussian
+// elimination, keeping each row's leading coefficient at that row. It
+// reports false if the equation contradicts the ones already banded.
+func bandInsert(coeffs []uint64, results []uint32, start, coeff uint64, fp uint32) bool {
+	for {
+		if coeffs[start] == 0 {
+			coeffs[start], results[start] = coeff, fp
+			return true
+		}
+		coeff ^= coeffs[start]
+		fp ^= results[start]
+		if coeff == 0 {
+			// Redundant (a duplicate key) if the results agree too.
+			return fp == 0
+		}
+		tz := uint64(bits.TrailingZeros64(coeff))
+		start += tz
+		coeff >>= tz
+	}
+}
+
+// backSubstitute solves the banded system from the last row up, one
+// fingerprint bit (column) at a time. Rows no equation leads with are free
+// and set to zero.
+func (rb *Ribbon) backSubstitute(coeffs []uint64, results []uint32) {
+	blocks := (rb.m + 63) / 64
+	rb.data = make([]uint64, (blocks+1)*rb.fpBits) // one padding block for queries
+	for b := uint64(0); b < rb.fpBits; b++ {
+		// state holds the solved bits of rows i+1..i+63 in bits 0..62.
+		var state uint64
+		for i := rb.m; i > 0; i-- {
+			row := i - 1
+			z := uint64(results[row]>>b)&1 ^ uint64(bits.OnesCount64(coeffs[row]>>1&state)&1)
+			state = state<<1 | z
+			rb.data[(row/64)*rb.fpBits+b] |= z << (row % 64)
+		}
+	}
+}
+
+// Contains reports whether data might be in the set the filter was built
+// from.
+// Returns false -> definitely not present.
+// Returns true  -> might be present (subject to false positives).
+func (rb *Ribbon) Contains(data []byte) bool {
+	if rb == nil || rb.m == 0 {
+		return false
+	}
+	h1, h2 := murmur3x64_128(data, 0)
+	start, coeff, fp := rb.equation(h1, h2)
+
+	block, shift := start/64, start%64
+	lo := rb.data[block*rb.fpBits : (block+1)*rb.fpBits]
+	hi := rb.data[(block+1)*rb.fpBits : (block+2)*rb.fpBits]
+	var got uint32
+	for b := range lo {
+		window := lo[b] >> shift
+		if shift != 0 {
+			window |= hi[b] << (64 - shift)
+		}
+		got |= uint32(bits.OnesCount64(window&coeff)&1) << b
+	}
+	return got == fp
+}
+
+// MightContain is Contains, under the name ColdTier and the other
+// filters use.
+func (rb *Ribbon) MightContain(data []byte) bool {
+	return rb.Contains(data)
+}
+
+// equation derives a key's starting row, coefficients and fingerprint
+// from its digest and the filter seed. The coefficients always have their
+// lowest bit set, so the equation leads at its starting row.
+func (rb *Ribbon) equation(h1, h2 uint64) (start, coeff uint64, fp uint32) {
+	a := mix64(h1 ^ rb.seed)
+	c := mix64(h2 + rb.seed)
+	start, _ = bits.Mul64(a, rb.m-ribbonWidth+1)
+	return start, c | 1, uint32(mix64(a^c) & (1<<rb.fpBits - 1))
+}
+
+// SizeInBytes reports the memory held by the filter.
+func (rb *Ribbon) SizeInBytes() uint64 {
+	if rb == nil {
+		return 0
+	}
+	return uint64(len(rb.data))*8 + uint64(unsafe.Sizeof(*rb))
+}
+
+// Info returns a small description of the filter's configuration.
+func (rb *Ribbon) Info() string {
+	if rb == nil {
+		return "Ribbon{nil}"
+	}
+	return fmt.Sprintf("Ribbon{m=%d rows, fpBits=%d}", rb.m, rb.fpBits)
+}
+
+// Ribbon binary format (all integers little-endian):
+//
+//	version  uint8   ribbonVersion
+//	fpBits   uint8
+//	seed     uint64
+//	m        uint64  solution rows
+//	data     (ceil(m/64)+1)*fpBits * uint64
+const (
+	ribbonVersion   = 1
+	ribbonHeaderLen = 1 + 1 + 8 + 8
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (rb *Ribbon) MarshalBinary() ([]byte, error) {
+	if rb == nil || rb.m == 0 {
+		return nil, ErrUninitialized
+	}
+	buf := make([]byte, 0, ribbonHeaderLen+len(rb.data)*8)
+	buf = append(buf, ribbonVersion, byte(rb.fpBits))
+	buf = binary.LittleEndian.AppendUint64(buf, rb.seed)
+	buf = binary.LittleEndian.AppendUint64(buf, rb.m)
+	for _, w := range rb.data {
+		buf = binary.LittleEndian.AppendUint64(buf, w)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, fully replacing
+// the receiver's contents. Malformed data fails with ErrCorrupt, an
+// unknown version with ErrUnsupportedVersion; the receiver is left
+// untouched on error.
+func (rb *Ribbon) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	if data[0] != ribbonVersion {
+		return fmt.Errorf("%w: ribbon version %d", ErrUnsupportedVersion, data[0])
+	}
+	if len(data) < ribbonHeaderLen {
+		return fmt.Errorf("%w: short header", ErrCorrupt)
+	}
+	fpBits := uint64(data[1])
+	seed := binary.LittleEndian.Uint64(data[2:])
+	m := binary.LittleEndian.Uint64(data[10:])
+	if fpBits < 1 || fpBits > 32 || m < ribbonWidth || m > 1<<52 {
+		return fmt.Errorf("%w: fpBits %d, %d rows", ErrCorrupt, fpBits, m)
+	}
+	words := ((m+63)/64 + 1) * fpBits
+	payload := data[ribbonHeaderLen:]
+	if uint64(len(payload)) != words*8 {
+		return fmt.Errorf("%w: payload is %d bytes, want %d", ErrCorrupt, len(payload), words*8)
+	}
+	decoded := &Ribbon{seed: seed, m: m, fpBits: fpBits, data: make([]uint64, words)}
+	for i := range decoded.data {
+		decoded.data[i] = binary.LittleEndian.Uint64(payload[i*8:])
+	}
+	*rb = *decoded
+	return nil
+}
@@ -0,0 +1,172 @@
+package bloom
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestRibbon_NoFalseNegatives(t *testing.T) {
+	keys := randomKeys("ribbon-", 50_000, 61)
+	rb, err := BuildRibbon(keys, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range keys {
+		if !rb.Contains(key) {
+			t.Fatalf("key %d missing", i)
+		}
+	}
+
+	falsePositives := 0
+	const trials = 200_000
+	for i := 0; i < trials; i++ {
+		if rb.Contains([]byte("absent-" + strconv.Itoa(i))) {
+			falsePositives++
+		}
+	}
+	// 2^-8 is ~0.39%.
+	if rate := float64(falsePositives) / trials; rate < 0.0032 || rate > 0.0047 {
+		t.Fatalf("false positive rate %.3f%%, want ~0.39%%", 100*rate)
+	}
+	if perKey := float64(len(rb.data)*64) / float64(len(keys)); perKey > 8*1.12 {
+		t.Fatalf("%.2f bits/key, want under %.2f", perKey, 8*1.12)
+	}
+}
+
+func TestRibbon_DuplicatesAndSmallSets(t *testing.T) {
+	keys := [][]byte{[]byte("a"), []byte("b"), []byte("a"), []byte("a"), []byte("c")}
+	for _, set := range [][][]byte{nil, keys[:1], keys} {
+		rb, err := BuildRibbon(set, 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range set {
+			if !rb.Contains(key) {
+				t.Fatalf("%q missing from a set of %d", key, len(set))
+			}
+		}
+	}
+}
+
+func TestRibbon_BuildFailsAfterBoundedSeeds(t *testing.T) {
+	keys := randomKeys("ribbon-", 1000, 62)
+	// Fewer rows than keys can never be solved.
+	_, err := buildRibbon(keys, 8, func(uint64) uint64 { return 500 })
+	if !errors.Is(err, ErrStaticBuildFailed) {
+		t.Fatalf("expected ErrStaticBuildFailed, got %v", err)
+	}
+}
+
+func TestRibbon_BinaryRoundTrip(t *testing.T) {
+	keys := randomKeys("ribbon-", 3000, 63)
+	rb, err := BuildRibbon(keys, 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := rb.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Ribbon
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6000; i++ {
+		key := []byte("probe-" + strconv.Itoa(i))
+		if i < len(keys) {
+			key = keys[i]
+		}
+		if got.Contains(key) != rb.Contains(key) {
+			t.Fatalf("probe %d: decoded filter disagrees", i)
+		}
+	}
+
+	for name, bad := range map[string][]byte{
+		"truncated": data[:len(data)-1],
+		"header":    data[:ribbonHeaderLen-1],
+		"fpBits":    append([]byte{ribbonVersion, 40}, data[2:]...),
+	} {
+		if err := got.UnmarshalBinary(bad); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: expected ErrCorrupt, got %v", name, err)
+		}
+	}
+	if err := got.UnmarshalBinary(append([]byte{9}, data[1:]...)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+// The benchmarks below build and query Ribbon, StaticFilter and
+// BloomFilter at the same 2^-7 (~0.78%) false positive rate, reporting
+// bits/key alongside time.
+const (
+	benchRibbonFPBits = 7
+	benchRibbonKeys   = 1 << 20
+)
+
+func BenchmarkRibbon_Build(b *testing.B) {
+	keys := benchmarkKeys(100_000)
+	for i := 0; i < b.N; i++ {
+		if _, err := BuildRibbon(keys, benchRibbonFPBits); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStatic_BuildAtRibbonTarget(b *testing.B) {
+	keys := benchmarkKeys(100_000)
+	for i := 0; i < b.N; i++ {
+		if _, err := BuildStatic(keys, 1.0/(1<<benchRibbonFPBits)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBloom_BuildAtRibbonTarget(b *testing.B) {
+	keys := benchmarkKeys(100_000)
+	for i := 0; i < b.N; i++ {
+		bf := NewWithEstimates(uint64(len(keys)), 1.0/(1<<benchRibbonFPBits))
+		for _, key := range keys {
+			bf.Add(key)
+		}
+	}
+}
+
+func BenchmarkRibbon_Contains(b *testing.B) {
+	keys := benchmarkKeys(benchRibbonKeys)
+	rb, err := BuildRibbon(keys, benchRibbonFPBits)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rb.Contains(keys[i&(len(keys)-1)])
+	}
+	b.ReportMetric(float64(rb.SizeInBytes()*8)/float64(len(keys)), "bits/key")
+}
+
+func BenchmarkStatic_MightContainAtRibbonTarget(b *testing.B) {
+	keys := benchmarkKeys(benchRibbonKeys)
+	sf, err := BuildStatic(keys, 1.0/(1<<benchRibbonFPBits))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sf.MightContain(keys[i&(len(keys)-1)])
+	}
+	b.ReportMetric(float64(sf.SizeInBytes()*8)/float64(len(keys)), "bits/key")
+}
+
+func BenchmarkBloom_MightContainAtRibbonTarget(b *testing.B) {
+	keys := benchmarkKeys(benchRibbonKeys)
+	bf := NewWithEstimates(uint64(len(keys)), 1.0/(1<<benchRibbonFPBits))
+	for _, key := range keys {
+		bf.Add(key)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.MightContain(keys[i&(len(keys)-1)])
+	}
+	b.ReportMetric(float64(bf.SizeInBytes()*8)/float64(len(keys)), "bits/key")
+}
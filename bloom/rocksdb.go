@@ -0,0 +1,162 @@
+package bloom
+
+import (
+	"fmt"
+	"math"
+)
+
+// RocksDB full filter block, FastLocalBloom implementation (format_version
+// 5 and later). The block is len_bytes of filter data, a multiple of the
+// 64-byte cache line, followed by 5 bytes of metadata:
+//
+//	0xff      marker for the newer Bloom implementations
+//	0x00      marker for FastLocalBloom
+//	probes    num_probes in the low 5 bits; upper bits 0 for 64-byte lines
+//	0x00 0x00 reserved
+//
+// A key's 64-bit hash picks a cache line with its lower 32 bits and probes
+// num_probes bits within that line with its upper 32 bits.
+const (
+	rocksMetadataLen = 5
+	rocksCacheLine   = 64
+	rocksMaxLenBytes = 0xffffffc0
+)
+
+// RocksDBFilter is a read-only view of a filter block in RocksDB's
+// FastLocalBloom layout, implemented from RocksDB's source
+// (util/bloom_impl.h). It has only been tested against blocks worked out
+// from that source, not against blocks extracted from SST files written by
+// RocksDB, so compatibility with RocksDB itself is unverified.
+//
+// Keys are identified by the 64-bit hash RocksDB computes for them with
+// GetSliceHash64 (the XXH3 preview variant RocksDB vendors as XXPH3). This
+// package does not implement that hash, since any divergence would surface
+// as silent false negatives in the database; callers must supply it.
+type RocksDBFilter struct {
+	data      []byte
+	numProbes int
+}
+
+// BuildRocksDBFilter returns a filter block for keys with the given 64-bit
+// RocksDB hashes, laid out as RocksDB's FastLocalBloomBitsBuilder does for
+// bloom.NewBloomFilterPolicy(bitsPerKey) (but see RocksDBFilter). Adjacent duplicate
+// hashes are counted once, as in RocksDB. bitsPerKey is clamped to
+// [1, 100].
+func BuildRocksDBFilter(bitsPerKey float64, hashes []uint64) []byte {
+	millibits := rocksMillibitsPerKey(bitsPerKey)
+	numProbes := rocksChooseNumProbes(millibits)
+
+	entries := uint64(0)
+	for i, h := range hashes {
+		if i == 0 || h != hashes[i-1] {
+			entries++
+		}
+	}
+	lenBytes := min((entries*uint64(millibits)+7999)/8000, rocksMaxLenBytes)
+	lenBytes = (lenBytes + rocksCacheLine - 1) &^ (rocksCacheLine - 1)
+
+	block := make([]byte, lenBytes+rocksMetadataLen)
+	if lenBytes > 0 {
+		for _, h := range hashes {
+			line := block[rocksCacheLineOffset(uint32(h), uint32(lenBytes)):]
+			for i, p := 0, uint32(h>>32); i < numProbes; i, p = i+1, p*0x9e3779b9 {
+				bit := p >> (32 - 9) // 9-bit position in the 512-bit line
+				line[bit>>3] |= 1 << (bit & 7)
+			}
+		}
+	}
+	meta := block[lenBytes:]
+	meta[0] = 0xff
+	meta[1] = 0x00
+	meta[2] = byte(numProbes)
+	return block
+}
+
+// ParseRocksDBFilter wraps a filter block extracted from an SST file. The
+// block is not copied. Blocks written by the legacy or Ribbon
+// implementations, or with a non-64-byte cache line, fail with
+// ErrUnsupportedFormat; malformed blocks fail with ErrCorrupt.
+func ParseRocksDBFilter(block []byte) (*RocksDBFilter, error) {
+	if len(block) < rocksMetadataLen {
+		return nil, fmt.Errorf("%w: rocksdb filter block of %d bytes", ErrCorrupt, len(block))
+	}
+	lenBytes := len(block) - rocksMetadataLen
+	meta := block[lenBytes:]
+	if meta[0] != 0xff || meta[1] != 0x00 {
+		return nil, fmt.Errorf("%w: rocksdb filter marker %#x %#x is not FastLocalBloom", ErrUnsupportedFormat, meta[0], meta[1])
+	}
+	if meta[2]>>5 != 0 {
+		return nil, fmt.Errorf("%w: rocksdb filter with %d-byte cache lines", ErrUnsupportedFormat, 64<<(meta[2]>>5))
+	}
+	numProbes := int(meta[2] & 0x1f)
+	if numProbes == 0 || lenBytes%rocksCacheLine != 0 {
+		return nil, fmt.Errorf("%w: rocksdb filter with %d probes over %d bytes", ErrCorrupt, numProbes, lenBytes)
+	}
+	return &RocksDBFilter{data: block[:lenBytes], numProbes: numProbes}, nil
+}
+
+// NumProbes returns the number of bits probed per key.
+func (f *RocksDBFilter) NumProbes() int {
+	return f.numProbes
+}
+
+// MayMatchHash reports whether the key with RocksDB hash h might be in the
+// filter. A block with no filter data, which RocksDB writes for zero keys,
+// matches nothing.
+func (f *RocksDBFilter) MayMatchHash(h uint64) bool {
+	if len(f.data) == 0 {
+		return false
+	}
+	line := f.data[rocksCacheLineOffset(uint32(h), uint32(len(f.data))):]
+	for i, p := 0, uint32(h>>32); i < f.numProbes; i, p = i+1, p*0x9e3779b9 {
+		bit := p >> (32 - 9)
+		if line[bit>>3]&(1<<(bit&7)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// rocksCacheLineOffset is RocksDB's FastRange32(len_bytes >> 6, h1) << 6.
+func rocksCacheLineOffset(h1, lenBytes uint32) uint32 {
+	return uint32((uint64(h1)*uint64(lenBytes>>6))>>32) << 6
+}
+
+// rocksMillibitsPerKey mirrors BloomFilterPolicy's rounding of bits_per_key.
+func rocksMillibitsPerKey(bitsPerKey float64) int {
+	bitsPerKey = math.Min(math.Max(bitsPerKey, 1), 100)
+	return int(bitsPerKey*1000.0 + 0.500001)
+}
+
+// rocksChooseNumProbes mirrors FastLocalBloomBitsBuilder::ChooseNumProbes.
+func rocksChooseNumProbes(millibitsPerKey int) int {
+	switch {
+	case millibitsPerKey <= 2080:
+		return 1
+	case millibitsPerKey <= 3580:
+		return 2
+	case millibitsPerKey <= 5100:
+		return 3
+	case millibitsPerKey <= 6640:
+		return 4
+	case millibitsPerKey <= 8300:
+		return 5
+	case millibitsPerKey <= 10070:
+		return 6
+	case millibitsPerKey <= 11720:
+		return 7
+	case millibitsPerKey <= 14001:
+		return 8
+	case millibitsPerKey <= 16050:
+		return 9
+	case millibitsPerKey <= 18300:
+		return 10
+	case millibitsPerKey <= 22001:
+		return 11
+	case millibitsPerKey <= 25501:
+		return 12
+	case millibitsPerKey > 50000:
+		return 24
+	}
+	return (millibitsPerKey-1)/2000 - 1
+}
@@ -0,0 +1,112 @@
+package bloom
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+// rocksOneKey is the block RocksDB should build at 3 bits/key (2 probes)
+// for one key whose hash has upper half 1, worked out by hand from
+// FastLocalBloom's source rather than taken from an SST file; these tests
+// check the package against that reading of the algorithm, not against
+// RocksDB. One entry needs (3000+7999)/8000 = 1
+// byte, rounded up to a single 64-byte line. The first probe is bit
+// 1>>23 = 0; the second is 0x9e3779b9>>23 = 316, i.e. byte 39 bit 4.
+var rocksOneKey = func() []byte {
+	b := make([]byte, 64, 64+rocksMetadataLen)
+	b[0] = 0x01
+	b[39] = 0x10
+	return append(b, 0xff, 0x00, 0x02, 0x00, 0x00)
+}()
+
+func TestRocksDB_KnownBlock(t *testing.T) {
+	const h = 0x00000001_deadbeef
+	block := BuildRocksDBFilter(3, []uint64{h, h})
+	if !bytes.Equal(block, rocksOneKey) {
+		t.Fatalf("block = %x, want %x", block, rocksOneKey)
+	}
+
+	f, err := ParseRocksDBFilter(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.NumProbes() != 2 || !f.MayMatchHash(h) {
+		t.Fatal("parsed block does not match its own key")
+	}
+	if f.MayMatchHash(0x00000002_deadbeef) {
+		t.Fatal("unexpected match for a key probing other bits")
+	}
+}
+
+func TestRocksDB_SizingAndFalsePositives(t *testing.T) {
+	hashes := make([]uint64, 10_000)
+	for i := range hashes {
+		hashes[i] = xxhash64([]byte("key-"+strconv.Itoa(i)), 0)
+	}
+	block := BuildRocksDBFilter(10, hashes)
+	if want := 12544 + rocksMetadataLen; len(block) != want {
+		t.Fatalf("block is %d bytes, want %d", len(block), want)
+	}
+
+	f, err := ParseRocksDBFilter(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.NumProbes() != 6 {
+		t.Fatalf("%d probes at 10 bits/key, want 6", f.NumProbes())
+	}
+	for i, h := range hashes {
+		if !f.MayMatchHash(h) {
+			t.Fatalf("false negative for key-%d", i)
+		}
+	}
+	falsePositives := 0
+	for i := 10_000; i < 110_000; i++ {
+		if f.MayMatchHash(xxhash64([]byte("key-"+strconv.Itoa(i)), 0)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / 100_000; rate > 0.015 {
+		t.Fatalf("false positive rate %.4f at 10 bits/key", rate)
+	}
+
+	empty, err := ParseRocksDBFilter(BuildRocksDBFilter(10, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.MayMatchHash(hashes[0]) {
+		t.Fatal("empty filter should match nothing")
+	}
+}
+
+func TestRocksDB_ChooseNumProbes(t *testing.T) {
+	cases := map[int]int{1000: 1, 2080: 1, 2081: 2, 10000: 6, 16000: 9, 28000: 12, 28001: 13, 50000: 23, 50001: 24}
+	for millibits, want := range cases {
+		if got := rocksChooseNumProbes(millibits); got != want {
+			t.Errorf("rocksChooseNumProbes(%d) = %d, want %d", millibits, got, want)
+		}
+	}
+}
+
+func TestParseRocksDBFilter_Rejects(t *testing.T) {
+	legacy := append([]byte(nil), rocksOneKey...)
+	legacy[len(legacy)-5] = 0x00
+	ribbon := append([]byte(nil), rocksOneKey...)
+	ribbon[len(ribbon)-4] = 0xfe
+	for name, block := range map[string][]byte{"legacy": legacy, "ribbon": ribbon} {
+		if _, err := ParseRocksDBFilter(block); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Fatalf("%s: expected ErrUnsupportedFormat, got %v", name, err)
+		}
+	}
+
+	ragged := append(make([]byte, 10), rocksOneKey[64:]...)
+	noProbes := append([]byte(nil), rocksOneKey...)
+	noProbes[len(noProbes)-3] = 0
+	for name, block := range map[string][]byte{"short": {0xff}, "ragged": ragged, "no probes": noProbes} {
+		if _, err := ParseRocksDBFilter(block); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: expected ErrCorrupt, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package bloom
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Rotating remembers keys for an approximate time window by keeping G
+// generations of Bloom filters with the same parameters. Add writes to the
+// newest generation and MightContain checks all of them. Each rotation
+// clears the oldest generation and makes it the newest, so a key is
+// forgotten G rotations after it was last added.
+//
+// With a window W, generations rotate every W/G, so a key is remembered
+// for between W - W/G and W after its last Add. Rotation is lazy: calls
+// rotate as many generations as are due according to the clock, so no
+// goroutine is needed. Rotate can also be called directly, and a zero
+// window leaves rotation entirely to the caller.
+//
+// Rotating is safe for concurrent use; rotation swaps generations under
+// the write lock, so no Add lands in a generation as it is being cleared.
+type Rotating struct {
+	mu       sync.RWMutex
+	gens     []*BloomFilter // ring; gens[head] is the newest
+	head     int
+	interval time.Duration // time between rotations (0 = manual only)
+	now      func() time.Time
+	next     time.Time // when the next rotation is due
+}
+
+// NewRotating creates a rotating filter of generations generations, each
+// sized for capacity keys at fpRate, that remembers keys for about window.
+// Size capacity for the keys added in one rotation interval, window /
+// generations. The overall false positive rate is up to generations times
+// fpRate, since a query checks every generation.
+// It panics if generations < 1 or window < 0, and as NewWithEstimates for
+// bad capacity or fpRate.
+func NewRotating(capacity uint64, fpRate float64, generations int, window time.Duration) *Rotating {
+	return NewRotatingWithClock(capacity, fpRate, generations, window, time.Now)
+}
+
+// NewRotatingWithClock is NewRotating with a custom clock, for tests.
+func NewRotatingWithClock(capacity uint64, fpRate float64, generations int, window time.Duration, now func() time.Time) *Rotating {
+	if generations < 1 {
+		panic("bloom: generations must be >= 1")
+	}
+	if window < 0 {
+		panic("bloom: window must be >= 0")
+	}
+	r := &Rotating{
+		gens:     make([]*BloomFilter, generations),
+		interval: window / time.Duration(generations),
+		now:      now,
+	}
+	for i := range r.gens {
+		r.gens[i] = NewWithEstimates(capacity, fpRate)
+	}
+	if r.interval > 0 {
+		r.next = now().Add(r.interval)
+	}
+	return r
+}
+
+// Add inserts data into the newest generation.
+func (r *Rotating) Add(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rotateDue()
+	r.gens[r.head].Add(data)
+}
+
+// MightContain checks if data was added within the window.
+// Returns false -> definitely not added within the window.
+// Returns true  -> might have been (subject to false positives).
+func (r *Rotating) MightContain(data []byte) bool {
+	r.mu.RLock()
+	due := r.due()
+	r.mu.RUnlock()
+	if due {
+		r.mu.Lock()
+		r.rotateDue()
+		r.mu.Unlock()
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := range r.gens {
+		if r.gens[(r.head+i)%len(r.gens)].MightContain(data) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rotate drops the oldest generation and starts a fresh one, immediately.
+// The next timed rotation is due one interval from now.
+func (r *Rotating) Rotate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rotate()
+	if r.interval > 0 {
+		r.next = r.now().Add(r.interval)
+	}
+}
+
+// Generations returns the number of generations.
+func (r *Rotating) Generations() int {
+	return len(r.gens)
+}
+
+// Reset clears every generation.
+func (r *Rotating) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, bf := range r.gens {
+		bf.Reset()
+	}
+}
+
+// SizeInBytes reports the memory held by all generations.
+func (r *Rotating) SizeInBytes() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var size uint64
+	for _, bf := range r.gens {
+		size += bf.SizeInBytes()
+	}
+	return size
+}
+
+// Info returns a small description of the filter's configuration.
+func (r *Rotating) Info() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return fmt.Sprintf("Rotating{generations=%d, interval=%s, %s}", len(r.gens), r.interval, r.gens[r.head].Info())
+}
+
+// due reports whether a timed rotation is due. Callers hold r.mu.
+func (r *Rotating) due() bool {
+	return r.interval > 0 && !r.now().Before(r.next)
+}
+
+// rotateDue performs every timed rotation that is due. Callers hold r.mu
+// for writing.
+func (r *Rotating) rotateDue() {
+	if !r.due() {
+		return
+	}
+	now := r.now()
+	// After a long idle period everything has expired; clearing each
+	// generation once is enough.
+	missed := int(now.Sub(r.next)/r.interval) + 1
+	for i := 0; i < min(missed, len(r.gens)); i++ {
+		r.rotate()
+	}
+	r.next = r.next.Add(time.Duration(missed) * r.interval)
+}
+
+// rotate clears the oldest generation and makes it the newest, reusing its
+// storage. Callers hold r.mu for writing.
+func (r *Rotating) rotate() {
+	r.head = (r.head + len(r.gens) - 1) % len(r.gens)
+	r.gens[r.head].Reset()
+}
@@ -0,0 +1,122 @@
+package bloom
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for time-driven tests.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func TestRotating_KeysExpireAfterWindow(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	r := NewRotatingWithClock(1000, 0.001, 4, 4*time.Minute, clock.Now)
+
+	r.Add([]byte("seen"))
+	for minute := 1; minute < 4; minute++ {
+		clock.Advance(time.Minute)
+		if !r.MightContain([]byte("seen")) {
+			t.Fatalf("key forgotten after %d of 4 minutes", minute)
+		}
+	}
+	clock.Advance(time.Minute)
+	if r.MightContain([]byte("seen")) {
+		t.Fatal("key still present a full window after it was added")
+	}
+}
+
+func TestRotating_ReAddExtendsLifetime(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	r := NewRotatingWithClock(100, 0.01, 3, 3*time.Second, clock.Now)
+	for i := 0; i < 10; i++ {
+		r.Add([]byte("hot"))
+		clock.Advance(time.Second)
+		if !r.MightContain([]byte("hot")) {
+			t.Fatalf("regularly re-added key lost at tick %d", i)
+		}
+	}
+}
+
+func TestRotating_LongIdleClearsEverything(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	r := NewRotatingWithClock(100, 0.01, 3, 3*time.Second, clock.Now)
+	r.Add([]byte("old"))
+	clock.Advance(time.Hour)
+	if r.MightContain([]byte("old")) {
+		t.Fatal("key survived an hour-long idle period")
+	}
+	// Timed rotations resume on the original schedule.
+	r.Add([]byte("new"))
+	clock.Advance(2 * time.Second)
+	if !r.MightContain([]byte("new")) {
+		t.Fatal("key added after the idle period expired too early")
+	}
+}
+
+func TestRotating_ManualRotate(t *testing.T) {
+	r := NewRotating(100, 0.01, 2, 0)
+	r.Add([]byte("a"))
+	r.Rotate()
+	if !r.MightContain([]byte("a")) {
+		t.Fatal("key lost after one of two rotations")
+	}
+	r.Rotate()
+	if r.MightContain([]byte("a")) {
+		t.Fatal("key still present after two of two rotations")
+	}
+}
+
+func TestRotating_ConcurrentRotate(t *testing.T) {
+	r := NewRotating(10_000, 0.01, 3, 0)
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				r.Rotate()
+			}
+		}
+	}()
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := []byte(strconv.Itoa(w) + "-" + strconv.Itoa(i))
+				r.Add(key)
+				r.MightContain(key)
+			}
+		}(w)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	// Right after a quiet Add, the key must be visible.
+	r.Add([]byte("final"))
+	if !r.MightContain([]byte("final")) {
+		t.Fatal("key lost without a rotation")
+	}
+}
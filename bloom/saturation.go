@@ -0,0 +1,116 @@
+package bloom
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultSaturationThreshold is the fill ratio (set bits / m) at which a
+// filter is considered saturated. An optimally sized filter sits at about
+// half full when it reaches its designed capacity.
+const DefaultSaturationThreshold = 0.5
+
+// ErrOverCapacity is returned by AddChecked once the filter has taken more
+// insertions than it was sized for, or its fill ratio has crossed the
+// saturation threshold. The element is still added; the error is a warning
+// that the false positive rate is now above the design target.
+var ErrOverCapacity = errors.New("bloom: filter over capacity")
+
+// SetCapacity records the number of insertions the filter was sized for.
+// NewWithEstimates sets it to n; filters built with New have no capacity
+// until one is set, and are then judged by fill ratio alone.
+func (bf *BloomFilter) SetCapacity(n uint64) {
+	if bf == nil {
+		panic(ErrUninitialized)
+	}
+	bf.capacity = n
+	bf.checkOverfill()
+}
+
+// Count returns the number of Add calls (including those made through
+// AddAll, TestAndAdd and the typed helpers) since construction or Reset.
+// It counts calls, not distinct keys: adding the same key twice counts
+// twice. The count is kept by MarshalBinary, WriteTo and MarshalJSON, and
+// Merge sums the counts of both filters.
+func (bf *BloomFilter) Count() uint64 {
+	if bf == nil {
+		return 0
+	}
+	return bf.inserts
+}
+
+// Capacity returns the number of insertions the filter was sized for:
+// n for NewWithEstimates, whatever SetCapacity recorded, or 0 if unknown.
+func (bf *BloomFilter) Capacity() uint64 {
+	if bf == nil {
+		return 0
+	}
+	return bf.capacity
+}
+
+// OverCapacity reports whether Count exceeds a known Capacity. Unlike
+// IsSaturated it ignores the fill ratio, and is always false for a filter
+// with no capacity.
+func (bf *BloomFilter) OverCapacity() bool {
+	return bf != nil && bf.capacity > 0 && bf.inserts > bf.capacity
+}
+
+// checkOverfill fires the overfill callback, once, when the filter first
+// goes over capacity.
+func (bf *BloomFilter) checkOverfill() {
+	if bf.onOverfill != nil && !bf.overfilled && bf.OverCapacity() {
+		bf.overfilled = true
+		bf.onOverfill(bf.inserts, bf.capacity)
+	}
+}
+
+// SetSaturationThreshold sets the fill ratio at which IsSaturated reports
+// true. ratio must be in (0, 1]; otherwise it panics with ErrInvalidOption.
+func (bf *BloomFilter) SetSaturationThreshold(ratio float64) {
+	if bf == nil {
+		panic(ErrUninitialized)
+	}
+	if ratio <= 0 || ratio > 1 {
+		panic(fmt.Errorf("%w: saturation threshold %v is not in (0, 1]", ErrInvalidOption, ratio))
+	}
+	bf.threshold = ratio
+}
+
+// IsSaturated reports whether the filter has taken more insertions than
+// its capacity, or whether its fill ratio has reached the saturation
+// threshold (DefaultSaturationThreshold unless changed).
+func (bf *BloomFilter) IsSaturated() bool {
+	if !bf.initialized() {
+		return false
+	}
+	if bf.OverCapacity() {
+		return true
+	}
+	return bf.fillRatio() >= bf.saturationThreshold()
+}
+
+// AddChecked inserts data like Add, then returns ErrOverCapacity if the
+// filter is saturated. Unlike Add it returns ErrUninitialized instead of
+// panicking on a zero-value or nil filter.
+func (bf *BloomFilter) AddChecked(data []byte) error {
+	if !bf.initialized() {
+		return ErrUninitialized
+	}
+	bf.Add(data)
+	if bf.IsSaturated() {
+		return ErrOverCapacity
+	}
+	return nil
+}
+
+// fillRatio returns the fraction of the m bits that are set.
+func (bf *BloomFilter) fillRatio() float64 {
+	return float64(bf.setBits) / float64(bf.m)
+}
+
+func (bf *BloomFilter) saturationThreshold() float64 {
+	if bf.threshold == 0 {
+		return DefaultSaturationThreshold
+	}
+	return bf.threshold
+}
@@ -0,0 +1,277 @@
+package bloom
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAddChecked_OverCapacity(t *testing.T) {
+	bf := NewWithEstimates(100, 0.01)
+
+	for i := 0; i < 100; i++ {
+		if err := bf.AddChecked([]byte("key-" + strconv.Itoa(i))); err != nil {
+			t.Fatalf("insert %d: unexpected error %v", i, err)
+		}
+	}
+	if bf.IsSaturated() {
+		t.Fatal("filter at exactly its capacity should not be saturated")
+	}
+
+	err := bf.AddChecked([]byte("one-too-many"))
+	if !errors.Is(err, ErrOverCapacity) {
+		t.Fatalf("expected ErrOverCapacity, got %v", err)
+	}
+	if !bf.MightContain([]byte("one-too-many")) {
+		t.Fatal("AddChecked must still insert the element")
+	}
+
+	bf.Reset()
+	if bf.IsSaturated() {
+		t.Fatal("filter should not be saturated after Reset")
+	}
+}
+
+func TestIsSaturated_FillRatio(t *testing.T) {
+	// No capacity: saturation is judged by fill ratio alone.
+	bf := New(64, 1)
+	bf.SetSaturationThreshold(0.25)
+
+	for i := 0; !bf.IsSaturated(); i++ {
+		if i > 1000 {
+			t.Fatal("filter never became saturated")
+		}
+		bf.Add([]byte(strconv.Itoa(i)))
+	}
+	if bf.fillRatio() < 0.25 {
+		t.Fatalf("saturated at fill ratio %v, below threshold", bf.fillRatio())
+	}
+
+	for _, ratio := range []float64{0, -0.5, 1.5} {
+		expectPanic(t, ErrInvalidOption, func() { bf.SetSaturationThreshold(ratio) })
+	}
+	expectPanic(t, ErrUninitialized, func() { (*BloomFilter)(nil).SetSaturationThreshold(0.5) })
+}
+
+func TestSafeBloom_Saturation(t *testing.T) {
+	s := NewSafe(1024, 3)
+	s.SetCapacity(2)
+
+	s.Add([]byte("a"))
+	s.Add([]byte("b"))
+	if s.IsSaturated() {
+		t.Fatal("unexpected saturation at capacity")
+	}
+	if err := s.AddChecked([]byte("c")); !errors.Is(err, ErrOverCapacity) {
+		t.Fatalf("expected ErrOverCapacity, got %v", err)
+	}
+}
+
+func TestEstimatedFalsePositiveRate(t *testing.T) {
+	bf := NewWithEstimates(10_000, 0.01)
+	s := NewSafeWithEstimates(10_000, 0.01)
+	if bf.EstimatedFalsePositiveRate() != 0 || s.EstimatedFalsePositiveRate() != 0 {
+		t.Fatal("empty filter has a non-zero estimate")
+	}
+	keys := randomKeys("fpr", 20_000, 3)
+	for _, key := range keys[:10_000] {
+		bf.Add(key)
+		s.Add(key)
+	}
+	if got := bf.EstimatedFalsePositiveRate(); got < 0.008 || got > 0.0125 {
+		t.Fatalf("at design capacity: estimate %.4f, want ~0.01", got)
+	}
+	if bf.Stats().EstimatedFPRate != bf.EstimatedFalsePositiveRate() || s.EstimatedFalsePositiveRate() != bf.EstimatedFalsePositiveRate() {
+		t.Fatal("Stats or SafeBloom disagree with EstimatedFalsePositiveRate")
+	}
+
+	for _, key := range keys[10_000:] {
+		bf.Add(key)
+	}
+	// (1 - e^(-2kn/m))^k for k=7, m/n=9.59 is about 0.157.
+	if got := bf.EstimatedFalsePositiveRate(); got < 0.13 || got > 0.19 {
+		t.Fatalf("at twice capacity: estimate %.4f, want ~0.157", got)
+	}
+
+	full := New(128, 4)
+	full.bits[0], full.bits[1] = ^uint64(0), ^uint64(0)
+	full.setBits = 128
+	if got := full.EstimatedFalsePositiveRate(); got != 1 {
+		t.Fatalf("saturated filter estimate %v, want 1", got)
+	}
+	if got := new(BloomFilter).EstimatedFalsePositiveRate(); got != 0 {
+		t.Fatalf("zero-value filter estimate %v", got)
+	}
+}
+
+func TestCount_Capacity(t *testing.T) {
+	var nilFilter *BloomFilter
+	if nilFilter.Count() != 0 || nilFilter.Capacity() != 0 || nilFilter.OverCapacity() {
+		t.Fatal("nil filter reports a count or capacity")
+	}
+
+	bf := NewWithEstimates(10, 0.01)
+	if bf.Capacity() != 10 {
+		t.Fatalf("capacity %d, want 10", bf.Capacity())
+	}
+	bf.AddAll([][]byte{[]byte("a"), []byte("b")})
+	bf.Add([]byte("a"))
+	bf.TestAndAdd([]byte("c"))
+	if bf.Count() != 4 {
+		t.Fatalf("count %d, want 4: every Add call counts, duplicates included", bf.Count())
+	}
+	for i := 0; i < 6; i++ {
+		bf.Add([]byte(strconv.Itoa(i)))
+	}
+	if bf.OverCapacity() {
+		t.Fatal("over capacity at exactly capacity")
+	}
+	bf.Add([]byte("one more"))
+	if !bf.OverCapacity() {
+		t.Fatal("not over capacity after capacity+1 adds")
+	}
+
+	// Merge sums the counts, even for keys both filters hold.
+	other := NewWithEstimates(10, 0.01)
+	other.Add([]byte("a"))
+	if err := other.Merge(bf); err != nil || other.Count() != 12 {
+		t.Fatalf("merged count %d (%v), want 12", other.Count(), err)
+	}
+
+	if New(64, 3).OverCapacity() {
+		t.Fatal("filter without a capacity is over capacity")
+	}
+}
+
+func TestOverfillCallback(t *testing.T) {
+	type call struct{ count, capacity uint64 }
+	var calls []call
+	bf, err := NewWithOptions(5, 0.01, WithOverfillCallback(func(count, capacity uint64) {
+		calls = append(calls, call{count, capacity})
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		bf.Add([]byte(strconv.Itoa(i)))
+	}
+	if len(calls) != 1 || calls[0] != (call{6, 5}) {
+		t.Fatalf("callback calls %v, want one call with count 6, capacity 5", calls)
+	}
+
+	// Reset re-arms it; a Merge can cross the threshold too.
+	bf.Reset()
+	full := NewWithEstimates(5, 0.01)
+	for i := 0; i < 8; i++ {
+		full.Add([]byte(strconv.Itoa(i)))
+	}
+	if err := bf.Merge(full); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 || calls[1] != (call{8, 5}) {
+		t.Fatalf("callback calls %v after Reset and Merge", calls)
+	}
+
+	// Under a SafeBloom the callback fires once across goroutines.
+	var fired atomic.Int32
+	s, err := NewSafeWithOptions(100, 0.01, WithOverfillCallback(func(uint64, uint64) { fired.Add(1) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				s.Add([]byte(strconv.Itoa(g*1000 + i)))
+			}
+		}()
+	}
+	wg.Wait()
+	if fired.Load() != 1 || s.Count() != 400 || !s.OverCapacity() {
+		t.Fatalf("SafeBloom: fired %d times, count %d", fired.Load(), s.Count())
+	}
+
+	for _, opts := range [][]Option{
+		{WithOverfillCallback(nil)},
+		{WithOverfillCallback(func(uint64, uint64) {}), WithOverfillCallback(func(uint64, uint64) {})},
+	} {
+		if _, err := NewWithOptions(5, 0.01, opts...); err == nil {
+			t.Fatal("bad overfill option accepted")
+		}
+	}
+	if _, err := NewWithOptions(0, 0, WithExplicitSize(64, 3), WithOverfillCallback(func(uint64, uint64) {})); !errors.Is(err, ErrConflictingOptions) {
+		t.Fatalf("callback without capacity: got %v, want ErrConflictingOptions", err)
+	}
+}
+
+func TestRemainingCapacity_MatchesSimulation(t *testing.T) {
+	keys := randomKeys("remaining", 60_000, 11)
+	probes := randomKeys("probe", 200_000, 12)
+	for _, target := range []float64{0.02, 0.05} {
+		bf := NewWithEstimates(10_000, 0.01)
+		for _, key := range keys[:4000] {
+			bf.Add(key)
+		}
+		remaining, err := bf.RemainingCapacity(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := bf.ProjectedFPRate(remaining); math.Abs(got-target) > target*0.001 {
+			t.Fatalf("target %v: ProjectedFPRate(%d) = %v", target, remaining, got)
+		}
+
+		for _, key := range keys[4000 : 4000+remaining] {
+			bf.Add(key)
+		}
+		var fp int
+		for _, p := range probes {
+			if bf.MightContain(p) {
+				fp++
+			}
+		}
+		measured := float64(fp) / float64(len(probes))
+		if measured < target*0.85 || measured > target*1.15 {
+			t.Fatalf("target %v: measured fp rate %.4f after %d more keys", target, measured, remaining)
+		}
+		if got, _ := bf.RemainingCapacity(target); got > remaining/100 {
+			t.Fatalf("target %v: %d keys still remaining after adding the projection", target, got)
+		}
+	}
+}
+
+func TestRemainingCapacity_Edges(t *testing.T) {
+	bf := NewWithEstimates(1000, 0.01)
+	if got := bf.ProjectedFPRate(0); got != 0 || bf.ProjectedFPRate(1000) < 0.009 || bf.ProjectedFPRate(1000) > 0.011 {
+		t.Fatalf("empty filter projections: %v now, %v at capacity", got, bf.ProjectedFPRate(1000))
+	}
+	if got, err := bf.RemainingCapacity(0.01); err != nil || got < 950 || got > 1050 {
+		t.Fatalf("empty filter sized for 1000 at 1%%: %d remaining (%v)", got, err)
+	}
+	for _, key := range randomKeys("full", 3000, 5) {
+		bf.Add(key)
+	}
+	if got, err := bf.RemainingCapacity(0.01); got != 0 || err != nil {
+		t.Fatalf("over target: %d remaining (%v), want 0", got, err)
+	}
+	if bf.ProjectedFPRate(0) != bf.EstimatedFalsePositiveRate() {
+		t.Fatal("ProjectedFPRate(0) differs from EstimatedFalsePositiveRate")
+	}
+	s := NewSafeWithEstimates(1000, 0.01)
+	if got, _ := s.RemainingCapacity(0.01); got < 950 || s.ProjectedFPRate(0) != 0 {
+		t.Fatal("SafeBloom projections disagree")
+	}
+
+	for _, target := range []float64{0, 1, -0.5, math.NaN()} {
+		if _, err := bf.RemainingCapacity(target); !errors.Is(err, ErrInvalidFPRate) {
+			t.Fatalf("target %v: got %v, want ErrInvalidFPRate", target, err)
+		}
+	}
+	if _, err := new(BloomFilter).RemainingCapacity(0.1); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("zero filter: got %v", err)
+	}
+}
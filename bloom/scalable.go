@@ -0,0 +1,291 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"unsafe"
+)
+
+// scalableTightening is the ratio r by which each new stage's false
+// positive rate shrinks. Almeida et al. recommend r in [0.8, 0.9]; with
+// r = 0.8 the first stage gets 20% of the overall target, and the sum
+// over all stages converges to the target.
+const scalableTightening = 0.8
+
+// Scalable is a scalable Bloom filter (Almeida et al., 2007) for when the
+// number of items is not known up front. It starts with one stage sized
+// for the initial capacity. Once that stage is saturated (see
+// IsSaturated), Add appends a new stage growth times larger with a false
+// positive rate scalableTightening times smaller. New items always go to
+// the newest stage, and MightContain checks every stage. The compound
+// false positive rate stays below the target however many stages are
+// added, and memory grows roughly linearly with the number of items.
+//
+// Note: This type is not safe for concurrent use without external locking.
+type Scalable struct {
+	initial uint64  // capacity of the first stage
+	fpRate  float64 // overall false positive target
+	growth  float64 // capacity ratio between consecutive stages
+	stages  []*BloomFilter
+}
+
+// NewScalable creates a scalable filter whose first stage holds
+// initialCapacity items, keeping the overall false positive rate under
+// fpRate. Each later stage holds growth times as many items as the one
+// before; 2 suits steadily growing sets, 4 fewer, larger jumps.
+// It panics if initialCapacity is 0, fpRate is not in (0, 1) or growth < 1.
+func NewScalable(initialCapacity uint64, fpRate, growth float64) *Scalable {
+	if initialCapacity == 0 {
+		panic("bloom: n (expected insertions) must be > 0")
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		panic("bloom: fpRate must be in (0, 1)")
+	}
+	if !(growth >= 1) {
+		panic("bloom: growth factor must be >= 1")
+	}
+	s := &Scalable{initial: initialCapacity, fpRate: fpRate, growth: growth}
+	s.addStage()
+	return s
+}
+
+// stageParams returns the capacity and false positive rate of stage i.
+func (s *Scalable) stageParams(i int) (uint64, float64) {
+	capacity := uint64(math.Ceil(float64(s.initial) * math.Pow(s.growth, float64(i))))
+	fpRate := s.fpRate * (1 - scalableTightening) * math.Pow(scalableTightening, float64(i))
+	return capacity, fpRate
+}
+
+func (s *Scalable) addStage() *BloomFilter {
+	capacity, fpRate := s.stageParams(len(s.stages))
+	// An odd m keeps the default FNV scheme clear of the weak low bits of
+	// FNV-1a, which skew positions when m has a large power-of-two factor
+	// and push a stage's false positive rate several times over target.
+	m, k := estimateParameters(capacity, fpRate)
+	bf := New(m|1, k)
+	bf.capacity = capacity
+	s.stages = append(s.stages, bf)
+	return bf
+}
+
+// active returns the stage new items go to, appending one if the newest
+// is saturated.
+func (s *Scalable) active() *BloomFilter {
+	bf := s.stages[len(s.stages)-1]
+	if bf.IsSaturated() {
+		bf = s.addStage()
+	}
+	return bf
+}
+
+// Add inserts data into the newest stage, first growing the filter if
+// that stage is saturated.
+// It panics with ErrUninitialized on a zero-value or nil filter.
+func (s *Scalable) Add(data []byte) {
+	if s == nil || len(s.stages) == 0 {
+		panic(ErrUninitialized)
+	}
+	s.active().Add(data)
+}
+
+// MightContain checks if data might be in any stage.
+// Returns false -> definitely not present.
+// Returns true  -> might be present (subject to false positives).
+func (s *Scalable) MightContain(data []byte) bool {
+	if s == nil {
+		return false
+	}
+	// Newest stages are largest and hold most items, so check them first.
+	for i := len(s.stages) - 1; i >= 0; i-- {
+		if s.stages[i].MightContain(data) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stages returns the number of stages.
+func (s *Scalable) Stages() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.stages)
+}
+
+// Reset drops every stage but the first and clears it.
+func (s *Scalable) Reset() {
+	if s == nil || len(s.stages) == 0 {
+		return
+	}
+	s.stages[0].Reset()
+	clear(s.stages[1:])
+	s.stages = s.stages[:1]
+}
+
+// SizeInBytes reports the memory held by the filter and all its stages.
+func (s *Scalable) SizeInBytes() uint64 {
+	if s == nil {
+		return 0
+	}
+	size := uint64(unsafe.Sizeof(*s)) + uint64(cap(s.stages))*8
+	for _, bf := range s.stages {
+		size += bf.SizeInBytes()
+	}
+	return size
+}
+
+// Info returns a small description of the filter's configuration.
+func (s *Scalable) Info() string {
+	if s == nil {
+		return "Scalable{nil}"
+	}
+	return fmt.Sprintf("Scalable{stages=%d, initial=%d, fpRate=%g, growth=%g}", len(s.stages), s.initial, s.fpRate, s.growth)
+}
+
+// ScalableStats is a point-in-time snapshot of a Scalable filter.
+type ScalableStats struct {
+	Stages          int     `json:"stages"`            // no. of stages
+	EstimatedItems  float64 `json:"estimated_items"`   // sum of the stages' estimates
+	Inserts         uint64  `json:"inserts"`           // Add calls since construction or Reset
+	EstimatedFPRate float64 `json:"estimated_fp_rate"` // chance any stage reports a false positive
+	SizeBytes       uint64  `json:"size_bytes"`        // see SizeInBytes
+	StageStats      []Stats `json:"stage_stats"`       // per stage, oldest first
+}
+
+// Stats returns a snapshot of the filter and each of its stages.
+func (s *Scalable) Stats() ScalableStats {
+	if s == nil {
+		return ScalableStats{}
+	}
+	st := ScalableStats{Stages: len(s.stages), SizeBytes: s.SizeInBytes()}
+	pass := 1.0
+	for _, bf := range s.stages {
+		stage := bf.Stats()
+		st.StageStats = append(st.StageStats, stage)
+		st.EstimatedItems += stage.EstimatedItems
+		st.Inserts += stage.Inserts
+		pass *= 1 - stage.EstimatedFPRate
+	}
+	st.EstimatedFPRate = 1 - pass
+	return st
+}
+
+// Scalable binary format (all integers little-endian):
+//
+//	version   uint8    scalableVersion
+//	initial   uint64
+//	fpRate    float64
+//	growth    float64
+//	stages    uint64   no. of stages that follow
+//	stages × (inserts uint64, BloomFilter binary encoding)
+//
+// Stage capacities are not stored; they follow from the parameters.
+const scalableVersion = 1
+
+// WriteTo implements io.WriterTo, streaming each stage with
+// BloomFilter.WriteTo.
+func (s *Scalable) WriteTo(w io.Writer) (int64, error) {
+	if s == nil || len(s.stages) == 0 {
+		return 0, ErrUninitialized
+	}
+	buf := append(make([]byte, 0, 33), scalableVersion)
+	buf = binary.LittleEndian.AppendUint64(buf, s.initial)
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(s.fpRate))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(s.growth))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(s.stages)))
+	n, err := w.Write(buf)
+	written := int64(n)
+	for _, bf := range s.stages {
+		if err != nil {
+			return written, err
+		}
+		n, err = w.Write(binary.LittleEndian.AppendUint64(buf[:0], bf.inserts))
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+		var nn int64
+		nn, err = bf.WriteTo(w)
+		written += nn
+	}
+	return written, err
+}
+
+// ReadFrom implements io.ReaderFrom, decoding a filter written by WriteTo
+// and replacing the receiver's contents. Malformed data fails with
+// ErrCorrupt and leaves the receiver untouched.
+func (s *Scalable) ReadFrom(r io.Reader) (int64, error) {
+	hdr := make([]byte, 33)
+	n, err := io.ReadFull(r, hdr)
+	read := int64(n)
+	if err != nil {
+		return read, fmt.Errorf("%w: short scalable header", ErrCorrupt)
+	}
+	if hdr[0] != scalableVersion {
+		return read, fmt.Errorf("%w: scalable version %d", ErrUnsupportedVersion, hdr[0])
+	}
+	decoded := &Scalable{
+		initial: binary.LittleEndian.Uint64(hdr[1:]),
+		fpRate:  math.Float64frombits(binary.LittleEndian.Uint64(hdr[9:])),
+		growth:  math.Float64frombits(binary.LittleEndian.Uint64(hdr[17:])),
+	}
+	count := binary.LittleEndian.Uint64(hdr[25:])
+	if decoded.initial == 0 || !(decoded.fpRate > 0 && decoded.fpRate < 1) || !(decoded.growth >= 1) || count == 0 {
+		return read, fmt.Errorf("%w: bad scalable parameters", ErrCorrupt)
+	}
+	for i := uint64(0); i < count; i++ {
+		n, err := io.ReadFull(r, hdr[:8])
+		read += int64(n)
+		if err != nil {
+			return read, fmt.Errorf("%w: stream ended before stage %d", ErrCorrupt, i)
+		}
+		bf, nn, err := readFilter(r)
+		read += nn
+		if err != nil {
+			return read, fmt.Errorf("stage %d: %w", i, err)
+		}
+		bf.inserts = binary.LittleEndian.Uint64(hdr)
+		bf.capacity, _ = decoded.stageParams(int(i))
+		decoded.stages = append(decoded.stages, bf)
+	}
+	*s = *decoded
+	return read, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler using the WriteTo
+// format.
+func (s *Scalable) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. The receiver is
+// left untouched on error.
+func (s *Scalable) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	var decoded Scalable
+	if _, err := decoded.ReadFrom(r); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrCorrupt, r.Len())
+	}
+	*s = decoded
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder using the binary format.
+func (s *Scalable) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder.
+func (s *Scalable) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
@@ -0,0 +1,144 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+)
+
+// randomKeys returns n distinct-looking keys with no shared structure
+// beyond a prefix; see cardinalityTolerance for why sequential keys are
+// avoided when measuring rates.
+func randomKeys(prefix string, n int, seed uint64) [][]byte {
+	r := rand.New(rand.NewPCG(seed, seed+1))
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte(prefix + strconv.FormatUint(r.Uint64(), 16))
+	}
+	return keys
+}
+
+func TestScalable_GrowsWithinTarget(t *testing.T) {
+	const target = 0.01
+	s := NewScalable(1000, target, 2)
+	keys := randomKeys("url-", 100_000, 1)
+	for _, key := range keys {
+		s.Add(key)
+	}
+	if s.Stages() < 5 {
+		t.Fatalf("expected the filter to grow past 5 stages, got %d", s.Stages())
+	}
+	for _, key := range keys {
+		if !s.MightContain(key) {
+			t.Fatalf("false negative for %s", key)
+		}
+	}
+
+	falsePositives := 0
+	for _, key := range randomKeys("absent-", 100_000, 2) {
+		if s.MightContain(key) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / 100_000; rate > target {
+		t.Fatalf("false positive rate %.4f over target %.2f", rate, target)
+	}
+
+	st := s.Stats()
+	if st.Stages != s.Stages() || len(st.StageStats) != st.Stages || st.Inserts != 100_000 {
+		t.Fatalf("inconsistent stats: %+v", st)
+	}
+	if st.EstimatedFPRate <= 0 || st.EstimatedFPRate > target {
+		t.Fatalf("estimated FP rate %.4f outside (0, %.2f]", st.EstimatedFPRate, target)
+	}
+	if st.EstimatedItems < 80_000 || st.EstimatedItems > 120_000 {
+		t.Fatalf("estimated %.0f items, want about 100000", st.EstimatedItems)
+	}
+	for i := 1; i < st.Stages; i++ {
+		if st.StageStats[i].M <= st.StageStats[i-1].M {
+			t.Fatalf("stage %d is not larger than stage %d", i, i-1)
+		}
+	}
+}
+
+func TestScalable_SmallSetStaysSmall(t *testing.T) {
+	s := NewScalable(1000, 0.01, 4)
+	for i := 0; i < 500; i++ {
+		s.Add([]byte(strconv.Itoa(i)))
+	}
+	if s.Stages() != 1 {
+		t.Fatalf("%d stages for a set under the initial capacity", s.Stages())
+	}
+	s.Reset()
+	if s.Stages() != 1 || s.MightContain([]byte("1")) {
+		t.Fatal("Reset did not empty the filter")
+	}
+}
+
+func TestScalable_BinaryRoundTrip(t *testing.T) {
+	s := NewScalable(100, 0.001, 2)
+	for i := 0; i < 2000; i++ {
+		s.Add([]byte("key-" + strconv.Itoa(i)))
+	}
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Scalable
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got.Info() != s.Info() || got.Stats().Inserts != 2000 {
+		t.Fatalf("decoded %s, want %s", got.Info(), s.Info())
+	}
+	for i := 0; i < 4000; i++ {
+		key := []byte("key-" + strconv.Itoa(i))
+		if got.MightContain(key) != s.MightContain(key) {
+			t.Fatalf("key %d: decoded filter disagrees", i)
+		}
+	}
+	// The decoded filter keeps growing where the original left off.
+	got.Add([]byte("more"))
+	if got.Stages() != s.Stages() && got.Stages() != s.Stages()+1 {
+		t.Fatalf("decoded filter jumped from %d to %d stages", s.Stages(), got.Stages())
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s); err != nil {
+		t.Fatal(err)
+	}
+	var viaGob Scalable
+	if err := gob.NewDecoder(&buf).Decode(&viaGob); err != nil {
+		t.Fatal(err)
+	}
+	if !viaGob.MightContain([]byte("key-7")) {
+		t.Fatal("gob round trip lost a key")
+	}
+
+	for _, bad := range [][]byte{data[:10], data[:len(data)-1], append(data, 0)} {
+		if err := got.UnmarshalBinary(bad); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%d bytes: expected ErrCorrupt, got %v", len(bad), err)
+		}
+	}
+}
+
+func TestScalable_Panics(t *testing.T) {
+	for name, fn := range map[string]func(){
+		"zero capacity": func() { NewScalable(0, 0.01, 2) },
+		"fpRate":        func() { NewScalable(10, 1, 2) },
+		"growth":        func() { NewScalable(10, 0.01, 0.5) },
+		"zero value":    func() { (&Scalable{}).Add([]byte("x")) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
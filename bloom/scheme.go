@@ -0,0 +1,81 @@
+package bloom
+
+// scheme identifies how a filter maps a key to its k probe positions.
+// It is recorded in the binary encoding, and filters with different
+// schemes can never be combined.
+type scheme uint8
+
+const (
+	// schemeFNV is the package default: two FNV-1a hashes combined by
+	// double hashing, (h1 + i*h2) mod m.
+	schemeFNV scheme = iota
+
+	// schemeGuava32 is Guava's MURMUR128_MITZ_32 strategy.
+	schemeGuava32
+
+	// schemeGuava64 is Guava's MURMUR128_MITZ_64 strategy.
+	schemeGuava64
+
+	// schemeBitsAndBlooms is github.com/bits-and-blooms/bloom/v3's
+	// murmur3-based enhanced double hashing.
+	schemeBitsAndBlooms
+
+	// schemeCassandra is Cassandra's BloomFilter index derivation over its
+	// sign-extending murmur3 variant.
+	schemeCassandra
+
+	// schemeEnhanced is the default hashing combined by enhanced double
+	// hashing, (h1 + i*h2 + (i^3-i)/6) mod m; see
+	// WithEnhancedDoubleHashing.
+	schemeEnhanced
+
+	// schemeSplit64 splits h1 of the default hashing into two 32-bit
+	// halves, as one-hash C implementations do; see WithSplit64Hashing.
+	schemeSplit64
+
+	// schemeFastRange is the default double hashing reduced to [0, m) by
+	// multiply-shift rather than modulo; see WithFastRange.
+	schemeFastRange
+)
+
+func (s scheme) valid() bool {
+	return s <= schemeFastRange
+}
+
+// native reports whether s derives positions from the filter's own base
+// hashes (its Hasher and seed) rather than a foreign format's hashing.
+func (s scheme) native() bool {
+	return s == schemeFNV || s == schemeEnhanced || s == schemeSplit64 || s == schemeFastRange
+}
+
+func (s scheme) String() string {
+	switch s {
+	case schemeFNV:
+		return "fnv"
+	case schemeGuava32:
+		return "guava-murmur128-mitz32"
+	case schemeGuava64:
+		return "guava-murmur128-mitz64"
+	case schemeBitsAndBlooms:
+		return "bits-and-blooms-murmur128"
+	case schemeCassandra:
+		return "cassandra-murmur128"
+	case schemeEnhanced:
+		return "fnv-enhanced"
+	case schemeSplit64:
+		return "fnv-split64"
+	case schemeFastRange:
+		return "fnv-fastrange"
+	}
+	return "unknown"
+}
+
+// parseScheme returns the scheme whose String form is name.
+func parseScheme(name string) (scheme, bool) {
+	for s := schemeFNV; s.valid(); s++ {
+		if s.String() == name {
+			return s, true
+		}
+	}
+	return 0, false
+}
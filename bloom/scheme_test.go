@@ -0,0 +1,81 @@
+package bloom
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestEnhancedDoubleHashing_FalsePositiveRate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("measures the false positive rate over millions of probes")
+	}
+	// At k=14 plain double hashing's correlated probe sequences put the
+	// measured rate several times above theory.
+	const n, probes = 100000, 2000000
+	plain := NewWithEstimates(n, 0.0001)
+	enhanced, err := NewWithOptions(n, 0.0001, WithEnhancedDoubleHashing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		k := []byte("key-" + strconv.Itoa(i))
+		plain.Add(k)
+		enhanced.Add(k)
+	}
+	var fpPlain, fpEnhanced int
+	for i := 0; i < probes; i++ {
+		k := []byte("absent-" + strconv.Itoa(i))
+		if plain.MightContain(k) {
+			fpPlain++
+		}
+		if enhanced.MightContain(k) {
+			fpEnhanced++
+		}
+	}
+
+	bound := EstimateFalsePositiveRate(enhanced.m, enhanced.k, n)
+	// Allow three standard deviations of sampling noise over the bound.
+	limit := bound + 3*math.Sqrt(bound/probes)
+	ratePlain, rateEnhanced := float64(fpPlain)/probes, float64(fpEnhanced)/probes
+	t.Logf("k=%d theory %.6f, plain %.6f, enhanced %.6f", enhanced.k, bound, ratePlain, rateEnhanced)
+	if rateEnhanced > limit {
+		t.Fatalf("enhanced double hashing rate %.6f exceeds the theoretical %.6f", rateEnhanced, bound)
+	}
+}
+
+func TestEnhancedDoubleHashing_Compatibility(t *testing.T) {
+	bf, _ := NewWithOptions(1000, 0.01, WithEnhancedDoubleHashing(), WithSeed(3))
+	keys := randomKeys("edh", 1000, 12)
+	bf.AddAll(keys)
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got BloomFilter
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got.scheme != schemeEnhanced || got.Params().Scheme != "fnv-enhanced" || !got.Equal(bf) {
+		t.Fatal("enhanced scheme lost in round trip")
+	}
+	for _, k := range keys {
+		if !got.MightContain(k) || !got.ContainsHash(got.Hash(k)) {
+			t.Fatalf("false negative for %q", k)
+		}
+	}
+
+	plain, _ := NewWithOptions(1000, 0.01, WithSeed(3))
+	if err := bf.Merge(plain); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("merging enhanced with plain: %v", err)
+	}
+	m, err := NewMatching(bf.Params())
+	if err != nil || m.checkCompatible(bf) != nil {
+		t.Fatalf("NewMatching: %v", err)
+	}
+	if _, err := NewWithOptions(1000, 0.01, WithEnhancedDoubleHashing(), withScheme(schemeGuava64)); !errors.Is(err, ErrConflictingOptions) {
+		t.Fatalf("conflicting schemes: %v", err)
+	}
+}
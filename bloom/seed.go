@@ -0,0 +1,132 @@
+package bloom
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+)
+
+// Seeded hashing. The default FNV hashes are public, so anyone who knows a
+// service uses this package can search offline for keys that all land on
+// the same few bits and drive its false positive rate towards 1. A seed
+// unknown to the attacker is absorbed into both FNV passes before the key,
+// as if its eight little-endian bytes were prepended, and both results are
+// then passed through murmur3's finalizer. The finalizer matters when m is
+// a power of two: FNV's low output bits depend only on the low bits of its
+// state, so without it keys colliding in the low bits under one seed would
+// keep colliding under most others. Together they make keys chosen against
+// one seed (or the unseeded default) collide under another no more often
+// than random keys do.
+//
+// The seed decides where every key lands: it is part of the filter's
+// Params and Fingerprint, it is persisted by the binary and JSON
+// encodings, and filters with different seeds cannot be merged or
+// compared. A seed of 0 means unseeded.
+
+// WithSeed sets the filter's hash seed. seed must be non-zero; use
+// WithRandomSeed to draw one. Seeds apply to the default FNV hashing only,
+// so WithSeed conflicts with WithHasher and the foreign-format
+// constructors.
+func WithSeed(seed uint64) Option {
+	return func(o *options) error {
+		if seed == 0 {
+			return fmt.Errorf("%w: seed 0 means unseeded", ErrInvalidOption)
+		}
+		if o.seed != 0 || o.randomSeed {
+			return fmt.Errorf("%w: seed given twice", ErrConflictingOptions)
+		}
+		o.seed = seed
+		return nil
+	}
+}
+
+// WithRandomSeed is WithSeed with a seed drawn from crypto/rand when the
+// filter is built. Read it back with Seed or Params to build matching
+// filters elsewhere.
+func WithRandomSeed() Option {
+	return func(o *options) error {
+		if o.seed != 0 || o.randomSeed {
+			return fmt.Errorf("%w: seed given twice", ErrConflictingOptions)
+		}
+		o.randomSeed = true
+		return nil
+	}
+}
+
+// randomSeed draws a non-zero seed from crypto/rand.
+func randomSeed() (uint64, error) {
+	var buf [8]byte
+	for {
+		if _, err := rand.Read(buf[:]); err != nil {
+			return 0, err
+		}
+		if seed := binary.LittleEndian.Uint64(buf[:]); seed != 0 {
+			return seed, nil
+		}
+	}
+}
+
+// Seed returns the filter's hash seed, or 0 if it is unseeded.
+func (bf *BloomFilter) Seed() uint64 {
+	if bf == nil {
+		return 0
+	}
+	return bf.seed
+}
+
+// Seed returns the current filter's hash seed. See BloomFilter.Seed.
+func (s *SafeBloom) Seed() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bf.Seed()
+}
+
+// seededFNVHashes is fnvHashes with seed absorbed into both passes ahead
+// of data.
+func seededFNVHashes(data []byte, seed uint64) (uint64, uint64) {
+	st := newFNVStream(seed)
+	st.write(data)
+	return st.sum()
+}
+
+// fnvStream is the incremental state of the two FNV-1a passes of hash128,
+// optionally seeded: writing a key in any number of pieces gives the
+// hashes of the whole key.
+type fnvStream struct {
+	h1, h2 uint64
+	seeded bool // finalize with mix64
+}
+
+// newFNVStream returns the state before the first byte of a key; a
+// non-zero seed is absorbed first.
+func newFNVStream(seed uint64) fnvStream {
+	const salt = 0x9e3779b97f4a7c15 // as in hash128
+	st := fnvStream{h1: fnv64Offset, h2: fnv64Offset ^ salt, seeded: seed != 0}
+	if seed != 0 {
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], seed)
+		st.write(buf[:])
+	}
+	return st
+}
+
+func (st *fnvStream) write(p []byte) {
+	h1, h2 := st.h1, st.h2
+	for _, b := range p {
+		h1 = (h1 ^ uint64(b)) * fnv64Prime
+		h2 = (h2 ^ uint64(b)) * fnv64Prime
+	}
+	st.h1, st.h2 = h1, h2
+}
+
+// sum returns the base hashes, with fnvHashes' h2 fix-up.
+func (st fnvStream) sum() (uint64, uint64) {
+	h1, h2 := st.h1, st.h2
+	if st.seeded {
+		h1, h2 = mix64(h1), mix64(h2)
+	}
+	if h2 == 0 {
+		h2 = 0x9e3779b97f4a7c15
+	}
+	return h1, h2
+}
@@ -0,0 +1,161 @@
+package bloom
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSeed_ChangesPositions(t *testing.T) {
+	a, _ := NewWithOptions(1000, 0.01, WithSeed(1))
+	b, _ := NewWithOptions(1000, 0.01, WithSeed(2))
+	plain := NewWithEstimates(1000, 0.01)
+
+	key := []byte("adversarial")
+	pa, pb, pp := a.hashes(key), b.hashes(key), plain.hashes(key)
+	if pa == pb || pa == pp {
+		t.Fatal("seed did not change the base hashes")
+	}
+	if a.location(pa, 0) == b.location(pb, 0) && a.location(pa, 1) == b.location(pb, 1) {
+		t.Fatal("same key landed on the same positions under different seeds")
+	}
+
+	// Keys that collide on every probe unseeded are spread out by a seed.
+	keys := randomKeys("seed", 1000, 5)
+	for _, f := range []*BloomFilter{a, b} {
+		f.AddAll(keys)
+		for _, k := range keys {
+			if !f.MightContain(k) {
+				t.Fatalf("seed %#x: false negative for %q", f.Seed(), k)
+			}
+		}
+	}
+}
+
+func TestSeed_Stable(t *testing.T) {
+	// Pinned: seeded filters are persisted, so seeded hashing must never
+	// change.
+	bf, _ := NewWithOptions(100, 0.01, WithSeed(42))
+	if got, want := bf.Hash([]byte("alice")), (Digest{0xa131bfd835caabf2, 0x54e18258b153588e}); got != want {
+		t.Fatalf("seed 42 digest of alice = %#x, want %#x", got, want)
+	}
+}
+
+func TestSeed_DefeatsPrecomputedCollisions(t *testing.T) {
+	// Keys picked to hit the same bit of an unseeded filter are no more
+	// likely than random keys to collide once a seed is set.
+	plain, _ := NewWithOptions(0, 0, WithExplicitSize(64, 1))
+	target := plain.location(plain.hashes([]byte("victim")), 0)
+	var crafted [][]byte
+	for _, k := range randomKeys("craft", 20000, 6) {
+		if plain.location(plain.hashes(k), 0) == target {
+			crafted = append(crafted, k)
+		}
+	}
+	if len(crafted) < 100 {
+		t.Fatalf("only %d crafted keys", len(crafted))
+	}
+
+	// m is a power of two, where FNV's low bits alone decide the position.
+	// Over many seeds a crafted key should collide about 1/64 of the time.
+	hits, trials := 0, 0
+	for seed := uint64(1); seed <= 50; seed++ {
+		seeded, _ := NewWithOptions(0, 0, WithExplicitSize(64, 1), WithSeed(seed*0x9e3779b97f4a7c15))
+		st := seeded.location(seeded.hashes([]byte("victim")), 0)
+		for _, k := range crafted {
+			if seeded.location(seeded.hashes(k), 0) == st {
+				hits++
+			}
+			trials++
+		}
+	}
+	if rate := float64(hits) / float64(trials); rate > 2.0/64 {
+		t.Fatalf("crafted keys collide at rate %.4f under seeds, want about %.4f", rate, 1.0/64)
+	}
+}
+
+func TestSeed_PersistedAndChecked(t *testing.T) {
+	bf, err := NewWithOptions(500, 0.01, WithRandomSeed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bf.Seed() == 0 || bf.Params().Seed != bf.Seed() {
+		t.Fatalf("Seed() = %#x, Params().Seed = %#x", bf.Seed(), bf.Params().Seed)
+	}
+	keys := randomKeys("persist", 500, 8)
+	bf.AddAll(keys)
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromBinary BloomFilter
+	if err := fromBinary.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(bf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON BloomFilter
+	if err := json.Unmarshal(js, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range []*BloomFilter{&fromBinary, &fromJSON} {
+		if got.Seed() != bf.Seed() || !got.Equal(bf) {
+			t.Fatal("decoded filter lost its seed")
+		}
+		for _, k := range keys {
+			if !got.MightContain(k) {
+				t.Fatalf("decoded filter lost %q", k)
+			}
+		}
+	}
+	if _, err := bf.MarshalText(); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("MarshalText of a seeded filter: %v", err)
+	}
+
+	other, _ := NewWithOptions(500, 0.01, WithSeed(bf.Seed()+1))
+	if err := bf.Merge(other); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("merging different seeds: %v", err)
+	}
+	if err := bf.Merge(NewWithEstimates(500, 0.01)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("merging seeded with unseeded: %v", err)
+	}
+	if bf.Fingerprint() == NewWithEstimates(500, 0.01).Fingerprint() {
+		t.Fatal("seed does not affect the fingerprint")
+	}
+
+	matching, err := NewMatching(bf.Params())
+	if err != nil {
+		t.Fatal(err)
+	}
+	matching.Add([]byte("late"))
+	if err := bf.Merge(matching); err != nil || !bf.MightContain([]byte("late")) {
+		t.Fatalf("merging a NewMatching filter: %v", err)
+	}
+
+	s, _ := NewSafeWithOptions(100, 0.01, WithSeed(9))
+	if s.Seed() != 9 {
+		t.Fatalf("SafeBloom.Seed() = %d, want 9", s.Seed())
+	}
+}
+
+func TestSeed_Options(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"zero":         {WithSeed(0)},
+		"twice":        {WithSeed(1), WithSeed(2)},
+		"fixed+random": {WithSeed(1), WithRandomSeed()},
+		"hasher":       {WithSeed(1), WithHasher(XXHasher{})},
+		"scheme":       {WithRandomSeed(), withScheme(schemeGuava64)},
+	} {
+		if _, err := NewWithOptions(100, 0.01, opts...); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	a, _ := NewWithOptions(100, 0.01, WithRandomSeed())
+	b, _ := NewWithOptions(100, 0.01, WithRandomSeed())
+	if a.Seed() == b.Seed() {
+		t.Fatal("two random seeds are equal")
+	}
+}
@@ -0,0 +1,373 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// Sharded is a concurrency-safe Bloom filter split into S independent
+// sub-filters, each behind its own lock. A key is hashed once, outside any
+// lock, and its hashes pick both the shard and the probe positions within
+// it, so goroutines working on keys in different shards never contend.
+// Under mixed read/write load this scales with the number of shards where
+// SafeBloom serializes every Add on one RWMutex.
+//
+// Each shard is sized for n/S keys at the full false positive target, and
+// every query consults exactly one shard, so with keys spread evenly the
+// overall rate is the target. A workload whose keys pile into few shards
+// overfills them; Stats reports every shard, so the skew can be spotted.
+type Sharded struct {
+	shards []shard
+	shift  uint // 64 - log2(len(shards)): the top bits of the route pick the shard
+}
+
+// shard is one lock-protected sub-filter, padded to its own cache line so
+// neighbouring locks do not false-share.
+type shard struct {
+	mu sync.RWMutex
+	bf *BloomFilter
+	_  [64 - (unsafe.Sizeof(sync.RWMutex{})+unsafe.Sizeof((*BloomFilter)(nil)))%64]byte
+}
+
+// DefaultShards returns the shard count NewSharded uses when given 0: four
+// per GOMAXPROCS, rounded up to a power of two, so contention stays low
+// even when every processor is writing.
+func DefaultShards() int {
+	return 1 << bits.Len(uint(4*runtime.GOMAXPROCS(0)-1))
+}
+
+// NewSharded creates a sharded filter for n keys at fpRate, split into
+// shards sub-filters; shards must be a power of two, or 0 for
+// DefaultShards. It panics with the error NewShardedWithOptions would
+// return.
+func NewSharded(n uint64, fpRate float64, shards int) *Sharded {
+	s, err := NewShardedWithOptions(n, fpRate, shards)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewShardedWithOptions is NewSharded with options applied to every
+// shard. Each shard is built by NewWithOptions for ceil(n/shards) keys, so
+// WithExplicitSize sets the size of each shard rather than of the whole
+// filter. A shard count that is negative or not a power of two, or
+// WithLazyReset, fails with ErrInvalidOption.
+func NewShardedWithOptions(n uint64, fpRate float64, shards int, opts ...Option) (*Sharded, error) {
+	if shards == 0 {
+		shards = DefaultShards()
+	}
+	if shards < 0 || shards&(shards-1) != 0 {
+		return nil, fmt.Errorf("%w: shard count %d is not a power of two", ErrInvalidOption, shards)
+	}
+	perShard := (n + uint64(shards) - 1) / uint64(shards)
+	filters := make([]*BloomFilter, shards)
+	for i := range filters {
+		bf, err := NewWithOptions(perShard, fpRate, opts...)
+		if err == nil {
+			err = rejectLazy(bf, "Sharded")
+		}
+		if err != nil {
+			return nil, err
+		}
+		filters[i] = bf
+	}
+	return newSharded(filters), nil
+}
+
+// newSharded wraps filters, whose count is a power of two and which share
+// their geometry and hashing.
+func newSharded(filters []*BloomFilter) *Sharded {
+	s := &Sharded{shards: make([]shard, len(filters)), shift: uint(65 - bits.Len(uint(len(filters))))}
+	for i, bf := range filters {
+		s.shards[i].bf = bf
+	}
+	return s
+}
+
+// route returns the base hashes of data and the shard they belong to. The
+// shard comes from the top bits of a finalizer over the hashes, which are
+// independent of the low-order bits that pick positions within a shard.
+// Every shard has the same geometry and hashing, and neither changes after
+// construction, so the first shard's is read without its lock.
+func (s *Sharded) route(data []byte) (baseHashes, *shard) {
+	h := s.shards[0].bf.hashes(data)
+	return h, &s.shards[mix64(h[0]^bits.RotateLeft64(h[1], 32))>>s.shift]
+}
+
+// initialized reports whether s has shards.
+func (s *Sharded) initialized() bool {
+	return s != nil && len(s.shards) > 0
+}
+
+// Add inserts data under the lock of its shard only. It panics with
+// ErrUninitialized on a zero-value or nil filter.
+func (s *Sharded) Add(data []byte) {
+	if !s.initialized() {
+		panic(ErrUninitialized)
+	}
+	h, sh := s.route(data)
+	sh.mu.Lock()
+	sh.bf.addHashes(h)
+	sh.mu.Unlock()
+}
+
+// AddString inserts str without copying it. See BloomFilter.AddString.
+func (s *Sharded) AddString(str string) {
+	s.Add(stringBytes(str))
+}
+
+// MightContain reports whether data might be in the filter, under the read
+// lock of its shard only. A zero-value or nil filter contains nothing.
+func (s *Sharded) MightContain(data []byte) bool {
+	if !s.initialized() {
+		return false
+	}
+	h, sh := s.route(data)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	return sh.bf.containsHashes(h)
+}
+
+// MightContainString checks membership of str without copying it. See
+// BloomFilter.MightContainString.
+func (s *Sharded) MightContainString(str string) bool {
+	return s.MightContain(stringBytes(str))
+}
+
+// Reset clears every shard. Shards are cleared one at a time, so a
+// concurrent reader may see some shards cleared and others not.
+func (s *Sharded) Reset() {
+	if s == nil {
+		return
+	}
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		sh.bf.Reset()
+		sh.mu.Unlock()
+	}
+}
+
+// Shards returns the number of shards.
+func (s *Sharded) Shards() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.shards)
+}
+
+// Count returns the total number of Add calls across all shards.
+func (s *Sharded) Count() uint64 {
+	var n uint64
+	s.each(func(bf *BloomFilter) { n += bf.inserts })
+	return n
+}
+
+// each calls fn on every shard in turn under its read lock.
+func (s *Sharded) each(fn func(bf *BloomFilter)) {
+	if s == nil {
+		return
+	}
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.RLock()
+		fn(sh.bf)
+		sh.mu.RUnlock()
+	}
+}
+
+// ShardedStats describes a Sharded filter and each of its shards.
+type ShardedStats struct {
+	Shards          int     `json:"shards"`            // no. of shards
+	EstimatedItems  float64 `json:"estimated_items"`   // sum of the shards' estimates
+	Inserts         uint64  `json:"inserts"`           // Add calls since construction or Reset
+	EstimatedFPRate float64 `json:"estimated_fp_rate"` // mean of the shards' rates: each query hits one shard
+	SizeBytes       uint64  `json:"size_bytes"`        // see SizeInBytes
+	ShardStats      []Stats `json:"shard_stats"`       // per shard, in routing order
+}
+
+// Stats returns a snapshot of the filter and each of its shards. Each shard
+// is read under its own lock, so the totals are not a single consistent cut
+// while Adds continue.
+func (s *Sharded) Stats() ShardedStats {
+	if !s.initialized() {
+		return ShardedStats{}
+	}
+	st := ShardedStats{Shards: len(s.shards), SizeBytes: s.SizeInBytes()}
+	s.each(func(bf *BloomFilter) {
+		shard := bf.Stats()
+		st.ShardStats = append(st.ShardStats, shard)
+		st.EstimatedItems += shard.EstimatedItems
+		st.Inserts += shard.Inserts
+		st.EstimatedFPRate += shard.EstimatedFPRate
+	})
+	st.EstimatedFPRate /= float64(len(s.shards))
+	return st
+}
+
+// SizeInBytes reports the memory held by the filter and its shards.
+func (s *Sharded) SizeInBytes() uint64 {
+	if s == nil {
+		return 0
+	}
+	size := uint64(unsafe.Sizeof(*s)) + uint64(len(s.shards))*uint64(unsafe.Sizeof(shard{}))
+	s.each(func(bf *BloomFilter) { size += bf.SizeInBytes() })
+	return size
+}
+
+// Params returns the parameters of each shard; all shards share them.
+func (s *Sharded) Params() Params {
+	if !s.initialized() {
+		return Params{}
+	}
+	return s.shards[0].bf.geometry().Params()
+}
+
+// Merge ORs each of other's shards into the matching shard of s. Both must
+// have the same number of shards with compatible parameters; otherwise it
+// fails with ErrIncompatible and s is unchanged. Each shard of other is
+// copied under its own read lock before s's shard is locked, so two
+// filters may be merged into each other concurrently without deadlock.
+func (s *Sharded) Merge(other *Sharded) error {
+	switch {
+	case !s.initialized() || !other.initialized():
+		return ErrUninitialized
+	case len(s.shards) != len(other.shards):
+		return fmt.Errorf("%w: %d shards != %d", ErrIncompatible, len(s.shards), len(other.shards))
+	}
+	if s == other {
+		return nil
+	}
+	if err := s.shards[0].bf.geometry().checkCompatible(other.shards[0].bf.geometry()); err != nil {
+		return err
+	}
+	for i := range s.shards {
+		src := other.shard(i)
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		err := sh.bf.Merge(src)
+		sh.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shard returns a private copy of shard i, taken under its read lock.
+func (s *Sharded) shard(i int) *BloomFilter {
+	sh := &s.shards[i]
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	return sh.bf.Clone()
+}
+
+// Sharded binary format (all integers little-endian):
+//
+//	version   uint8    shardedVersion
+//	shards    uint64   no. of shards that follow, a power of two
+//	shards × BloomFilter binary encoding, in routing order
+const shardedVersion = 1
+
+// WriteTo implements io.WriterTo. Each shard is copied under its read lock
+// and streamed with BloomFilter.WriteTo, so the shards are not a single
+// consistent cut while Adds continue.
+func (s *Sharded) WriteTo(w io.Writer) (int64, error) {
+	if !s.initialized() {
+		return 0, ErrUninitialized
+	}
+	if err := s.shards[0].bf.checkEncodable(); err != nil {
+		return 0, err
+	}
+	buf := binary.LittleEndian.AppendUint64([]byte{shardedVersion}, uint64(len(s.shards)))
+	n, err := w.Write(buf)
+	written := int64(n)
+	for i := range s.shards {
+		if err != nil {
+			return written, err
+		}
+		var nn int64
+		nn, err = s.shard(i).WriteTo(w)
+		written += nn
+	}
+	return written, err
+}
+
+// ReadFrom implements io.ReaderFrom, decoding a filter written by WriteTo
+// and replacing the receiver's shards. Malformed data, or shards that do
+// not share their parameters, fail with ErrCorrupt and leave the receiver
+// untouched. The receiver must not be in use concurrently.
+func (s *Sharded) ReadFrom(r io.Reader) (int64, error) {
+	hdr := make([]byte, 9)
+	n, err := io.ReadFull(r, hdr)
+	read := int64(n)
+	if err != nil {
+		return read, fmt.Errorf("%w: short sharded header", ErrCorrupt)
+	}
+	if hdr[0] != shardedVersion {
+		return read, fmt.Errorf("%w: sharded version %d", ErrUnsupportedVersion, hdr[0])
+	}
+	count := binary.LittleEndian.Uint64(hdr[1:])
+	if count == 0 || count&(count-1) != 0 || count > math.MaxInt32 {
+		return read, fmt.Errorf("%w: bad shard count %d", ErrCorrupt, count)
+	}
+	var filters []*BloomFilter
+	for i := uint64(0); i < count; i++ {
+		bf, nn, err := readFilter(r)
+		read += nn
+		if err != nil {
+			return read, fmt.Errorf("shard %d: %w", i, err)
+		}
+		if i > 0 {
+			if err := filters[0].checkCompatible(bf); err != nil {
+				return read, fmt.Errorf("%w: shard %d: %v", ErrCorrupt, i, err)
+			}
+		}
+		filters = append(filters, bf)
+	}
+	*s = *newSharded(filters)
+	return read, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler using the WriteTo
+// format.
+func (s *Sharded) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. The receiver is
+// left untouched on error.
+func (s *Sharded) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	var decoded Sharded
+	if _, err := decoded.ReadFrom(r); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrCorrupt, r.Len())
+	}
+	*s = decoded
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder using the binary format.
+func (s *Sharded) GobEncode() ([]byte, error) {
+	return s.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder.
+func (s *Sharded) GobDecode(data []byte) error {
+	return s.UnmarshalBinary(data)
+}
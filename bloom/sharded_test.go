@@ -0,0 +1,198 @@
+package bloom
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSharded_Concurrent(t *testing.T) {
+	const writers, perWriter = 32, 1000
+	s := NewSharded(writers*perWriter, 0.01, 16)
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		keys := randomKeys("shard-"+strconv.Itoa(w), perWriter, uint64(w))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, k := range keys {
+				s.Add(k)
+				if !s.MightContain(k) {
+					t.Errorf("false negative for %q right after Add", k)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	st := s.Stats()
+	if st.Shards != 16 || st.Inserts != writers*perWriter || s.Count() != st.Inserts {
+		t.Fatalf("Stats() = %d shards, %d inserts", st.Shards, st.Inserts)
+	}
+	// Keys spread evenly: no shard is far from n/S.
+	for i, sh := range st.ShardStats {
+		if sh.Inserts < writers*perWriter/16*3/4 || sh.Inserts > writers*perWriter/16*5/4 {
+			t.Errorf("shard %d took %d of %d keys", i, sh.Inserts, writers*perWriter)
+		}
+	}
+
+	fp := 0
+	const probes = 50000
+	for _, k := range randomKeys("absent", probes, 99) {
+		if s.MightContain(k) {
+			fp++
+		}
+	}
+	rate := float64(fp) / probes
+	if limit := 0.01 + 3*math.Sqrt(0.01/probes); rate > limit {
+		t.Fatalf("false positive rate %.4f over the 0.01 target", rate)
+	}
+
+	s.Reset()
+	if s.Count() != 0 || s.MightContain(randomKeys("shard-0", 1, 0)[0]) {
+		t.Fatal("Reset left keys behind")
+	}
+}
+
+func TestSharded_Skew(t *testing.T) {
+	// Keys that all route to one shard are still found; that shard is
+	// overfilled and Stats says so, while the others stay empty.
+	const n = 4000
+	s := NewSharded(n, 0.01, 8)
+	_, target := s.route([]byte("victim"))
+	var skewed [][]byte
+	for _, k := range randomKeys("skew", 20*n, 4) {
+		if _, sh := s.route(k); sh == target && len(skewed) < n {
+			skewed = append(skewed, k)
+		}
+	}
+	if len(skewed) < n {
+		t.Fatalf("only %d keys route to the target shard", len(skewed))
+	}
+	for _, k := range skewed {
+		s.Add(k)
+	}
+	for _, k := range skewed {
+		if !s.MightContain(k) {
+			t.Fatalf("false negative for %q", k)
+		}
+	}
+
+	st := s.Stats()
+	hot := 0
+	for i, sh := range st.ShardStats {
+		if &s.shards[i] == target {
+			hot = i
+			continue
+		}
+		if sh.Inserts != 0 {
+			t.Fatalf("shard %d took %d keys", i, sh.Inserts)
+		}
+	}
+	if sh := st.ShardStats[hot]; sh.Inserts != n || !sh.Saturated {
+		t.Fatalf("hot shard: %d inserts, saturated %v", sh.Inserts, sh.Saturated)
+	}
+	// Only one query in eight reaches the hot shard, but it answers those
+	// far above target.
+	if hotFP := st.ShardStats[hot].EstimatedFPRate; hotFP < 0.1 || math.Abs(st.EstimatedFPRate-hotFP/8) > 1e-9 {
+		t.Fatalf("Stats hides the skew: overall %.4f, hot shard %.4f", st.EstimatedFPRate, hotFP)
+	}
+}
+
+func TestSharded_MergeAndEncoding(t *testing.T) {
+	a, _ := NewShardedWithOptions(2000, 0.01, 4, WithSeed(11))
+	b, _ := NewShardedWithOptions(2000, 0.01, 4, WithSeed(11))
+	keysA, keysB := randomKeys("a", 1000, 1), randomKeys("b", 1000, 2)
+	for _, k := range keysA {
+		a.Add(k)
+	}
+	for _, k := range keysB {
+		b.Add(k)
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Sharded
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got.Shards() != 4 || got.Count() != 2000 || got.Params() != a.Params() {
+		t.Fatalf("decoded %d shards, %d inserts, %+v", got.Shards(), got.Count(), got.Params())
+	}
+	for _, k := range append(keysA, keysB...) {
+		if !got.MightContain(k) {
+			t.Fatalf("decoded filter lost %q", k)
+		}
+	}
+
+	for name, other := range map[string]*Sharded{
+		"shard count": NewSharded(2000, 0.01, 8),
+		"seed":        NewSharded(2000, 0.01, 4),
+	} {
+		if err := a.Merge(other); !errors.Is(err, ErrIncompatible) {
+			t.Fatalf("%s: Merge error %v, want ErrIncompatible", name, err)
+		}
+	}
+
+	// Shards that disagree on their parameters are corrupt.
+	mixed := newSharded([]*BloomFilter{NewWithEstimates(100, 0.01), NewWithEstimates(200, 0.01)})
+	bad, err := mixed.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := got.UnmarshalBinary(bad); !errors.Is(err, ErrCorrupt) || got.Shards() != 4 {
+		t.Fatalf("mismatched shards: %v", err)
+	}
+	if err := got.UnmarshalBinary(data[:20]); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("truncated: %v", err)
+	}
+}
+
+func TestSharded_Options(t *testing.T) {
+	for _, n := range []int{-1, 3, 12} {
+		if _, err := NewShardedWithOptions(100, 0.01, n); !errors.Is(err, ErrInvalidOption) {
+			t.Fatalf("%d shards: %v", n, err)
+		}
+	}
+	s := NewSharded(100, 0.01, 0)
+	if s.Shards() != DefaultShards() || DefaultShards()&(DefaultShards()-1) != 0 {
+		t.Fatalf("default shards = %d", s.Shards())
+	}
+	one := NewSharded(100, 0.01, 1)
+	one.AddString("x")
+	if !one.MightContainString("x") {
+		t.Fatal("single shard lost a key")
+	}
+
+	var zero Sharded
+	if zero.MightContain([]byte("x")) || zero.Count() != 0 || zero.Stats().Shards != 0 {
+		t.Fatal("zero-value Sharded is not empty")
+	}
+	expectPanic(t, ErrUninitialized, func() { zero.Add([]byte("x")) })
+	if _, err := zero.MarshalBinary(); !errors.Is(err, ErrUninitialized) {
+		t.Fatalf("MarshalBinary of zero value: %v", err)
+	}
+}
+
+func BenchmarkSharded(b *testing.B) {
+	const g = 32
+	b.Run("SafeBloom", func(b *testing.B) {
+		s := NewSafeWithEstimates(1<<20, 0.01)
+		benchmarkConcurrent(b, g, s.Add, s.MightContain)
+	})
+	for _, shards := range []int{8, 64} {
+		b.Run("Sharded/shards="+strconv.Itoa(shards), func(b *testing.B) {
+			s := NewSharded(1<<20, 0.01, shards)
+			benchmarkConcurrent(b, g, s.Add, s.MightContain)
+		})
+	}
+}
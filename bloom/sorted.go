@@ -0,0 +1,86 @@
+package bloom
+
+const (
+	// sortedRegionShift bins positions by 2 MiB region of the bitset, 1<<24
+	// bits: a region fits in cache and under one huge page, so the sets
+	// that land in it hit memory that is already close. Sorting within a
+	// region as well measured slower: the comparisons cost more than the
+	// misses they save.
+	sortedRegionShift = 24
+
+	// sortedBatch is the most keys AddAllSorted positions at once. A batch
+	// of positions spread over a filter of several GiB still puts hundreds
+	// in each region, while the buffer stays a few tens of MiB.
+	sortedBatch = 1 << 18
+)
+
+// AddAllSorted inserts every key, leaving the filter exactly as AddAll
+// would, for bulk loads into filters far bigger than cache. Where AddAll
+// sets each key's bits as it goes, a random cache miss apiece, AddAllSorted
+// first computes the positions of a batch of keys, groups them by 2 MiB
+// region of the bitset, and then sets them region by region in ascending
+// address order, so memory is swept once per batch rather than hit at
+// random for every bit. Within a region the order is the keys' order. On a
+// filter that fits in cache the extra passes only cost time; use AddAll.
+//
+// The positions are staged in buf, which is grown if its capacity is short
+// and returned; pass the result back in on the next call to load without
+// allocating. A nil buf is fine. The insert count goes up once per key, as
+// for Add, but an overfill callback fires after the batch that crosses
+// capacity has been applied.
+// It panics with ErrUninitialized on a zero-value or nil filter.
+func (bf *BloomFilter) AddAllSorted(keys [][]byte, buf []uint64) []uint64 {
+	if !bf.initialized() {
+		panic(ErrUninitialized)
+	}
+
+	// buf holds the positions in key order, then the same positions
+	// grouped by region.
+	n := min(len(keys), sortedBatch) * int(bf.k)
+	if cap(buf) < 2*n {
+		buf = make([]uint64, 2*n)
+	}
+	positions, binned := buf[:n], buf[n:2*n]
+	starts := make([]int, bf.m>>sortedRegionShift+2)
+	for len(keys) > 0 {
+		chunk := keys[:min(len(keys), sortedBatch)]
+		keys = keys[len(chunk):]
+
+		p := positions[:0]
+		for _, key := range chunk {
+			h := bf.hashes(key)
+			for i := uint64(0); i < bf.k; i++ {
+				p = append(p, bf.location(h, i))
+			}
+		}
+
+		// Counting sort by region: starts[r+1] counts region r, then the
+		// prefix sums give where each region begins in binned.
+		clear(starts)
+		for _, pos := range p {
+			starts[pos>>sortedRegionShift+1]++
+		}
+		for r := 1; r < len(starts); r++ {
+			starts[r] += starts[r-1]
+		}
+		for _, pos := range p {
+			r := pos >> sortedRegionShift
+			binned[starts[r]] = pos
+			starts[r]++
+		}
+		for _, pos := range binned[:len(p)] {
+			if bf.stale() {
+				bf.freshen(pos)
+			}
+			bf.setBit(pos)
+		}
+
+		for range chunk {
+			bf.inserts++
+			if bf.onOverfill != nil {
+				bf.checkOverfill()
+			}
+		}
+	}
+	return buf
+}
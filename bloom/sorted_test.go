@@ -0,0 +1,85 @@
+package bloom
+
+import (
+	"math/bits"
+	"testing"
+)
+
+func TestAddAllSorted_MatchesAddAll(t *testing.T) {
+	// Over three regions and more than one batch of keys.
+	const m = 3<<sortedRegionShift + 77
+	keys := randomKeys("sorted", sortedBatch+1000, 31)
+	for name, opts := range map[string][]Option{
+		"default":  nil,
+		"enhanced": {WithEnhancedDoubleHashing()},
+		"split64":  {WithSplit64Hashing()},
+		"chunked":  {withChunkShift(16)},
+	} {
+		want, err := NewWithOptions(0, 0, append(opts, WithExplicitSize(m, 6))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := want.Clone()
+		want.AddAll(keys)
+		buf := got.AddAllSorted(keys, nil)
+		if !got.Equal(want) || got.Stats() != want.Stats() {
+			t.Fatalf("%s: AddAllSorted differs from AddAll", name)
+		}
+		if len(buf) < 2*sortedBatch*6 {
+			t.Fatalf("%s: buffer of %d positions", name, len(buf))
+		}
+	}
+}
+
+func TestAddAllSorted_ReusesBuffer(t *testing.T) {
+	bf := New(1<<20, 4)
+	keys := randomKeys("reuse", 500, 32)
+	buf := bf.AddAllSorted(keys, nil)
+	if again := bf.AddAllSorted(keys, buf); &again[0] != &buf[0] {
+		t.Fatal("AddAllSorted reallocated a big enough buffer")
+	}
+	if allocs := testing.AllocsPerRun(10, func() { bf.AddAllSorted(keys, buf) }); allocs > 1 {
+		t.Fatalf("AddAllSorted with a buffer allocates %v times, want at most 1", allocs)
+	}
+	// Two calls, plus AllocsPerRun's warm-up and ten runs.
+	if bf.Count() != 13*500 {
+		t.Fatalf("Count() = %d, want %d", bf.Count(), 13*500)
+	}
+}
+
+func TestAddAllSorted_Overfill(t *testing.T) {
+	var fired uint64
+	bf, err := NewWithOptions(100, 0.01, WithOverfillCallback(func(count, _ uint64) { fired = count }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf.AddAllSorted(randomKeys("overfill", 150, 33), nil)
+	if fired != 101 || bf.Count() != 150 {
+		t.Fatalf("callback fired at %d inserts, Count() = %d", fired, bf.Count())
+	}
+	expectPanic(t, ErrUninitialized, func() { new(BloomFilter).AddAllSorted(nil, nil) })
+}
+
+// BenchmarkAddAllSorted loads a 4 GiB filter, where AddAll misses cache on
+// nearly every bit. It needs that much free memory, so it only runs on
+// 64-bit platforms and not with -short.
+func BenchmarkAddAllSorted(b *testing.B) {
+	if testing.Short() || bits.UintSize < 64 {
+		b.Skip("needs a 4 GiB filter")
+	}
+	keys := benchmarkKeys(2_000_000)
+	bf := New(1<<35, 7)
+	b.Run("AddAll", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			bf.AddAll(keys)
+		}
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/key")
+	})
+	var buf []uint64
+	b.Run("AddAllSorted", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			buf = bf.AddAllSorted(keys, buf)
+		}
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(keys)), "ns/key")
+	})
+}
@@ -0,0 +1,113 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Sparse format:
+//
+//	flag     uint8   sparseDense or sparsePositions
+//
+// sparseDense is followed by the binary encoding (see MarshalBinary).
+// sparsePositions is followed by the binary encoding header, a uvarint
+// count of set bits, then each set bit position as a uvarint delta from
+// the previous one (the first is absolute).
+const (
+	sparseDense     = 0
+	sparsePositions = 1
+)
+
+// MarshalSparse encodes bf compactly when few bits are set, listing the
+// positions of set bits instead of every word. When the dense encoding
+// would be no larger it is used instead; a leading flag byte tells
+// UnmarshalSparse which form follows.
+func (bf *BloomFilter) MarshalSparse() ([]byte, error) {
+	if !bf.initialized() {
+		return nil, ErrUninitialized
+	}
+	if err := bf.checkEncodable(); err != nil {
+		return nil, err
+	}
+	h := bf.header()
+	denseLen := headerLen(encodingVersion) + bf.wordCount()*8
+
+	buf := append(make([]byte, 0, 64), sparsePositions)
+	buf = h.append(buf)
+	buf = binary.AppendUvarint(buf, bf.setBits)
+	var prev uint64
+	bf.ForEachSetBit(func(pos uint64) bool {
+		buf = binary.AppendUvarint(buf, pos-prev)
+		prev = pos
+		return len(buf) <= denseLen
+	})
+	if len(buf) <= denseLen {
+		return buf, nil
+	}
+
+	dense, err := bf.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{sparseDense}, dense...), nil
+}
+
+// UnmarshalSparse decodes data produced by MarshalSparse, fully replacing
+// the receiver's contents. Malformed data fails with ErrCorrupt and leaves
+// the receiver untouched.
+func (bf *BloomFilter) UnmarshalSparse(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: missing sparse flag", ErrCorrupt)
+	}
+	var decoded *BloomFilter
+	var err error
+	switch data[0] {
+	case sparseDense:
+		decoded, err = unmarshal(data[1:])
+	case sparsePositions:
+		decoded, err = unmarshalPositions(data[1:])
+	default:
+		return fmt.Errorf("%w: unknown sparse flag %d", ErrCorrupt, data[0])
+	}
+	if err != nil {
+		return err
+	}
+	*bf = *decoded
+	return nil
+}
+
+func unmarshalPositions(data []byte) (*BloomFilter, error) {
+	h, err := parseHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	data = data[headerLen(h.version):]
+
+	count, n := binary.Uvarint(data)
+	if n <= 0 || count > h.m {
+		return nil, fmt.Errorf("%w: invalid set-bit count", ErrCorrupt)
+	}
+	data = data[n:]
+
+	words := make([]uint64, h.words)
+	var pos uint64
+	for i := uint64(0); i < count; i++ {
+		delta, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("%w: truncated position list", ErrCorrupt)
+		}
+		data = data[n:]
+		if i > 0 && delta == 0 {
+			return nil, fmt.Errorf("%w: repeated bit position %d", ErrCorrupt, pos)
+		}
+		if delta >= h.m-pos {
+			return nil, fmt.Errorf("%w: bit position beyond m=%d", ErrCorrupt, h.m)
+		}
+		pos += delta
+		words[pos/64] |= 1 << (pos % 64)
+	}
+	if len(data) != 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrCorrupt, len(data))
+	}
+	return newFromHeader(h, contiguous(words))
+}
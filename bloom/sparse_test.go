@@ -0,0 +1,73 @@
+package bloom
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestSparse_RoundTrip(t *testing.T) {
+	cases := []struct {
+		name   string
+		keys   int
+		sparse bool
+	}{
+		{"empty", 0, true},
+		{"nearly empty", 20, true},
+		{"half full", 7000, false},
+	}
+	for _, tc := range cases {
+		bf := NewWithEstimates(10_000, 0.01)
+		for i := 0; i < tc.keys; i++ {
+			bf.Add([]byte(strconv.Itoa(i)))
+		}
+
+		data, err := bf.MarshalSparse()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := data[0] == sparsePositions; got != tc.sparse {
+			t.Fatalf("%s: sparse form chosen = %v, want %v", tc.name, got, tc.sparse)
+		}
+		dense, _ := bf.MarshalBinary()
+		if len(data) > len(dense)+1 {
+			t.Fatalf("%s: sparse encoding is %d bytes, dense is %d", tc.name, len(data), len(dense))
+		}
+
+		var got BloomFilter
+		if err := got.UnmarshalSparse(data); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got.setBits != bf.setBits {
+			t.Fatalf("%s: decoded %d set bits, want %d", tc.name, got.setBits, bf.setBits)
+		}
+		for i := 0; i < 20_000; i++ {
+			key := []byte(strconv.Itoa(i))
+			if got.MightContain(key) != bf.MightContain(key) {
+				t.Fatalf("%s: key %d: decoded filter disagrees with original", tc.name, i)
+			}
+		}
+	}
+}
+
+func TestSparse_RejectsCorruption(t *testing.T) {
+	bf := New(1000, 3)
+	bf.Add([]byte("a"))
+	data, err := bf.MarshalSparse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bad := map[string][]byte{
+		"empty":     nil,
+		"flag":      append([]byte{7}, data[1:]...),
+		"truncated": data[:len(data)-1],
+		"trailing":  append(append([]byte(nil), data...), 0),
+	}
+	for name, b := range bad {
+		var got BloomFilter
+		if err := got.UnmarshalSparse(b); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: expected ErrCorrupt, got %v", name, err)
+		}
+	}
+}
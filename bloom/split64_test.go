@@ -0,0 +1,120 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// split64Golden holds the words of an m=1000, k=5 split64 filter over
+// key-0 .. key-99, written by testdata/split64/split64_vectors.c, which
+// hashes and probes as the legacy C service does. The same program
+// reports 109 false positives over key-100 .. key-10099.
+const split64Golden = "testdata/split64_fnv_m1000_k5.bin"
+
+func TestSplit64_Vectors(t *testing.T) {
+	// Printed by testdata/split64/split64_vectors.c, m = 1<<20.
+	for _, tc := range []struct {
+		key  string
+		hash uint64
+		pos  []uint64
+	}{
+		{"", 0xcbf29ce484222325, []uint64{140069, 311306, 482543, 653780, 825017, 996254, 118915}},
+		{"a", 0xaf63dc4c8601ec8c, []uint64{126092, 379097, 632102, 885107, 89536, 342541, 595546}},
+		{"alice", 0x508b2abb65a03907, []uint64{14599, 746434, 429693, 112952, 844787, 528046, 211305}},
+		{"The quick brown fox jumps over the lazy dog", 0xf3f9b7f5e7e47110, []uint64{291088, 928005, 516346, 104687, 741604, 329945, 966862}},
+	} {
+		bf, _ := NewWithOptions(0, 0, WithExplicitSize(1<<20, 7), WithSplit64Hashing())
+		h := bf.hashes([]byte(tc.key))
+		if h[0] != tc.hash {
+			t.Fatalf("%q: hash %#x, want %#x", tc.key, h[0], tc.hash)
+		}
+		for i, want := range tc.pos {
+			if got := bf.location(h, uint64(i)); got != want {
+				t.Fatalf("%q: probe %d at %d, want %d", tc.key, i, got, want)
+			}
+		}
+	}
+}
+
+func TestSplit64_Golden(t *testing.T) {
+	golden, err := os.ReadFile(split64Golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := make([]uint64, len(golden)/8)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint64(golden[i*8:])
+	}
+	bf, err := NewFromParts(1000, 5, words, WithSplit64Hashing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if !bf.MightContain([]byte("key-" + strconv.Itoa(i))) {
+			t.Fatalf("false negative for key-%d", i)
+		}
+	}
+	falsePositives := 0
+	for i := 100; i < 10100; i++ {
+		if bf.MightContain([]byte("key-" + strconv.Itoa(i))) {
+			falsePositives++
+		}
+	}
+	if falsePositives != 109 {
+		t.Fatalf("%d false positives, want 109 as reported by the C reference", falsePositives)
+	}
+
+	built, _ := NewWithOptions(0, 0, WithExplicitSize(1000, 5), WithSplit64Hashing())
+	for i := 0; i < 100; i++ {
+		built.Add([]byte("key-" + strconv.Itoa(i)))
+	}
+	if !built.Equal(bf) {
+		t.Fatal("Go-built filter differs from the golden bits")
+	}
+}
+
+func TestSplit64_Compatibility(t *testing.T) {
+	bf, _ := NewWithOptions(1000, 0.01, WithSplit64Hashing())
+	keys := randomKeys("split", 1000, 13)
+	bf.AddAll(keys)
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got BloomFilter
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got.scheme != schemeSplit64 || got.Params().Scheme != "fnv-split64" || !got.Equal(bf) {
+		t.Fatal("split64 scheme lost in round trip")
+	}
+	for _, k := range keys {
+		if !got.MightContain(k) || !got.ContainsHash(got.Hash(k)) {
+			t.Fatalf("false negative for %q", k)
+		}
+	}
+
+	enhanced, _ := NewWithOptions(1000, 0.01, WithEnhancedDoubleHashing())
+	for name, other := range map[string]*BloomFilter{
+		"default":  NewWithEstimates(1000, 0.01),
+		"enhanced": enhanced,
+	} {
+		if err := bf.Merge(other); !errors.Is(err, ErrIncompatible) {
+			t.Fatalf("merging split64 with %s: %v", name, err)
+		}
+		if err := other.Merge(bf); !errors.Is(err, ErrIncompatible) {
+			t.Fatalf("merging %s with split64: %v", name, err)
+		}
+	}
+	m, err := NewMatching(bf.Params())
+	if err != nil || m.checkCompatible(bf) != nil {
+		t.Fatalf("NewMatching: %v", err)
+	}
+	if _, err := NewWithOptions(1000, 0.01, WithSplit64Hashing(), WithEnhancedDoubleHashing()); !errors.Is(err, ErrConflictingOptions) {
+		t.Fatalf("conflicting schemes: %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"unsafe"
+)
+
+// Parquet split-block Bloom filter (SBBF), as specified in parquet-format's
+// BloomFilter.md. The bitset is a sequence of 32-byte blocks, each eight
+// little-endian uint32 words. A 64-bit value hash selects a block with its
+// upper 32 bits and sets one bit in every word of that block with its lower
+// 32 bits.
+const (
+	splitBlockBytes = 32
+
+	// MinSplitBlockBytes and MaxSplitBlockBytes bound a SplitBlock's
+	// bitset, matching the limits Parquet writers apply.
+	MinSplitBlockBytes = splitBlockBytes
+	MaxSplitBlockBytes = 128 << 20
+)
+
+// splitBlockSalts are the eight odd salt constants from the Parquet spec.
+var splitBlockSalts = [8]uint32{
+	0x47b6137b, 0x44974d91, 0x8824ad5b, 0xa2b7289d,
+	0x705495c7, 0x2df1424b, 0x9efc4947, 0x5c6bfb31,
+}
+
+type splitBlockBlock [8]uint32
+
+// SplitBlock is a Parquet split-block Bloom filter. Values are hashed with
+// xxhash64 (seed 0) of their Parquet plain encoding, e.g. the raw bytes of
+// a BYTE_ARRAY or the 8 little-endian bytes of an INT64, so a filter
+// written with MarshalBinary is usable by Spark, Trino and other Parquet
+// readers. It is not safe for concurrent use.
+type SplitBlock struct {
+	blocks []splitBlockBlock
+}
+
+var splitBlockOverhead = uint64(unsafe.Sizeof(SplitBlock{}))
+
+// NewSplitBlock creates an empty filter with a bitset of numBytes bytes,
+// rounded up to a power of two as Parquet writers do.
+//
+// This panics if numBytes is not in [MinSplitBlockBytes, MaxSplitBlockBytes].
+func NewSplitBlock(numBytes int) *SplitBlock {
+	if numBytes < MinSplitBlockBytes || numBytes > MaxSplitBlockBytes {
+		panic(fmt.Sprintf("bloom: split-block size must be between %d and %d bytes", MinSplitBlockBytes, MaxSplitBlockBytes))
+	}
+	size := 1 << bits.Len(uint(numBytes-1))
+	return &SplitBlock{blocks: make([]splitBlockBlock, size/splitBlockBytes)}
+}
+
+// SplitBlockBytesFor returns the bitset size, in bytes, Parquet writers
+// choose for ndv distinct values at false positive rate fpRate:
+// -8*ndv / ln(1 - fpRate^(1/8)) bits, rounded up to a power of two and
+// clamped to [MinSplitBlockBytes, MaxSplitBlockBytes].
+//
+// This panics if fpRate is not in (0, 1).
+func SplitBlockBytesFor(ndv uint64, fpRate float64) int {
+	if fpRate <= 0.0 || fpRate >= 1.0 {
+		panic("bloom: fpRate must be between 0 and 1 (exclusive)")
+	}
+	numBits := -8 * float64(ndv) / math.Log(1-math.Pow(fpRate, 1.0/8))
+	numBytes := math.Ceil(numBits / 8)
+	if numBytes >= MaxSplitBlockBytes {
+		return MaxSplitBlockBytes
+	}
+	if numBytes <= MinSplitBlockBytes {
+		return MinSplitBlockBytes
+	}
+	return 1 << bits.Len(uint(numBytes)-1)
+}
+
+// BuildSplitBlock creates a filter of numBytes bytes (see NewSplitBlock)
+// and inserts every value received from values until the channel is
+// closed.
+func BuildSplitBlock(numBytes int, values <-chan []byte) *SplitBlock {
+	sb := NewSplitBlock(numBytes)
+	for v := range values {
+		sb.Insert(v)
+	}
+	return sb
+}
+
+// Insert adds a plain-encoded value.
+func (sb *SplitBlock) Insert(value []byte) {
+	sb.InsertHash(xxhash64(value, 0))
+}
+
+// Check reports whether a plain-encoded value might be in the filter.
+func (sb *SplitBlock) Check(value []byte) bool {
+	return sb.CheckHash(xxhash64(value, 0))
+}
+
+// Add is Insert, and MightContain is Check, under the names of the Filter
+// interface.
+func (sb *SplitBlock) Add(value []byte) {
+	sb.Insert(value)
+}
+
+// MightContain is Check. See Add.
+func (sb *SplitBlock) MightContain(value []byte) bool {
+	return sb.Check(value)
+}
+
+// Reset clears all bits in the filter.
+func (sb *SplitBlock) Reset() {
+	clear(sb.blocks)
+}
+
+// InsertHash adds a value by its precomputed xxhash64.
+func (sb *SplitBlock) InsertHash(h uint64) {
+	b := &sb.blocks[sb.blockIndex(h)]
+	for i, salt := range splitBlockSalts {
+		b[i] |= 1 << ((uint32(h) * salt) >> 27)
+	}
+}
+
+// CheckHash reports whether a value with the precomputed xxhash64 h might
+// be in the filter.
+func (sb *SplitBlock) CheckHash(h uint64) bool {
+	b := &sb.blocks[sb.blockIndex(h)]
+	for i, salt := range splitBlockSalts {
+		if b[i]&(1<<((uint32(h)*salt)>>27)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// blockIndex maps the upper 32 bits of h onto [0, len(blocks)).
+func (sb *SplitBlock) blockIndex(h uint64) uint64 {
+	return ((h >> 32) * uint64(len(sb.blocks))) >> 32
+}
+
+// NumBytes returns the size of the bitset in bytes.
+func (sb *SplitBlock) NumBytes() int {
+	return len(sb.blocks) * splitBlockBytes
+}
+
+// Info returns a small description of the filter's configuration.
+func (sb *SplitBlock) Info() string {
+	return fmt.Sprintf("SplitBlock{%d bytes}", sb.NumBytes())
+}
+
+// SizeInBytes reports the memory held by the filter: the bitset storage
+// plus the fixed struct overhead.
+func (sb *SplitBlock) SizeInBytes() uint64 {
+	return uint64(sb.NumBytes()) + splitBlockOverhead
+}
+
+// MarshalBinary returns the bitset exactly as Parquet stores it after the
+// BloomFilterHeader: each block's eight words in order, little-endian.
+func (sb *SplitBlock) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, sb.NumBytes())
+	for _, b := range sb.blocks {
+		for _, word := range b {
+			buf = binary.LittleEndian.AppendUint32(buf, word)
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary replaces the filter with a bitset read from a Parquet
+// file. The length must be a multiple of 32 bytes within
+// [MinSplitBlockBytes, MaxSplitBlockBytes], or ErrCorrupt is returned.
+func (sb *SplitBlock) UnmarshalBinary(data []byte) error {
+	if len(data) < MinSplitBlockBytes || len(data) > MaxSplitBlockBytes || len(data)%splitBlockBytes != 0 {
+		return fmt.Errorf("%w: split-block bitset of %d bytes", ErrCorrupt, len(data))
+	}
+	blocks := make([]splitBlockBlock, len(data)/splitBlockBytes)
+	for i := range blocks {
+		for j := range blocks[i] {
+			blocks[i][j] = binary.LittleEndian.Uint32(data[i*splitBlockBytes+j*4:])
+		}
+	}
+	sb.blocks = blocks
+	return nil
+}
@@ -0,0 +1,100 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestSplitBlock_MaskMatchesSpec(t *testing.T) {
+	sb := NewSplitBlock(64) // two blocks
+
+	// A key of 1 multiplies to the salts themselves, so word i gets bit
+	// salt[i] >> 27; the upper hash bits 0x80000000 select block 1.
+	sb.InsertHash(0x80000000_00000001)
+	want := splitBlockBlock{1 << 8, 1 << 8, 1 << 17, 1 << 20, 1 << 14, 1 << 5, 1 << 19, 1 << 11}
+	if sb.blocks[0] != (splitBlockBlock{}) || sb.blocks[1] != want {
+		t.Fatalf("blocks = %x, want block 1 = %x", sb.blocks, want)
+	}
+
+	data, err := sb.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 64 || binary.LittleEndian.Uint32(data[32+8:]) != 1<<17 {
+		t.Fatalf("bitset layout = %x", data)
+	}
+	if !sb.CheckHash(0x80000000_00000001) || sb.CheckHash(0x00000000_00000001) {
+		t.Fatal("CheckHash disagrees with the inserted block")
+	}
+}
+
+func TestSplitBlockBytesFor(t *testing.T) {
+	cases := []struct {
+		ndv    uint64
+		fpRate float64
+		want   int
+	}{
+		{0, 0.01, MinSplitBlockBytes},
+		{1_000_000, 0.01, 2 << 20},
+		{1 << 40, 0.01, MaxSplitBlockBytes},
+	}
+	for _, c := range cases {
+		if got := SplitBlockBytesFor(c.ndv, c.fpRate); got != c.want {
+			t.Errorf("SplitBlockBytesFor(%d, %v) = %d, want %d", c.ndv, c.fpRate, got, c.want)
+		}
+	}
+	if got := NewSplitBlock(100).NumBytes(); got != 128 {
+		t.Fatalf("NewSplitBlock(100) has %d bytes, want 128", got)
+	}
+}
+
+func TestSplitBlock_FalsePositiveRate(t *testing.T) {
+	values := make(chan []byte)
+	go func() {
+		for i := 0; i < 10_000; i++ {
+			values <- []byte("value-" + strconv.Itoa(i))
+		}
+		close(values)
+	}()
+	sb := BuildSplitBlock(SplitBlockBytesFor(10_000, 0.01), values)
+
+	for i := 0; i < 10_000; i++ {
+		if !sb.Check([]byte("value-" + strconv.Itoa(i))) {
+			t.Fatalf("false negative for value-%d", i)
+		}
+	}
+	falsePositives := 0
+	for i := 10_000; i < 110_000; i++ {
+		if sb.Check([]byte("value-" + strconv.Itoa(i))) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / 100_000; rate > 0.01 {
+		t.Fatalf("false positive rate %.4f exceeds 0.01", rate)
+	}
+
+	data, err := sb.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got SplitBlock
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := got.MarshalBinary()
+	if !bytes.Equal(again, data) {
+		t.Fatal("bitset changed across a round trip")
+	}
+}
+
+func TestSplitBlock_UnmarshalRejects(t *testing.T) {
+	for _, n := range []int{0, 16, 33, MaxSplitBlockBytes + 32} {
+		var sb SplitBlock
+		if err := sb.UnmarshalBinary(make([]byte, n)); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%d bytes: expected ErrCorrupt, got %v", n, err)
+		}
+	}
+}
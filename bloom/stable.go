@@ -0,0 +1,195 @@
+package bloom
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"unsafe"
+)
+
+// Stable is a Stable Bloom Filter (Deng & Rafiei, 2006) for deduplicating
+// unbounded streams. Each of its m cells holds a small counter. Every Add
+// first decrements P cells, then sets the key's k cells to the maximum, so
+// keys that stop arriving fade out and the filter never saturates. The
+// fraction of zero cells converges to a fixed point, and with it the false
+// positive rate (see StableFalsePositiveRate).
+//
+// The price is false negatives: a key can fade before it is seen again,
+// more likely the longer ago it was added.
+//
+// The P decremented cells are consecutive, starting at a random cell and
+// wrapping around, as in most implementations; this keeps the analysis of
+// the paper while touching one or two cache lines instead of P.
+//
+// Note: This type is not safe for concurrent use without external locking.
+type Stable struct {
+	m, k    uint64
+	p       uint64   // cells decremented per Add
+	width   uint64   // bits per cell: 1, 2, 4 or 8
+	cells   []uint64 // packed cells, 64/width per word
+	rng     *rand.Rand
+	inserts uint64
+}
+
+// NewStable creates a stable filter with m cells of cellBits bits each, k
+// hash functions, and p cells decremented on every Add. cellBits must be
+// 1, 2, 4 or 8; m, k and p must be > 0, and p <= m.
+func NewStable(m, cellBits, k, p uint64) *Stable {
+	if m == 0 {
+		panic("bloom: m (no. of cells) must be > 0")
+	}
+	if k == 0 {
+		panic("bloom: k (no. of hash fucntions) must be > 0")
+	}
+	switch cellBits {
+	case 1, 2, 4, 8:
+	default:
+		panic("bloom: cell bits must be 1, 2, 4 or 8")
+	}
+	if p == 0 || p > m {
+		panic("bloom: p (cells decremented per Add) must be in [1, m]")
+	}
+	return &Stable{
+		m:     m,
+		k:     k,
+		p:     p,
+		width: cellBits,
+		cells: make([]uint64, countingWords(m, cellBits)),
+		rng:   rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+}
+
+// NewStableWithRate creates a stable filter with m cells of cellBits bits
+// whose false positive rate settles at fpRate, choosing k and P with
+// StableParams.
+func NewStableWithRate(m, cellBits uint64, fpRate float64) *Stable {
+	k, p := StableParams(m, cellBits, fpRate)
+	return NewStable(m, cellBits, k, p)
+}
+
+// StableParams returns k and P for a stable filter of m cells with
+// cellBits bits each whose false positive rate settles at fpRate. k is
+// ceil(log2(1/fpRate)); P solves the paper's stable-point equation
+//
+//	fpRate = (1 - (1 / (1 + 1/(P(1/k - 1/m))))^Max)^k
+//
+// for Max = 2^cellBits - 1, rounded up so the rate settles at or below
+// fpRate.
+func StableParams(m, cellBits uint64, fpRate float64) (k, p uint64) {
+	if fpRate <= 0 || fpRate >= 1 {
+		panic("bloom: fpRate must be in (0, 1)")
+	}
+	k = uint64(math.Ceil(math.Log2(1 / fpRate)))
+	k = max(1, min(k, m))
+	cellMax := math.Exp2(float64(cellBits)) - 1
+	zeros := 1 - math.Pow(fpRate, 1/float64(k)) // stable fraction of zero cells
+	denom := (1/math.Pow(zeros, 1/cellMax) - 1) * (1/float64(k) - 1/float64(m))
+	p = uint64(math.Ceil(1 / denom))
+	return k, max(1, min(p, m))
+}
+
+// Add decrements P cells and then sets data's k cells to the maximum.
+// It panics with ErrUninitialized on a zero-value or nil filter.
+func (s *Stable) Add(data []byte) {
+	if s == nil || s.m == 0 {
+		panic(ErrUninitialized)
+	}
+	start := s.rng.Uint64N(s.m)
+	for i := uint64(0); i < s.p; i++ {
+		pos := (start + i) % s.m
+		if n := getCell(s.cells, s.width, pos); n > 0 {
+			setCell(s.cells, s.width, pos, n-1)
+		}
+	}
+	h1, h2 := stableHashes(data)
+	cellMax := uint64(1)<<s.width - 1
+	for i := uint64(0); i < s.k; i++ {
+		setCell(s.cells, s.width, (h1+i*h2)%s.m, cellMax)
+	}
+	s.inserts++
+}
+
+// MightContain reports whether all of data's k cells are non-zero.
+// Returns false -> not seen recently (or faded; see Stable).
+// Returns true  -> might have been seen (subject to false positives).
+// A zero-value or nil filter contains nothing.
+func (s *Stable) MightContain(data []byte) bool {
+	if s == nil || s.m == 0 {
+		return false
+	}
+	h1, h2 := stableHashes(data)
+	for i := uint64(0); i < s.k; i++ {
+		if getCell(s.cells, s.width, (h1+i*h2)%s.m) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// stableHashes returns the FNV base hashes passed through the murmur3
+// finalizer. FNV-1a's low bits depend only on the low bits of the input,
+// which skews positions when m has a power-of-two factor; settling at the
+// predicted rate needs well-mixed positions for any m, and a Stable filter
+// has no persisted layout to stay compatible with.
+func stableHashes(data []byte) (uint64, uint64) {
+	h1, h2 := fnvHashes(data)
+	return mix64(h1), mix64(h2)
+}
+
+// TestAndAdd reports whether data might have been seen, then adds it. This
+// is the usual way to deduplicate a stream.
+func (s *Stable) TestAndAdd(data []byte) bool {
+	seen := s.MightContain(data)
+	s.Add(data)
+	return seen
+}
+
+// StableFalsePositiveRate returns the false positive rate the filter
+// converges to, from the stable-point equation in StableParams.
+func (s *Stable) StableFalsePositiveRate() float64 {
+	if s == nil || s.m == 0 {
+		return 0
+	}
+	cellMax := math.Exp2(float64(s.width)) - 1
+	zeros := math.Pow(1/(1+1/(float64(s.p)*(1/float64(s.k)-1/float64(s.m)))), cellMax)
+	return math.Pow(1-zeros, float64(s.k))
+}
+
+// FillRatio returns the fraction of non-zero cells.
+func (s *Stable) FillRatio() float64 {
+	if s == nil || s.m == 0 {
+		return 0
+	}
+	var nonZero uint64
+	for pos := uint64(0); pos < s.m; pos++ {
+		if getCell(s.cells, s.width, pos) != 0 {
+			nonZero++
+		}
+	}
+	return float64(nonZero) / float64(s.m)
+}
+
+// Reset sets every cell to zero.
+func (s *Stable) Reset() {
+	if s == nil {
+		return
+	}
+	clear(s.cells)
+	s.inserts = 0
+}
+
+// SizeInBytes reports the memory held by the filter.
+func (s *Stable) SizeInBytes() uint64 {
+	if s == nil {
+		return 0
+	}
+	return uint64(len(s.cells))*8 + uint64(unsafe.Sizeof(*s))
+}
+
+// Info returns a small description of the filter's configuration.
+func (s *Stable) Info() string {
+	if s == nil {
+		return "Stable{nil}"
+	}
+	return fmt.Sprintf("Stable{m=%d cells of %d bits, k=%d, p=%d}", s.m, s.width, s.k, s.p)
+}
@@ -0,0 +1,109 @@
+package bloom
+
+import (
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+)
+
+func TestStableParams(t *testing.T) {
+	k, p := StableParams(200_000, 4, 0.02)
+	if k != 6 {
+		t.Fatalf("k = %d, want ceil(log2(50)) = 6", k)
+	}
+	s := NewStable(200_000, 4, k, p)
+	if got := s.StableFalsePositiveRate(); got > 0.02 || got < 0.015 {
+		t.Fatalf("stable FP rate %.4f for p=%d, want just under 0.02", got, p)
+	}
+	// One more decrement per Add would settle above the target.
+	if over := NewStable(200_000, 4, k, p-1).StableFalsePositiveRate(); over <= 0.02 {
+		t.Fatalf("p-1 settles at %.4f, so p=%d is not the smallest that meets 0.02", over, p)
+	}
+}
+
+// TestStable_FalsePositiveRateSettles streams millions of keys through a
+// stable filter and checks that the false positive rate levels off near
+// StableFalsePositiveRate instead of climbing towards 1 as a plain filter's
+// would.
+func TestStable_FalsePositiveRateSettles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("streams millions of keys")
+	}
+	s := NewStableWithRate(200_000, 4, 0.02)
+	s.rng = rand.New(rand.NewPCG(7, 8))
+	want := s.StableFalsePositiveRate()
+
+	r := rand.New(rand.NewPCG(9, 10))
+	key := make([]byte, 0, 32)
+	next := func(prefix string) []byte {
+		key = append(key[:0], prefix...)
+		return strconv.AppendUint(key, r.Uint64(), 16)
+	}
+
+	var rates []float64
+	for checkpoint := 0; checkpoint < 4; checkpoint++ {
+		for i := 0; i < 1_000_000; i++ {
+			s.Add(next("event-"))
+		}
+		falsePositives := 0
+		const probes = 20_000
+		for i := 0; i < probes; i++ {
+			if s.MightContain(next("probe-")) {
+				falsePositives++
+			}
+		}
+		rates = append(rates, float64(falsePositives)/probes)
+	}
+	t.Logf("stable rate %.4f, measured %.4f", want, rates)
+
+	for i, rate := range rates {
+		if math.Abs(rate-want) > 0.3*want {
+			t.Errorf("after %dM keys: FP rate %.4f, want about %.4f", i+1, rate, want)
+		}
+	}
+	if rates[3] > rates[0]*1.3 {
+		t.Errorf("FP rate still climbing: %.4f", rates)
+	}
+	if fill := s.FillRatio(); fill > 0.9 {
+		t.Errorf("fill ratio %.2f; the filter should not saturate", fill)
+	}
+}
+
+func TestStable_RecentKeysPresent(t *testing.T) {
+	s := NewStableWithRate(100_000, 4, 0.01)
+	for i := 0; i < 200_000; i++ {
+		s.Add([]byte("old-" + strconv.Itoa(i)))
+	}
+	// The most recent keys have not had time to fade.
+	for i := 0; i < 100; i++ {
+		key := []byte("new-" + strconv.Itoa(i))
+		if s.TestAndAdd(key) && i == 0 {
+			continue
+		}
+		if !s.MightContain(key) {
+			t.Fatalf("just-added key %s already missing", key)
+		}
+	}
+	s.Reset()
+	if s.MightContain([]byte("new-1")) || s.FillRatio() != 0 {
+		t.Fatal("Reset did not clear the filter")
+	}
+}
+
+func TestStable_Panics(t *testing.T) {
+	for name, fn := range map[string]func(){
+		"cell bits":  func() { NewStable(100, 3, 3, 1) },
+		"p > m":      func() { NewStable(100, 2, 3, 101) },
+		"zero value": func() { (&Stable{}).Add([]byte("x")) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
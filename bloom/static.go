@@ -0,0 +1,271 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"unsafe"
+)
+
+// maxStaticAttempts bounds the number of seeds BuildStatic tries before
+// giving up. With 1.23n+32 slots a random seed peels with probability well
+// above 0.8, so exhausting this means the input is pathological.
+const maxStaticAttempts = 100
+
+var (
+	// ErrStaticBuildFailed is returned when no seed produced a solvable static
+	// filter (a peelable graph for StaticFilter, a consistent band for Ribbon).
+	ErrStaticBuildFailed = errors.New("bloom: static filter construction failed")
+
+	// ErrInvalidStaticData is returned when decoding malformed StaticFilter bytes.
+	ErrInvalidStaticData = errors.New("bloom: invalid static filter data")
+)
+
+// StaticFilter is an immutable xor filter (Graf & Lemire) for a key set that
+// is fully known at build time. It uses about 1.23 * fingerprint bits per key,
+// against roughly 1.44 * log2(1/fpRate) for a BloomFilter at the same rate.
+//
+// Like BloomFilter it never reports a false negative for a key it was built
+// from; other keys are reported present with probability 2^-fpBits.
+type StaticFilter struct {
+	seed        uint64
+	blockLength uint64   // slots per segment; the table has 3 segments
+	fpBits      uint64   // fingerprint width in bits (1..32)
+	fps         []uint64 // packed fingerprints, fpBits each
+}
+
+// BuildStatic constructs a StaticFilter for keys with a false positive rate
+// of at most fpRate. Duplicate keys are ignored. It returns
+// ErrStaticBuildFailed if construction does not succeed within a bounded
+// number of seeds.
+//
+// This panics if fpRate is not in (0, 1).
+func BuildStatic(keys [][]byte, fpRate float64) (*StaticFilter, error) {
+	if fpRate <= 0.0 || fpRate >= 1.0 {
+		panic("bloom: fpRate must be between 0 and 1 (exclusive)")
+	}
+
+	fpBits := uint64(math.Ceil(-math.Log2(fpRate)))
+	if fpBits < 1 {
+		fpBits = 1
+	}
+	if fpBits > 32 {
+		fpBits = 32
+	}
+
+	digests := dedupeDigests(keys)
+
+	capacity := uint64(math.Floor(1.23*float64(len(digests)))) + 32
+	blockLength := (capacity + 2) / 3
+	capacity = blockLength * 3
+
+	counts := make([]uint8, capacity)
+	xorMasks := make([]uint64, capacity)
+	queue := make([]uint64, 0, capacity)
+	stackHash := make([]uint64, 0, len(digests))
+	stackSlot := make([]uint64, 0, len(digests))
+
+	for attempt := uint64(0); attempt < maxStaticAttempts; attempt++ {
+		sf := &StaticFilter{
+			seed:        attempt * 0x9e3779b97f4a7c15,
+			blockLength: blockLength,
+			fpBits:      fpBits,
+		}
+
+		for i := range counts {
+			counts[i] = 0
+			xorMasks[i] = 0
+		}
+		for _, d := range digests {
+			h := sf.keyHash(d[0], d[1])
+			for _, slot := range sf.slots(h) {
+				counts[slot]++
+				xorMasks[slot] ^= h
+			}
+		}
+
+		// Peel slots that are referenced by exactly one key.
+		queue = queue[:0]
+		stackHash = stackHash[:0]
+		stackSlot = stackSlot[:0]
+		for slot, c := range counts {
+			if c == 1 {
+				queue = append(queue, uint64(slot))
+			}
+		}
+		for len(queue) > 0 {
+			slot := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			if counts[slot] != 1 {
+				continue
+			}
+			h := xorMasks[slot]
+			stackHash = append(stackHash, h)
+			stackSlot = append(stackSlot, slot)
+			for _, other := range sf.slots(h) {
+				counts[other]--
+				xorMasks[other] ^= h
+				if counts[other] == 1 {
+					queue = append(queue, other)
+				}
+			}
+		}
+		if len(stackHash) != len(digests) {
+			continue
+		}
+
+		// Assign fingerprints in reverse peeling order.
+		sf.fps = make([]uint64, (capacity*fpBits+63)/64)
+		for i := len(stackHash) - 1; i >= 0; i-- {
+			h := stackHash[i]
+			fp := sf.fingerprint(h)
+			for _, slot := range sf.slots(h) {
+				if slot != stackSlot[i] {
+					fp ^= sf.get(slot)
+				}
+			}
+			sf.put(stackSlot[i], fp)
+		}
+		return sf, nil
+	}
+	return nil, ErrStaticBuildFailed
+}
+
+// MightContain checks if data might be in the set the filter was built from.
+// Returns false -> definitely not present.
+// Returns true  -> might be present (subject to false positives).
+func (sf *StaticFilter) MightContain(data []byte) bool {
+	h1, h2 := hash128(data)
+	h := sf.keyHash(h1, h2)
+	s := sf.slots(h)
+	return sf.fingerprint(h) == sf.get(s[0])^sf.get(s[1])^sf.get(s[2])
+}
+
+// SizeInBytes reports the memory held by the filter.
+func (sf *StaticFilter) SizeInBytes() uint64 {
+	return uint64(len(sf.fps))*8 + staticOverhead
+}
+
+// Info returns a small description of the filter's configuration.
+func (sf *StaticFilter) Info() string {
+	return fmt.Sprintf("StaticFilter{slots=%d, fpBits=%d}", 3*sf.blockLength, sf.fpBits)
+}
+
+const staticOverhead = uint64(unsafe.Sizeof(StaticFilter{}))
+
+// staticHeaderSize is version(1) | seed(8) | blockLength(8) | fpBits(1) | words(8).
+const staticHeaderSize = 26
+
+// MarshalBinary encodes the filter. The format is a version byte, the seed,
+// block length, fingerprint width and word count, followed by the packed
+// fingerprint words, all little-endian.
+func (sf *StaticFilter) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, staticHeaderSize, staticHeaderSize+len(sf.fps)*8)
+	buf[0] = 1
+	binary.LittleEndian.PutUint64(buf[1:], sf.seed)
+	binary.LittleEndian.PutUint64(buf[9:], sf.blockLength)
+	buf[17] = byte(sf.fpBits)
+	binary.LittleEndian.PutUint64(buf[18:], uint64(len(sf.fps)))
+	for _, w := range sf.fps {
+		buf = binary.LittleEndian.AppendUint64(buf, w)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a filter produced by MarshalBinary, replacing the
+// receiver's contents.
+func (sf *StaticFilter) UnmarshalBinary(data []byte) error {
+	if len(data) < staticHeaderSize || data[0] != 1 {
+		return ErrInvalidStaticData
+	}
+	seed := binary.LittleEndian.Uint64(data[1:])
+	blockLength := binary.LittleEndian.Uint64(data[9:])
+	fpBits := uint64(data[17])
+	words := binary.LittleEndian.Uint64(data[18:])
+
+	if fpBits < 1 || fpBits > 32 || blockLength == 0 || blockLength > math.MaxUint64/(3*32) {
+		return ErrInvalidStaticData
+	}
+	if words != (blockLength*3*fpBits+63)/64 || uint64(len(data)-staticHeaderSize) != words*8 {
+		return ErrInvalidStaticData
+	}
+
+	fps := make([]uint64, words)
+	for i := range fps {
+		fps[i] = binary.LittleEndian.Uint64(data[staticHeaderSize+i*8:])
+	}
+	*sf = StaticFilter{seed: seed, blockLength: blockLength, fpBits: fpBits, fps: fps}
+	return nil
+}
+
+// keyHash mixes a key's 128-bit digest with the filter seed.
+func (sf *StaticFilter) keyHash(h1, h2 uint64) uint64 {
+	return mix64(h1+sf.seed) ^ mix64(h2^sf.seed)
+}
+
+// slots returns the key's slot in each of the three segments.
+func (sf *StaticFilter) slots(h uint64) [3]uint64 {
+	return [3]uint64{
+		reduce32(uint32(h), sf.blockLength),
+		reduce32(uint32(bits.RotateLeft64(h, 21)), sf.blockLength) + sf.blockLength,
+		reduce32(uint32(bits.RotateLeft64(h, 42)), sf.blockLength) + 2*sf.blockLength,
+	}
+}
+
+func (sf *StaticFilter) fingerprint(h uint64) uint64 {
+	return (h ^ h>>32) & (1<<sf.fpBits - 1)
+}
+
+// get returns the fpBits-wide fingerprint stored in slot.
+func (sf *StaticFilter) get(slot uint64) uint64 {
+	off := slot * sf.fpBits
+	word, shift := off/64, off%64
+	v := sf.fps[word] >> shift
+	if shift+sf.fpBits > 64 {
+		v |= sf.fps[word+1] << (64 - shift)
+	}
+	return v & (1<<sf.fpBits - 1)
+}
+
+// put stores fp in an empty slot.
+func (sf *StaticFilter) put(slot, fp uint64) {
+	off := slot * sf.fpBits
+	word, shift := off/64, off%64
+	sf.fps[word] |= fp << shift
+	if shift+sf.fpBits > 64 {
+		sf.fps[word+1] |= fp >> (64 - shift)
+	}
+}
+
+// dedupeDigests hashes keys and drops duplicates, which would otherwise
+// make the peeling graph unsolvable.
+func dedupeDigests(keys [][]byte) [][2]uint64 {
+	digests := make([][2]uint64, len(keys))
+	for i, key := range keys {
+		h1, h2 := hash128(key)
+		digests[i] = [2]uint64{h1, h2}
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		if digests[i][0] != digests[j][0] {
+			return digests[i][0] < digests[j][0]
+		}
+		return digests[i][1] < digests[j][1]
+	})
+
+	out := digests[:0]
+	for i, d := range digests {
+		if i == 0 || d != digests[i-1] {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// reduce32 maps a 32-bit hash uniformly onto [0, n) without division.
+func reduce32(h uint32, n uint64) uint64 {
+	hi, _ := bits.Mul64(uint64(h)<<32, n)
+	return hi
+}
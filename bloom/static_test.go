@@ -0,0 +1,123 @@
+package bloom
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestStatic_NoFalseNegatives(t *testing.T) {
+	const count = 10000
+	keys := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		keys = append(keys, []byte("key-"+strconv.Itoa(i)))
+	}
+
+	sf, err := BuildStatic(keys, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range keys {
+		if !sf.MightContain(key) {
+			t.Fatalf("expected key %d to be present, but got false", i)
+		}
+	}
+
+	// fpRate 0.01 rounds to 7-bit fingerprints, i.e. ~0.0078.
+	falsePositives := 0
+	for i := 0; i < count; i++ {
+		if sf.MightContain([]byte("absent-" + strconv.Itoa(i))) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / count; rate > 0.015 {
+		t.Fatalf("false positive rate %v too high", rate)
+	}
+}
+
+func TestStatic_Duplicates(t *testing.T) {
+	keys := [][]byte{[]byte("a"), []byte("b"), []byte("a"), []byte("a"), []byte("c")}
+	sf, err := BuildStatic(keys, 0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if !sf.MightContain(key) {
+			t.Fatalf("expected %q to be present", key)
+		}
+	}
+}
+
+func TestStatic_Empty(t *testing.T) {
+	sf, err := BuildStatic(nil, 0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf.MightContain([]byte("anything")) {
+		t.Log(`"anything" reported as present (false positive is allowed)`)
+	}
+}
+
+func TestStatic_MarshalRoundTrip(t *testing.T) {
+	keys := make([][]byte, 0, 500)
+	for i := 0; i < 500; i++ {
+		keys = append(keys, []byte(strconv.Itoa(i)))
+	}
+	sf, err := BuildStatic(keys, 0.0001)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := sf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got StaticFilter
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		key := []byte(strconv.Itoa(i))
+		if got.MightContain(key) != sf.MightContain(key) {
+			t.Fatalf("key %d: decoded filter disagrees with original", i)
+		}
+	}
+
+	if err := got.UnmarshalBinary(data[:len(data)-1]); !errors.Is(err, ErrInvalidStaticData) {
+		t.Fatalf("expected ErrInvalidStaticData for truncated data, got %v", err)
+	}
+}
+
+func benchmarkKeys(n int) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte("key-" + strconv.Itoa(i))
+	}
+	return keys
+}
+
+func BenchmarkStatic_MightContain(b *testing.B) {
+	keys := benchmarkKeys(1 << 20)
+	sf, err := BuildStatic(keys, 0.01)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sf.MightContain(keys[i&(len(keys)-1)])
+	}
+	b.ReportMetric(float64(sf.SizeInBytes()*8)/float64(len(keys)), "bits/key")
+}
+
+func BenchmarkBloom_MightContain(b *testing.B) {
+	keys := benchmarkKeys(1 << 20)
+	bf := NewWithEstimates(uint64(len(keys)), 0.01)
+	for _, key := range keys {
+		bf.Add(key)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.MightContain(keys[i&(len(keys)-1)])
+	}
+	b.ReportMetric(float64(bf.SizeInBytes()*8)/float64(len(keys)), "bits/key")
+}
@@ -0,0 +1,127 @@
+package bloom
+
+import (
+	"fmt"
+	"math"
+)
+
+// Stats is a point-in-time snapshot of a filter's configuration and fill.
+type Stats struct {
+	M               uint64  `json:"m"`                 // no. of bits
+	K               uint64  `json:"k"`                 // no. of hash functions
+	WordCount       uint64  `json:"word_count"`        // no. of 64-bit storage words
+	BitsSet         uint64  `json:"bits_set"`          // no. of bits currently set
+	FillRatio       float64 `json:"fill_ratio"`        // BitsSet / M
+	EstimatedItems  float64 `json:"estimated_items"`   // distinct items, estimated from fill; always finite
+	EstimatedFPRate float64 `json:"estimated_fp_rate"` // (BitsSet / M)^K
+	Inserts         uint64  `json:"inserts"`           // Add calls since construction or Reset
+	Capacity        uint64  `json:"capacity"`          // designed insertions, 0 if unknown
+	Saturated       bool    `json:"saturated"`         // see IsSaturated
+	SizeBytes       uint64  `json:"size_bytes"`        // see SizeInBytes
+}
+
+// Stats returns a snapshot of the filter's configuration and fill. Every
+// field is read from counters kept up to date by the write paths, so Stats
+// is O(1) however large the filter.
+// A zero-value or nil filter reports all zeros.
+//
+// EstimatedItems counts a full filter as having all but one bit set, the
+// largest estimate the fill can give, so Stats stays encodable as JSON;
+// ApproximateItemCount reports +Inf instead.
+func (bf *BloomFilter) Stats() Stats {
+	if !bf.initialized() {
+		return Stats{SizeBytes: bf.SizeInBytes()}
+	}
+	set := bf.setBits
+	fill := bf.fillRatio()
+	return Stats{
+		M:               bf.m,
+		K:               bf.k,
+		WordCount:       uint64(bf.wordCount()),
+		BitsSet:         set,
+		FillRatio:       fill,
+		EstimatedItems:  estimateItems(bf.m, bf.k, min(set, bf.m-1)),
+		EstimatedFPRate: math.Pow(fill, float64(bf.k)),
+		Inserts:         bf.inserts,
+		Capacity:        bf.capacity,
+		Saturated:       bf.IsSaturated(),
+		SizeBytes:       bf.SizeInBytes(),
+	}
+}
+
+// String formats the stats on one line for logs. It is also what
+// BloomFilter.Info returns, and starts with the "m=... bits, k=..." fields
+// Info has always reported.
+func (st Stats) String() string {
+	return fmt.Sprintf("BloomFilter{m=%d bits, k=%d, bits set=%d, fill=%.4f, est. items=%.0f, est. fp rate=%.3g}",
+		st.M, st.K, st.BitsSet, st.FillRatio, st.EstimatedItems, st.EstimatedFPRate)
+}
+
+// EstimatedFalsePositiveRate returns the false positive rate the filter
+// has now, (X/m)^k for X bits set, rather than the rate it was designed
+// for. It is 0 for an empty filter and 1 once every bit is set; watch it
+// to alert on a degrading filter. It is also Stats().EstimatedFPRate.
+func (bf *BloomFilter) EstimatedFalsePositiveRate() float64 {
+	if !bf.initialized() {
+		return 0
+	}
+	return math.Pow(bf.fillRatio(), float64(bf.k))
+}
+
+// RemainingCapacity estimates how many more distinct keys the filter can
+// take before its false positive rate exceeds targetFP. Each new key sets
+// k bits at random, so after n more keys a fraction
+//
+//	f' = 1 - (1 - f) * (1 - 1/m)^(k*n)
+//
+// of the bits is set, where f is the current fill, and the rate is f'^k.
+// Solving f'^k = targetFP for n gives the result, rounded down. It is 0 if
+// the filter is already at or above targetFP. targetFP outside (0, 1)
+// fails with ErrInvalidFPRate.
+//
+// Keys already in the filter set no new bits, so re-adding them does not
+// use up capacity; the estimate is for keys not yet added.
+func (bf *BloomFilter) RemainingCapacity(targetFP float64) (uint64, error) {
+	if !bf.initialized() {
+		return 0, ErrUninitialized
+	}
+	if !(targetFP > 0 && targetFP < 1) {
+		return 0, fmt.Errorf("%w: target %v", ErrInvalidFPRate, targetFP)
+	}
+	fill := bf.fillRatio()
+	targetFill := math.Pow(targetFP, 1/float64(bf.k))
+	if fill >= targetFill {
+		return 0, nil
+	}
+	n := math.Log((1-targetFill)/(1-fill)) / (float64(bf.k) * math.Log1p(-1/float64(bf.m)))
+	if n >= math.MaxUint64 {
+		return math.MaxUint64, nil
+	}
+	return uint64(n), nil
+}
+
+// ProjectedFPRate returns the false positive rate expected after
+// additionalItems more distinct keys are added, from the current fill as
+// RemainingCapacity describes. ProjectedFPRate(0) is
+// EstimatedFalsePositiveRate.
+func (bf *BloomFilter) ProjectedFPRate(additionalItems uint64) float64 {
+	if !bf.initialized() {
+		return 0
+	}
+	k := float64(bf.k)
+	unset := (1 - bf.fillRatio()) * math.Exp(k*float64(additionalItems)*math.Log1p(-1/float64(bf.m)))
+	return math.Pow(1-unset, k)
+}
+
+// estimateItems estimates the number of distinct insertions into a filter
+// with x of its m bits set (Swamidass & Baldi):
+//
+// n ≈ -(m / k) * ln(1 - x/m)
+//
+// It returns +Inf once every bit is set.
+func estimateItems(m, k, x uint64) float64 {
+	if x >= m {
+		return math.Inf(1)
+	}
+	return -(float64(m) / float64(k)) * math.Log1p(-float64(x)/float64(m))
+}
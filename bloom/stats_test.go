@@ -0,0 +1,209 @@
+package bloom
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// countBits popcounts the first m bits of bf's words, for checking the
+// running set-bit count.
+func countBits(bf *BloomFilter) uint64 {
+	var n uint64
+	words := bf.BitWords()
+	last := len(words) - 1
+	for i, w := range words {
+		if i == last {
+			w &= lastWordMask(bf.m)
+		}
+		n += uint64(bits.OnesCount64(w))
+	}
+	return n
+}
+
+func TestStats_FillAndString(t *testing.T) {
+	// m = 1000 leaves 24 padding bits in the last word.
+	bf := New(1000, 3)
+	for _, key := range randomKeys("stats", 200, 5) {
+		bf.Add(key)
+	}
+	st := bf.Stats()
+	if st.M != 1000 || st.K != 3 || st.WordCount != 16 {
+		t.Fatalf("geometry %+v", st)
+	}
+	if want := countBits(bf); st.BitsSet != want {
+		t.Fatalf("BitsSet = %d, want %d", st.BitsSet, want)
+	}
+	if want := float64(st.BitsSet) / 1000; st.FillRatio != want {
+		t.Fatalf("FillRatio = %v, want %v", st.FillRatio, want)
+	}
+	if want := math.Pow(st.FillRatio, 3); st.EstimatedFPRate != want {
+		t.Fatalf("EstimatedFPRate = %v, want %v", st.EstimatedFPRate, want)
+	}
+	if math.Abs(st.EstimatedItems-200) > 20 {
+		t.Fatalf("EstimatedItems = %.0f for 200 keys", st.EstimatedItems)
+	}
+
+	if bf.Info() != st.String() || !strings.HasPrefix(st.String(), "BloomFilter{m=1000 bits, k=3,") {
+		t.Fatalf("Info = %q", bf.Info())
+	}
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Stats
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded != st {
+		t.Fatalf("JSON round trip gave %+v, %v", decoded, err)
+	}
+
+	s := NewSafe(1000, 3)
+	s.Swap(bf)
+	safe := s.Stats()
+	if safe.BitsSet != st.BitsSet || safe.SizeBytes <= st.SizeBytes {
+		t.Fatalf("SafeBloom Stats %+v", safe)
+	}
+	if got := new(BloomFilter).Stats(); got.BitsSet != 0 || got.M != 0 {
+		t.Fatalf("zero-value Stats %+v", got)
+	}
+}
+
+func TestStats_SaturatedJSON(t *testing.T) {
+	bf := New(256, 3)
+	for i := range uint64(256) {
+		bf.setBit(i)
+	}
+	st := bf.Stats()
+	if !st.Saturated || math.IsInf(st.EstimatedItems, 0) || st.EstimatedItems < 256 {
+		t.Fatalf("full filter Stats %+v", st)
+	}
+	if st.EstimatedItems != estimateItems(256, 3, 255) {
+		t.Fatalf("EstimatedItems = %v, want the estimate for 255 bits", st.EstimatedItems)
+	}
+	if _, err := json.Marshal(st); err != nil {
+		t.Fatal(err)
+	}
+	sh := NewSharded(100, 0.01, 2)
+	for i := range 10_000 {
+		sh.AddString(strconv.Itoa(i))
+	}
+	if _, err := json.Marshal(sh.Stats()); err != nil {
+		t.Fatalf("Sharded: %v", err)
+	}
+}
+
+// TestSetBits_MatchesPopcount runs random sequences of every operation
+// that writes bits and checks the running set-bit count against a full
+// popcount after each one.
+func TestSetBits_MatchesPopcount(t *testing.T) {
+	r := rand.New(rand.NewPCG(55, 5))
+	keys := randomKeys("audit", 5000, 9)
+	key := func() []byte { return keys[r.IntN(len(keys))] }
+	batch := func() [][]byte {
+		start := r.IntN(len(keys) - 100)
+		return keys[start : start+r.IntN(100)]
+	}
+	// m = 4032 is a multiple of 64 with room to fold by 2 and 4; m = 1000
+	// exercises the padding word.
+	for _, m := range []uint64{4032, 1000} {
+		bf := New(m, 4)
+		other := New(m, 4)
+		big := New(4*m, 4)
+		for op := 0; op < 3000; op++ {
+			var name string
+			switch r.IntN(12) {
+			case 0, 1, 2:
+				name = "Add"
+				bf.Add(key())
+			case 3:
+				name = "AddAll"
+				bf.AddAll(batch())
+			case 4:
+				name = "TestAndAdd"
+				bf.TestAndAdd(key())
+			case 5:
+				name = "Merge"
+				other.Add(key())
+				if err := bf.Merge(other); err != nil {
+					t.Fatal(err)
+				}
+			case 6:
+				name = "Intersect"
+				other.AddAll(batch())
+				if err := bf.Intersect(other); err != nil {
+					t.Fatal(err)
+				}
+			case 7:
+				name = "Merge folded"
+				big.AddAll(batch())
+				if err := bf.Merge(big); err != nil {
+					t.Fatal(err)
+				}
+			case 8:
+				name = "ApplyDelta"
+				src := bf.Clone()
+				src.AddAll(batch())
+				d, _ := src.DeltaSince(0)
+				if err := bf.ApplyDelta(d); err != nil {
+					t.Fatal(err)
+				}
+			case 9:
+				name = "UnmarshalBinary"
+				data, _ := bf.MarshalBinary()
+				if err := bf.UnmarshalBinary(data); err != nil {
+					t.Fatal(err)
+				}
+			case 10:
+				name = "ParallelAddAll"
+				ch := make(chan []byte)
+				go func() {
+					for _, k := range batch() {
+						ch <- k
+					}
+					close(ch)
+				}()
+				if _, err := bf.ParallelAddAll(context.Background(), ch, 3); err != nil {
+					t.Fatal(err)
+				}
+			case 11:
+				if r.IntN(10) == 0 {
+					name = "Reset"
+					bf.Reset()
+				} else {
+					name = "CopyFrom"
+					src := New(m, 4)
+					src.AddAll(batch())
+					if err := bf.CopyFrom(src); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if got, want := bf.setBits, countBits(bf); got != want {
+				t.Fatalf("m=%d op %d (%s): counter %d, popcount %d", m, op, name, got, want)
+			}
+		}
+	}
+
+	// Concurrent SafeBloom writers.
+	s := NewSafe(4096, 5)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := g; i < len(keys); i += 8 {
+				s.TestAndAdd(keys[i])
+			}
+		}()
+	}
+	wg.Wait()
+	if got, want := s.Stats().BitsSet, countBits(s.Snapshot()); got != want {
+		t.Fatalf("SafeBloom: counter %d, popcount %d", got, want)
+	}
+}
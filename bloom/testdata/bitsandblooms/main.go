@@ -0,0 +1,60 @@
+// Regenerates testdata/bitsandblooms_v3_*.bin with
+// github.com/bits-and-blooms/bloom/v3 itself. It has its own module so the
+// library never becomes a dependency of this one:
+//
+//	cp main.go /tmp/babgen && cd /tmp/babgen
+//	go mod init babgen && go get github.com/bits-and-blooms/bloom/v3@v3.7.1
+//	go run . <path to bloom/testdata>
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bits-and-blooms/bloom/v3"
+)
+
+func main() {
+	write(os.Args[1], "bitsandblooms_v3_n100_p001.bin", 100, 0.01, shortKey)
+	write(os.Args[1], "bitsandblooms_v3_n1000_p0001_varied.bin", 1000, 0.001, variedKey)
+}
+
+// shortKey is "key-i".
+func shortKey(i int) []byte {
+	return []byte("key-" + strconv.Itoa(i))
+}
+
+// variedKey is 0 to 47 bytes long, covering murmur3's 16-byte blocks and
+// every tail length.
+func variedKey(i int) []byte {
+	return []byte(strings.Repeat("v", i%40) + strconv.Itoa(i))
+}
+
+// write adds keys 0 to n-1 to a filter sized for n and fpRate, saves its
+// WriteTo output as name, and reports false positives among keys n to
+// n+9999.
+func write(dir, name string, n uint, fpRate float64, key func(int) []byte) {
+	f := bloom.NewWithEstimates(n, fpRate)
+	for i := 0; i < int(n); i++ {
+		f.Add(key(i))
+	}
+	out, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		panic(err)
+	}
+	defer out.Close()
+	if _, err := f.WriteTo(out); err != nil {
+		panic(err)
+	}
+
+	falsePositives := 0
+	for i := int(n); i < int(n)+10000; i++ {
+		if f.Test(key(i)) {
+			falsePositives++
+		}
+	}
+	fmt.Printf("%s: m=%d k=%d false positives: %d\n", name, f.Cap(), f.K(), falsePositives)
+}
@@ -0,0 +1,30 @@
+// Regenerates the Guava fixtures in bloom/testdata:
+//
+//	javac -cp guava.jar GuavaVectors.java
+//	java -cp guava.jar:. GuavaVectors ..
+import com.google.common.hash.BloomFilter;
+import com.google.common.hash.Funnels;
+import java.io.FileOutputStream;
+import java.io.OutputStream;
+import java.nio.charset.StandardCharsets;
+
+public class GuavaVectors {
+  public static void main(String[] args) throws Exception {
+    BloomFilter<CharSequence> bf =
+        BloomFilter.create(Funnels.stringFunnel(StandardCharsets.UTF_8), 100, 0.01);
+    for (int i = 0; i < 100; i++) {
+      bf.put("key-" + i);
+    }
+    try (OutputStream out = new FileOutputStream(args[0] + "/guava_mitz64_n100_p001.bin")) {
+      bf.writeTo(out);
+    }
+
+    int falsePositives = 0;
+    for (int i = 100; i < 10100; i++) {
+      if (bf.mightContain("key-" + i)) {
+        falsePositives++;
+      }
+    }
+    System.out.println("false positives: " + falsePositives);
+  }
+}
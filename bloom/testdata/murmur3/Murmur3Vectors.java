@@ -0,0 +1,61 @@
+// Regenerates testdata/murmur3_seed42_m959_k7.bin the way our Java
+// producers build filters: Guava's murmur3_128(seed) gives h1 and h2 as
+// two little-endian longs, and bit (h1 + i*h2) mod m is set for i < k.
+//
+//	javac -cp guava.jar Murmur3Vectors.java
+//	java -cp guava.jar:. Murmur3Vectors ..
+//
+// murmur3_vectors.py is a line-for-line port producing identical output.
+import com.google.common.hash.Hashing;
+import java.io.FileOutputStream;
+import java.io.OutputStream;
+import java.nio.ByteBuffer;
+import java.nio.ByteOrder;
+import java.nio.charset.StandardCharsets;
+
+public class Murmur3Vectors {
+  static final int SEED = 42;
+  static final long M = 959;
+  static final int K = 7;
+
+  public static void main(String[] args) throws Exception {
+    long[] words = new long[(int) ((M + 63) / 64)];
+    for (int i = 0; i < 100; i++) {
+      for (long pos : positions("key-" + i)) {
+        words[(int) (pos / 64)] |= 1L << (pos % 64);
+      }
+    }
+    ByteBuffer out = ByteBuffer.allocate(words.length * 8).order(ByteOrder.LITTLE_ENDIAN);
+    for (long w : words) {
+      out.putLong(w);
+    }
+    try (OutputStream f = new FileOutputStream(args[0] + "/murmur3_seed42_m959_k7.bin")) {
+      f.write(out.array());
+    }
+
+    int falsePositives = 0;
+    for (int i = 100; i < 10100; i++) {
+      boolean present = true;
+      for (long pos : positions("key-" + i)) {
+        present &= (words[(int) (pos / 64)] & (1L << (pos % 64))) != 0;
+      }
+      if (present) {
+        falsePositives++;
+      }
+    }
+    System.out.println("false positives: " + falsePositives);
+  }
+
+  static long[] positions(String key) {
+    ByteBuffer digest =
+        ByteBuffer.wrap(Hashing.murmur3_128(SEED).hashString(key, StandardCharsets.UTF_8).asBytes())
+            .order(ByteOrder.LITTLE_ENDIAN);
+    long h1 = digest.getLong(0);
+    long h2 = digest.getLong(8);
+    long[] pos = new long[K];
+    for (int i = 0; i < K; i++) {
+      pos[i] = Long.remainderUnsigned(h1 + i * h2, M);
+    }
+    return pos;
+  }
+}
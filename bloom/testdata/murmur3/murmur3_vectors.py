@@ -0,0 +1,87 @@
+# Port of Murmur3Vectors.java with a self-contained MurmurHash3_x64_128, so
+# the fixture can be regenerated without a JVM:
+#
+#	python3 murmur3_vectors.py ..
+import struct
+import sys
+
+MASK = (1 << 64) - 1
+SEED, M, K = 42, 959, 7
+
+
+def rotl(x, r):
+    return ((x << r) | (x >> (64 - r))) & MASK
+
+
+def fmix(k):
+    k ^= k >> 33
+    k = (k * 0xFF51AFD7ED558CCD) & MASK
+    k ^= k >> 33
+    k = (k * 0xC4CEB9FE1A85EC53) & MASK
+    return k ^ (k >> 33)
+
+
+def murmur3_128(data, seed):
+    c1, c2 = 0x87C37B91114253D5, 0x4CF5AD432745937F
+    h1 = h2 = seed
+    nblocks = len(data) // 16
+    for b in range(nblocks):
+        k1, k2 = struct.unpack_from("<QQ", data, b * 16)
+        k1 = rotl((k1 * c1) & MASK, 31) * c2 & MASK
+        h1 ^= k1
+        h1 = (rotl(h1, 27) + h2) & MASK
+        h1 = (h1 * 5 + 0x52DCE729) & MASK
+        k2 = rotl((k2 * c2) & MASK, 33) * c1 & MASK
+        h2 ^= k2
+        h2 = (rotl(h2, 31) + h1) & MASK
+        h2 = (h2 * 5 + 0x38495AB5) & MASK
+    tail = data[nblocks * 16:]
+    k1 = k2 = 0
+    for i in range(len(tail) - 1, 7, -1):
+        k2 ^= tail[i] << ((i - 8) * 8)
+    if len(tail) > 8:
+        k2 = rotl((k2 * c2) & MASK, 33) * c1 & MASK
+        h2 ^= k2
+    for i in range(min(len(tail), 8) - 1, -1, -1):
+        k1 ^= tail[i] << (i * 8)
+    if tail:
+        k1 = rotl((k1 * c1) & MASK, 31) * c2 & MASK
+        h1 ^= k1
+    h1 ^= len(data)
+    h2 ^= len(data)
+    h1 = (h1 + h2) & MASK
+    h2 = (h2 + h1) & MASK
+    h1, h2 = fmix(h1), fmix(h2)
+    h1 = (h1 + h2) & MASK
+    h2 = (h2 + h1) & MASK
+    return h1, h2
+
+
+def positions(key, seed=SEED):
+    h1, h2 = murmur3_128(key.encode(), seed)
+    return [((h1 + i * h2) & MASK) % M for i in range(K)]
+
+
+def main(outdir):
+    words = [0] * ((M + 63) // 64)
+    for i in range(100):
+        for pos in positions("key-%d" % i):
+            words[pos // 64] |= 1 << (pos % 64)
+    with open(outdir + "/murmur3_seed42_m959_k7.bin", "wb") as f:
+        f.write(struct.pack("<%dQ" % len(words), *words))
+
+    false_positives = 0
+    for i in range(100, 10100):
+        if all(words[p // 64] >> (p % 64) & 1 for p in positions("key-%d" % i)):
+            false_positives += 1
+    print("false positives: %d" % false_positives)
+    # Spot values for the Go unit test.
+    for key in ("", "hello", "The quick brown fox jumps over the lazy dog"):
+        print("%r %#x %#x" % (key, *murmur3_128(key.encode(), SEED)))
+    # Under seed 0 the empty key hashes to h1 = h2 = 0, so every probe lands
+    # on bit 0; Go must not substitute another h2.
+    print("empty key, seed 0: positions %s" % positions("", 0))
+
+
+if __name__ == "__main__":
+    main(sys.argv[1])
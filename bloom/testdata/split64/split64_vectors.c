@@ -0,0 +1,87 @@
+/*
+ * Reference for the split64 probe scheme, as used by the legacy C service:
+ * one 64-bit FNV-1a hash per key, g1 = low 32 bits, g2 = high 32 bits
+ * forced odd, probe i at (g1 + i*g2) mod m in 64-bit arithmetic.
+ *
+ * Regenerates testdata/split64_fnv_m1000_k5.bin and prints the probe
+ * vectors pinned in split64_test.go:
+ *
+ *	cc -O2 -o /tmp/split64_vectors split64_vectors.c
+ *	/tmp/split64_vectors ..
+ */
+#include <inttypes.h>
+#include <stdio.h>
+#include <string.h>
+
+static uint64_t fnv1a64(const char *s, size_t n) {
+	uint64_t h = 14695981039346656037ULL;
+	for (size_t i = 0; i < n; i++) {
+		h ^= (unsigned char)s[i];
+		h *= 1099511628211ULL;
+	}
+	return h;
+}
+
+static uint64_t probe(uint64_t h, uint64_t i, uint64_t m) {
+	uint64_t g1 = h & 0xffffffffULL;
+	uint64_t g2 = (h >> 32) | 1;
+	return (g1 + i * g2) % m;
+}
+
+#define M 1000
+#define K 5
+#define WORDS ((M + 63) / 64)
+
+int main(int argc, char **argv) {
+	static const char *keys[] = {"", "a", "alice", "The quick brown fox jumps over the lazy dog"};
+	for (size_t j = 0; j < sizeof keys / sizeof keys[0]; j++) {
+		uint64_t h = fnv1a64(keys[j], strlen(keys[j]));
+		printf("{\"%s\", 0x%016" PRIx64 ", []uint64{", keys[j], h);
+		for (uint64_t i = 0; i < 7; i++) {
+			printf("%s%" PRIu64, i ? ", " : "", probe(h, i, 1 << 20));
+		}
+		printf("}},\n");
+	}
+
+	uint64_t words[WORDS] = {0};
+	char key[32];
+	for (int n = 0; n < 100; n++) {
+		int len = snprintf(key, sizeof key, "key-%d", n);
+		uint64_t h = fnv1a64(key, len);
+		for (uint64_t i = 0; i < K; i++) {
+			uint64_t pos = probe(h, i, M);
+			words[pos / 64] |= 1ULL << (pos % 64);
+		}
+	}
+	int fp = 0;
+	for (int n = 100; n < 10100; n++) {
+		int len = snprintf(key, sizeof key, "key-%d", n);
+		uint64_t h = fnv1a64(key, len);
+		int present = 1;
+		for (uint64_t i = 0; i < K; i++) {
+			uint64_t pos = probe(h, i, M);
+			present &= (words[pos / 64] >> (pos % 64)) & 1;
+		}
+		fp += present;
+	}
+	printf("false positives: %d\n", fp);
+
+	if (argc > 1) {
+		char path[4096];
+		snprintf(path, sizeof path, "%s/split64_fnv_m1000_k5.bin", argv[1]);
+		FILE *f = fopen(path, "wb");
+		if (!f) {
+			perror(path);
+			return 1;
+		}
+		for (int w = 0; w < WORDS; w++) {
+			unsigned char le[8];
+			for (int b = 0; b < 8; b++) {
+				le[b] = (unsigned char)(words[w] >> (8 * b));
+			}
+			fwrite(le, 1, 8, f);
+		}
+		fclose(f);
+	}
+	return 0;
+}
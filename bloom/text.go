@@ -0,0 +1,86 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+// textPrefix tags the text encoding and its version.
+const textPrefix = "bf1"
+
+var (
+	_ encoding.TextMarshaler   = (*BloomFilter)(nil)
+	_ encoding.TextUnmarshaler = (*BloomFilter)(nil)
+)
+
+// MarshalText implements encoding.TextMarshaler, producing a single line of
+// the form
+//
+//	bf1:<m>:<k>:<bits>
+//
+// where bits is the little-endian word bitset in unpadded URL-safe base64.
+// The output uses only [A-Za-z0-9:_-], so it can be pasted into YAML,
+// environment variables or URLs without escaping. Only unseeded filters
+// using the default scheme and hasher have a text form.
+func (bf *BloomFilter) MarshalText() ([]byte, error) {
+	if !bf.initialized() {
+		return nil, ErrUninitialized
+	}
+	if bf.scheme != schemeFNV {
+		return nil, fmt.Errorf("%w: text encoding does not support probe scheme %s", ErrUnsupportedFormat, bf.scheme)
+	}
+	if bf.hasher != nil {
+		return nil, fmt.Errorf("%w: text encoding does not support hasher %s", ErrUnsupportedFormat, bf.hasher)
+	}
+	if bf.seed != 0 {
+		return nil, fmt.Errorf("%w: text encoding does not support seeded filters", ErrUnsupportedFormat)
+	}
+
+	raw := bf.appendWords(make([]byte, 0, bf.wordCount()*8), binary.LittleEndian)
+	out := fmt.Appendf(nil, "%s:%d:%d:", textPrefix, bf.m, bf.k)
+	return base64.RawURLEncoding.AppendEncode(out, raw), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, replacing the
+// receiver's contents. A wrong prefix or field count, unparsable numbers,
+// or a bitset whose length disagrees with m all fail with ErrCorrupt.
+func (bf *BloomFilter) UnmarshalText(text []byte) error {
+	fields := bytes.Split(text, []byte(":"))
+	if len(fields) != 4 {
+		return fmt.Errorf("%w: text form has %d fields, want 4 (%s:<m>:<k>:<bits>)", ErrCorrupt, len(fields), textPrefix)
+	}
+	if string(fields[0]) != textPrefix {
+		return fmt.Errorf("%w: text form has prefix %q, want %q", ErrCorrupt, fields[0], textPrefix)
+	}
+	m, err := strconv.ParseUint(string(fields[1]), 10, 64)
+	if err != nil || m == 0 {
+		return fmt.Errorf("%w: invalid m %q", ErrCorrupt, fields[1])
+	}
+	k, err := strconv.ParseUint(string(fields[2]), 10, 64)
+	if err != nil || k == 0 {
+		return fmt.Errorf("%w: invalid k %q", ErrCorrupt, fields[2])
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(string(fields[3]))
+	if err != nil {
+		return fmt.Errorf("%w: invalid bitset: %v", ErrCorrupt, err)
+	}
+	words := wordsFor(m)
+	if uint64(len(raw)) != words*8 {
+		return fmt.Errorf("%w: bitset is %d bytes, want %d for m=%d", ErrCorrupt, len(raw), words*8, m)
+	}
+
+	w := make([]uint64, words)
+	for i := range w {
+		w[i] = binary.LittleEndian.Uint64(raw[i*8:])
+	}
+	decoded, err := newFromHeader(header{m: m, k: k, words: words}, contiguous(w))
+	if err != nil {
+		return err
+	}
+	*bf = *decoded
+	return nil
+}
@@ -0,0 +1,62 @@
+package bloom
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestText_RoundTrip(t *testing.T) {
+	bf := New(1000, 3)
+	for i := 0; i < 50; i++ {
+		bf.Add([]byte("allow-" + strconv.Itoa(i)))
+	}
+
+	text, err := bf.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^bf1:1000:3:[A-Za-z0-9_-]+$`).Match(text) {
+		t.Fatalf("text form contains unexpected characters: %s", text)
+	}
+
+	var got BloomFilter
+	if err := got.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		key := []byte("allow-" + strconv.Itoa(i))
+		if got.MightContain(key) != bf.MightContain(key) {
+			t.Fatalf("key %d: text-decoded filter disagrees with original", i)
+		}
+	}
+}
+
+func TestText_Rejects(t *testing.T) {
+	valid, err := New(64, 2).MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"prefix":       "bf9" + string(valid[3:]),
+		"fields":       "bf1:64:2",
+		"extra field":  string(valid) + ":x",
+		"m":            "bf1:sixty:2:AAAAAAAAAAA",
+		"zero k":       "bf1:64:0:AAAAAAAAAAA",
+		"base64":       "bf1:64:2:!!!",
+		"length":       "bf1:128:2:AAAAAAAAAAA",
+		"padding bits": "bf1:4:1:EAAAAAAAAAA",
+	}
+	for name, text := range cases {
+		var bf BloomFilter
+		if err := bf.UnmarshalText([]byte(text)); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: expected ErrCorrupt, got %v", name, err)
+		}
+	}
+
+	if _, err := NewGuavaWithEstimates(10, 0.1).MarshalText(); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("expected ErrUnsupportedFormat for a guava filter, got %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+package bloom
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// ColdTier is a read-only layer of a Tiered filter. A memory-mapped
+// *BloomFilter (NewMmap), a *ReaderAtFilter (OpenReaderAt) and a *Frozen
+// all qualify. Tiers implementing io.Closer are closed by Tiered.Close.
+type ColdTier interface {
+	MightContain(data []byte) bool
+}
+
+// Tiered composes a small in-memory hot filter, which receives every Add,
+// with read-only cold tiers such as large memory-mapped history.
+// MightContain asks the hot tier first and then the cold tiers, newest
+// first, so queries for recent keys never touch cold storage.
+//
+// Compact moves the hot tier's contents into a new cold artifact on disk
+// and starts a fresh hot tier. Queries keep seeing the old hot contents
+// while the artifact is written, so no key goes missing across the switch.
+//
+// A key is reported present if any tier reports it, so the false positive
+// rate is roughly the sum of the tiers' rates.
+//
+// Tiered is safe for concurrent use.
+type Tiered struct {
+	mu         sync.RWMutex
+	hot        *BloomFilter
+	compacting *tier // old hot contents while Compact writes them out
+	cold       []*tier
+
+	newHot    func() *BloomFilter
+	compactMu sync.Mutex // serializes Compact
+	queries   atomic.Uint64
+	misses    atomic.Uint64
+	hotHits   atomic.Uint64
+}
+
+// tier is a cold tier with its hit counter.
+type tier struct {
+	name string
+	f    ColdTier
+	hits atomic.Uint64
+}
+
+// TieredStats reports where queries were answered.
+type TieredStats struct {
+	Queries uint64      `json:"queries"`
+	Misses  uint64      `json:"misses"` // queries no tier reported present
+	Hot     TierStats   `json:"hot"`
+	Cold    []TierStats `json:"cold"` // newest first
+	HotFill Stats       `json:"hot_fill"`
+}
+
+// TierStats is the hit count of one tier of a Tiered filter. Hits counts
+// the queries that tier answered present first; later tiers were not
+// consulted for them.
+type TierStats struct {
+	Name string `json:"name"`
+	Hits uint64 `json:"hits"`
+}
+
+// NewTiered creates a tiered filter whose hot tier is sized for n keys at
+// fpRate, as NewWithEstimates, with no cold tiers. Size n for the keys
+// added between compactions.
+func NewTiered(n uint64, fpRate float64) *Tiered {
+	m, k := EstimateParameters(n, fpRate)
+	newHot := func() *BloomFilter {
+		bf := New(m, k)
+		bf.capacity = n
+		return bf
+	}
+	return &Tiered{hot: newHot(), newHot: newHot}
+}
+
+// AddCold appends a cold tier, consulted after every existing tier. Use it
+// to attach history opened at startup, oldest last.
+func (t *Tiered) AddCold(name string, c ColdTier) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cold = append(t.cold, &tier{name: name, f: c})
+}
+
+// Add inserts data into the hot tier.
+func (t *Tiered) Add(data []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hot.Add(data)
+}
+
+// MightContain checks the hot tier and then each cold tier, newest first.
+// Returns false -> definitely not present in any tier.
+// Returns true  -> might be present (subject to false positives).
+func (t *Tiered) MightContain(data []byte) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	t.queries.Add(1)
+	if t.hot.MightContain(data) {
+		t.hotHits.Add(1)
+		return true
+	}
+	if t.compacting != nil && t.compacting.f.MightContain(data) {
+		t.compacting.hits.Add(1)
+		return true
+	}
+	for _, c := range t.cold {
+		if c.f.MightContain(data) {
+			c.hits.Add(1)
+			return true
+		}
+	}
+	t.misses.Add(1)
+	return false
+}
+
+// Compact writes the hot tier's contents to a new cold artifact at path,
+// attaches it as the newest cold tier and resets the hot tier.
+//
+// Adds go to a fresh hot tier as soon as Compact starts, and queries keep
+// consulting the old contents until the artifact replaces them, so Compact
+// blocks neither for the duration of the write. The artifact is a NewMmap
+// file where mmap is supported and a SaveFile file read through
+// OpenReaderAt elsewhere. On error the old contents stay attached in
+// memory as a cold tier, so nothing is lost.
+func (t *Tiered) Compact(path string) error {
+	t.compactMu.Lock()
+	defer t.compactMu.Unlock()
+
+	t.mu.Lock()
+	old := t.hot
+	frozen := &tier{name: path, f: old}
+	t.compacting = frozen
+	t.hot = t.newHot()
+	t.mu.Unlock()
+
+	artifact, err := writeColdArtifact(path, old)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.compacting = nil
+	if err != nil {
+		artifact = old
+	}
+	next := &tier{name: path, f: artifact}
+	next.hits.Store(frozen.hits.Load())
+	t.cold = append([]*tier{next}, t.cold...)
+	return err
+}
+
+// writeColdArtifact saves bf at path and reopens it read-only.
+func writeColdArtifact(path string, bf *BloomFilter) (ColdTier, error) {
+	mapped, err := NewMmap(path, bf.m, bf.k)
+	if errors.Is(err, ErrMmapUnsupported) {
+		if err := bf.SaveFile(path); err != nil {
+			return nil, err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		cold, err := OpenReaderAt(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return cold, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := mapped.CopyFrom(bf); err != nil {
+		mapped.Close()
+		return nil, err
+	}
+	if err := mapped.Flush(); err != nil {
+		mapped.Close()
+		return nil, err
+	}
+	return mapped, nil
+}
+
+// Stats returns the query counts per tier and the hot tier's fill.
+func (t *Tiered) Stats() TieredStats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	st := TieredStats{
+		Queries: t.queries.Load(),
+		Misses:  t.misses.Load(),
+		Hot:     TierStats{Name: "hot", Hits: t.hotHits.Load()},
+		HotFill: t.hot.Stats(),
+	}
+	tiers := t.cold
+	if t.compacting != nil {
+		tiers = append([]*tier{t.compacting}, tiers...)
+	}
+	for i, c := range tiers {
+		name := c.name
+		if name == "" {
+			name = "cold-" + strconv.Itoa(i)
+		}
+		st.Cold = append(st.Cold, TierStats{Name: name, Hits: c.hits.Load()})
+	}
+	return st
+}
+
+// SizeInBytes reports the memory held by the hot tier, plus that of any
+// cold tier with a SizeInBytes method, such as a *Frozen. A mapped cold
+// tier counts its whole file, though the kernel pages it in and out.
+func (t *Tiered) SizeInBytes() uint64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	size := uint64(unsafe.Sizeof(*t)) + t.hot.SizeInBytes()
+	tiers := t.cold
+	if t.compacting != nil {
+		tiers = append([]*tier{t.compacting}, tiers...)
+	}
+	for _, c := range tiers {
+		size += uint64(unsafe.Sizeof(*c))
+		if sized, ok := c.f.(interface{ SizeInBytes() uint64 }); ok {
+			size += sized.SizeInBytes()
+		}
+	}
+	return size
+}
+
+// Info returns a small description of the tiers.
+func (t *Tiered) Info() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	cold := len(t.cold)
+	if t.compacting != nil {
+		cold++
+	}
+	return fmt.Sprintf("Tiered{cold tiers=%d, hot=%s}", cold, t.hot.Info())
+}
+
+// Close closes every cold tier that implements io.Closer and detaches all
+// cold tiers. The hot tier stays usable.
+func (t *Tiered) Close() error {
+	t.compactMu.Lock()
+	defer t.compactMu.Unlock()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var err error
+	for _, c := range t.cold {
+		if closer, ok := c.f.(io.Closer); ok {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	t.cold = nil
+	return err
+}
@@ -0,0 +1,105 @@
+package bloom
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// TestTiered_CompactionCycles adds a batch per cycle, compacts it to disk
+// while readers query every key added so far, and checks no key is ever
+// reported missing across the tier switch.
+func TestTiered_CompactionCycles(t *testing.T) {
+	const cycles, perCycle = 5, 2000
+	tf := NewTiered(perCycle, 0.01)
+	defer tf.Close()
+	dir := t.TempDir()
+
+	var added [][]byte
+	for cycle := 0; cycle < cycles; cycle++ {
+		batch := randomKeys("c"+strconv.Itoa(cycle)+"-", perCycle, uint64(90+cycle))
+		for _, key := range batch {
+			tf.Add(key)
+		}
+		added = append(added, batch...)
+
+		var wg sync.WaitGroup
+		stop := make(chan struct{})
+		for r := 0; r < 3; r++ {
+			wg.Add(1)
+			go func(r int) {
+				defer wg.Done()
+				for i := r; ; i = (i + 7) % len(added) {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					if !tf.MightContain(added[i]) {
+						t.Errorf("key %d missing during compaction %d", i, cycle)
+						return
+					}
+				}
+			}(r)
+		}
+		if err := tf.Compact(filepath.Join(dir, "cold-"+strconv.Itoa(cycle)+".bloom")); err != nil {
+			t.Fatal(err)
+		}
+		close(stop)
+		wg.Wait()
+
+		for i, key := range added {
+			if !tf.MightContain(key) {
+				t.Fatalf("key %d missing after compaction %d", i, cycle)
+			}
+		}
+	}
+
+	st := tf.Stats()
+	if len(st.Cold) != cycles || st.HotFill.Inserts != 0 {
+		t.Fatalf("want %d cold tiers and an empty hot tier, got %+v", cycles, st)
+	}
+	var hits uint64 = st.Hot.Hits
+	for _, c := range st.Cold {
+		hits += c.Hits
+	}
+	if hits+st.Misses != st.Queries {
+		t.Fatalf("hits %d + misses %d != queries %d", hits, st.Misses, st.Queries)
+	}
+	// The oldest batch is answered by the last tier.
+	before := st.Cold[cycles-1].Hits
+	tf.MightContain(added[0])
+	if got := tf.Stats().Cold[cycles-1].Hits; got != before+1 {
+		t.Fatalf("oldest tier hits %d, want %d", got, before+1)
+	}
+}
+
+func TestTiered_HotFirstAndAttachedCold(t *testing.T) {
+	history := NewWithEstimates(1000, 0.01)
+	history.Add([]byte("old"))
+	tf := NewTiered(1000, 0.01)
+	tf.AddCold("history", history.Freeze())
+	tf.Add([]byte("new"))
+
+	if !tf.MightContain([]byte("new")) || !tf.MightContain([]byte("old")) || tf.MightContain([]byte("never")) {
+		t.Fatal("unexpected membership")
+	}
+	st := tf.Stats()
+	if st.Hot.Hits != 1 || st.Cold[0].Name != "history" || st.Cold[0].Hits != 1 || st.Misses != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestTiered_CompactFailureKeepsKeys(t *testing.T) {
+	tf := NewTiered(1000, 0.01)
+	tf.Add([]byte("kept"))
+	err := tf.Compact(filepath.Join(t.TempDir(), "missing-dir", "cold.bloom"))
+	if err == nil {
+		t.Fatal("expected an error writing to a missing directory")
+	}
+	if errors.Is(err, ErrUninitialized) || !tf.MightContain([]byte("kept")) {
+		t.Fatalf("a failed compaction lost the hot contents: %v", err)
+	}
+}
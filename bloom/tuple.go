@@ -0,0 +1,87 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"sync"
+)
+
+// Tuple keys. Joining parts with a separator lets ("ab", "c") and
+// ("a", "bc") collide, so AddTuple prefixes every part with its length
+// instead. The encoding is fixed, since filters built from tuples must
+// stay queryable across releases:
+//
+//	for each part: uvarint(len(part)) followed by the part's bytes
+//
+// AddTuple([]byte("ab"), []byte("c")) is exactly
+// Add([]byte{2, 'a', 'b', 1, 'c'}). A tuple of one part is therefore a
+// different key from the part on its own, and the empty tuple is the
+// empty key.
+//
+// Tuples encoding to at most tupleStackBytes are built on the stack;
+// longer ones borrow a pooled buffer, so neither allocates in steady state.
+
+// tupleStackBytes is the largest encoded tuple built on the stack.
+const tupleStackBytes = 256
+
+// scratchPool holds buffers for encoding keys too large or too dynamic
+// for the stack.
+var scratchPool = sync.Pool{New: func() any { return new([]byte) }}
+
+// AddTuple inserts the tuple of parts, encoded as described above.
+func (bf *BloomFilter) AddTuple(parts ...[]byte) {
+	var buf [tupleStackBytes]byte
+	key, pooled := encodeTuple(&buf, parts)
+	bf.Add(key)
+	releaseScratch(pooled)
+}
+
+// MightContainTuple checks if the tuple of parts, encoded as AddTuple does,
+// might be in the filter.
+func (bf *BloomFilter) MightContainTuple(parts ...[]byte) bool {
+	var buf [tupleStackBytes]byte
+	key, pooled := encodeTuple(&buf, parts)
+	found := bf.MightContain(key)
+	releaseScratch(pooled)
+	return found
+}
+
+// encodeTuple returns the encoding of parts, built in buf if it fits and
+// otherwise in a pooled buffer, which is also returned and must be handed
+// to releaseScratch once the key is no longer needed.
+func encodeTuple(buf *[tupleStackBytes]byte, parts [][]byte) ([]byte, *[]byte) {
+	size := 0
+	for _, p := range parts {
+		size += uvarintLen(uint64(len(p))) + len(p)
+	}
+	if size <= tupleStackBytes {
+		return appendTuple(buf[:0], parts), nil
+	}
+	pooled := scratchPool.Get().(*[]byte)
+	*pooled = appendTuple((*pooled)[:0], parts)
+	return *pooled, pooled
+}
+
+// releaseScratch returns a buffer from scratchPool, if there is one.
+func releaseScratch(pooled *[]byte) {
+	if pooled != nil {
+		scratchPool.Put(pooled)
+	}
+}
+
+// appendTuple appends the encoding of parts to dst.
+func appendTuple(dst []byte, parts [][]byte) []byte {
+	for _, p := range parts {
+		dst = binary.AppendUvarint(dst, uint64(len(p)))
+		dst = append(dst, p...)
+	}
+	return dst
+}
+
+// uvarintLen returns the number of bytes binary.AppendUvarint writes for v.
+func uvarintLen(v uint64) int {
+	n := 1
+	for ; v >= 0x80; v >>= 7 {
+		n++
+	}
+	return n
+}
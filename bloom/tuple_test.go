@@ -0,0 +1,75 @@
+package bloom
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestTuple_PinnedEncoding fixes the tuple encoding: each tuple must set
+// exactly the bits Add sets for the literal bytes below. Filters built with
+// AddTuple depend on it, so these literals must never be edited.
+func TestTuple_PinnedEncoding(t *testing.T) {
+	long := bytes.Repeat([]byte{'x'}, 300)
+	cases := []struct {
+		name  string
+		parts [][]byte
+		bytes []byte
+	}{
+		{"empty tuple", nil, []byte{}},
+		{"one empty part", [][]byte{{}}, []byte{0}},
+		{"ab,c", [][]byte{[]byte("ab"), []byte("c")}, []byte{2, 'a', 'b', 1, 'c'}},
+		{"a,bc", [][]byte{[]byte("a"), []byte("bc")}, []byte{1, 'a', 2, 'b', 'c'}},
+		{"user,,id", [][]byte{[]byte("user"), nil, []byte("id")}, []byte{4, 'u', 's', 'e', 'r', 0, 2, 'i', 'd'}},
+		{"300 bytes", [][]byte{long, []byte("z")}, append(append([]byte{0xac, 0x02}, long...), 1, 'z')},
+	}
+	for _, c := range cases {
+		if got := appendTuple(nil, c.parts); !bytes.Equal(got, c.bytes) {
+			t.Fatalf("%s: encoding is % x, want % x", c.name, got, c.bytes)
+		}
+		tuple, raw := New(4099, 5), New(4099, 5)
+		tuple.AddTuple(c.parts...)
+		raw.Add(c.bytes)
+		if !tuple.Equal(raw) {
+			t.Fatalf("%s: AddTuple differs from Add(% x)", c.name, c.bytes)
+		}
+		if !raw.MightContainTuple(c.parts...) {
+			t.Fatalf("%s: tuple lookup misses the byte-slice key", c.name)
+		}
+	}
+}
+
+func TestTuple_NoAliasing(t *testing.T) {
+	bf := NewWithEstimates(1000, 1e-6)
+	bf.AddTuple([]byte("ab"), []byte("c"))
+	for _, parts := range [][][]byte{
+		{[]byte("a"), []byte("bc")},
+		{[]byte("abc")},
+		{[]byte("ab"), []byte("c"), nil},
+		{[]byte("ab"), nil, []byte("c")},
+	} {
+		if bf.MightContainTuple(parts...) {
+			t.Fatalf("tuple %q aliases (ab, c)", parts)
+		}
+	}
+	if bf.MightContain([]byte("abc")) {
+		t.Fatal("tuple (ab, c) aliases the plain key abc")
+	}
+}
+
+func TestTuple_SafeBloomAndAllocs(t *testing.T) {
+	s := NewSafeWithEstimates(1000, 0.01)
+	s.AddTuple([]byte("tenant"), []byte("key"))
+	if !s.MightContainTuple([]byte("tenant"), []byte("key")) {
+		t.Fatal("SafeBloom tuple missing")
+	}
+
+	bf := NewWithEstimates(1000, 0.01)
+	parts := [][]byte{[]byte("tenant-42"), []byte("user-1234567")}
+	allocs := testing.AllocsPerRun(100, func() {
+		bf.AddTuple(parts...)
+		bf.MightContainTuple(parts...)
+	})
+	if allocs != 0 {
+		t.Fatalf("tuples allocate %.1f times per call", allocs)
+	}
+}
@@ -0,0 +1,102 @@
+package bloom
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+)
+
+// Typed is a filter over keys of type T. Every key goes through one
+// encoding function on both Add and MightContain, so keys of another type
+// cannot be added or queried by mistake, and the bytes hashed for a key
+// never depend on the call site.
+//
+// Typed delegates to a BloomFilter or SafeBloom and inherits its
+// concurrency guarantees. The underlying filter can still be used
+// directly: Typed[string] with StringKey and raw Add([]byte(s)) see the
+// same keys.
+type Typed[T any] struct {
+	encode       func(T) []byte
+	add          func([]byte)
+	mightContain func([]byte) bool
+	addAll       func([][]byte)
+}
+
+// NewTyped wraps bf for keys of type T encoded by encode. encode must be
+// deterministic: equal keys must always encode to equal bytes.
+func NewTyped[T any](bf *BloomFilter, encode func(T) []byte) *Typed[T] {
+	return &Typed[T]{encode: encode, add: bf.Add, mightContain: bf.MightContain, addAll: bf.AddAll}
+}
+
+// NewTypedSafe wraps s for keys of type T encoded by encode. See NewTyped.
+func NewTypedSafe[T any](s *SafeBloom, encode func(T) []byte) *Typed[T] {
+	return &Typed[T]{encode: encode, add: s.Add, mightContain: s.MightContain, addAll: func(keys [][]byte) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.bf.AddAll(keys)
+	}}
+}
+
+// Add inserts v.
+func (t *Typed[T]) Add(v T) {
+	t.add(t.encode(v))
+}
+
+// MightContain checks if v might be in the filter.
+func (t *Typed[T]) MightContain(v T) bool {
+	return t.mightContain(t.encode(v))
+}
+
+// AddAll inserts every key in vs, encoding and adding them a chunk at a
+// time; a SafeBloom is locked once per chunk.
+func (t *Typed[T]) AddAll(vs []T) {
+	keys := make([][]byte, 0, min(len(vs), batchChunk))
+	for len(vs) > 0 {
+		chunk := vs[:min(len(vs), batchChunk)]
+		vs = vs[len(chunk):]
+		keys = keys[:0]
+		for _, v := range chunk {
+			keys = append(keys, t.encode(v))
+		}
+		t.addAll(keys)
+	}
+}
+
+// Key encoders for Typed. Each uses the same layout as the corresponding
+// BloomFilter method, so typed and untyped calls interoperate.
+
+// BytesKey encodes a byte slice as itself.
+func BytesKey(b []byte) []byte {
+	return b
+}
+
+// StringKey encodes s as its bytes, like AddString.
+func StringKey(s string) []byte {
+	return []byte(s)
+}
+
+// integer is the set of types IntKey accepts.
+type integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// IntKey encodes v as 8 little-endian bytes, converting it to 64 bits
+// first (sign-extending signed types), exactly like AddUint64 and
+// AddInt64. Every integer type therefore shares one key space: int32(-2)
+// and int64(-2) are the same key. Note that AddUint32's 4-byte layout is
+// different; encode with a custom function to match it.
+func IntKey[T integer](v T) []byte {
+	return binary.LittleEndian.AppendUint64(make([]byte, 0, 8), uint64(v))
+}
+
+// MarshalerKey encodes v with its MarshalBinary method. It panics if
+// MarshalBinary fails, so use it only for types whose marshaling cannot
+// fail, and only if the encoding is deterministic.
+func MarshalerKey[T encoding.BinaryMarshaler](v T) []byte {
+	b, err := v.MarshalBinary()
+	if err != nil {
+		panic(fmt.Errorf("bloom: encoding key: %w", err))
+	}
+	return b
+}
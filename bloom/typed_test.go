@@ -0,0 +1,85 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"strconv"
+	"testing"
+)
+
+type orderKey struct {
+	Tenant string
+	ID     uint64
+}
+
+// encodeOrder encodes an orderKey as a tuple, so tenant names of any
+// length cannot run into the ID.
+func encodeOrder(k orderKey) []byte {
+	var id [8]byte
+	binary.LittleEndian.PutUint64(id[:], k.ID)
+	return appendTuple(nil, [][]byte{[]byte(k.Tenant), id[:]})
+}
+
+func TestTyped_StructKeys(t *testing.T) {
+	orders := NewTyped(NewWithEstimates(1000, 0.001), encodeOrder)
+	var keys []orderKey
+	for i := 0; i < 200; i++ {
+		keys = append(keys, orderKey{"tenant-" + strconv.Itoa(i%7), uint64(i)})
+	}
+	orders.AddAll(keys[:100])
+	orders.Add(keys[100])
+	for i, k := range keys {
+		if got, want := orders.MightContain(k), i <= 100; got && !want {
+			t.Logf("false positive for %+v", k)
+		} else if got != want {
+			t.Fatalf("%+v missing", k)
+		}
+	}
+}
+
+func TestTyped_InteroperatesWithRawKeys(t *testing.T) {
+	bf := NewWithEstimates(1000, 0.001)
+	names := NewTyped(bf, StringKey)
+	names.Add("alice")
+	bf.Add([]byte("bob"))
+	if !bf.MightContain([]byte("alice")) || !names.MightContain("bob") {
+		t.Fatal("Typed[string] and raw keys do not interoperate")
+	}
+	raw := NewTyped(bf, BytesKey)
+	if !raw.MightContain([]byte("alice")) {
+		t.Fatal("BytesKey is not the identity")
+	}
+
+	// IntKey uses the AddUint64/AddInt64 layout for every integer type.
+	ints := NewTyped(bf, IntKey[int32])
+	ints.Add(-2)
+	bf.AddUint64(7)
+	if !bf.MightContainInt64(-2) || !ints.MightContain(7) {
+		t.Fatal("IntKey does not match AddInt64/AddUint64")
+	}
+
+	addrs := NewTyped(bf, MarshalerKey[netip.Addr])
+	addr := netip.MustParseAddr("192.0.2.1")
+	addrs.Add(addr)
+	if !bf.MightContain(addr.AsSlice()) {
+		t.Fatal("MarshalerKey does not use MarshalBinary's bytes")
+	}
+}
+
+func TestTyped_SafeBloom(t *testing.T) {
+	s := NewSafeWithEstimates(1000, 0.01)
+	ids := NewTypedSafe(s, IntKey[uint64])
+	vs := make([]uint64, 150)
+	for i := range vs {
+		vs[i] = uint64(i) * 1000
+	}
+	ids.AddAll(vs)
+	for _, v := range vs {
+		if !ids.MightContain(v) || !s.MightContainUint64(v) {
+			t.Fatalf("%d missing", v)
+		}
+	}
+	if s.Count() != 150 {
+		t.Fatalf("count %d, want 150", s.Count())
+	}
+}
@@ -0,0 +1,253 @@
+package bloom
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"unsafe"
+)
+
+// WAL file format:
+//
+//	magic    [4]byte "BLMW"
+//	version  uint8
+//	scheme   uint8   probe scheme of the logged hashes
+//	records  each: the key's base hashes as little-endian uint64s (two, or
+//	         four for schemes that use four), then a CRC-32 (IEEE) of them
+//
+// Records hold hashes rather than keys, so they are fixed-size and can be
+// replayed into any filter using the same scheme, whatever its m and k.
+const walVersion = 1
+
+var walMagic = [4]byte{'B', 'L', 'M', 'W'}
+
+const walHeaderLen = 4 + 1 + 1 // magic, version, scheme
+
+// WALFilter is a BloomFilter whose insertions are also appended to a
+// write-ahead log, so keys added since the last snapshot survive a crash.
+// Appends are buffered; call Sync to make them durable. After saving a
+// snapshot, Checkpoint empties the log.
+//
+// Note: This type is not safe for concurrent use without external locking.
+type WALFilter struct {
+	bf   *BloomFilter
+	file *os.File
+	w    *bufio.Writer
+	rec  []byte // scratch record buffer
+
+	closed bool
+}
+
+// NewWithWAL creates a filter sized for n items at fpRate (as
+// NewWithEstimates) that logs insertions to the file at path. An existing
+// log is replayed first.
+func NewWithWAL(path string, n uint64, fpRate float64) (*WALFilter, error) {
+	return OpenWAL(path, NewWithEstimates(n, fpRate))
+}
+
+// OpenWAL replays the log at path into bf, typically a filter just loaded
+// from a snapshot, and continues logging to it. The file is created if
+// missing, and a torn final record is truncated away before appending.
+func OpenWAL(path string, bf *BloomFilter) (*WALFilter, error) {
+	if !bf.initialized() {
+		return nil, ErrUninitialized
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	end, err := replayWAL(f, bf)
+	if err == nil {
+		err = initWAL(f, bf.scheme, end)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &WALFilter{bf: bf, file: f, w: bufio.NewWriter(f)}, nil
+}
+
+// Recover replays the log at path into bf and returns the number of
+// records applied. Replay only sets bits, so applying a log more than once
+// is harmless. A torn final record, left by a crash mid-append, is
+// ignored; damage anywhere else fails with ErrCorrupt.
+func Recover(path string, bf *BloomFilter) (uint64, error) {
+	if !bf.initialized() {
+		return 0, ErrUninitialized
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	before := bf.inserts
+	if _, err := replayWAL(f, bf); err != nil {
+		return bf.inserts - before, fmt.Errorf("%s: %w", path, err)
+	}
+	return bf.inserts - before, nil
+}
+
+// replayWAL applies every intact record in f to bf and returns the offset
+// just past the last one (0 for an empty file).
+func replayWAL(f *os.File, bf *BloomFilter) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() == 0 {
+		return 0, nil
+	}
+
+	r := bufio.NewReader(f)
+	var hdr [walHeaderLen]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, fmt.Errorf("%w: short wal header", ErrCorrupt)
+	}
+	if string(hdr[:len(walMagic)]) != string(walMagic[:]) {
+		return 0, fmt.Errorf("%w: bad wal magic number", ErrCorrupt)
+	}
+	if v := hdr[len(walMagic)]; v != walVersion {
+		return 0, fmt.Errorf("%w: wal version %d", ErrUnsupportedVersion, v)
+	}
+	if s := scheme(hdr[len(walMagic)+1]); s != bf.scheme {
+		return 0, fmt.Errorf("%w: wal logs probe scheme %s, filter uses %s", ErrIncompatible, s, bf.scheme)
+	}
+
+	size := walRecordLen(bf.scheme)
+	rec := make([]byte, size)
+	end := int64(walHeaderLen)
+	for {
+		_, err := io.ReadFull(r, rec)
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			return end, nil // clean end, or a torn final record
+		}
+		if err != nil {
+			return end, err
+		}
+		body := rec[:size-4]
+		if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(rec[size-4:]) {
+			if end+int64(size) == info.Size() {
+				return end, nil // torn final record
+			}
+			return end, fmt.Errorf("%w: wal checksum mismatch at offset %d", ErrCorrupt, end)
+		}
+		var h baseHashes
+		for i := range len(body) / 8 {
+			h[i] = binary.LittleEndian.Uint64(body[i*8:])
+		}
+		bf.addHashes(h)
+		end += int64(size)
+	}
+}
+
+// initWAL truncates f to end, writing a fresh header if it is empty, and
+// positions it for appending.
+func initWAL(f *os.File, s scheme, end int64) error {
+	if end == 0 {
+		hdr := append(walMagic[:len(walMagic):len(walMagic)], walVersion, byte(s))
+		if _, err := f.WriteAt(hdr, 0); err != nil {
+			return err
+		}
+		end = walHeaderLen
+	}
+	if err := f.Truncate(end); err != nil {
+		return err
+	}
+	_, err := f.Seek(end, io.SeekStart)
+	return err
+}
+
+// walRecordLen returns the size of one record for scheme s.
+func walRecordLen(s scheme) int {
+	if s == schemeBitsAndBlooms {
+		return 4*8 + 4
+	}
+	return 2*8 + 4
+}
+
+// Add inserts data and appends its hashes to the log buffer. An error
+// means the record could not be buffered or flushed; the key is still in
+// the in-memory filter.
+func (w *WALFilter) Add(data []byte) error {
+	if w.closed {
+		return os.ErrClosed
+	}
+	h := w.bf.hashes(data)
+	w.bf.addHashes(h)
+
+	rec := w.rec[:0]
+	for _, v := range h[:(walRecordLen(w.bf.scheme)-4)/8] {
+		rec = binary.LittleEndian.AppendUint64(rec, v)
+	}
+	rec = binary.LittleEndian.AppendUint32(rec, crc32.ChecksumIEEE(rec))
+	w.rec = rec
+	_, err := w.w.Write(rec)
+	return err
+}
+
+// MightContain checks if data might be in the filter.
+func (w *WALFilter) MightContain(data []byte) bool {
+	return w.bf.MightContain(data)
+}
+
+// Filter returns the underlying filter. Keys added to it directly are not
+// logged.
+func (w *WALFilter) Filter() *BloomFilter {
+	return w.bf
+}
+
+// SizeInBytes reports the memory held by the filter and its log write
+// buffer.
+func (w *WALFilter) SizeInBytes() uint64 {
+	return uint64(unsafe.Sizeof(*w)) + w.bf.SizeInBytes() + uint64(w.w.Size()+cap(w.rec))
+}
+
+// Info describes the underlying filter.
+func (w *WALFilter) Info() string {
+	return w.bf.Info()
+}
+
+// Sync flushes buffered records and fsyncs the log, making every Add so
+// far durable.
+func (w *WALFilter) Sync() error {
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// Checkpoint saves the filter to snapshotPath with SaveFile and then
+// empties the log, so it only ever holds keys added since the latest
+// snapshot. A crash between the two steps leaves a log that is already
+// covered by the snapshot, which is safe to replay.
+func (w *WALFilter) Checkpoint(snapshotPath string) error {
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	if err := w.bf.SaveFile(snapshotPath); err != nil {
+		return err
+	}
+	if err := initWAL(w.file, w.bf.scheme, walHeaderLen); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// Close syncs and closes the log. The filter remains queryable, but
+// further Adds fail with os.ErrClosed.
+func (w *WALFilter) Close() error {
+	if w.closed {
+		return os.ErrClosed
+	}
+	w.closed = true
+	err := w.Sync()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
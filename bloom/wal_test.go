@@ -0,0 +1,139 @@
+package bloom
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func walKeys(t *testing.T, w *WALFilter, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		if err := w.Add([]byte("key-" + strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWAL_RecoverAfterCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.wal")
+	w, err := NewWithWAL(path, 1000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	walKeys(t, w, 0, 500)
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	// The process dies here without Close; a torn record follows.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{1, 2, 3, 4, 5, 6, 7})
+	f.Close()
+
+	restarted, err := NewWithWAL(path, 1000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 500; i++ {
+		if !restarted.MightContain([]byte("key-" + strconv.Itoa(i))) {
+			t.Fatalf("key-%d lost across restart", i)
+		}
+	}
+	if restarted.Filter().setBits != w.Filter().setBits {
+		t.Fatal("replayed filter differs from the one that crashed")
+	}
+
+	// The torn tail is dropped, so new records append cleanly.
+	walKeys(t, restarted, 500, 600)
+	if err := restarted.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := restarted.Add([]byte("late")); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("expected os.ErrClosed after Close, got %v", err)
+	}
+
+	bf := NewWithEstimates(1000, 0.01)
+	n, err := Recover(path, bf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 600 {
+		t.Fatalf("recovered %d records, want 600", n)
+	}
+	setBits := bf.setBits
+	if _, err := Recover(path, bf); err != nil || bf.setBits != setBits {
+		t.Fatalf("replaying twice changed the filter (err %v)", err)
+	}
+}
+
+func TestWAL_Checkpoint(t *testing.T) {
+	dir := t.TempDir()
+	walPath, snapPath := filepath.Join(dir, "filter.wal"), filepath.Join(dir, "filter.bf")
+	w, err := NewWithWAL(walPath, 1000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	walKeys(t, w, 0, 300)
+	if err := w.Checkpoint(snapPath); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(walPath); err != nil || info.Size() != walHeaderLen {
+		t.Fatalf("log not emptied by Checkpoint: %v, %v", info.Size(), err)
+	}
+	walKeys(t, w, 300, 400)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := LoadFile(snapPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := OpenWAL(walPath, snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	for i := 0; i < 400; i++ {
+		if !restored.MightContain([]byte("key-" + strconv.Itoa(i))) {
+			t.Fatalf("key-%d missing after snapshot plus log replay", i)
+		}
+	}
+}
+
+func TestWAL_Rejects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.wal")
+	w, err := NewWithWAL(path, 100, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	walKeys(t, w, 0, 10)
+	w.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[walHeaderLen+3] ^= 0xff // damage the first of ten records
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Recover(path, New(1000, 3)); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt for a damaged middle record, got %v", err)
+	}
+
+	other := filepath.Join(t.TempDir(), "guava.wal")
+	g, err := OpenWAL(other, NewGuavaWithEstimates(100, 0.01))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Close()
+	if _, err := Recover(other, New(1000, 3)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible replaying across schemes, got %v", err)
+	}
+}
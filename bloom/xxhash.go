@@ -0,0 +1,95 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	xxPrime1 = 11400714785074694791
+	xxPrime2 = 14029467366897019727
+	xxPrime3 = 1609587929392839161
+	xxPrime4 = 9650029242287828579
+	xxPrime5 = 2870177450012600261
+)
+
+// XXHasher is a Hasher built on XXH64, which consumes 32 bytes per round
+// instead of FNV-1a's one, and so is several times faster on keys longer
+// than a few dozen bytes. h1 and h2 are XXH64 of the key under two fixed
+// seeds. It is registered as "xxh64"; select it with
+// WithHasher(XXHasher{}).
+type XXHasher struct{}
+
+// Seeds of the two XXH64 passes made by XXHasher. They are part of the
+// persisted format and must never change.
+const (
+	xxHasherSeed1 = 0
+	xxHasherSeed2 = 0x9e3779b97f4a7c15
+)
+
+// Hash128 implements Hasher.
+func (XXHasher) Hash128(data []byte) (uint64, uint64) {
+	return xxhash64(data, xxHasherSeed1), xxhash64(data, xxHasherSeed2)
+}
+
+// xxhash64 is XXH64 (Yann Collet), matching the reference implementation
+// and github.com/cespare/xxhash for the given seed.
+func xxhash64(data []byte, seed uint64) uint64 {
+	length := uint64(len(data))
+	var h uint64
+
+	if len(data) >= 32 {
+		v1 := seed + xxPrime1 + xxPrime2
+		v2 := seed + xxPrime2
+		v3 := seed
+		v4 := seed - xxPrime1
+		for len(data) >= 32 {
+			v1 = xxRound(v1, binary.LittleEndian.Uint64(data[0:]))
+			v2 = xxRound(v2, binary.LittleEndian.Uint64(data[8:]))
+			v3 = xxRound(v3, binary.LittleEndian.Uint64(data[16:]))
+			v4 = xxRound(v4, binary.LittleEndian.Uint64(data[24:]))
+			data = data[32:]
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) +
+			bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMergeRound(h, v1)
+		h = xxMergeRound(h, v2)
+		h = xxMergeRound(h, v3)
+		h = xxMergeRound(h, v4)
+	} else {
+		h = seed + xxPrime5
+	}
+	h += length
+
+	for ; len(data) >= 8; data = data[8:] {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(data))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(data) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(data)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		data = data[4:]
+	}
+	for _, b := range data {
+		h ^= uint64(b) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMergeRound(acc, val uint64) uint64 {
+	acc ^= xxRound(0, val)
+	return acc*xxPrime1 + xxPrime4
+}
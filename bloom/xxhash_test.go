@@ -0,0 +1,94 @@
+package bloom
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Vectors from the reference XXH64, as published in cespare/xxhash's tests
+// and reproduced with its Sum64 for the longer inputs.
+func TestXXHash64(t *testing.T) {
+	cases := []struct {
+		input string
+		want  uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"as", 0x1c330fb2d66be179},
+		{"asd", 0x631c37ce72a97393},
+		{"asdf", 0x415872f599cea71e},
+		{"The quick brown fox jumps over the lazy dog.", 0x44ad33705751ad73},
+		{strings.Repeat("0123456789", 10), 0xf80e7b96315afffa},
+	}
+	for _, c := range cases {
+		if got := xxhash64([]byte(c.input), 0); got != c.want {
+			t.Errorf("xxhash64(%q) = %#x, want %#x", c.input, got, c.want)
+		}
+	}
+}
+
+func TestXXHasher(t *testing.T) {
+	// Pinned: filters persisted with "xxh64" depend on these values.
+	h1, h2 := XXHasher{}.Hash128([]byte("a"))
+	if h1 != 0xd24ec4f1a98c6e5b || h2 != xxhash64([]byte("a"), xxHasherSeed2) {
+		t.Fatalf("XXHasher.Hash128(\"a\") = %#x, %#x", h1, h2)
+	}
+
+	keys := randomKeys("xxh", 5000, 3)
+	bf, err := NewWithOptions(5000, 0.01, WithHasher(XXHasher{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf.AddAll(keys)
+	for _, k := range keys {
+		if !bf.MightContain(k) {
+			t.Fatalf("false negative for %q", k)
+		}
+	}
+	if bf.Hasher() != "xxh64" {
+		t.Fatalf("Hasher() = %q, want xxh64", bf.Hasher())
+	}
+	fp := 0
+	for _, k := range randomKeys("absent", 20000, 4) {
+		if bf.MightContain(k) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / 20000; rate > 0.02 {
+		t.Fatalf("false positive rate %.4f, want about 0.01", rate)
+	}
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got BloomFilter
+	if err := got.UnmarshalBinary(data); err != nil || !got.Equal(bf) {
+		t.Fatalf("round trip: %v", err)
+	}
+	if err := got.Merge(NewWithEstimates(5000, 0.01)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("merge with an FNV filter: %v", err)
+	}
+}
+
+func BenchmarkHasher(b *testing.B) {
+	for _, size := range []int{16, 256, 4096} {
+		key := []byte(strings.Repeat("k", size))
+		for _, h := range []Hasher{FNVHasher{}, XXHasher{}} {
+			bf, _ := NewWithOptions(1<<20, 0.01, WithHasher(h))
+			name := "fnv"
+			if _, ok := h.(XXHasher); ok {
+				name = "xxh64"
+			}
+			b.Run(fmt.Sprintf("%s/%dB", name, size), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					bf.Add(key)
+				}
+			})
+		}
+	}
+}
@@ -1,15 +1,20 @@
 package main
 
 import (
+	"expvar"
 	"fmt"
 
 	"github.com/Abhisheklearn12/bloom-filter/bloom"
+	"github.com/Abhisheklearn12/bloom-filter/bloom/metrics"
 )
 
 func main() {
 	bf := bloom.NewSafeWithEstimates(10000, 0.01)
 	fmt.Println(bf.Info())
 
+	// Served on /debug/vars by any http.Server using the default mux.
+	metrics.Publish("demo_filter", bf)
+
 	keys := [][]byte{
 		[]byte("abhi"),
 		[]byte("golang"),
@@ -31,4 +36,6 @@ func main() {
 	for _, c := range checks {
 		fmt.Printf("%s: %v\n", c, bf.MightContain(c))
 	}
+
+	fmt.Println("expvar demo_filter:", expvar.Get("demo_filter"))
 }